@@ -0,0 +1,38 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newBuildEnvironmentCollector returns a collector exposing details of the
+// build environment which are not part of node_exporter_build_info. The build
+// tags are already its tags label.
+func newBuildEnvironmentCollector() prometheus.Collector {
+	return prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "node_exporter",
+			Name:      "build_environment_info",
+			Help:      "A metric with a constant '1' value labeled by whether node_exporter was built with cgo and the libc it was linked against.",
+			ConstLabels: prometheus.Labels{
+				"cgo":  strconv.FormatBool(cgoEnabled),
+				"libc": libc,
+			},
+		},
+		func() float64 { return 1 },
+	)
+}
@@ -0,0 +1,37 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build cgo && linux
+// +build cgo,linux
+
+package main
+
+/*
+#include <features.h>
+
+// uClibc also defines __GLIBC__ for compatibility, so it has to be checked
+// first. musl deliberately doesn't provide a feature macro.
+#if defined(__UCLIBC__)
+#define NODE_EXPORTER_LIBC "uclibc"
+#elif defined(__GLIBC__)
+#define NODE_EXPORTER_LIBC "glibc"
+#else
+#define NODE_EXPORTER_LIBC "musl"
+#endif
+*/
+import "C"
+
+const (
+	cgoEnabled = true
+	libc       = C.NODE_EXPORTER_LIBC
+)
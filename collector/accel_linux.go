@@ -0,0 +1,183 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noaccel
+// +build !noaccel
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const accelCollectorSubsystem = "accel"
+
+// accelClasses are the sysfs classes accelerators register with. Habana
+// devices used their own class before the accel subsystem was added in Linux
+// 6.2, FPGAs are programmed through the FPGA manager.
+var accelClasses = []string{"accel", "habanalabs", "fpga_manager"}
+
+// accelBusyTimeFiles maps drivers to the device attribute holding the time
+// the accelerator was busy, in microseconds.
+var accelBusyTimeFiles = map[string]string{
+	"intel_vpu": "npu_busy_time_us",
+}
+
+// accelOperationalStates maps the attribute holding the state of a device to
+// the value it has if the device is usable.
+var accelOperationalStates = map[string]string{
+	"status": "operational", // habanalabs
+	"state":  "operating",   // fpga_manager
+}
+
+type accelCollector struct {
+	info        *prometheus.Desc
+	operational *prometheus.Desc
+	busy        *prometheus.Desc
+	temperature *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(accelCollectorSubsystem, defaultDisabled, NewAccelCollector)
+}
+
+// NewAccelCollector returns a new Collector exposing inventory and basic
+// statistics of compute accelerators like NPUs and FPGAs.
+func NewAccelCollector(logger log.Logger) (Collector, error) {
+	return &accelCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, accelCollectorSubsystem, "info"),
+			"Information about an accelerator, the PCI IDs are empty for other buses.",
+			[]string{"device", "class", "driver", "model", "pci_vendor", "pci_device"}, nil,
+		),
+		operational: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, accelCollectorSubsystem, "operational"),
+			"Whether the driver reports the accelerator as operational.",
+			[]string{"device"}, nil,
+		),
+		busy: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, accelCollectorSubsystem, "busy_seconds_total"),
+			"Time the accelerator was busy executing work.",
+			[]string{"device"}, nil,
+		),
+		temperature: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, accelCollectorSubsystem, "temperature_celsius"),
+			"Temperature reported by a hwmon sensor of the accelerator.",
+			[]string{"device", "sensor"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *accelCollector) Update(ch chan<- prometheus.Metric) error {
+	// Habana devices are registered with both the accel and the habanalabs
+	// class on newer kernels, they are exposed once, under the first class.
+	seen := map[string]bool{}
+	for _, class := range accelClasses {
+		devices, err := filepath.Glob(sysFilePath(filepath.Join("class", class, "*")))
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			parent, err := filepath.EvalSymlinks(filepath.Join(device, "device"))
+			if err != nil {
+				parent = device
+			}
+			if seen[parent] {
+				continue
+			}
+			seen[parent] = true
+			if err := c.updateDevice(ch, class, device); err != nil {
+				return fmt.Errorf("couldn't get statistics of accelerator %s: %w", filepath.Base(device), err)
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return ErrNoData
+	}
+	return nil
+}
+
+func (c *accelCollector) updateDevice(ch chan<- prometheus.Metric, class, device string) error {
+	name := filepath.Base(device)
+	driver := sysfsDeviceDriver(device)
+	// The PCI IDs and the hwmon devices belong to the parent device.
+	parent := filepath.Join(device, "device")
+
+	model := accelAttribute(device, "device_type")
+	if model == "" {
+		model = accelAttribute(device, "name")
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+		name, class, driver, model,
+		accelAttribute(parent, "vendor"), accelAttribute(parent, "device"))
+
+	for attr, operational := range accelOperationalStates {
+		state := accelAttribute(device, attr)
+		if state == "" {
+			continue
+		}
+		value := 0.0
+		if state == operational {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.operational, prometheus.GaugeValue, value, name)
+	}
+
+	if file, ok := accelBusyTimeFiles[driver]; ok {
+		busy, err := readUintFromFile(filepath.Join(parent, file))
+		if err == nil {
+			ch <- prometheus.MustNewConstMetric(c.busy, prometheus.CounterValue, float64(busy)/1e6, name)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	temps, err := filepath.Glob(filepath.Join(parent, "hwmon/hwmon*/temp*_input"))
+	if err != nil {
+		return err
+	}
+	for _, temp := range temps {
+		millidegrees, err := readIntFromFile(temp)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		sensor := strings.TrimSuffix(filepath.Base(temp), "_input")
+		if label := accelAttribute(filepath.Dir(temp), sensor+"_label"); label != "" {
+			sensor = label
+		}
+		ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, float64(millidegrees)/1e3, name, sensor)
+	}
+	return nil
+}
+
+// accelAttribute returns the trimmed content of a sysfs attribute, or an empty
+// string if it can't be read.
+func accelAttribute(dir, attr string) string {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var resourceAccounting = kingpin.Flag(
+	"collector.resource-accounting",
+	"Account the CPU time and the memory allocated by each collector. Collectors then run one at a time, so that the usage can be attributed to them.",
+).Bool()
+
+var (
+	scrapeCPUDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_cpu_seconds_total"),
+		"node_exporter: CPU time used by the exporter while running a collector.",
+		[]string{"collector"},
+		nil,
+	)
+	scrapeAllocDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_allocated_bytes_total"),
+		"node_exporter: Heap memory allocated by the exporter while running a collector.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// heapAllocsMetric is the runtime metric counting the bytes allocated on the
+// heap.
+const heapAllocsMetric = "/gc/heap/allocs:bytes"
+
+// collectorUsage is the resource usage accounted to a collector since the
+// start of the exporter.
+type collectorUsage struct {
+	cpuTime        time.Duration
+	allocatedBytes uint64
+}
+
+var (
+	// accountingMtx serializes collectors while accounting, across
+	// concurrent scrapes too, and protects accountedUsage.
+	accountingMtx  sync.Mutex
+	accountedUsage = map[string]*collectorUsage{}
+)
+
+// resourceSample is a reading of the resource usage of the process.
+type resourceSample struct {
+	cpuTime        time.Duration
+	cpuOK          bool
+	allocatedBytes uint64
+}
+
+func sampleResources() resourceSample {
+	s := resourceSample{}
+	s.cpuTime, s.cpuOK = processCPUTime()
+	sample := []metrics.Sample{{Name: heapAllocsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() == metrics.KindUint64 {
+		s.allocatedBytes = sample[0].Value.Uint64()
+	}
+	return s
+}
+
+// collectAccounted runs the collectors one after the other and accounts the
+// resources used meanwhile to them. The CPU time and allocations are the ones
+// of the whole process, which includes the garbage collector and the serving
+// of concurrent requests.
+func (n NodeCollector) collectAccounted(ch chan<- prometheus.Metric, snapshot *procSnapshot) {
+	accountingMtx.Lock()
+	defer accountingMtx.Unlock()
+	for name, c := range n.Collectors {
+		before := sampleResources()
+		execute(name, c, ch, n.logger, snapshot)
+		after := sampleResources()
+
+		usage, ok := accountedUsage[name]
+		if !ok {
+			usage = &collectorUsage{}
+			accountedUsage[name] = usage
+		}
+		usage.allocatedBytes += after.allocatedBytes - before.allocatedBytes
+		ch <- prometheus.MustNewConstMetric(scrapeAllocDesc, prometheus.CounterValue, float64(usage.allocatedBytes), name)
+		if before.cpuOK && after.cpuOK {
+			usage.cpuTime += after.cpuTime - before.cpuTime
+			ch <- prometheus.MustNewConstMetric(scrapeCPUDesc, prometheus.CounterValue, usage.cpuTime.Seconds(), name)
+		}
+	}
+}
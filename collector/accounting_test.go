@@ -0,0 +1,60 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type allocatingCollector struct {
+	sink [][]byte
+}
+
+func (c *allocatingCollector) Update(ch chan<- prometheus.Metric) error {
+	for i := 0; i < 64; i++ {
+		c.sink = append(c.sink, make([]byte, 1<<16))
+	}
+	return nil
+}
+
+func TestCollectAccounted(t *testing.T) {
+	n := NodeCollector{
+		Collectors: map[string]Collector{"allocating": &allocatingCollector{}},
+		logger:     log.NewNopLogger(),
+	}
+	defer delete(accountedUsage, "allocating")
+
+	ch := make(chan prometheus.Metric, 16)
+	n.collectAccounted(ch, nil)
+	close(ch)
+
+	var allocated float64
+	for m := range ch {
+		if m.Desc() != scrapeAllocDesc {
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		allocated = pb.GetCounter().GetValue()
+	}
+	if allocated < 64<<16 {
+		t.Errorf("want at least %d allocated bytes accounted, got %v", 64<<16, allocated)
+	}
+}
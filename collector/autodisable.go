@@ -0,0 +1,94 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var autoDisabledDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "collector", "auto_disabled"),
+	"node_exporter: Collector disabled at startup as the hardware or kernel feature it exposes wasn't found.",
+	[]string{"collector", "reason"},
+	nil,
+)
+
+// hardwareProbe reports whether what a collector exposes is present.
+type hardwareProbe struct {
+	reason  string
+	present func() bool
+}
+
+var (
+	hardwareProbes = map[string]hardwareProbe{}
+	// autoDisabledCollectors holds the reason by collector disabled by
+	// AutoDisableCollectors.
+	autoDisabledCollectors = map[string]string{}
+)
+
+// registerHardwareProbe registers a check run by AutoDisableCollectors to
+// find out whether the collector applies to the machine. reason describes
+// what is missing if it doesn't.
+func registerHardwareProbe(collector, reason string, present func() bool) {
+	hardwareProbes[collector] = hardwareProbe{reason: reason, present: present}
+}
+
+// sysfsProbe returns a probe reporting whether any path matches the pattern
+// below the sysfs mountpoint.
+func sysfsProbe(pattern string) func() bool {
+	return func() bool {
+		matches, err := filepath.Glob(sysFilePath(pattern))
+		return err == nil && len(matches) > 0
+	}
+}
+
+// procfsProbe returns a probe reporting whether any path matches the pattern
+// below the procfs mountpoint.
+func procfsProbe(pattern string) func() bool {
+	return func() bool {
+		matches, err := filepath.Glob(procFilePath(pattern))
+		return err == nil && len(matches) > 0
+	}
+}
+
+// AutoDisableCollectors disables the enabled collectors whose hardware probe
+// doesn't find what they expose, unless they have been explicitly enabled on
+// the command line.
+func AutoDisableCollectors(logger log.Logger) {
+	names := make([]string, 0, len(hardwareProbes))
+	for name := range hardwareProbes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		probe := hardwareProbes[name]
+		if !*collectorState[name] || forcedCollectors[name] || probe.present() {
+			continue
+		}
+		level.Info(logger).Log("msg", "Disabling collector", "collector", name, "reason", probe.reason)
+		*collectorState[name] = false
+		autoDisabledCollectors[name] = probe.reason
+	}
+}
+
+func collectAutoDisabled(ch chan<- prometheus.Metric) {
+	for name, reason := range autoDisabledCollectors {
+		ch <- prometheus.MustNewConstMetric(autoDisabledDesc, prometheus.GaugeValue, 1, name, reason)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestAutoDisableCollectors(t *testing.T) {
+	savedProbes, savedState := hardwareProbes, collectorState
+	defer func() {
+		hardwareProbes, collectorState = savedProbes, savedState
+		autoDisabledCollectors = map[string]string{}
+		delete(forcedCollectors, "forced")
+	}()
+
+	enabled := func() *bool { b := true; return &b }
+	collectorState = map[string]*bool{
+		"present": enabled(),
+		"absent":  enabled(),
+		"forced":  enabled(),
+	}
+	forcedCollectors["forced"] = true
+	hardwareProbes = map[string]hardwareProbe{}
+	registerHardwareProbe("present", "none", func() bool { return true })
+	registerHardwareProbe("absent", "no device", func() bool { return false })
+	registerHardwareProbe("forced", "no device", func() bool { return false })
+
+	AutoDisableCollectors(log.NewNopLogger())
+
+	for name, want := range map[string]bool{"present": true, "absent": false, "forced": true} {
+		if *collectorState[name] != want {
+			t.Errorf("%s: want enabled %t, got %t", name, want, *collectorState[name])
+		}
+	}
+	if len(autoDisabledCollectors) != 1 || autoDisabledCollectors["absent"] != "no device" {
+		t.Errorf("unexpected auto-disabled collectors %v", autoDisabledCollectors)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noautofs
+// +build !noautofs
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+const autofsSubsystem = "autofs"
+
+var (
+	autofsLogFile        = kingpin.Flag("collector.autofs.log-file", "Log file of the automount daemon to count the mount failures in, like /var/log/messages. Failures aren't counted if empty.").String()
+	autofsFailurePattern = kingpin.Flag("collector.autofs.failure-pattern", "Regexp of the lines of the log file counted as mount failures.").Default(`automount\[[0-9]+\]: .*(failed to mount|mount failure)`).String()
+)
+
+// autofsMountPointReplacer handles the translation of \040 and \011 in
+// mountinfo as per fstab(5).
+var autofsMountPointReplacer = strings.NewReplacer(`\040`, " ", `\011`, "\t")
+
+type autofsCollector struct {
+	fs             procfs.FS
+	failurePattern *regexp.Regexp
+
+	mapInfo  *prometheus.Desc
+	timeout  *prometheus.Desc
+	mounted  *prometheus.Desc
+	expired  *prometheus.Desc
+	failures *prometheus.Desc
+	logger   log.Logger
+
+	mtx sync.Mutex
+	// entries are the autofs mount IDs of the entries mounted at the last
+	// scrape, by the mount ID of the entry.
+	entries      map[int]int
+	expiredCount map[string]float64
+	// The log file is read from the offset it was read to at the last
+	// scrape, the end of the file at the first one.
+	logStarted   bool
+	logInfo      os.FileInfo
+	logOffset    int64
+	failureCount float64
+}
+
+func init() {
+	registerCollector(autofsSubsystem, defaultDisabled, NewAutofsCollector)
+}
+
+// NewAutofsCollector returns a new Collector exposing the autofs mounts of
+// the automount maps, their mounted and expired entries and the mount
+// failures logged by the automount daemon.
+func NewAutofsCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	failurePattern, err := regexp.Compile(*autofsFailurePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --collector.autofs.failure-pattern: %w", err)
+	}
+	return &autofsCollector{
+		fs:             fs,
+		failurePattern: failurePattern,
+		mapInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "map_info"),
+			"Automount map of the autofs mount point and whether it is direct, indirect or an offset of a multi-mount entry.",
+			[]string{"mountpoint", "map", "type"}, nil,
+		),
+		timeout: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "timeout_seconds"),
+			"Seconds an unused entry of the autofs mount point stays mounted before it expires, 0 if never.",
+			[]string{"mountpoint"}, nil,
+		),
+		mounted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "mounted_entries"),
+			"Number of entries currently mounted on the autofs mount point.",
+			[]string{"mountpoint"}, nil,
+		),
+		expired: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "expired_entries_total"),
+			"Number of entries of the autofs mount point seen unmounted since the exporter started, entries mounted and unmounted between two scrapes aren't counted.",
+			[]string{"mountpoint"}, nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, autofsSubsystem, "mount_failures_total"),
+			"Number of lines of the log file of the automount daemon matching --collector.autofs.failure-pattern.",
+			nil, nil,
+		),
+		logger:       logger,
+		entries:      map[int]int{},
+		expiredCount: map[string]float64{},
+	}, nil
+}
+
+func (c *autofsCollector) Update(ch chan<- prometheus.Metric) error {
+	mounts, err := c.mountInfo()
+	if err != nil {
+		return fmt.Errorf("couldn't get mountinfo: %w", err)
+	}
+	autofs := map[int]*procfs.MountInfo{}
+	for _, m := range mounts {
+		if m.FSType == "autofs" {
+			autofs[m.MountID] = m
+		}
+	}
+	if len(autofs) == 0 && *autofsLogFile == "" {
+		return ErrNoData
+	}
+	// The entries are the mounts on the autofs mounts, over the mount point
+	// of direct maps or below that of indirect ones. The offsets of
+	// multi-mount entries are autofs mounts themselves.
+	entries := map[int]int{}
+	mounted := map[int]float64{}
+	for _, m := range mounts {
+		if _, ok := autofs[m.ParentID]; ok && m.FSType != "autofs" {
+			entries[m.MountID] = m.ParentID
+			mounted[m.ParentID]++
+		}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for id, parent := range c.entries {
+		// The entries of stopped automount maps didn't expire.
+		if _, ok := entries[id]; !ok && autofs[parent] != nil {
+			c.expiredCount[autofsMountPoint(autofs[parent])]++
+		}
+	}
+	c.entries = entries
+
+	seen := map[string]bool{}
+	for id, m := range autofs {
+		mountPoint := autofsMountPoint(m)
+		if seen[mountPoint] {
+			level.Debug(c.logger).Log("msg", "Ignoring autofs mount over another one", "mountpoint", mountPoint)
+			continue
+		}
+		seen[mountPoint] = true
+
+		mapType := "indirect"
+		for _, t := range []string{"direct", "offset"} {
+			if _, ok := m.SuperOptions[t]; ok {
+				mapType = t
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(c.mapInfo, prometheus.GaugeValue, 1, mountPoint, m.Source, mapType)
+		if value, ok := m.SuperOptions["timeout"]; ok {
+			timeout, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid timeout %q of autofs mount %s: %w", value, mountPoint, err)
+			}
+			ch <- prometheus.MustNewConstMetric(c.timeout, prometheus.GaugeValue, timeout, mountPoint)
+		}
+		ch <- prometheus.MustNewConstMetric(c.mounted, prometheus.GaugeValue, mounted[id], mountPoint)
+		ch <- prometheus.MustNewConstMetric(c.expired, prometheus.CounterValue, c.expiredCount[mountPoint], mountPoint)
+	}
+
+	if *autofsLogFile != "" {
+		if err := c.readLog(); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("couldn't read automount log: %w", err)
+			}
+			// The log file is being rotated.
+			level.Debug(c.logger).Log("msg", "Automount log file missing", "err", err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.failures, prometheus.CounterValue, c.failureCount)
+	}
+	return nil
+}
+
+// mountInfo returns the mounts of the mount namespace of init, or of the own
+// one if the processes of others are hidden.
+func (c *autofsCollector) mountInfo() ([]*procfs.MountInfo, error) {
+	p, err := c.fs.Proc(1)
+	if err == nil {
+		var mounts []*procfs.MountInfo
+		if mounts, err = p.MountInfo(); err == nil {
+			return mounts, nil
+		}
+	}
+	level.Debug(c.logger).Log("msg", "Reading root mountinfo failed, falling back to own mountinfo", "err", err)
+	if p, err = c.fs.Self(); err != nil {
+		return nil, err
+	}
+	return p.MountInfo()
+}
+
+func autofsMountPoint(m *procfs.MountInfo) string {
+	return rootfsStripPrefix(autofsMountPointReplacer.Replace(m.MountPoint))
+}
+
+// readLog counts the mount failures in the lines appended to the log file
+// since the last scrape, c.mtx must be held. A rotated or truncated log file
+// is read from its start, the lines appended to the old one after the last
+// scrape aren't counted.
+func (c *autofsCollector) readLog() error {
+	first := !c.logStarted
+	c.logStarted = true
+	file, err := os.Open(*autofsLogFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	switch {
+	case first:
+		c.logOffset = info.Size()
+	case c.logInfo == nil, !os.SameFile(c.logInfo, info), info.Size() < c.logOffset:
+		c.logOffset = 0
+	}
+	c.logInfo = info
+	if _, err := file.Seek(c.logOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// A partial last line is read again once complete.
+				return nil
+			}
+			return err
+		}
+		c.logOffset += int64(len(line))
+		if c.failurePattern.MatchString(line) {
+			c.failureCount++
+		}
+	}
+}
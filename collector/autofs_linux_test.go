@@ -0,0 +1,116 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noautofs
+// +build !noautofs
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAutofs(t *testing.T) {
+	dir := t.TempDir()
+	defer func(path string) { *procPath = path }(*procPath)
+	*procPath = filepath.Join(dir, "proc")
+	if err := os.MkdirAll(filepath.Join(*procPath, "1"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	*autofsLogFile = filepath.Join(dir, "messages")
+	*autofsFailurePattern = `automount\[[0-9]+\]: .*(failed to mount|mount failure)`
+
+	const (
+		home  = "47 21 0:42 / /home rw,relatime - autofs auto.home rw,fd=7,pgrp=1234,timeout=600,minproto=5,maxproto=5,indirect\n"
+		alice = "48 47 0:43 / /home/alice rw,relatime - nfs4 fileserver:/export/home/alice rw,vers=4.2\n"
+		bob   = "49 47 0:44 / /home/bob rw,relatime - nfs4 fileserver:/export/home/bob rw,vers=4.2\n"
+	)
+	write := func(path, content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendLog := func(lines string) {
+		f, err := os.OpenFile(*autofsLogFile, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(lines); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mountinfo := filepath.Join(*procPath, "1", "mountinfo")
+	write(mountinfo, home+alice+bob)
+	// Failures logged before the exporter started aren't counted.
+	appendLog("Oct 15 10:00:00 host automount[1234]: failed to mount /home/carol\n")
+
+	c, err := NewAutofsCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+	check := func(mounted, expired, failures string) {
+		t.Helper()
+		want := `# HELP node_autofs_expired_entries_total Number of entries of the autofs mount point seen unmounted since the exporter started, entries mounted and unmounted between two scrapes aren't counted.
+# TYPE node_autofs_expired_entries_total counter
+node_autofs_expired_entries_total{mountpoint="/home"} ` + expired + `
+# HELP node_autofs_map_info Automount map of the autofs mount point and whether it is direct, indirect or an offset of a multi-mount entry.
+# TYPE node_autofs_map_info gauge
+node_autofs_map_info{map="auto.home",mountpoint="/home",type="indirect"} 1
+# HELP node_autofs_mount_failures_total Number of lines of the log file of the automount daemon matching --collector.autofs.failure-pattern.
+# TYPE node_autofs_mount_failures_total counter
+node_autofs_mount_failures_total ` + failures + `
+# HELP node_autofs_mounted_entries Number of entries currently mounted on the autofs mount point.
+# TYPE node_autofs_mounted_entries gauge
+node_autofs_mounted_entries{mountpoint="/home"} ` + mounted + `
+# HELP node_autofs_timeout_seconds Seconds an unused entry of the autofs mount point stays mounted before it expires, 0 if never.
+# TYPE node_autofs_timeout_seconds gauge
+node_autofs_timeout_seconds{mountpoint="/home"} 600
+`
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check("2", "0", "0")
+
+	// bob expired, a partial line is only counted once complete.
+	write(mountinfo, home+alice)
+	appendLog("Oct 15 10:05:00 host automount[1234]: mount(nfs): nfs: mount failure fileserver:/export/home/carol on /home/carol\n" +
+		"Oct 15 10:05:01 host automount[1234]: lookup(file): lookup for dave failed\n" +
+		"Oct 15 10:05:02 host automount[1234]: failed to mount")
+	check("1", "1", "1")
+	appendLog(" /home/carol\n")
+	check("1", "1", "2")
+
+	// The entries of a stopped automount map didn't expire.
+	write(mountinfo, "")
+	if err := c.Update(make(chan prometheus.Metric, 100)); err != nil {
+		t.Fatal(err)
+	}
+	write(mountinfo, home)
+	// The rotated log file is read from its start.
+	if err := os.Rename(*autofsLogFile, *autofsLogFile+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendLog("Oct 15 10:10:00 host automount[1234]: failed to mount /home/erin\n")
+	check("0", "1", "3")
+}
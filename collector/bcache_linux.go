@@ -31,6 +31,7 @@ var (
 
 func init() {
 	registerCollector("bcache", defaultEnabled, NewBcacheCollector)
+	registerHardwareProbe("bcache", "bcache not loaded", sysfsProbe("fs/bcache"))
 }
 
 // A bcacheCollector is a Collector which gathers metrics from Linux bcache.
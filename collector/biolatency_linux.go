@@ -0,0 +1,415 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build ebpf && !nobiolatency
+// +build ebpf,!nobiolatency
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	biolatencySubsystem = "biolatency"
+
+	// Latencies are counted in power of two buckets of microseconds, the
+	// last one also holding everything above. The sum of all latencies in
+	// nanoseconds is stored in an extra bucket.
+	biolatencyBuckets   = 27
+	biolatencySumBucket = 64
+
+	biolatencyMaxRequests = 10240
+	biolatencyMaxKeys     = 4096
+)
+
+// biolatencyOperations are the values of the operation label, indexed by the
+// operation number computed by the BPF program.
+var biolatencyOperations = []string{"read", "write", "discard", "other"}
+
+var tracepointFieldRE = regexp.MustCompile(`field:[^;]*?(\w+)(\[\d+\])?;\s*offset:(\d+);`)
+
+type biolatencyCollector struct {
+	latency *prometheus.Desc
+	logger  log.Logger
+
+	mtx   sync.Mutex
+	hist  *ebpf.Map
+	links []link.Link
+}
+
+// biolatencyKey is the key of the histogram map, it must match the layout
+// written by the BPF program.
+type biolatencyKey struct {
+	Dev    uint32
+	Op     uint32
+	Bucket uint32
+}
+
+func init() {
+	registerCollector(biolatencySubsystem, defaultDisabled, NewBiolatencyCollector)
+}
+
+// NewBiolatencyCollector returns a new Collector exposing block device I/O
+// latency histograms gathered by eBPF programs attached to the block layer
+// tracepoints.
+func NewBiolatencyCollector(logger log.Logger) (Collector, error) {
+	return &biolatencyCollector{
+		latency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, biolatencySubsystem, "seconds"),
+			"Histogram of the time between issuing a block I/O request to the device and its completion.",
+			[]string{"device", "operation"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *biolatencyCollector) Update(ch chan<- prometheus.Metric) error {
+	// The programs are loaded on first use and stay attached for the
+	// lifetime of the exporter. Failed loads are retried on the next scrape,
+	// e.g. once tracefs is mounted.
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.hist == nil {
+		if err := c.load(); err != nil {
+			return fmt.Errorf("couldn't load eBPF programs: %w", err)
+		}
+	}
+
+	type series struct {
+		dev uint32
+		op  uint32
+	}
+	counts := map[series][]uint64{}
+	sums := map[series]uint64{}
+	var (
+		key   biolatencyKey
+		value uint64
+	)
+	entries := c.hist.Iterate()
+	for entries.Next(&key, &value) {
+		s := series{key.Dev, key.Op}
+		if key.Bucket == biolatencySumBucket {
+			sums[s] = value
+			continue
+		}
+		if counts[s] == nil {
+			counts[s] = make([]uint64, biolatencyBuckets)
+		}
+		b := key.Bucket
+		if b >= biolatencyBuckets {
+			b = biolatencyBuckets - 1
+		}
+		counts[s][b] += value
+	}
+	if err := entries.Err(); err != nil {
+		return fmt.Errorf("couldn't read latency histogram: %w", err)
+	}
+
+	for s, bucketCounts := range counts {
+		if int(s.op) >= len(biolatencyOperations) {
+			continue
+		}
+		// The kernel encodes dev_t with 20 bits for the minor number.
+		device := blockDeviceName(fmt.Sprintf("%d:%d", s.dev>>20, s.dev&(1<<20-1)))
+
+		buckets := make(map[float64]uint64, biolatencyBuckets-1)
+		var count uint64
+		for b, n := range bucketCounts {
+			count += n
+			if b < biolatencyBuckets-1 {
+				buckets[math.Ldexp(1, b+1)/1e6] = count
+			}
+		}
+		ch <- prometheus.MustNewConstHistogram(c.latency, count, float64(sums[s])/1e9, buckets,
+			device, biolatencyOperations[s.op])
+	}
+	return nil
+}
+
+// load creates the maps and attaches the programs, everything created is
+// closed again if it fails.
+func (c *biolatencyCollector) load() (err error) {
+	var resources []io.Closer
+	defer func() {
+		if err != nil {
+			for i := len(resources) - 1; i >= 0; i-- {
+				resources[i].Close()
+			}
+		}
+	}()
+
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return err
+	}
+	tracefs, err := tracefsPath()
+	if err != nil {
+		return err
+	}
+	issueFields, err := tracepointFields(filepath.Join(tracefs, "events/block/block_rq_issue/format"))
+	if err != nil {
+		return err
+	}
+	completeFields, err := tracepointFields(filepath.Join(tracefs, "events/block/block_rq_complete/format"))
+	if err != nil {
+		return err
+	}
+
+	start, err := ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "biolat_start",
+		Type:       ebpf.Hash,
+		KeySize:    16,
+		ValueSize:  8,
+		MaxEntries: biolatencyMaxRequests,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create map: %w", err)
+	}
+	resources = append(resources, start)
+	hist, err := ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "biolat_hist",
+		Type:       ebpf.Hash,
+		KeySize:    12,
+		ValueSize:  8,
+		MaxEntries: biolatencyMaxKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create map: %w", err)
+	}
+	resources = append(resources, hist)
+
+	issue, err := biolatencyProgram("biolat_issue", biolatencyIssueInstructions(start.FD(), issueFields))
+	if err != nil {
+		return err
+	}
+	resources = append(resources, issue)
+	complete, err := biolatencyProgram("biolat_complete", biolatencyCompleteInstructions(start.FD(), hist.FD(), completeFields))
+	if err != nil {
+		return err
+	}
+	resources = append(resources, complete)
+	var links []link.Link
+	for name, prog := range map[string]*ebpf.Program{"block_rq_issue": issue, "block_rq_complete": complete} {
+		l, err := link.Tracepoint("block", name, prog, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't attach to tracepoint %s: %w", name, err)
+		}
+		resources = append(resources, l)
+		links = append(links, l)
+	}
+	c.hist, c.links = hist, links
+	return nil
+}
+
+func biolatencyProgram(name string, insns asm.Instructions) (*ebpf.Program, error) {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Name:         name,
+		Type:         ebpf.TracePoint,
+		License:      "Apache-2.0",
+		Instructions: insns,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load program %s: %w", name, err)
+	}
+	return prog, nil
+}
+
+// biolatencyRequestKey returns instructions storing the key identifying the
+// request of the tracepoint record in R6 at FP-16.
+func biolatencyRequestKey(fields map[string]int16) asm.Instructions {
+	return asm.Instructions{
+		asm.LoadMem(asm.R2, asm.R6, fields["sector"], asm.DWord),
+		asm.StoreMem(asm.RFP, -16, asm.R2, asm.DWord),
+		asm.LoadMem(asm.R2, asm.R6, fields["dev"], asm.Word),
+		asm.StoreMem(asm.RFP, -8, asm.R2, asm.Word),
+		asm.StoreImm(asm.RFP, -4, 0, asm.Word),
+	}
+}
+
+// biolatencyIssueInstructions records the time each request is issued.
+func biolatencyIssueInstructions(start int, fields map[string]int16) asm.Instructions {
+	insns := asm.Instructions{asm.Mov.Reg(asm.R6, asm.R1)}
+	insns = append(insns, biolatencyRequestKey(fields)...)
+	return append(insns,
+		asm.FnKtimeGetNs.Call(),
+		asm.StoreMem(asm.RFP, -24, asm.R0, asm.DWord),
+		asm.LoadMapPtr(asm.R1, start),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -16),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -24),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Imm(asm.R0, 0),
+		asm.Return(),
+	)
+}
+
+// biolatencyCompleteInstructions computes the latency of each completed
+// request and adds it to the histogram.
+func biolatencyCompleteInstructions(start, hist int, fields map[string]int16) asm.Instructions {
+	insns := asm.Instructions{asm.Mov.Reg(asm.R6, asm.R1)}
+	insns = append(insns, biolatencyRequestKey(fields)...)
+	insns = append(insns,
+		// R7 = start[key], skipping requests issued before the program was
+		// attached.
+		asm.LoadMapPtr(asm.R1, start),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -16),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		asm.LoadMem(asm.R7, asm.R0, 0, asm.DWord),
+		asm.LoadMapPtr(asm.R1, start),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -16),
+		asm.FnMapDeleteElem.Call(),
+
+		// R8 = latency in nanoseconds.
+		asm.FnKtimeGetNs.Call(),
+		asm.Mov.Reg(asm.R8, asm.R0),
+		asm.Sub.Reg(asm.R8, asm.R7),
+
+		// Histogram key at FP-40: dev, operation, bucket. The operation is
+		// the first letter of rwbs after an optional F for a preflush.
+		asm.LoadMem(asm.R2, asm.R6, fields["dev"], asm.Word),
+		asm.StoreMem(asm.RFP, -40, asm.R2, asm.Word),
+		asm.LoadMem(asm.R2, asm.R6, fields["rwbs"], asm.Byte),
+		asm.JNE.Imm(asm.R2, 'F', "op"),
+		asm.LoadMem(asm.R2, asm.R6, fields["rwbs"]+1, asm.Byte),
+		asm.Mov.Imm(asm.R3, 0).WithSymbol("op"),
+		asm.JEq.Imm(asm.R2, 'R', "op_done"),
+		asm.Mov.Imm(asm.R3, 1),
+		asm.JEq.Imm(asm.R2, 'W', "op_done"),
+		asm.Mov.Imm(asm.R3, 2),
+		asm.JEq.Imm(asm.R2, 'D', "op_done"),
+		asm.Mov.Imm(asm.R3, 3),
+		asm.StoreMem(asm.RFP, -36, asm.R3, asm.Word).WithSymbol("op_done"),
+
+		// R2 = floor(log2(latency in microseconds)).
+		asm.Mov.Reg(asm.R1, asm.R8),
+		asm.Div.Imm(asm.R1, 1000),
+		asm.Mov.Imm(asm.R2, 0),
+	)
+	for _, shift := range []int32{32, 16, 8, 4, 2, 1} {
+		label := fmt.Sprintf("log2_%d", shift)
+		insns = append(insns,
+			asm.Mov.Reg(asm.R3, asm.R1),
+			asm.RSh.Imm(asm.R3, shift),
+			asm.JEq.Imm(asm.R3, 0, label),
+			asm.Mov.Reg(asm.R1, asm.R3),
+			asm.Add.Imm(asm.R2, shift),
+			asm.Mov.Imm(asm.R0, 0).WithSymbol(label),
+		)
+	}
+	insns = append(insns, asm.StoreMem(asm.RFP, -32, asm.R2, asm.Word))
+	insns = append(insns, biolatencyHistAdd(hist, "count", 1)...)
+	insns = append(insns, asm.StoreImm(asm.RFP, -32, biolatencySumBucket, asm.Word))
+	insns = append(insns, biolatencyHistAdd(hist, "sum", asm.R8)...)
+	return append(insns,
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+		asm.Return(),
+	)
+}
+
+// biolatencyHistAdd returns instructions adding either a constant or the
+// value of a register to the histogram entry whose key is at FP-40.
+func biolatencyHistAdd(hist int, label string, value interface{}) asm.Instructions {
+	insns := asm.Instructions{}
+	switch v := value.(type) {
+	case asm.Register:
+		insns = append(insns, asm.StoreMem(asm.RFP, -48, v, asm.DWord))
+	case int:
+		insns = append(insns, asm.StoreImm(asm.RFP, -48, int64(v), asm.DWord))
+	}
+	return append(insns,
+		asm.LoadMapPtr(asm.R1, hist),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -40),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, label+"_new"),
+		asm.LoadMem(asm.R1, asm.RFP, -48, asm.DWord),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Ja.Label(label+"_done"),
+		// Racing with another CPU creating the entry loses the value, which
+		// only happens for its very first one.
+		asm.LoadMapPtr(asm.R1, hist).WithSymbol(label+"_new"),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -40),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -48),
+		asm.Mov.Imm(asm.R4, int32(ebpf.UpdateNoExist)),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Imm(asm.R0, 0).WithSymbol(label+"_done"),
+	)
+}
+
+// tracefsPath returns the mountpoint of tracefs.
+func tracefsPath() (string, error) {
+	for _, p := range []string{"kernel/tracing", "kernel/debug/tracing"} {
+		path := sysFilePath(p)
+		if _, err := os.Stat(filepath.Join(path, "events")); err == nil {
+			return path, nil
+		}
+	}
+	return "", errors.New("tracefs not found")
+}
+
+// tracepointFields returns the offsets of the dev, sector and rwbs fields
+// from the format file of a block tracepoint, as their layout changes
+// between kernel versions.
+func tracepointFields(path string) (map[string]int16, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fields := map[string]int16{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		m := tracepointFieldRE.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		offset, err := strconv.ParseInt(m[3], 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset of field %s: %w", m[1], err)
+		}
+		fields[m[1]] = int16(offset)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"dev", "sector", "rwbs"} {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("field %s not found in %s", name, path)
+		}
+	}
+	return fields, nil
+}
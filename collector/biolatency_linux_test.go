@@ -0,0 +1,87 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build ebpf && !nobiolatency
+// +build ebpf,!nobiolatency
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/asm"
+)
+
+// blockRqCompleteFormat is the format of the block_rq_complete tracepoint of
+// Linux 6.1.
+const blockRqCompleteFormat = `name: block_rq_complete
+ID: 1334
+format:
+	field:unsigned short common_type;	offset:0;	size:2;	signed:0;
+	field:unsigned char common_flags;	offset:2;	size:1;	signed:0;
+	field:unsigned char common_preempt_count;	offset:3;	size:1;	signed:0;
+	field:int common_pid;	offset:4;	size:4;	signed:1;
+
+	field:dev_t dev;	offset:8;	size:4;	signed:0;
+	field:sector_t sector;	offset:16;	size:8;	signed:0;
+	field:unsigned int nr_sector;	offset:24;	size:4;	signed:0;
+	field:int error;	offset:28;	size:4;	signed:1;
+	field:char rwbs[8];	offset:32;	size:8;	signed:1;
+	field:__data_loc char[] cmd;	offset:40;	size:4;	signed:1;
+
+print fmt: "%d,%d %s (%s) %llu + %u [%d]", ((unsigned int) ((REC->dev) >> 20)), ((unsigned int) ((REC->dev) & ((1U << 20) - 1))), REC->rwbs, __get_str(cmd), (unsigned long long)REC->sector, REC->nr_sector, REC->error
+`
+
+func TestTracepointFields(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "format")
+	if err := os.WriteFile(path, []byte(blockRqCompleteFormat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := tracepointFields(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int16{"common_type": 0, "dev": 8, "sector": 16, "error": 28, "rwbs": 32, "cmd": 40} {
+		if got, ok := fields[name]; !ok || got != want {
+			t.Errorf("field %s: want offset %d, got %d (found %v)", name, want, got, ok)
+		}
+	}
+
+	if err := os.WriteFile(path, bytes.Replace([]byte(blockRqCompleteFormat), []byte("rwbs[8]"), []byte("flags[8]"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tracepointFields(path); err == nil {
+		t.Error("missing rwbs field: expected error")
+	}
+	if _, err := tracepointFields(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing file: expected error")
+	}
+}
+
+func TestBiolatencyInstructions(t *testing.T) {
+	fields := map[string]int16{"dev": 8, "sector": 16, "rwbs": 32}
+	for name, insns := range map[string]asm.Instructions{
+		"issue":    biolatencyIssueInstructions(3, fields),
+		"complete": biolatencyCompleteInstructions(3, 4, fields),
+	} {
+		// Marshalling fails on jumps to missing labels.
+		if err := insns.Marshal(&bytes.Buffer{}, binary.LittleEndian); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
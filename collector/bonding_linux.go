@@ -17,10 +17,13 @@
 package collector
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/go-kit/log"
@@ -29,16 +32,22 @@ import (
 )
 
 type bondingCollector struct {
-	slaves, active typedDesc
-	logger         log.Logger
+	slaves, active                          typedDesc
+	slaveMIIStatus, slaveLinkFailures       typedDesc
+	slaveActive, slaveAggregated            typedDesc
+	slaveChurned                            typedDesc
+	lacpAggregatorInfo, lacpAggregatorPorts typedDesc
+	logger                                  log.Logger
 }
 
 func init() {
 	registerCollector("bonding", defaultEnabled, NewBondingCollector)
+	registerHardwareProbe("bonding", "bonding not loaded", sysfsProbe("class/net/bonding_masters"))
 }
 
 // NewBondingCollector returns a newly allocated bondingCollector.
-// It exposes the number of configured and active slave of linux bonding interfaces.
+// It exposes the number of configured and active slave of linux bonding interfaces,
+// the state of each slave and the LACP aggregator state.
 func NewBondingCollector(logger log.Logger) (Collector, error) {
 	return &bondingCollector{
 		slaves: typedDesc{prometheus.NewDesc(
@@ -51,6 +60,41 @@ func NewBondingCollector(logger log.Logger) (Collector, error) {
 			"Number of active slaves per bonding interface.",
 			[]string{"master"}, nil,
 		), prometheus.GaugeValue},
+		slaveMIIStatus: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "slave_mii_status"),
+			"Whether the link of the slave is up according to MII monitoring.",
+			[]string{"master", "slave"}, nil,
+		), prometheus.GaugeValue},
+		slaveLinkFailures: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "slave_link_failures_total"),
+			"Number of times the link of the slave went down.",
+			[]string{"master", "slave"}, nil,
+		), prometheus.CounterValue},
+		slaveActive: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "slave_active"),
+			"Whether the slave is the currently active slave, only for modes using a single active slave.",
+			[]string{"master", "slave"}, nil,
+		), prometheus.GaugeValue},
+		slaveAggregated: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "slave_lacp_aggregated"),
+			"Whether the slave is part of the active 802.3ad aggregator.",
+			[]string{"master", "slave"}, nil,
+		), prometheus.GaugeValue},
+		slaveChurned: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "slave_lacp_churned_total"),
+			"Number of times the LACP state of the actor or partner of the slave churned.",
+			[]string{"master", "slave", "side"}, nil,
+		), prometheus.CounterValue},
+		lacpAggregatorInfo: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "lacp_aggregator_info"),
+			"Information about the active 802.3ad aggregator of the bonding interface.",
+			[]string{"master", "aggregator_id", "actor_key", "partner_key", "partner_mac"}, nil,
+		), prometheus.GaugeValue},
+		lacpAggregatorPorts: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "bonding", "lacp_aggregator_ports"),
+			"Number of ports in the active 802.3ad aggregator of the bonding interface.",
+			[]string{"master"}, nil,
+		), prometheus.GaugeValue},
 		logger: logger,
 	}, nil
 }
@@ -69,10 +113,40 @@ func (c *bondingCollector) Update(ch chan<- prometheus.Metric) error {
 	for master, status := range bondingStats {
 		ch <- c.slaves.mustNewConstMetric(float64(status[0]), master)
 		ch <- c.active.mustNewConstMetric(float64(status[1]), master)
+
+		detailsFile := procFilePath(filepath.Join("net/bonding", master))
+		details, err := readBondingDetails(detailsFile)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				level.Debug(c.logger).Log("msg", "Not collecting bonding slave details, file does not exist", "file", detailsFile)
+				continue
+			}
+			return fmt.Errorf("couldn't get bonding details of %s: %w", master, err)
+		}
+		c.updateDetails(ch, master, details)
 	}
 	return nil
 }
 
+func (c *bondingCollector) updateDetails(ch chan<- prometheus.Metric, master string, details *bondingDetails) {
+	if agg := details.aggregator; agg != nil {
+		ch <- c.lacpAggregatorInfo.mustNewConstMetric(1, master, agg.id, agg.actorKey, agg.partnerKey, agg.partnerMAC)
+		ch <- c.lacpAggregatorPorts.mustNewConstMetric(float64(agg.ports), master)
+	}
+	for _, slave := range details.slaves {
+		ch <- c.slaveMIIStatus.mustNewConstMetric(boolToFloat(slave.miiStatus == "up"), master, slave.name)
+		ch <- c.slaveLinkFailures.mustNewConstMetric(float64(slave.linkFailures), master, slave.name)
+		if details.activeSlave != "" {
+			ch <- c.slaveActive.mustNewConstMetric(boolToFloat(slave.name == details.activeSlave), master, slave.name)
+		}
+		if details.aggregator != nil {
+			ch <- c.slaveAggregated.mustNewConstMetric(boolToFloat(slave.aggregatorID == details.aggregator.id), master, slave.name)
+			ch <- c.slaveChurned.mustNewConstMetric(float64(slave.actorChurned), master, slave.name, "actor")
+			ch <- c.slaveChurned.mustNewConstMetric(float64(slave.partnerChurned), master, slave.name, "partner")
+		}
+	}
+}
+
 func readBondingStats(root string) (status map[string][2]int, err error) {
 	status = map[string][2]int{}
 	masters, err := os.ReadFile(filepath.Join(root, "bonding_masters"))
@@ -103,3 +177,102 @@ func readBondingStats(root string) (status map[string][2]int, err error) {
 	}
 	return status, err
 }
+
+// bondingDetails is the state of a bonding interface from
+// /proc/net/bonding/<master>.
+type bondingDetails struct {
+	activeSlave string
+	aggregator  *bondingAggregator
+	slaves      []bondingSlave
+}
+
+// bondingAggregator is the active 802.3ad aggregator.
+type bondingAggregator struct {
+	id         string
+	ports      uint64
+	actorKey   string
+	partnerKey string
+	partnerMAC string
+}
+
+type bondingSlave struct {
+	name           string
+	miiStatus      string
+	linkFailures   uint64
+	aggregatorID   string
+	actorChurned   uint64
+	partnerChurned uint64
+}
+
+func readBondingDetails(file string) (*bondingDetails, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseBondingDetails(f)
+}
+
+// parseBondingDetails parses the "key: value" lines of /proc/net/bonding/<master>.
+// The lines before the first "Slave Interface" are about the bonding
+// interface, the following ones about the slave they follow.
+func parseBondingDetails(r io.Reader) (*bondingDetails, error) {
+	details := &bondingDetails{}
+	var slave *bondingSlave
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		var err error
+		if key == "Slave Interface" {
+			details.slaves = append(details.slaves, bondingSlave{name: value})
+			slave = &details.slaves[len(details.slaves)-1]
+			continue
+		}
+		if slave == nil {
+			switch key {
+			case "Currently Active Slave":
+				if value != "None" {
+					details.activeSlave = value
+				}
+			case "Active Aggregator Info":
+				details.aggregator = &bondingAggregator{}
+			}
+			if agg := details.aggregator; agg != nil {
+				switch key {
+				case "Aggregator ID":
+					agg.id = value
+				case "Number of ports":
+					agg.ports, err = strconv.ParseUint(value, 10, 64)
+				case "Actor Key":
+					agg.actorKey = value
+				case "Partner Key":
+					agg.partnerKey = value
+				case "Partner Mac Address":
+					agg.partnerMAC = value
+				}
+			}
+		} else {
+			switch key {
+			case "MII Status":
+				slave.miiStatus = value
+			case "Link Failure Count":
+				slave.linkFailures, err = strconv.ParseUint(value, 10, 64)
+			case "Aggregator ID":
+				slave.aggregatorID = value
+			case "Actor Churned Count":
+				slave.actorChurned, err = strconv.ParseUint(value, 10, 64)
+			case "Partner Churned Count":
+				slave.partnerChurned, err = strconv.ParseUint(value, 10, 64)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %w", key, err)
+		}
+	}
+	return details, s.Err()
+}
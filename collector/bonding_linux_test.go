@@ -14,6 +14,7 @@
 package collector
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -34,3 +35,42 @@ func TestBonding(t *testing.T) {
 		t.Fatal("dmz in unexpected state")
 	}
 }
+
+func TestBondingDetails(t *testing.T) {
+	details, err := readBondingDetails("fixtures/proc/net/bonding/dmz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.activeSlave != "" {
+		t.Errorf("dmz: unexpected active slave %q", details.activeSlave)
+	}
+	wantAgg := bondingAggregator{id: "1", ports: 1, actorKey: "15", partnerKey: "32769", partnerMAC: "00:1c:73:00:00:99"}
+	if details.aggregator == nil || *details.aggregator != wantAgg {
+		t.Errorf("dmz: want aggregator %+v, got %+v", wantAgg, details.aggregator)
+	}
+	wantSlaves := []bondingSlave{
+		{name: "eth0", miiStatus: "up", aggregatorID: "1"},
+		{name: "eth4", miiStatus: "up", linkFailures: 2, aggregatorID: "2", actorChurned: 1, partnerChurned: 3},
+	}
+	if !reflect.DeepEqual(details.slaves, wantSlaves) {
+		t.Errorf("dmz: want slaves %+v, got %+v", wantSlaves, details.slaves)
+	}
+
+	details, err = readBondingDetails("fixtures/proc/net/bonding/int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.activeSlave != "eth5" {
+		t.Errorf("int: want active slave eth5, got %q", details.activeSlave)
+	}
+	if details.aggregator != nil {
+		t.Errorf("int: unexpected aggregator %+v", details.aggregator)
+	}
+	wantSlaves = []bondingSlave{
+		{name: "eth5", miiStatus: "up", linkFailures: 1},
+		{name: "eth1", miiStatus: "down", linkFailures: 4},
+	}
+	if !reflect.DeepEqual(details.slaves, wantSlaves) {
+		t.Errorf("int: want slaves %+v, got %+v", wantSlaves, details.slaves)
+	}
+}
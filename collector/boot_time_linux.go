@@ -0,0 +1,146 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noboottime
+// +build !noboottime
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+// suspendFailedSteps maps the failed_* files of /sys/power/suspend_stats to
+// the step label of node_suspend_step_failures_total.
+var suspendFailedSteps = []string{
+	"freeze",
+	"prepare",
+	"suspend",
+	"suspend_late",
+	"suspend_noirq",
+	"resume",
+	"resume_early",
+	"resume_noirq",
+}
+
+type bootTimeCollector struct {
+	suspendedTime typedDesc
+	success       typedDesc
+	fail          typedDesc
+	stepFailures  typedDesc
+	hwSleepTime   typedDesc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector("boottime", defaultEnabled, newBootTimeCollector)
+}
+
+// newBootTimeCollector returns a new Collector exposing the time spent in
+// suspend and suspend/resume statistics. The boot time itself is exposed by
+// the stat collector on Linux.
+func newBootTimeCollector(logger log.Logger) (Collector, error) {
+	const subsystem = "suspend"
+	return &bootTimeCollector{
+		suspendedTime: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "time_seconds_total"),
+			"Total time the system spent suspended since boot.",
+			nil, nil,
+		), prometheus.CounterValue},
+		success: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "success_total"),
+			"Number of successful suspend/resume cycles since boot.",
+			nil, nil,
+		), prometheus.CounterValue},
+		fail: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "fail_total"),
+			"Number of failed suspend attempts since boot.",
+			nil, nil,
+		), prometheus.CounterValue},
+		stepFailures: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "step_failures_total"),
+			"Number of suspend/resume failures by the step that failed.",
+			[]string{"step"}, nil,
+		), prometheus.CounterValue},
+		hwSleepTime: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "hw_sleep_seconds_total"),
+			"Total time the hardware reported spending in its deepest sleep state.",
+			nil, nil,
+		), prometheus.CounterValue},
+		logger: logger,
+	}, nil
+}
+
+func (c *bootTimeCollector) Update(ch chan<- prometheus.Metric) error {
+	// CLOCK_BOOTTIME includes the time spent suspended, CLOCK_MONOTONIC
+	// doesn't.
+	var boottime, monotonic unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &boottime); err != nil {
+		return fmt.Errorf("couldn't get CLOCK_BOOTTIME: %w", err)
+	}
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &monotonic); err != nil {
+		return fmt.Errorf("couldn't get CLOCK_MONOTONIC: %w", err)
+	}
+	suspended := float64(boottime.Nano()-monotonic.Nano()) / 1e9
+	if suspended < 0 {
+		suspended = 0
+	}
+	ch <- c.suspendedTime.mustNewConstMetric(suspended)
+
+	return c.updateSuspendStats(ch)
+}
+
+func (c *bootTimeCollector) updateSuspendStats(ch chan<- prometheus.Metric) error {
+	dir := sysFilePath("power/suspend_stats")
+	success, err := readUintFromFile(dir + "/success")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			level.Debug(c.logger).Log("msg", "suspend_stats not found, skipping", "path", dir)
+			return nil
+		}
+		return fmt.Errorf("couldn't read suspend stats: %w", err)
+	}
+	ch <- c.success.mustNewConstMetric(float64(success))
+
+	fail, err := readUintFromFile(dir + "/fail")
+	if err != nil {
+		return fmt.Errorf("couldn't read suspend stats: %w", err)
+	}
+	ch <- c.fail.mustNewConstMetric(float64(fail))
+
+	for _, step := range suspendFailedSteps {
+		v, err := readUintFromFile(dir + "/failed_" + step)
+		if err != nil {
+			return fmt.Errorf("couldn't read suspend stats: %w", err)
+		}
+		ch <- c.stepFailures.mustNewConstMetric(float64(v), step)
+	}
+
+	// total_hw_sleep is only available since Linux 6.8 and on hardware
+	// reporting it.
+	hwSleep, err := readUintFromFile(dir + "/total_hw_sleep")
+	switch {
+	case err == nil:
+		ch <- c.hwSleepTime.mustNewConstMetric(float64(hwSleep) / 1e6)
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("couldn't read suspend stats: %w", err)
+	}
+	return nil
+}
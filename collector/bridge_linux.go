@@ -0,0 +1,197 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nobridge
+// +build !nobridge
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const bridgeCollectorSubsystem = "bridge"
+
+// bridgeFDBEntrySize is the size of a struct __fdb_entry in the brforward
+// file.
+const bridgeFDBEntrySize = 16
+
+// bridgePortStates are the STP states of a bridge port by their value in
+// brport/state, see BR_STATE_* in linux/if_bridge.h.
+var bridgePortStates = []string{"disabled", "listening", "learning", "forwarding", "blocking"}
+
+type bridgeCollector struct {
+	info                   *prometheus.Desc
+	stpEnabled             *prometheus.Desc
+	fdbEntries             *prometheus.Desc
+	ports                  *prometheus.Desc
+	rootPathCost           *prometheus.Desc
+	topologyChange         *prometheus.Desc
+	topologyChangeDetected *prometheus.Desc
+	portState              *prometheus.Desc
+	portPathCost           *prometheus.Desc
+	logger                 log.Logger
+}
+
+func init() {
+	registerCollector(bridgeCollectorSubsystem, defaultDisabled, NewBridgeCollector)
+}
+
+// NewBridgeCollector returns a new Collector exposing the state of Linux
+// bridges and their ports.
+func NewBridgeCollector(logger log.Logger) (Collector, error) {
+	return &bridgeCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "info"),
+			"Information about a bridge, the IDs are the ones used by STP.",
+			[]string{"bridge", "bridge_id", "root_id"}, nil,
+		),
+		stpEnabled: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "stp_enabled"),
+			"Whether STP is enabled on the bridge, by the kernel or a user space daemon.",
+			[]string{"bridge"}, nil,
+		),
+		fdbEntries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "fdb_entries"),
+			"Number of entries in the forwarding database of the bridge.",
+			[]string{"bridge"}, nil,
+		),
+		ports: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "ports"),
+			"Number of ports of the bridge.",
+			[]string{"bridge"}, nil,
+		),
+		rootPathCost: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "root_path_cost"),
+			"STP path cost from the bridge to the root bridge.",
+			[]string{"bridge"}, nil,
+		),
+		topologyChange: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "topology_change"),
+			"Whether the root bridge signals a topology change.",
+			[]string{"bridge"}, nil,
+		),
+		topologyChangeDetected: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "topology_change_detected"),
+			"Whether the bridge detected a topology change.",
+			[]string{"bridge"}, nil,
+		),
+		portState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "port_state"),
+			"STP state of a bridge port.",
+			[]string{"bridge", "port", "state"}, nil,
+		),
+		portPathCost: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, bridgeCollectorSubsystem, "port_path_cost"),
+			"STP path cost of a bridge port.",
+			[]string{"bridge", "port"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *bridgeCollector) Update(ch chan<- prometheus.Metric) error {
+	bridges, err := filepath.Glob(sysFilePath("class/net/*/bridge"))
+	if err != nil {
+		return err
+	}
+	if len(bridges) == 0 {
+		return ErrNoData
+	}
+	for _, bridge := range bridges {
+		dir := filepath.Dir(bridge)
+		if err := c.updateBridge(ch, dir); err != nil {
+			return fmt.Errorf("couldn't get statistics of bridge %s: %w", filepath.Base(dir), err)
+		}
+	}
+	return nil
+}
+
+func (c *bridgeCollector) updateBridge(ch chan<- prometheus.Metric, dir string) error {
+	name := filepath.Base(dir)
+	attr := func(a string) string {
+		b, err := os.ReadFile(filepath.Join(dir, "bridge", a))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name, attr("bridge_id"), attr("root_id"))
+	if stpState, err := readUintFromFile(filepath.Join(dir, "bridge/stp_state")); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.stpEnabled, prometheus.GaugeValue, boolToFloat(stpState != 0), name)
+	}
+	for file, desc := range map[string]*prometheus.Desc{
+		"root_path_cost":           c.rootPathCost,
+		"topology_change":          c.topologyChange,
+		"topology_change_detected": c.topologyChangeDetected,
+	} {
+		v, err := readUintFromFile(filepath.Join(dir, "bridge", file))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(v), name)
+	}
+
+	// brforward is a binary file of struct __fdb_entry, its size isn't known
+	// before reading it.
+	fdb, err := os.ReadFile(filepath.Join(dir, "brforward"))
+	if err == nil {
+		ch <- prometheus.MustNewConstMetric(c.fdbEntries, prometheus.GaugeValue, float64(len(fdb)/bridgeFDBEntrySize), name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	ports, err := os.ReadDir(filepath.Join(dir, "brif"))
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.ports, prometheus.GaugeValue, float64(len(ports)), name)
+	for _, port := range ports {
+		if err := c.updatePort(ch, name, filepath.Join(dir, "brif", port.Name())); err != nil {
+			return fmt.Errorf("couldn't get statistics of port %s: %w", port.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (c *bridgeCollector) updatePort(ch chan<- prometheus.Metric, bridge, dir string) error {
+	port := filepath.Base(dir)
+	state, err := readUintFromFile(filepath.Join(dir, "state"))
+	if err != nil {
+		return err
+	}
+	for i, s := range bridgePortStates {
+		ch <- prometheus.MustNewConstMetric(c.portState, prometheus.GaugeValue, boolToFloat(uint64(i) == state), bridge, port, s)
+	}
+
+	pathCost, err := readUintFromFile(filepath.Join(dir, "path_cost"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.portPathCost, prometheus.GaugeValue, float64(pathCost), bridge, port)
+	return nil
+}
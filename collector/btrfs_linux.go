@@ -37,6 +37,7 @@ type btrfsCollector struct {
 
 func init() {
 	registerCollector("btrfs", defaultEnabled, NewBtrfsCollector)
+	registerHardwareProbe("btrfs", "btrfs not loaded", sysfsProbe("fs/btrfs"))
 }
 
 // NewBtrfsCollector returns a new Collector exposing Btrfs statistics.
@@ -274,6 +275,18 @@ func (c *btrfsCollector) getMetrics(s *btrfs.Stats, ioctlStats *btrfsIoctlFsStat
 			metricType: prometheus.GaugeValue,
 			value:      float64(s.Allocation.GlobalRsvSize),
 		},
+		{
+			name:       "global_rsv_reserved_bytes",
+			desc:       "Amount of space currently reserved in the global reserve.",
+			metricType: prometheus.GaugeValue,
+			value:      float64(s.Allocation.GlobalRsvReserved),
+		},
+		{
+			name:       "unallocated_bytes",
+			desc:       "Amount of raw device space not allocated to any block group.",
+			metricType: prometheus.GaugeValue,
+			value:      float64(btrfsUnallocatedBytes(s)),
+		},
 	}
 
 	// Information about data, metadata and system data.
@@ -370,6 +383,46 @@ func (c *btrfsCollector) getAllocationStats(a string, s *btrfs.AllocationStats)
 			extraLabel:      []string{"block_group_type"},
 			extraLabelValue: []string{a},
 		},
+		{
+			name:            "may_use_bytes",
+			desc:            "Amount of space reserved for pending writes of a data type",
+			metricType:      prometheus.GaugeValue,
+			value:           float64(s.MayUseBytes),
+			extraLabel:      []string{"block_group_type"},
+			extraLabelValue: []string{a},
+		},
+		{
+			name:            "pinned_bytes",
+			desc:            "Amount of space of a data type freed but not yet reusable until the transaction commits",
+			metricType:      prometheus.GaugeValue,
+			value:           float64(s.PinnedBytes),
+			extraLabel:      []string{"block_group_type"},
+			extraLabelValue: []string{a},
+		},
+		{
+			name:            "readonly_bytes",
+			desc:            "Amount of space of a data type in read-only block groups",
+			metricType:      prometheus.GaugeValue,
+			value:           float64(s.ReadOnlyBytes),
+			extraLabel:      []string{"block_group_type"},
+			extraLabelValue: []string{a},
+		},
+		{
+			name:            "disk_used_bytes",
+			desc:            "Amount of raw device space used by a data type, including redundancy",
+			metricType:      prometheus.GaugeValue,
+			value:           float64(s.DiskUsedBytes),
+			extraLabel:      []string{"block_group_type"},
+			extraLabelValue: []string{a},
+		},
+		{
+			name:            "disk_size_bytes",
+			desc:            "Amount of raw device space allocated for a data type, including redundancy",
+			metricType:      prometheus.GaugeValue,
+			value:           float64(s.DiskTotalBytes),
+			extraLabel:      []string{"block_group_type"},
+			extraLabelValue: []string{a},
+		},
 	}
 
 	// Add all layout statistics.
@@ -409,3 +462,22 @@ func (c *btrfsCollector) getLayoutStats(a, l string, s *btrfs.LayoutUsage) []btr
 		},
 	}
 }
+
+// btrfsUnallocatedBytes returns the raw device space that isn't allocated to
+// block groups yet. This is what data and metadata can still grow into, unlike
+// the free space reported by statfs it isn't an estimate.
+func btrfsUnallocatedBytes(s *btrfs.Stats) uint64 {
+	var size, allocated uint64
+	for _, dev := range s.Devices {
+		size += dev.Size
+	}
+	for _, a := range []*btrfs.AllocationStats{s.Allocation.Data, s.Allocation.Metadata, s.Allocation.System} {
+		if a != nil {
+			allocated += a.DiskTotalBytes
+		}
+	}
+	if allocated > size {
+		return 0
+	}
+	return size - allocated
+}
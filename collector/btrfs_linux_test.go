@@ -27,15 +27,32 @@ var expectedBtrfsMetrics = [][]btrfsMetric{
 	{
 		{name: "info", value: 1, extraLabel: []string{"label"}, extraLabelValue: []string{"fixture"}},
 		{name: "global_rsv_size_bytes", value: 1.6777216e+07},
+		{name: "global_rsv_reserved_bytes", value: 1.6777216e+07},
+		{name: "unallocated_bytes", value: 1.7163091968e+10},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "may_use_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "readonly_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "disk_used_bytes", value: 8.08189952e+08, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "disk_size_bytes", value: 2.147483648e+09, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
 		{name: "used_bytes", value: 8.08189952e+08, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid0"}},
 		{name: "size_bytes", value: 2.147483648e+09, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid0"}},
 		{name: "allocation_ratio", value: 1, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid0"}},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "may_use_bytes", value: 1.6777216e+07, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "readonly_bytes", value: 131072, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "disk_used_bytes", value: 1.867776e+06, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "disk_size_bytes", value: 2.147483648e+09, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
 		{name: "used_bytes", value: 933888, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid1"}},
 		{name: "size_bytes", value: 1.073741824e+09, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid1"}},
 		{name: "allocation_ratio", value: 2, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid1"}},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "may_use_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "readonly_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "disk_used_bytes", value: 32768, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "disk_size_bytes", value: 1.6777216e+07, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
 		{name: "used_bytes", value: 16384, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid1"}},
 		{name: "size_bytes", value: 8.388608e+06, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid1"}},
 		{name: "allocation_ratio", value: 2, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid1"}},
@@ -45,15 +62,32 @@ var expectedBtrfsMetrics = [][]btrfsMetric{
 	{
 		{name: "info", value: 1, extraLabel: []string{"label"}, extraLabelValue: []string{""}},
 		{name: "global_rsv_size_bytes", value: 1.6777216e+07},
+		{name: "global_rsv_reserved_bytes", value: 1.6777216e+07},
+		{name: "unallocated_bytes", value: 4.1859416064e+10},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "may_use_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "readonly_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "disk_used_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
+		{name: "disk_size_bytes", value: 6.44087808e+08, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"data"}},
 		{name: "used_bytes", value: 0, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid5"}},
 		{name: "size_bytes", value: 6.44087808e+08, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid5"}},
 		{name: "allocation_ratio", value: 1.3333333333333333, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"data", "raid5"}},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "may_use_bytes", value: 1.6777216e+07, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "readonly_bytes", value: 262144, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "disk_used_bytes", value: 114688, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
+		{name: "disk_size_bytes", value: 4.29391872e+08, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"metadata"}},
 		{name: "used_bytes", value: 114688, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid6"}},
 		{name: "size_bytes", value: 4.29391872e+08, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid6"}},
 		{name: "allocation_ratio", value: 2, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"metadata", "raid6"}},
 		{name: "reserved_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "may_use_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "pinned_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "readonly_bytes", value: 0, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "disk_used_bytes", value: 16384, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
+		{name: "disk_size_bytes", value: 1.6777216e+07, extraLabel: []string{"block_group_type"}, extraLabelValue: []string{"system"}},
 		{name: "used_bytes", value: 16384, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid6"}},
 		{name: "size_bytes", value: 1.6777216e+07, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid6"}},
 		{name: "allocation_ratio", value: 2, extraLabel: []string{"block_group_type", "mode"}, extraLabelValue: []string{"system", "raid6"}},
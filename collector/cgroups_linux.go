@@ -17,20 +17,104 @@
 package collector
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
 )
 
 const cgroupsCollectorSubsystem = "cgroups"
 
+var (
+	cgroupsSliceDepth  = kingpin.Flag("collector.cgroups.slice-depth", "Depth of systemd slices below the cgroup v2 root to expose per-unit resource usage for, 0 disables.").Default("1").Int()
+	cgroupsUnitInclude = kingpin.Flag("collector.cgroups.unit-include", "Regexp of cgroup paths of systemd slices, scopes and services to expose resource usage for.").Default(".+").String()
+)
+
+// cgroupMemoryStats lists the memory.stat keys exposed as
+// node_cgroups_{slice,unit}_memory_stat_bytes, all of them are in bytes.
+var cgroupMemoryStats = []string{
+	"anon",
+	"file",
+	"kernel_stack",
+	"pagetables",
+	"shmem",
+	"sock",
+	"slab",
+	"file_mapped",
+	"file_dirty",
+	"file_writeback",
+}
+
+// cgroupPressureResources lists the resources of the <resource>.pressure
+// files. The pressure collector has its own list, it can be built without.
+var cgroupPressureResources = []string{"cpu", "io", "memory"}
+
 type cgroupSummaryCollector struct {
-	fs      procfs.FS
-	cgroups *prometheus.Desc
-	enabled *prometheus.Desc
-	logger  log.Logger
+	fs          procfs.FS
+	unitInclude *regexp.Regexp
+	cgroups     *prometheus.Desc
+	enabled     *prometheus.Desc
+	slice       cgroupUnitDescs
+	unit        cgroupUnitDescs
+	logger      log.Logger
+}
+
+// cgroupUnitDescs are the descriptors of the resource usage of a cgroup. The
+// top-level slices are exposed as node_cgroups_slice_* and the units nested
+// below them as node_cgroups_unit_*, so that a parent and its children aren't
+// summed up together.
+type cgroupUnitDescs struct {
+	cpuUsage      *prometheus.Desc
+	cpu           *prometheus.Desc
+	memoryCurrent *prometheus.Desc
+	memoryMax     *prometheus.Desc
+	memoryStat    *prometheus.Desc
+	memoryEvents  *prometheus.Desc
+	oomKills      *prometheus.Desc
+	ioBytes       *prometheus.Desc
+	ioOps         *prometheus.Desc
+	pids          *prometheus.Desc
+	pressure      *prometheus.Desc
+	pressureFull  *prometheus.Desc
+}
+
+func newCgroupUnitDescs(prefix, noun string, labels []string) cgroupUnitDescs {
+	desc := func(name, help string, extra ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cgroupsCollectorSubsystem, prefix+"_"+name),
+			fmt.Sprintf(help, noun),
+			cgroupLabels(labels, extra...), nil,
+		)
+	}
+	return cgroupUnitDescs{
+		cpuUsage:      desc("cpu_usage_seconds_total", "Total CPU time consumed by the tasks of the %s."),
+		cpu:           desc("cpu_seconds_total", "CPU time consumed by the tasks of the %s in each mode.", "mode"),
+		memoryCurrent: desc("memory_current_bytes", "Memory currently used by the %s and its descendants."),
+		memoryMax:     desc("memory_max_bytes", "Memory usage hard limit of the %s, only exposed if set."),
+		memoryStat:    desc("memory_stat_bytes", "Memory used by the %s by type, from memory.stat.", "type"),
+		memoryEvents:  desc("memory_page_faults_total", "Page faults of the %s by type, from memory.stat.", "type"),
+		oomKills:      desc("oom_kills_total", "Number of processes of the %s and its descendants killed by the OOM killer."),
+		ioBytes:       desc("io_bytes_total", "Bytes transferred by the %s per device and operation, from io.stat.", "device", "operation"),
+		ioOps:         desc("io_operations_total", "I/O operations issued by the %s per device and operation, from io.stat.", "device", "operation"),
+		pids:          desc("pids", "Number of processes in the %s and its descendants."),
+		pressure:      desc("pressure_waiting_seconds_total", "Time some tasks of the %s waited for the resource, from its pressure stall information.", "resource"),
+		pressureFull:  desc("pressure_stalled_seconds_total", "Time no task of the %s could make progress due to congestion of the resource, from its pressure stall information.", "resource"),
+	}
+}
+
+// cgroupLabels returns a copy of labels with extra appended.
+func cgroupLabels(labels []string, extra ...string) []string {
+	return append(append(make([]string, 0, len(labels)+len(extra)), labels...), extra...)
 }
 
 func init() {
@@ -43,8 +127,13 @@ func NewCgroupSummaryCollector(logger log.Logger) (Collector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open procfs: %w", err)
 	}
+	unitInclude, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", *cgroupsUnitInclude))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --collector.cgroups.unit-include: %w", err)
+	}
 	return &cgroupSummaryCollector{
-		fs: fs,
+		fs:          fs,
+		unitInclude: unitInclude,
 		cgroups: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cgroupsCollectorSubsystem, "cgroups"),
 			"Current cgroup number of the subsystem.",
@@ -55,6 +144,8 @@ func NewCgroupSummaryCollector(logger log.Logger) (Collector, error) {
 			"Current cgroup number of the subsystem.",
 			[]string{"subsys_name"}, nil,
 		),
+		slice:  newCgroupUnitDescs("slice", "top-level systemd slice", []string{"slice"}),
+		unit:   newCgroupUnitDescs("unit", "systemd unit", []string{"unit", "kind", "depth"}),
 		logger: logger,
 	}, nil
 }
@@ -69,5 +160,287 @@ func (c *cgroupSummaryCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- prometheus.MustNewConstMetric(c.cgroups, prometheus.GaugeValue, float64(cs.Cgroups), cs.SubsysName)
 		ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, float64(cs.Enabled), cs.SubsysName)
 	}
+	if *cgroupsSliceDepth > 0 {
+		return c.updateUnits(ch)
+	}
+	return nil
+}
+
+func (c *cgroupSummaryCollector) updateUnits(ch chan<- prometheus.Metric) error {
+	root, ok := cgroupUnifiedRoot()
+	if !ok {
+		level.Debug(c.logger).Log("msg", "cgroup v2 hierarchy not found, skipping unit statistics")
+		return nil
+	}
+	units, err := cgroupUnits(root, *cgroupsSliceDepth)
+	if err != nil {
+		return fmt.Errorf("couldn't list cgroup units: %w", err)
+	}
+	for _, unit := range units {
+		if !c.unitInclude.MatchString(unit.path) {
+			continue
+		}
+		descs, labels := c.unit, []string{unit.path, unit.kind(), strconv.Itoa(unit.depth)}
+		if unit.depth == 1 && unit.kind() == "slice" {
+			descs, labels = c.slice, []string{unit.path}
+		}
+		// Controllers may not be enabled for every cgroup, so missing files
+		// are skipped.
+		dir := filepath.Join(root, unit.path)
+		if err := updateCgroupCPU(ch, descs, dir, labels); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("couldn't get CPU statistics for %s: %w", unit.path, err)
+		}
+		if err := updateCgroupMemory(ch, descs, dir, labels); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("couldn't get memory statistics for %s: %w", unit.path, err)
+		}
+		if err := updateCgroupIO(ch, descs, dir, labels); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("couldn't get I/O statistics for %s: %w", unit.path, err)
+		}
+		for _, resource := range cgroupPressureResources {
+			if err := updateCgroupPressure(ch, descs, dir, labels, resource); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("couldn't get %s pressure for %s: %w", resource, unit.path, err)
+			}
+		}
+		if pids, err := readUintFromFile(filepath.Join(dir, "pids.current")); err == nil {
+			ch <- prometheus.MustNewConstMetric(descs.pids, prometheus.GaugeValue, float64(pids), labels...)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("couldn't get pids.current for %s: %w", unit.path, err)
+		}
+	}
+	return nil
+}
+
+func updateCgroupCPU(ch chan<- prometheus.Metric, d cgroupUnitDescs, dir string, labels []string) error {
+	stat, err := parseCgroupFlatKeyed(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	if v, ok := stat["usage_usec"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.cpuUsage, prometheus.CounterValue, float64(v)/1e6, labels...)
+	}
+	if v, ok := stat["user_usec"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.cpu, prometheus.CounterValue, float64(v)/1e6, cgroupLabels(labels, "user")...)
+	}
+	if v, ok := stat["system_usec"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.cpu, prometheus.CounterValue, float64(v)/1e6, cgroupLabels(labels, "system")...)
+	}
+	return nil
+}
+
+func updateCgroupMemory(ch chan<- prometheus.Metric, d cgroupUnitDescs, dir string, labels []string) error {
+	current, err := readUintFromFile(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(d.memoryCurrent, prometheus.GaugeValue, float64(current), labels...)
+
+	// memory.max contains "max" if no limit is set.
+	if max, err := readUintFromFile(filepath.Join(dir, "memory.max")); err == nil {
+		ch <- prometheus.MustNewConstMetric(d.memoryMax, prometheus.GaugeValue, float64(max), labels...)
+	}
+
+	stat, err := parseCgroupFlatKeyed(filepath.Join(dir, "memory.stat"))
+	if err != nil {
+		return err
+	}
+	for _, key := range cgroupMemoryStats {
+		if v, ok := stat[key]; ok {
+			ch <- prometheus.MustNewConstMetric(d.memoryStat, prometheus.GaugeValue, float64(v), cgroupLabels(labels, key)...)
+		}
+	}
+	if v, ok := stat["pgfault"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.memoryEvents, prometheus.CounterValue, float64(v), cgroupLabels(labels, "minor")...)
+	}
+	if v, ok := stat["pgmajfault"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.memoryEvents, prometheus.CounterValue, float64(v), cgroupLabels(labels, "major")...)
+	}
+
+	// memory.events counts the kills in the unit and its descendants.
+	events, err := parseCgroupFlatKeyed(filepath.Join(dir, "memory.events"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if v, ok := events["oom_kill"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.oomKills, prometheus.CounterValue, float64(v), labels...)
+	}
+	return nil
+}
+
+func updateCgroupIO(ch chan<- prometheus.Metric, d cgroupUnitDescs, dir string, labels []string) error {
+	stats, err := parseCgroupIOStat(filepath.Join(dir, "io.stat"))
+	if err != nil {
+		return err
+	}
+	for dev, stat := range stats {
+		device := blockDeviceName(dev)
+		ch <- prometheus.MustNewConstMetric(d.ioBytes, prometheus.CounterValue, float64(stat["rbytes"]), cgroupLabels(labels, device, "read")...)
+		ch <- prometheus.MustNewConstMetric(d.ioBytes, prometheus.CounterValue, float64(stat["wbytes"]), cgroupLabels(labels, device, "write")...)
+		ch <- prometheus.MustNewConstMetric(d.ioBytes, prometheus.CounterValue, float64(stat["dbytes"]), cgroupLabels(labels, device, "discard")...)
+		ch <- prometheus.MustNewConstMetric(d.ioOps, prometheus.CounterValue, float64(stat["rios"]), cgroupLabels(labels, device, "read")...)
+		ch <- prometheus.MustNewConstMetric(d.ioOps, prometheus.CounterValue, float64(stat["wios"]), cgroupLabels(labels, device, "write")...)
+		ch <- prometheus.MustNewConstMetric(d.ioOps, prometheus.CounterValue, float64(stat["dios"]), cgroupLabels(labels, device, "discard")...)
+	}
+	return nil
+}
+
+func updateCgroupPressure(ch chan<- prometheus.Metric, d cgroupUnitDescs, dir string, labels []string, resource string) error {
+	totals, err := parseCgroupPressure(filepath.Join(dir, resource+".pressure"))
+	if err != nil {
+		return err
+	}
+	if v, ok := totals["some"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.pressure, prometheus.CounterValue, float64(v)/1e6, cgroupLabels(labels, resource)...)
+	}
+	if v, ok := totals["full"]; ok {
+		ch <- prometheus.MustNewConstMetric(d.pressureFull, prometheus.CounterValue, float64(v)/1e6, cgroupLabels(labels, resource)...)
+	}
 	return nil
 }
+
+// cgroupUnifiedRoot returns the mountpoint of the cgroup v2 hierarchy, which
+// is either mounted directly at /sys/fs/cgroup or, in hybrid mode, at
+// /sys/fs/cgroup/unified.
+func cgroupUnifiedRoot() (string, bool) {
+	for _, p := range []string{"fs/cgroup", "fs/cgroup/unified"} {
+		root := sysFilePath(p)
+		if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+// cgroupUnit is a systemd slice, scope or service in the cgroup v2 hierarchy.
+type cgroupUnit struct {
+	// path is relative to the cgroup root.
+	path string
+	// depth is 1 for the units directly below the root.
+	depth int
+}
+
+// kind returns the kind of the unit, like "slice" or "service".
+func (u cgroupUnit) kind() string {
+	return strings.TrimPrefix(filepath.Ext(u.path), ".")
+}
+
+// cgroupUnits returns the systemd slices, scopes and services nested at most
+// depth levels below root. The walk only descends into slices above that
+// depth, so the units within the deepest slices aren't returned.
+func cgroupUnits(root string, depth int) ([]cgroupUnit, error) {
+	var units []cgroupUnit
+	var walk func(dir string, d int) error
+	walk = func(dir string, d int) error {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+			unit := filepath.Join(dir, e.Name())
+			switch filepath.Ext(e.Name()) {
+			case ".scope", ".service":
+				units = append(units, cgroupUnit{path: unit, depth: d})
+			case ".slice":
+				units = append(units, cgroupUnit{path: unit, depth: d})
+				if d < depth {
+					if err := walk(unit, d+1); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	return units, walk("", 1)
+}
+
+// parseCgroupFlatKeyed parses a cgroup v2 flat keyed file like cpu.stat or
+// memory.stat into a map of values.
+func parseCgroupFlatKeyed(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stat := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", fields[1], fields[0], err)
+		}
+		stat[fields[0]] = v
+	}
+	return stat, scanner.Err()
+}
+
+// parseCgroupIOStat parses a cgroup v2 io.stat file, a nested keyed file
+// with one line of key=value pairs per "major:minor" device.
+func parseCgroupIOStat(path string) (map[string]map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stats := map[string]map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		stat := map[string]uint64{}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid field %q for device %s", field, fields[0])
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+			}
+			stat[key] = v
+		}
+		stats[fields[0]] = stat
+	}
+	return stats, scanner.Err()
+}
+
+// parseCgroupPressure returns the total stall time in microseconds of each
+// line, some and full, of a cgroup v2 <resource>.pressure file.
+func parseCgroupPressure(path string) (map[string]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	totals := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, _ := strings.Cut(field, "=")
+			if key != "total" {
+				continue
+			}
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid total %q of %s: %w", value, fields[0], err)
+			}
+			totals[fields[0]] = v
+		}
+	}
+	return totals, scanner.Err()
+}
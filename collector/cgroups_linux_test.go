@@ -0,0 +1,76 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nostat
+// +build !nostat
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCgroupIOStat(t *testing.T) {
+	stats, err := parseCgroupIOStat("fixtures/sys/fs/cgroup/system.slice/io.stat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]uint64{
+		"8:0":   {"rbytes": 1073741824, "wbytes": 536870912, "rios": 65536, "wios": 32768, "dbytes": 0, "dios": 0},
+		"259:0": {"rbytes": 4096, "wbytes": 8192, "rios": 1, "wios": 2, "dbytes": 0, "dios": 0},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("want %v, got %v", want, stats)
+	}
+}
+
+func TestParseCgroupPressure(t *testing.T) {
+	totals, err := parseCgroupPressure("fixtures/sys/fs/cgroup/system.slice/cpu.pressure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]uint64{"some": 48213654, "full": 12087113}
+	if !reflect.DeepEqual(totals, want) {
+		t.Errorf("want %v, got %v", want, totals)
+	}
+}
+
+func TestCgroupUnits(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	root, ok := cgroupUnifiedRoot()
+	if !ok {
+		t.Fatal("cgroup v2 hierarchy not found")
+	}
+	for depth, want := range map[int][]cgroupUnit{
+		1: {{"init.scope", 1}, {"system.slice", 1}, {"user.slice", 1}},
+		2: {{"init.scope", 1}, {"system.slice", 1}, {"system.slice/sshd.service", 2}, {"user.slice", 1}, {"user.slice/user-1000.slice", 2}},
+	} {
+		units, err := cgroupUnits(root, depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(units, want) {
+			t.Errorf("depth %d: want %v, got %v", depth, want, units)
+		}
+	}
+	for unit, want := range map[cgroupUnit]string{
+		{"init.scope", 1}:                 "scope",
+		{"system.slice/sshd.service", 2}:  "service",
+		{"user.slice/user-1000.slice", 2}: "slice",
+	} {
+		if got := unit.kind(); got != want {
+			t.Errorf("%s: want kind %q, got %q", unit.path, want, got)
+		}
+	}
+}
@@ -0,0 +1,541 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nochrony
+// +build !nochrony
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	chronySubsystem = "chrony"
+
+	// Command protocol of chronyd, see candm.h of chrony.
+	chronyProtoVersion  = 6
+	chronyPktRequest    = 1
+	chronyPktReply      = 2
+	chronyReqNSources   = 14
+	chronyReqSourceData = 15
+	chronyReqTracking   = 33
+	chronyRpyNSources   = 2
+	chronyRpySourceData = 3
+	chronyRpyTracking   = 5
+	chronyStatusUnauth  = 2
+	chronyLeapUnsynced  = 3
+	chronySourceModeRef = 2
+	// Requests are padded so that they aren't shorter than the reply,
+	// chronyd drops them otherwise.
+	chronyRequestLen     = 416
+	chronyReplyHeaderLen = 28
+
+	// Control messages of ntpd, see RFC 1305 appendix B.
+	ntpdOpReadStat = 1
+	ntpdOpReadVar  = 2
+	ntpdResponse   = 0x80
+	ntpdError      = 0x40
+	ntpdMore       = 0x20
+	// Selection states of a peer which is used to discipline the clock,
+	// sys.peer and pps.peer.
+	ntpdSelSysPeer = 6
+	ntpdSelPPSPeer = 7
+)
+
+var (
+	chronyAddress     = kingpin.Flag("collector.chrony.address", "Address of the UDP command port of chronyd.").Default("127.0.0.1:323").String()
+	chronyNtpdAddress = kingpin.Flag("collector.chrony.ntpd-address", "Address of ntpd, queried with mode 6 control messages if chronyd doesn't answer. Empty to only query chronyd.").Default("127.0.0.1:123").String()
+	chronyTimeout     = kingpin.Flag("collector.chrony.timeout", "Timeout of the queries to the time daemon.").Default("1s").Duration()
+)
+
+type chronyCollector struct {
+	info           *prometheus.Desc
+	stratum        *prometheus.Desc
+	synchronized   *prometheus.Desc
+	offset         *prometheus.Desc
+	rootDelay      *prometheus.Desc
+	rootDispersion *prometheus.Desc
+	sourceReach    *prometheus.Desc
+	sourceStratum  *prometheus.Desc
+	sourceSelected *prometheus.Desc
+	address        string
+	ntpdAddress    string
+	timeout        time.Duration
+	logger         log.Logger
+}
+
+// timeDaemonStatus is the synchronization state reported by chronyd or ntpd.
+type timeDaemonStatus struct {
+	daemon       string
+	reference    string
+	stratum      uint16
+	synchronized bool
+	// offset is positive if the system clock is ahead.
+	offset         float64
+	rootDelay      float64
+	rootDispersion float64
+	sources        []timeSource
+}
+
+type timeSource struct {
+	address  string
+	stratum  uint16
+	reach    uint8
+	selected bool
+}
+
+func init() {
+	registerCollector(chronySubsystem, defaultDisabled, NewChronyCollector)
+}
+
+// NewChronyCollector returns a new Collector exposing the synchronization
+// state of the local chronyd or ntpd.
+func NewChronyCollector(logger log.Logger) (Collector, error) {
+	return &chronyCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "info"),
+			"Time daemon answering the queries and its reference, the address or refid of the source it is synchronized to.",
+			[]string{"daemon", "reference"}, nil,
+		),
+		stratum: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "stratum"),
+			"Stratum of the local clock.",
+			nil, nil,
+		),
+		synchronized: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "synchronized"),
+			"Whether the time daemon considers the local clock synchronized.",
+			nil, nil,
+		),
+		offset: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "offset_seconds"),
+			"Offset of the local clock measured at the last clock update, positive if it is ahead.",
+			nil, nil,
+		),
+		rootDelay: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "root_delay_seconds"),
+			"Total round-trip delay to the stratum 1 clock.",
+			nil, nil,
+		),
+		rootDispersion: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "root_dispersion_seconds"),
+			"Total dispersion accumulated up to the stratum 1 clock.",
+			nil, nil,
+		),
+		sourceReach: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "source_reach"),
+			"Reachability register of a source, a bit is shifted in for each poll and set if it was answered. 255 if the last 8 polls were answered.",
+			[]string{"source"}, nil,
+		),
+		sourceStratum: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "source_stratum"),
+			"Stratum of a source.",
+			[]string{"source"}, nil,
+		),
+		sourceSelected: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, chronySubsystem, "source_selected"),
+			"Whether a source is used to synchronize the local clock.",
+			[]string{"source"}, nil,
+		),
+		address:     *chronyAddress,
+		ntpdAddress: *chronyNtpdAddress,
+		timeout:     *chronyTimeout,
+		logger:      logger,
+	}, nil
+}
+
+func (c *chronyCollector) Update(ch chan<- prometheus.Metric) error {
+	status, err := queryChronyd(c.address, c.timeout)
+	if err != nil {
+		if c.ntpdAddress == "" {
+			return fmt.Errorf("couldn't query chronyd: %w", err)
+		}
+		level.Debug(c.logger).Log("msg", "Couldn't query chronyd, querying ntpd", "err", err)
+		if status, err = queryNtpd(c.ntpdAddress, c.timeout); err != nil {
+			return fmt.Errorf("couldn't query chronyd or ntpd: %w", err)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, status.daemon, status.reference)
+	ch <- prometheus.MustNewConstMetric(c.stratum, prometheus.GaugeValue, float64(status.stratum))
+	ch <- prometheus.MustNewConstMetric(c.synchronized, prometheus.GaugeValue, boolToFloat(status.synchronized))
+	ch <- prometheus.MustNewConstMetric(c.offset, prometheus.GaugeValue, status.offset)
+	ch <- prometheus.MustNewConstMetric(c.rootDelay, prometheus.GaugeValue, status.rootDelay)
+	ch <- prometheus.MustNewConstMetric(c.rootDispersion, prometheus.GaugeValue, status.rootDispersion)
+	for _, s := range status.sources {
+		ch <- prometheus.MustNewConstMetric(c.sourceReach, prometheus.GaugeValue, float64(s.reach), s.address)
+		ch <- prometheus.MustNewConstMetric(c.sourceStratum, prometheus.GaugeValue, float64(s.stratum), s.address)
+		ch <- prometheus.MustNewConstMetric(c.sourceSelected, prometheus.GaugeValue, boolToFloat(s.selected), s.address)
+	}
+	return nil
+}
+
+// chronydConn sends commands to chronyd.
+type chronydConn struct {
+	conn     net.Conn
+	sequence uint32
+}
+
+// queryChronyd returns the tracking state and sources of chronyd, like
+// `chronyc tracking` and `chronyc sources`.
+func queryChronyd(address string, timeout time.Duration) (*timeDaemonStatus, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	c := &chronydConn{conn: conn, sequence: uint32(time.Now().UnixNano())}
+
+	tracking, err := c.request(chronyReqTracking, nil, chronyRpyTracking, 80)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get tracking: %w", err)
+	}
+	status := parseChronyTracking(tracking)
+
+	n, err := c.request(chronyReqNSources, nil, chronyRpyNSources, 4)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get number of sources: %w", err)
+	}
+	for i := uint32(0); i < binary.BigEndian.Uint32(n); i++ {
+		index := make([]byte, 4)
+		binary.BigEndian.PutUint32(index, i)
+		data, err := c.request(chronyReqSourceData, index, chronyRpySourceData, 48)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't get source %d: %w", i, err)
+		}
+		status.sources = append(status.sources, parseChronySource(data))
+	}
+	return status, nil
+}
+
+// request sends a command to chronyd and returns the data of the reply,
+// which is at least size bytes long.
+func (c *chronydConn) request(command uint16, data []byte, reply uint16, size int) ([]byte, error) {
+	c.sequence++
+	req := make([]byte, chronyRequestLen)
+	req[0] = chronyProtoVersion
+	req[1] = chronyPktRequest
+	binary.BigEndian.PutUint16(req[4:], command)
+	binary.BigEndian.PutUint32(req[8:], c.sequence)
+	copy(req[20:], data)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1024)
+	for {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		rpy := buf[:n]
+		// Skip stray replies to earlier requests.
+		if n < chronyReplyHeaderLen || rpy[1] != chronyPktReply || binary.BigEndian.Uint32(rpy[16:]) != c.sequence {
+			continue
+		}
+		if rpy[0] != chronyProtoVersion {
+			return nil, fmt.Errorf("unsupported protocol version %d", rpy[0])
+		}
+		switch status := binary.BigEndian.Uint16(rpy[8:]); status {
+		case 0:
+		case chronyStatusUnauth:
+			return nil, fmt.Errorf("%w: command %d not authorized", ErrPermission, command)
+		default:
+			return nil, fmt.Errorf("command %d failed with status %d", command, status)
+		}
+		if code := binary.BigEndian.Uint16(rpy[6:]); code != reply {
+			return nil, fmt.Errorf("unexpected reply %d to command %d", code, command)
+		}
+		if n < chronyReplyHeaderLen+size {
+			return nil, fmt.Errorf("%w: reply to command %d too short", ErrParse, command)
+		}
+		return rpy[chronyReplyHeaderLen:], nil
+	}
+}
+
+// parseChronyTracking parses the RPY_Tracking reply.
+func parseChronyTracking(b []byte) *timeDaemonStatus {
+	refID := binary.BigEndian.Uint32(b)
+	reference := chronyAddr(b[4:24])
+	if reference == "" {
+		reference = chronyRefID(refID)
+	}
+	return &timeDaemonStatus{
+		daemon:         "chronyd",
+		reference:      reference,
+		stratum:        binary.BigEndian.Uint16(b[24:]),
+		synchronized:   binary.BigEndian.Uint16(b[26:]) != chronyLeapUnsynced,
+		offset:         chronyFloat(b[44:]),
+		rootDelay:      chronyFloat(b[64:]),
+		rootDispersion: chronyFloat(b[68:]),
+	}
+}
+
+// parseChronySource parses the RPY_Source_Data reply.
+func parseChronySource(b []byte) timeSource {
+	address := chronyAddr(b[0:20])
+	if binary.BigEndian.Uint16(b[26:]) == chronySourceModeRef {
+		// Reference clocks have their refid in place of the address.
+		address = chronyRefID(binary.BigEndian.Uint32(b))
+	}
+	return timeSource{
+		address:  address,
+		stratum:  binary.BigEndian.Uint16(b[22:]),
+		reach:    uint8(binary.BigEndian.Uint16(b[30:])),
+		selected: binary.BigEndian.Uint16(b[24:]) == 0,
+	}
+}
+
+// chronyAddr formats an IPAddr of the command protocol, it returns an empty
+// string for unspecified addresses.
+func chronyAddr(b []byte) string {
+	switch binary.BigEndian.Uint16(b[16:]) {
+	case 1:
+		return net.IP(b[0:4]).String()
+	case 2:
+		return net.IP(b[0:16]).String()
+	case 3:
+		// Sources whose name isn't resolved yet.
+		return fmt.Sprintf("ID#%010d", binary.BigEndian.Uint32(b))
+	}
+	return ""
+}
+
+// chronyRefID formats a reference ID like chronyc, as the printable
+// characters it is made of.
+func chronyRefID(id uint32) string {
+	var sb strings.Builder
+	for i := 24; i >= 0; i -= 8 {
+		if c := byte(id >> i); c >= 0x20 && c < 0x7f {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// chronyFloat decodes the floating point format of the command protocol, a 7
+// bit exponent followed by a 25 bit coefficient.
+func chronyFloat(b []byte) float64 {
+	x := binary.BigEndian.Uint32(b)
+	exp := int(x >> 25)
+	if exp >= 1<<6 {
+		exp -= 1 << 7
+	}
+	coef := int(x % (1 << 25))
+	if coef >= 1<<24 {
+		coef -= 1 << 25
+	}
+	return float64(coef) * math.Pow(2, float64(exp-25))
+}
+
+// ntpdConn sends control messages to ntpd.
+type ntpdConn struct {
+	conn     net.Conn
+	sequence uint16
+}
+
+// queryNtpd returns the system and peer variables of ntpd, like `ntpq -c rv
+// -c peers`.
+func queryNtpd(address string, timeout time.Duration) (*timeDaemonStatus, error) {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	c := &ntpdConn{conn: conn, sequence: uint16(time.Now().UnixNano())}
+
+	data, err := c.request(ntpdOpReadVar, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read system variables: %w", err)
+	}
+	status, err := parseNtpdSystem(parseNtpdVars(data))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = c.request(ntpdOpReadStat, 0)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read peer status: %w", err)
+	}
+	for i := 0; i+4 <= len(data); i += 4 {
+		assoc := binary.BigEndian.Uint16(data[i:])
+		sel := (binary.BigEndian.Uint16(data[i+2:]) >> 8) & 0x7
+		vars, err := c.request(ntpdOpReadVar, assoc)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read variables of peer %d: %w", assoc, err)
+		}
+		source, err := parseNtpdPeer(parseNtpdVars(vars))
+		if err != nil {
+			return nil, err
+		}
+		source.selected = sel == ntpdSelSysPeer || sel == ntpdSelPPSPeer
+		status.sources = append(status.sources, source)
+	}
+	return status, nil
+}
+
+// request sends a control message to ntpd and returns the data of the
+// response, reassembled from its fragments.
+func (c *ntpdConn) request(op byte, assoc uint16) ([]byte, error) {
+	c.sequence++
+	req := make([]byte, 12)
+	req[0] = 2<<3 | 6 // Version 2, mode 6.
+	req[1] = op
+	binary.BigEndian.PutUint16(req[2:], c.sequence)
+	binary.BigEndian.PutUint16(req[6:], assoc)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	received, total := 0, -1
+	buf := make([]byte, 1024)
+	for total < 0 || received < total {
+		n, err := c.conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		rsp := buf[:n]
+		if n < 12 || rsp[1]&ntpdResponse == 0 || rsp[1]&0x1f != op || binary.BigEndian.Uint16(rsp[2:]) != c.sequence {
+			continue
+		}
+		if rsp[1]&ntpdError != 0 {
+			return nil, fmt.Errorf("request failed with error code %d", rsp[4])
+		}
+		offset := int(binary.BigEndian.Uint16(rsp[8:]))
+		count := int(binary.BigEndian.Uint16(rsp[10:]))
+		if 12+count > n {
+			return nil, fmt.Errorf("%w: truncated response", ErrParse)
+		}
+		if end := offset + count; end > len(data) {
+			data = append(data, make([]byte, end-len(data))...)
+		}
+		copy(data[offset:], rsp[12:12+count])
+		received += count
+		if rsp[1]&ntpdMore == 0 {
+			total = offset + count
+		}
+	}
+	return data, nil
+}
+
+// parseNtpdVars parses the variables of a read variables response, a comma
+// separated list of name=value pairs whose values may be quoted.
+func parseNtpdVars(data []byte) map[string]string {
+	vars := map[string]string{}
+	s := strings.TrimRight(string(data), "\x00\r\n")
+	for s != "" {
+		quoted := false
+		i := 0
+		for ; i < len(s); i++ {
+			if s[i] == '"' {
+				quoted = !quoted
+			} else if s[i] == ',' && !quoted {
+				break
+			}
+		}
+		name, value, _ := strings.Cut(strings.TrimSpace(s[:i]), "=")
+		vars[name] = strings.Trim(value, `"`)
+		if i == len(s) {
+			break
+		}
+		s = s[i+1:]
+	}
+	return vars
+}
+
+// parseNtpdSystem returns the status of the system variables of ntpd, whose
+// times are in milliseconds.
+func parseNtpdSystem(vars map[string]string) (*timeDaemonStatus, error) {
+	status := &timeDaemonStatus{
+		daemon:    "ntpd",
+		reference: strings.Trim(vars["refid"], "."),
+	}
+	stratum, err := strconv.ParseUint(vars["stratum"], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid stratum: %s", ErrParse, err)
+	}
+	status.stratum = uint16(stratum)
+	// The leap indicator is 2 binary digits, some versions print a number.
+	base := 10
+	if len(vars["leap"]) == 2 {
+		base = 2
+	}
+	leap, err := strconv.ParseUint(vars["leap"], base, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid leap indicator: %s", ErrParse, err)
+	}
+	status.synchronized = leap != chronyLeapUnsynced
+
+	dispersion := vars["rootdisp"]
+	if dispersion == "" {
+		dispersion = vars["rootdispersion"]
+	}
+	for _, v := range []struct {
+		value string
+		dst   *float64
+	}{
+		{vars["offset"], &status.offset},
+		{vars["rootdelay"], &status.rootDelay},
+		{dispersion, &status.rootDispersion},
+	} {
+		ms, err := strconv.ParseFloat(v.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid system variable: %s", ErrParse, err)
+		}
+		*v.dst = ms / 1000
+	}
+	// ntpd's offset is the one of the sources to the local clock.
+	status.offset = -status.offset
+	return status, nil
+}
+
+// parseNtpdPeer returns the source of the variables of an ntpd peer.
+func parseNtpdPeer(vars map[string]string) (timeSource, error) {
+	address, ok := vars["srcadr"]
+	if !ok {
+		return timeSource{}, errors.New("peer without source address")
+	}
+	stratum, err := strconv.ParseUint(vars["stratum"], 10, 16)
+	if err != nil {
+		return timeSource{}, fmt.Errorf("%w: invalid stratum of peer %s: %s", ErrParse, address, err)
+	}
+	reach, err := strconv.ParseUint(vars["reach"], 8, 8)
+	if err != nil {
+		return timeSource{}, fmt.Errorf("%w: invalid reach of peer %s: %s", ErrParse, address, err)
+	}
+	return timeSource{
+		address: address,
+		stratum: uint16(stratum),
+		reach:   uint8(reach),
+	}, nil
+}
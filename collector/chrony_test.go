@@ -0,0 +1,287 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nochrony
+// +build !nochrony
+
+package collector
+
+import (
+	"encoding/binary"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// serveUDP answers the requests received on a local UDP socket with the
+// replies returned by handle, and returns the address of the socket.
+func serveUDP(t *testing.T, handle func(req []byte) [][]byte) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			for _, rpy := range handle(buf[:n]) {
+				conn.WriteTo(rpy, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// encodeChronyFloat is the inverse of chronyFloat, for values whose
+// coefficient fits in 24 bits.
+func encodeChronyFloat(v float64) []byte {
+	frac, exp := math.Frexp(v)
+	coef := int32(frac * (1 << 23))
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(exp+2)&0x7f<<25|uint32(coef)&0x1ffffff)
+	return b
+}
+
+func fakeChronyd(t *testing.T) string {
+	tracking := make([]byte, 80)
+	copy(tracking[4:], net.ParseIP("192.0.2.1").To4())
+	binary.BigEndian.PutUint16(tracking[20:], 1)
+	binary.BigEndian.PutUint16(tracking[24:], 3)
+	copy(tracking[44:], encodeChronyFloat(-0.0078125))
+	copy(tracking[64:], encodeChronyFloat(0.015625))
+	copy(tracking[68:], encodeChronyFloat(0.001953125))
+
+	server := make([]byte, 48)
+	copy(server, net.ParseIP("192.0.2.1").To4())
+	binary.BigEndian.PutUint16(server[16:], 1)
+	binary.BigEndian.PutUint16(server[22:], 2)
+	binary.BigEndian.PutUint16(server[30:], 0377)
+	refclock := make([]byte, 48)
+	copy(refclock, "PPS")
+	binary.BigEndian.PutUint16(refclock[16:], 1)
+	binary.BigEndian.PutUint16(refclock[24:], 5)
+	binary.BigEndian.PutUint16(refclock[26:], chronySourceModeRef)
+	binary.BigEndian.PutUint16(refclock[30:], 017)
+
+	return serveUDP(t, func(req []byte) [][]byte {
+		if len(req) < chronyRequestLen {
+			return nil
+		}
+		rpy := make([]byte, chronyReplyHeaderLen, 128)
+		rpy[0] = chronyProtoVersion
+		rpy[1] = chronyPktReply
+		copy(rpy[4:6], req[4:6])
+		copy(rpy[16:20], req[8:12])
+		switch binary.BigEndian.Uint16(req[4:]) {
+		case chronyReqTracking:
+			binary.BigEndian.PutUint16(rpy[6:], chronyRpyTracking)
+			rpy = append(rpy, tracking...)
+		case chronyReqNSources:
+			binary.BigEndian.PutUint16(rpy[6:], chronyRpyNSources)
+			rpy = append(rpy, 0, 0, 0, 2)
+		case chronyReqSourceData:
+			binary.BigEndian.PutUint16(rpy[6:], chronyRpySourceData)
+			if binary.BigEndian.Uint32(req[20:]) == 0 {
+				rpy = append(rpy, server...)
+			} else {
+				rpy = append(rpy, refclock...)
+			}
+		default:
+			binary.BigEndian.PutUint16(rpy[8:], 1)
+		}
+		return [][]byte{rpy}
+	})
+}
+
+func ntpdReply(req []byte, offset int, data string, more bool) []byte {
+	rsp := make([]byte, 12, 12+len(data))
+	copy(rsp, req[:12])
+	rsp[1] = req[1] | ntpdResponse
+	if more {
+		rsp[1] |= ntpdMore
+	}
+	binary.BigEndian.PutUint16(rsp[8:], uint16(offset))
+	binary.BigEndian.PutUint16(rsp[10:], uint16(len(data)))
+	return append(rsp, data...)
+}
+
+func fakeNtpd(t *testing.T) string {
+	system := `version="ntpd 4.2.8p15@1.3728-o (1)", leap=00, stratum=2, rootdelay=15.500, rootdisp=1.250, refid=192.0.2.1, offset=-2.500`
+	return serveUDP(t, func(req []byte) [][]byte {
+		switch assoc := binary.BigEndian.Uint16(req[6:]); {
+		case req[1] == ntpdOpReadStat:
+			return [][]byte{ntpdReply(req, 0, "\x00\x01\x96\x14\x00\x02\x90\x14", false)}
+		case assoc == 0:
+			// Send the fragments out of order.
+			return [][]byte{
+				ntpdReply(req, 40, system[40:], false),
+				ntpdReply(req, 0, system[:40], true),
+			}
+		case assoc == 1:
+			return [][]byte{ntpdReply(req, 0, "srcadr=192.0.2.1, stratum=1, reach=377\r\n", false)}
+		default:
+			return [][]byte{ntpdReply(req, 0, "srcadr=198.51.100.7, stratum=2, reach=17\r\n", false)}
+		}
+	})
+}
+
+// closedUDPAddress returns the address of a local UDP port nothing listens
+// on.
+func closedUDPAddress(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().String()
+}
+
+func TestChronyCollector(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		address     string
+		ntpdAddress string
+		want        string
+	}{
+		{
+			name:        "chronyd",
+			address:     fakeChronyd(t),
+			ntpdAddress: closedUDPAddress(t),
+			want: `# HELP node_chrony_info Time daemon answering the queries and its reference, the address or refid of the source it is synchronized to.
+# TYPE node_chrony_info gauge
+node_chrony_info{daemon="chronyd",reference="192.0.2.1"} 1
+# HELP node_chrony_offset_seconds Offset of the local clock measured at the last clock update, positive if it is ahead.
+# TYPE node_chrony_offset_seconds gauge
+node_chrony_offset_seconds -0.0078125
+# HELP node_chrony_root_delay_seconds Total round-trip delay to the stratum 1 clock.
+# TYPE node_chrony_root_delay_seconds gauge
+node_chrony_root_delay_seconds 0.015625
+# HELP node_chrony_root_dispersion_seconds Total dispersion accumulated up to the stratum 1 clock.
+# TYPE node_chrony_root_dispersion_seconds gauge
+node_chrony_root_dispersion_seconds 0.001953125
+# HELP node_chrony_source_reach Reachability register of a source, a bit is shifted in for each poll and set if it was answered. 255 if the last 8 polls were answered.
+# TYPE node_chrony_source_reach gauge
+node_chrony_source_reach{source="192.0.2.1"} 255
+node_chrony_source_reach{source="PPS"} 15
+# HELP node_chrony_source_selected Whether a source is used to synchronize the local clock.
+# TYPE node_chrony_source_selected gauge
+node_chrony_source_selected{source="192.0.2.1"} 1
+node_chrony_source_selected{source="PPS"} 0
+# HELP node_chrony_source_stratum Stratum of a source.
+# TYPE node_chrony_source_stratum gauge
+node_chrony_source_stratum{source="192.0.2.1"} 2
+node_chrony_source_stratum{source="PPS"} 0
+# HELP node_chrony_stratum Stratum of the local clock.
+# TYPE node_chrony_stratum gauge
+node_chrony_stratum 3
+# HELP node_chrony_synchronized Whether the time daemon considers the local clock synchronized.
+# TYPE node_chrony_synchronized gauge
+node_chrony_synchronized 1
+`,
+		},
+		{
+			name:        "ntpd fallback",
+			address:     closedUDPAddress(t),
+			ntpdAddress: fakeNtpd(t),
+			want: `# HELP node_chrony_info Time daemon answering the queries and its reference, the address or refid of the source it is synchronized to.
+# TYPE node_chrony_info gauge
+node_chrony_info{daemon="ntpd",reference="192.0.2.1"} 1
+# HELP node_chrony_offset_seconds Offset of the local clock measured at the last clock update, positive if it is ahead.
+# TYPE node_chrony_offset_seconds gauge
+node_chrony_offset_seconds 0.0025
+# HELP node_chrony_root_delay_seconds Total round-trip delay to the stratum 1 clock.
+# TYPE node_chrony_root_delay_seconds gauge
+node_chrony_root_delay_seconds 0.0155
+# HELP node_chrony_root_dispersion_seconds Total dispersion accumulated up to the stratum 1 clock.
+# TYPE node_chrony_root_dispersion_seconds gauge
+node_chrony_root_dispersion_seconds 0.00125
+# HELP node_chrony_source_reach Reachability register of a source, a bit is shifted in for each poll and set if it was answered. 255 if the last 8 polls were answered.
+# TYPE node_chrony_source_reach gauge
+node_chrony_source_reach{source="192.0.2.1"} 255
+node_chrony_source_reach{source="198.51.100.7"} 15
+# HELP node_chrony_source_selected Whether a source is used to synchronize the local clock.
+# TYPE node_chrony_source_selected gauge
+node_chrony_source_selected{source="192.0.2.1"} 1
+node_chrony_source_selected{source="198.51.100.7"} 0
+# HELP node_chrony_source_stratum Stratum of a source.
+# TYPE node_chrony_source_stratum gauge
+node_chrony_source_stratum{source="192.0.2.1"} 1
+node_chrony_source_stratum{source="198.51.100.7"} 2
+# HELP node_chrony_stratum Stratum of the local clock.
+# TYPE node_chrony_stratum gauge
+node_chrony_stratum 2
+# HELP node_chrony_synchronized Whether the time daemon considers the local clock synchronized.
+# TYPE node_chrony_synchronized gauge
+node_chrony_synchronized 1
+`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewChronyCollector(log.NewNopLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			cc := c.(*chronyCollector)
+			cc.address = tc.address
+			cc.ntpdAddress = tc.ntpdAddress
+			cc.timeout = time.Second
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(collectorAdapter{c})
+			if err := testutil.GatherAndCompare(reg, strings.NewReader(tc.want)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestChronyFloat(t *testing.T) {
+	for _, tc := range []struct {
+		encoded uint32
+		want    float64
+	}{
+		{0x00000000, 0},
+		{0x04800000, 1},
+		{0x05800000, -1},
+		{0x06c00000, 3},
+	} {
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, tc.encoded)
+		if got := chronyFloat(b); got != tc.want {
+			t.Errorf("chronyFloat(%#08x) = %v, want %v", tc.encoded, got, tc.want)
+		}
+	}
+}
+
+func TestParseNtpdVars(t *testing.T) {
+	vars := parseNtpdVars([]byte("version=\"ntpd 4.2.8, built\", leap=11,\r\nstratum=16\r\n"))
+	want := map[string]string{"version": "ntpd 4.2.8, built", "leap": "11", "stratum": "16"}
+	if len(vars) != len(want) {
+		t.Fatalf("got %v, want %v", vars, want)
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s = %q, want %q", k, vars[k], v)
+		}
+	}
+}
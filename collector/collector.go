@@ -15,8 +15,11 @@
 package collector
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -42,6 +45,12 @@ var (
 		[]string{"collector"},
 		nil,
 	)
+	scrapeFailureReasonDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_failure_reason"),
+		"node_exporter: Why a collector failed, one of no_data, permission, timeout, parse or error.",
+		[]string{"collector", "reason"},
+		nil,
+	)
 )
 
 const (
@@ -55,6 +64,10 @@ var (
 	initiatedCollectors    = make(map[string]Collector)
 	collectorState         = make(map[string]*bool)
 	forcedCollectors       = map[string]bool{} // collectors which have been explicitly enabled or disabled
+	// collectorSchedules is loaded with the first NodeCollector, under
+	// initiatedCollectorsMtx.
+	collectorSchedules       map[string]*cronSchedule
+	collectorSchedulesLoaded bool
 )
 
 func registerCollector(collector string, isDefaultEnabled bool, factory func(logger log.Logger) (Collector, error)) {
@@ -91,6 +104,31 @@ func DisableDefaultCollectors() {
 	}
 }
 
+// EnableCollectors enables the given collectors like their --collector.<name>
+// flags, for programs which select collectors without a command line.
+func EnableCollectors(names ...string) error {
+	initiatedCollectorsMtx.Lock()
+	defer initiatedCollectorsMtx.Unlock()
+	for _, name := range names {
+		enabled, ok := collectorState[name]
+		if !ok {
+			return fmt.Errorf("missing collector: %s", name)
+		}
+		*enabled = true
+	}
+	return nil
+}
+
+// Collectors returns the names of the collectors compiled in, with whether
+// they are enabled.
+func Collectors() map[string]bool {
+	collectors := make(map[string]bool, len(collectorState))
+	for name, enabled := range collectorState {
+		collectors[name] = *enabled
+	}
+	return collectors
+}
+
 // collectorFlagAction generates a new action function for the given collector
 // to track whether it has been explicitly enabled or disabled from the command line.
 // A new action function is needed for each collector flag because the ParseContext
@@ -119,6 +157,13 @@ func NewNodeCollector(logger log.Logger, filters ...string) (*NodeCollector, err
 	collectors := make(map[string]Collector)
 	initiatedCollectorsMtx.Lock()
 	defer initiatedCollectorsMtx.Unlock()
+	if !collectorSchedulesLoaded {
+		schedules, err := loadSchedules()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load collector schedules: %w", err)
+		}
+		collectorSchedules, collectorSchedulesLoaded = schedules, true
+	}
 	for key, enabled := range collectorState {
 		if !*enabled || (len(f) > 0 && !f[key]) {
 			continue
@@ -130,6 +175,9 @@ func NewNodeCollector(logger log.Logger, filters ...string) (*NodeCollector, err
 			if err != nil {
 				return nil, err
 			}
+			if schedule, ok := collectorSchedules[key]; ok {
+				collector = newScheduledCollector(key, collector, schedule)
+			}
 			collectors[key] = collector
 			initiatedCollectors[key] = collector
 		}
@@ -141,33 +189,76 @@ func NewNodeCollector(logger log.Logger, filters ...string) (*NodeCollector, err
 func (n NodeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	ch <- scrapeFailureReasonDesc
+	ch <- autoDisabledDesc
+	if *pressureThreshold > 0 {
+		ch <- scrapeSkippedDesc
+	}
+	if haEnabled() {
+		ch <- leaderDesc
+	}
+	if len(collectorSchedules) > 0 {
+		ch <- scrapeLastRunDesc
+	}
+	if *resourceAccounting {
+		ch <- scrapeCPUDesc
+		ch <- scrapeAllocDesc
+	}
 }
 
 // Collect implements the prometheus.Collector interface.
 func (n NodeCollector) Collect(ch chan<- prometheus.Metric) {
+	collectAutoDisabled(ch)
+	if skipped := n.pressureSkipped(ch); len(skipped) > 0 {
+		n = n.without(skipped)
+	}
+	if skipped := n.leaderSkipped(ch); len(skipped) > 0 {
+		n = n.without(skipped)
+	}
+	snapshot := n.snapshot()
+	if *resourceAccounting {
+		n.collectAccounted(ch, snapshot)
+		return
+	}
 	wg := sync.WaitGroup{}
 	wg.Add(len(n.Collectors))
 	for name, c := range n.Collectors {
 		go func(name string, c Collector) {
-			execute(name, c, ch, n.logger)
+			execute(name, c, ch, n.logger, snapshot)
 			wg.Done()
 		}(name, c)
 	}
 	wg.Wait()
 }
 
-func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.Logger) {
+func execute(name string, c Collector, ch chan<- prometheus.Metric, logger log.Logger, snapshot *procSnapshot) {
 	begin := time.Now()
-	err := c.Update(ch)
+	var err error
+	if sc, ok := c.(snapshotCollector); ok && snapshot != nil {
+		err = sc.updateFromSnapshot(ch, snapshot)
+	} else {
+		err = c.Update(ch)
+	}
 	duration := time.Since(begin)
 	var success float64
 
 	if err != nil {
-		if IsNoDataError(err) {
+		reason := errorReason(err)
+		switch reason {
+		case reasonNoData:
 			level.Debug(logger).Log("msg", "collector returned no data", "name", name, "duration_seconds", duration.Seconds(), "err", err)
-		} else {
-			level.Error(logger).Log("msg", "collector failed", "name", name, "duration_seconds", duration.Seconds(), "err", err)
+		case reasonPermission:
+			if errors.Is(err, ErrPermission) {
+				// The collector expects to lack privileges on some hosts,
+				// like missing hardware.
+				level.Debug(logger).Log("msg", "collector lacks permission", "name", name, "duration_seconds", duration.Seconds(), "err", err)
+				break
+			}
+			level.Error(logger).Log("msg", "collector failed", "name", name, "reason", reason, "duration_seconds", duration.Seconds(), "err", err)
+		default:
+			level.Error(logger).Log("msg", "collector failed", "name", name, "reason", reason, "duration_seconds", duration.Seconds(), "err", err)
 		}
+		ch <- prometheus.MustNewConstMetric(scrapeFailureReasonDesc, prometheus.GaugeValue, 1, name, reason)
 		success = 0
 	} else {
 		level.Debug(logger).Log("msg", "collector succeeded", "name", name, "duration_seconds", duration.Seconds())
@@ -192,11 +283,60 @@ func (d *typedDesc) mustNewConstMetric(value float64, labels ...string) promethe
 	return prometheus.MustNewConstMetric(d.desc, d.valueType, value, labels...)
 }
 
-// ErrNoData indicates the collector found no data to collect, but had no other error.
-var ErrNoData = errors.New("collector returned no data")
+var (
+	// ErrNoData indicates the collector found no data to collect, but had no other error.
+	ErrNoData = errors.New("collector returned no data")
+	// ErrPermission indicates the collector lacks the privileges to read its data.
+	ErrPermission = errors.New("permission denied")
+	// ErrTimeout indicates the source of the data didn't answer in time.
+	ErrTimeout = errors.New("timed out")
+	// ErrParse indicates the data was read but couldn't be understood.
+	ErrParse = errors.New("couldn't parse data")
+)
+
+// Reasons of collector failures, the reason label of
+// node_scrape_collector_failure_reason.
+const (
+	reasonNoData     = "no_data"
+	reasonPermission = "permission"
+	reasonTimeout    = "timeout"
+	reasonParse      = "parse"
+	reasonError      = "error"
+)
+
+// expectedPermissionError wraps err with ErrPermission if it's a permission
+// error, for collectors whose data is only readable with privileges the
+// exporter commonly runs without. These failures are only logged at debug
+// level, other permission errors are logged as errors.
+func expectedPermissionError(err error) error {
+	if errors.Is(err, os.ErrPermission) && !errors.Is(err, ErrPermission) {
+		return fmt.Errorf("%w: %v", ErrPermission, err)
+	}
+	return err
+}
 
 func IsNoDataError(err error) bool {
-	return err == ErrNoData
+	return errors.Is(err, ErrNoData)
+}
+
+// errorReason classifies the error returned by a collector. Collectors can
+// wrap the Err* errors with %w, common errors of the standard library are
+// classified as well.
+func errorReason(err error) string {
+	var numErr *strconv.NumError
+	var timeoutErr interface{ Timeout() bool }
+	switch {
+	case errors.Is(err, ErrNoData):
+		return reasonNoData
+	case errors.Is(err, ErrPermission), errors.Is(err, os.ErrPermission):
+		return reasonPermission
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &timeoutErr) && timeoutErr.Timeout():
+		return reasonTimeout
+	case errors.Is(err, ErrParse), errors.As(err, &numErr):
+		return reasonParse
+	}
+	return reasonError
 }
 
 // pushMetric helps construct and convert a variety of value types into Prometheus float64 metrics.
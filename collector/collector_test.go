@@ -0,0 +1,61 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestErrorReason(t *testing.T) {
+	_, numErr := strconv.ParseUint("x", 10, 64)
+	for _, c := range []struct {
+		err  error
+		want string
+	}{
+		{ErrNoData, "no_data"},
+		{fmt.Errorf("no device: %w", ErrNoData), "no_data"},
+		{fmt.Errorf("couldn't list tables: %w", syscall.EPERM), "permission"},
+		{&os.PathError{Op: "open", Path: "/sys/class/powercap", Err: syscall.EACCES}, "permission"},
+		{ErrPermission, "permission"},
+		{fmt.Errorf("dbus: %w", context.DeadlineExceeded), "timeout"},
+		{os.ErrDeadlineExceeded, "timeout"},
+		{fmt.Errorf("invalid value: %w", numErr), "parse"},
+		{fmt.Errorf("%w: unexpected field count", ErrParse), "parse"},
+		{errors.New("something broke"), "error"},
+	} {
+		if got := errorReason(c.err); got != c.want {
+			t.Errorf("%v: want %s, got %s", c.err, c.want, got)
+		}
+	}
+}
+
+func TestExpectedPermissionError(t *testing.T) {
+	err := expectedPermissionError(&os.PathError{Op: "open", Path: "/sys/class/powercap", Err: syscall.EACCES})
+	if !errors.Is(err, ErrPermission) {
+		t.Errorf("%v: want ErrPermission", err)
+	}
+	if got := errorReason(err); got != "permission" {
+		t.Errorf("%v: want permission, got %s", err, got)
+	}
+	other := errors.New("something broke")
+	if err := expectedPermissionError(other); err != other {
+		t.Errorf("want other errors unchanged, got %v", err)
+	}
+}
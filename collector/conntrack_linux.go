@@ -20,13 +20,17 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
 )
 
+var conntrackPerCPU = kingpin.Flag("collector.conntrack.per-cpu", "Expose the conntrack statistics of each CPU.").Default("false").Bool()
+
 type conntrackCollector struct {
 	current       *prometheus.Desc
 	limit         *prometheus.Desc
@@ -38,6 +42,7 @@ type conntrackCollector struct {
 	drop          *prometheus.Desc
 	earlyDrop     *prometheus.Desc
 	searchRestart *prometheus.Desc
+	perCPU        map[string]*prometheus.Desc
 	logger        log.Logger
 }
 
@@ -50,6 +55,17 @@ type conntrackStatistics struct {
 	drop          uint64 // Number of packets dropped due to conntrack failure. Either new conntrack entry allocation failed, or protocol helper dropped the packet
 	earlyDrop     uint64 // Number of dropped conntrack entries to make room for new ones, if maximum table size was reached
 	searchRestart uint64 // Number of conntrack table lookups which had to be restarted due to hashtable resizes
+	perCPU        []procfs.ConntrackStatEntry
+}
+
+// conntrackPerCPUStats are the statistics exposed per CPU, with their help.
+var conntrackPerCPUStats = map[string]string{
+	"found":          "Number of searched entries which were successful.",
+	"invalid":        "Number of packets seen which can not be tracked.",
+	"insert_failed":  "Number of entries for which list insertion was attempted but failed.",
+	"drop":           "Number of packets dropped due to conntrack failure.",
+	"early_drop":     "Number of dropped conntrack entries to make room for new ones, if maximum table size was reached.",
+	"search_restart": "Number of conntrack table lookups which had to be restarted due to hashtable resizes.",
 }
 
 func init() {
@@ -58,6 +74,14 @@ func init() {
 
 // NewConntrackCollector returns a new Collector exposing conntrack stats.
 func NewConntrackCollector(logger log.Logger) (Collector, error) {
+	perCPU := make(map[string]*prometheus.Desc, len(conntrackPerCPUStats))
+	for stat, help := range conntrackPerCPUStats {
+		perCPU[stat] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "nf_conntrack_cpu_"+stat+"_total"),
+			help+" Per CPU.",
+			[]string{"cpu"}, nil,
+		)
+	}
 	return &conntrackCollector{
 		current: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "", "nf_conntrack_entries"),
@@ -109,6 +133,7 @@ func NewConntrackCollector(logger log.Logger) (Collector, error) {
 			"Number of conntrack table lookups which had to be restarted due to hashtable resizes.",
 			nil, nil,
 		),
+		perCPU: perCPU,
 		logger: logger,
 	}, nil
 }
@@ -149,6 +174,23 @@ func (c *conntrackCollector) Update(ch chan<- prometheus.Metric) error {
 		c.earlyDrop, prometheus.GaugeValue, float64(conntrackStats.earlyDrop))
 	ch <- prometheus.MustNewConstMetric(
 		c.searchRestart, prometheus.GaugeValue, float64(conntrackStats.searchRestart))
+
+	if *conntrackPerCPU {
+		// The rows of /proc/net/stat/nf_conntrack are in CPU order.
+		for i, s := range conntrackStats.perCPU {
+			cpu := strconv.Itoa(i)
+			for stat, v := range map[string]uint64{
+				"found":          s.Found,
+				"invalid":        s.Invalid,
+				"insert_failed":  s.InsertFailed,
+				"drop":           s.Drop,
+				"early_drop":     s.EarlyDrop,
+				"search_restart": s.SearchRestart,
+			} {
+				ch <- prometheus.MustNewConstMetric(c.perCPU[stat], prometheus.CounterValue, float64(v), cpu)
+			}
+		}
+	}
 	return nil
 }
 
@@ -183,6 +225,7 @@ func getConntrackStatistics() (*conntrackStatistics, error) {
 		c.earlyDrop += connStat.EarlyDrop
 		c.searchRestart += connStat.SearchRestart
 	}
+	c.perCPU = connStats
 
 	return &c, nil
 }
@@ -35,19 +35,21 @@ import (
 )
 
 type cpuCollector struct {
-	fs                 procfs.FS
-	cpu                *prometheus.Desc
-	cpuInfo            *prometheus.Desc
-	cpuFlagsInfo       *prometheus.Desc
-	cpuBugsInfo        *prometheus.Desc
-	cpuGuest           *prometheus.Desc
-	cpuCoreThrottle    *prometheus.Desc
-	cpuPackageThrottle *prometheus.Desc
-	cpuIsolated        *prometheus.Desc
-	logger             log.Logger
-	cpuStats           map[int64]procfs.CPUStat
-	cpuStatsMutex      sync.Mutex
-	isolatedCpus       []uint16
+	fs                     procfs.FS
+	cpu                    *prometheus.Desc
+	cpuInfo                *prometheus.Desc
+	cpuFlagsInfo           *prometheus.Desc
+	cpuBugsInfo            *prometheus.Desc
+	cpuGuest               *prometheus.Desc
+	cpuCoreThrottle        *prometheus.Desc
+	cpuPackageThrottle     *prometheus.Desc
+	cpuCoreThrottleTime    *prometheus.Desc
+	cpuPackageThrottleTime *prometheus.Desc
+	cpuIsolated            *prometheus.Desc
+	logger                 log.Logger
+	cpuStats               map[int64]procfs.CPUStat
+	cpuStatsMutex          sync.Mutex
+	isolatedCpus           []uint16
 
 	cpuFlagsIncludeRegexp *regexp.Regexp
 	cpuBugsIncludeRegexp  *regexp.Regexp
@@ -121,6 +123,16 @@ func NewCPUCollector(logger log.Logger) (Collector, error) {
 			"Number of times this CPU package has been throttled.",
 			[]string{"package"}, nil,
 		),
+		cpuCoreThrottleTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "core_throttle_seconds_total"),
+			"Seconds this CPU core has been throttled.",
+			[]string{"package", "core"}, nil,
+		),
+		cpuPackageThrottleTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "package_throttle_seconds_total"),
+			"Seconds this CPU package has been throttled.",
+			[]string{"package"}, nil,
+		),
 		cpuIsolated: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "isolated"),
 			"Whether each core is isolated, information from /sys/devices/system/cpu/isolated.",
@@ -161,12 +173,21 @@ func (c *cpuCollector) compileIncludeFlags(flagsIncludeFlag, bugsIncludeFlag *st
 
 // Update implements Collector and exposes cpu related metrics from /proc/stat and /sys/.../cpu/.
 func (c *cpuCollector) Update(ch chan<- prometheus.Metric) error {
+	return c.update(ch, c.fs.Stat)
+}
+
+// updateFromSnapshot implements snapshotCollector.
+func (c *cpuCollector) updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error {
+	return c.update(ch, s.Stat)
+}
+
+func (c *cpuCollector) update(ch chan<- prometheus.Metric, stat func() (procfs.Stat, error)) error {
 	if *enableCPUInfo {
 		if err := c.updateInfo(ch); err != nil {
 			return err
 		}
 	}
-	if err := c.updateStat(ch); err != nil {
+	if err := c.updateStat(ch, stat); err != nil {
 		return err
 	}
 	if c.isolatedCpus != nil {
@@ -237,6 +258,9 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 
 	packageThrottles := make(map[uint64]uint64)
 	packageCoreThrottles := make(map[uint64]map[uint64]uint64)
+	// The times throttled in milliseconds, since Linux 5.18.
+	packageThrottleTimes := make(map[uint64]uint64)
+	packageCoreThrottleTimes := make(map[uint64]map[uint64]uint64)
 
 	// cpu loop
 	for _, cpu := range cpus {
@@ -266,6 +290,7 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 		// Seen e.g. on an Intel Xeon E5472 system with RHEL 6.9 kernel.
 		if _, present := packageCoreThrottles[physicalPackageID]; !present {
 			packageCoreThrottles[physicalPackageID] = make(map[uint64]uint64)
+			packageCoreThrottleTimes[physicalPackageID] = make(map[uint64]uint64)
 		}
 		if _, present := packageCoreThrottles[physicalPackageID][coreID]; !present {
 			// Read thermal_throttle/core_throttle_count only once
@@ -274,6 +299,9 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 			} else {
 				level.Debug(c.logger).Log("msg", "CPU is missing core_throttle_count", "cpu", cpu)
 			}
+			if coreThrottleTime, err := readUintFromFile(filepath.Join(cpu, "thermal_throttle", "core_throttle_total_time_ms")); err == nil {
+				packageCoreThrottleTimes[physicalPackageID][coreID] = coreThrottleTime
+			}
 		}
 
 		// metric node_cpu_package_throttles_total
@@ -284,6 +312,9 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 			} else {
 				level.Debug(c.logger).Log("msg", "CPU is missing package_throttle_count", "cpu", cpu)
 			}
+			if packageThrottleTime, err := readUintFromFile(filepath.Join(cpu, "thermal_throttle", "package_throttle_total_time_ms")); err == nil {
+				packageThrottleTimes[physicalPackageID] = packageThrottleTime
+			}
 		}
 	}
 
@@ -303,6 +334,23 @@ func (c *cpuCollector) updateThermalThrottle(ch chan<- prometheus.Metric) error
 				strconv.FormatUint(coreID, 10))
 		}
 	}
+
+	for physicalPackageID, packageThrottleTime := range packageThrottleTimes {
+		ch <- prometheus.MustNewConstMetric(c.cpuPackageThrottleTime,
+			prometheus.CounterValue,
+			float64(packageThrottleTime)/1000,
+			strconv.FormatUint(physicalPackageID, 10))
+	}
+
+	for physicalPackageID, coreMap := range packageCoreThrottleTimes {
+		for coreID, coreThrottleTime := range coreMap {
+			ch <- prometheus.MustNewConstMetric(c.cpuCoreThrottleTime,
+				prometheus.CounterValue,
+				float64(coreThrottleTime)/1000,
+				strconv.FormatUint(physicalPackageID, 10),
+				strconv.FormatUint(coreID, 10))
+		}
+	}
 	return nil
 }
 
@@ -315,8 +363,8 @@ func (c *cpuCollector) updateIsolated(ch chan<- prometheus.Metric) {
 }
 
 // updateStat reads /proc/stat through procfs and exports CPU-related metrics.
-func (c *cpuCollector) updateStat(ch chan<- prometheus.Metric) error {
-	stats, err := c.fs.Stat()
+func (c *cpuCollector) updateStat(ch chan<- prometheus.Metric, stat func() (procfs.Stat, error)) error {
+	stats, err := stat()
 	if err != nil {
 		return err
 	}
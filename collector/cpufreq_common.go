@@ -56,4 +56,14 @@ var (
 		"Current enabled CPU frequency governor.",
 		[]string{"cpu", "governor"}, nil,
 	)
+	cpuFreqScalingDriverDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "scaling_driver_info"),
+		"CPU frequency scaling driver.",
+		[]string{"cpu", "driver"}, nil,
+	)
+	cpuFreqBIOSLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, cpuCollectorSubsystem, "frequency_bios_limit_hertz"),
+		"Maximum CPU frequency the firmware allows, lowered e.g. when running on battery or overheating.",
+		[]string{"cpu"}, nil,
+	)
 )
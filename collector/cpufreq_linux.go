@@ -21,6 +21,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
+	"path/filepath"
 	"strings"
 )
 
@@ -31,6 +32,7 @@ type cpuFreqCollector struct {
 
 func init() {
 	registerCollector("cpufreq", defaultEnabled, NewCPUFreqCollector)
+	registerHardwareProbe("cpufreq", "no cpufreq policies", sysfsProbe("devices/system/cpu/cpufreq/policy*"))
 }
 
 // NewCPUFreqCollector returns a new Collector exposing kernel/system statistics.
@@ -104,6 +106,24 @@ func (c *cpuFreqCollector) Update(ch chan<- prometheus.Metric) error {
 				stats.Name,
 			)
 		}
+		if stats.Driver != "" {
+			ch <- prometheus.MustNewConstMetric(
+				cpuFreqScalingDriverDesc,
+				prometheus.GaugeValue,
+				1,
+				stats.Name,
+				stats.Driver,
+			)
+		}
+		// bios_limit is only present with the ACPI drivers.
+		if limit, err := readUintFromFile(sysFilePath(filepath.Join("devices/system/cpu", "cpu"+stats.Name, "cpufreq/bios_limit"))); err == nil {
+			ch <- prometheus.MustNewConstMetric(
+				cpuFreqBIOSLimitDesc,
+				prometheus.GaugeValue,
+				float64(limit)*1000.0,
+				stats.Name,
+			)
+		}
 		if stats.Governor != "" {
 			availableGovernors := strings.Split(stats.AvailableGovernors, " ")
 			for _, g := range availableGovernors {
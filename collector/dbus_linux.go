@@ -0,0 +1,225 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nodbus
+// +build !nodbus
+
+package collector
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/godbus/dbus/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const dbusCollectorSubsystem = "dbus"
+
+var dbusTimeout = kingpin.Flag("collector.dbus.timeout", "Timeout for connecting to and querying the system bus.").Default("5s").Duration()
+
+// dbusDaemonStats maps the keys of org.freedesktop.DBus.Debug.Stats.GetStats
+// to the metrics they are exposed as.
+var dbusDaemonStats = map[string]string{
+	"ActiveConnections":     "active_connections",
+	"IncompleteConnections": "incomplete_connections",
+	"MatchRules":            "match_rules",
+	"PeakMatchRules":        "match_rules_peak",
+	"BusNames":              "bus_names",
+	"PeakBusNames":          "bus_names_peak",
+}
+
+type dbusCollector struct {
+	up           *prometheus.Desc
+	pingDuration *prometheus.Desc
+	connections  *prometheus.Desc
+	names        *prometheus.Desc
+	queued       *prometheus.Desc
+	queuedBytes  *prometheus.Desc
+	daemonStats  map[string]*prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(dbusCollectorSubsystem, defaultDisabled, NewDbusCollector)
+}
+
+// NewDbusCollector returns a new Collector exposing the health of the system
+// D-Bus.
+func NewDbusCollector(logger log.Logger) (Collector, error) {
+	daemonStats := make(map[string]*prometheus.Desc, len(dbusDaemonStats))
+	for key, name := range dbusDaemonStats {
+		daemonStats[key] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "daemon_"+name),
+			"dbus-daemon statistic "+key+" from org.freedesktop.DBus.Debug.Stats.",
+			nil, nil,
+		)
+	}
+	return &dbusCollector{
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "up"),
+			"Whether the system bus could be connected to and answered a ping within the timeout.",
+			nil, nil,
+		),
+		pingDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "ping_duration_seconds"),
+			"Round trip time of a ping to the bus daemon.",
+			nil, nil,
+		),
+		connections: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "connections"),
+			"Number of connections to the system bus, i.e. unique names.",
+			nil, nil,
+		),
+		names: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "names"),
+			"Number of well-known names owned on the system bus.",
+			nil, nil,
+		),
+		queued: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "daemon_queued_messages"),
+			"Number of messages queued by dbus-daemon across all connections.",
+			[]string{"direction"}, nil,
+		),
+		queuedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, dbusCollectorSubsystem, "daemon_queued_bytes"),
+			"Size of the messages queued by dbus-daemon across all connections.",
+			[]string{"direction"}, nil,
+		),
+		daemonStats: daemonStats,
+		logger:      logger,
+	}, nil
+}
+
+// Validate implements Validator.
+func (c *dbusCollector) Validate() error {
+	return checkSystemBus()
+}
+
+func (c *dbusCollector) Update(ch chan<- prometheus.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), *dbusTimeout)
+	defer cancel()
+
+	conn, err := dbusConnect(ctx)
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "unable to connect to the system bus", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return nil
+	}
+	defer conn.Close()
+
+	bus := conn.BusObject()
+	begin := time.Now()
+	if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.Peer.Ping", 0).Err; err != nil {
+		level.Debug(c.logger).Log("msg", "system bus didn't answer ping", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(c.pingDuration, prometheus.GaugeValue, time.Since(begin).Seconds())
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	var names []string
+	if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return err
+	}
+	unique, wellKnown := dbusSplitNames(names)
+	ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(len(unique)))
+	ch <- prometheus.MustNewConstMetric(c.names, prometheus.GaugeValue, float64(wellKnown))
+
+	c.updateDaemonStats(ctx, ch, bus, unique)
+	return nil
+}
+
+// updateDaemonStats exposes the statistics of dbus-daemon. They are only
+// available if it was built with statistics support and to privileged
+// callers, and dbus-broker doesn't implement them at all.
+func (c *dbusCollector) updateDaemonStats(ctx context.Context, ch chan<- prometheus.Metric, bus dbus.BusObject, unique []string) {
+	var stats map[string]dbus.Variant
+	if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.Debug.Stats.GetStats", 0).Store(&stats); err != nil {
+		level.Debug(c.logger).Log("msg", "bus statistics not available", "err", err)
+		return
+	}
+	for key, desc := range c.daemonStats {
+		if v, ok := dbusVariantFloat(stats[key]); ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+		}
+	}
+
+	queued := map[string]float64{}
+	for _, name := range unique {
+		var connStats map[string]dbus.Variant
+		if err := bus.CallWithContext(ctx, "org.freedesktop.DBus.Debug.Stats.GetConnectionStats", 0, name).Store(&connStats); err != nil {
+			// The connection may have gone away since it was listed.
+			continue
+		}
+		for _, key := range []string{"IncomingMessages", "OutgoingMessages", "IncomingBytes", "OutgoingBytes"} {
+			if v, ok := dbusVariantFloat(connStats[key]); ok {
+				queued[key] += v
+			}
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, queued["IncomingMessages"], "incoming")
+	ch <- prometheus.MustNewConstMetric(c.queued, prometheus.GaugeValue, queued["OutgoingMessages"], "outgoing")
+	ch <- prometheus.MustNewConstMetric(c.queuedBytes, prometheus.GaugeValue, queued["IncomingBytes"], "incoming")
+	ch <- prometheus.MustNewConstMetric(c.queuedBytes, prometheus.GaugeValue, queued["OutgoingBytes"], "outgoing")
+}
+
+// dbusSplitNames returns the unique names of the connections to the bus and
+// the number of well-known names, without the one of the bus itself.
+func dbusSplitNames(names []string) ([]string, int) {
+	var unique []string
+	wellKnown := 0
+	for _, name := range names {
+		switch {
+		case strings.HasPrefix(name, ":"):
+			unique = append(unique, name)
+		case name != "org.freedesktop.DBus":
+			wellKnown++
+		}
+	}
+	return unique, wellKnown
+}
+
+func dbusConnect(ctx context.Context) (*dbus.Conn, error) {
+	conn, err := dbus.SystemBusPrivate(dbus.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth([]dbus.Auth{dbus.AuthExternal(strconv.Itoa(os.Getuid()))}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func dbusVariantFloat(v dbus.Variant) (float64, bool) {
+	switch n := v.Value().(type) {
+	case uint32:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nodbus
+// +build !nodbus
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func TestDbusSplitNames(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		names     []string
+		unique    []string
+		wellKnown int
+	}{
+		{"empty", nil, nil, 0},
+		{"bus only", []string{"org.freedesktop.DBus"}, nil, 0},
+		{
+			"mixed",
+			[]string{"org.freedesktop.DBus", ":1.0", "org.freedesktop.systemd1", ":1.12", "org.freedesktop.login1"},
+			[]string{":1.0", ":1.12"},
+			2,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			unique, wellKnown := dbusSplitNames(tc.names)
+			if !reflect.DeepEqual(unique, tc.unique) || wellKnown != tc.wellKnown {
+				t.Errorf("want %v and %d well-known names, got %v and %d", tc.unique, tc.wellKnown, unique, wellKnown)
+			}
+		})
+	}
+}
+
+func TestDbusVariantFloat(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		want  float64
+		ok    bool
+	}{
+		{uint32(42), 42, true},
+		{int32(-1), -1, true},
+		{uint64(1 << 40), 1 << 40, true},
+		{"42", 0, false},
+		{true, 0, false},
+	} {
+		got, ok := dbusVariantFloat(dbus.MakeVariant(tc.value))
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("%#v: want %v, %v, got %v, %v", tc.value, tc.want, tc.ok, got, ok)
+		}
+	}
+	if _, ok := dbusVariantFloat(dbus.Variant{}); ok {
+		t.Error("missing statistic: want not ok")
+	}
+}
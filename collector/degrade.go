@@ -0,0 +1,74 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	pressureThreshold = kingpin.Flag("collector.pressure-threshold", "Skip the collectors given with --collector.pressure-skip for a scrape while the CPU or memory pressure of the host, the \"some avg10\" percentage of PSI, is above this value. 0 disables skipping.").Default("0").Float64()
+	pressureSkip      = kingpin.Flag("collector.pressure-skip", "Comma separated list of the expensive collectors to skip under pressure.").Default("hwmon,mountstats,processes,systemd,tcpstat").String()
+
+	scrapeSkippedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_skipped"),
+		"node_exporter: Whether a collector was skipped because of the pressure on the host.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// pressureSkipped returns the collectors to skip for this scrape because the
+// host is under pressure, and exposes whether the collectors which may be
+// skipped were.
+func (n NodeCollector) pressureSkipped(ch chan<- prometheus.Metric) map[string]bool {
+	if *pressureThreshold <= 0 {
+		return nil
+	}
+	pressure, err := hostPressure()
+	if err != nil {
+		level.Debug(n.logger).Log("msg", "Couldn't get the pressure of the host, not skipping collectors", "err", err)
+	}
+	underPressure := err == nil && pressure > *pressureThreshold
+
+	skipped := map[string]bool{}
+	for _, name := range strings.Split(*pressureSkip, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := n.Collectors[name]; !ok {
+			continue
+		}
+		skipped[name] = underPressure
+		ch <- prometheus.MustNewConstMetric(scrapeSkippedDesc, prometheus.GaugeValue, boolToFloat(underPressure), name)
+	}
+	if underPressure {
+		level.Warn(n.logger).Log("msg", "Host under pressure, skipping expensive collectors", "pressure", pressure, "threshold", *pressureThreshold)
+		return skipped
+	}
+	return nil
+}
+
+// without returns a copy of the NodeCollector without the given collectors.
+func (n NodeCollector) without(skipped map[string]bool) NodeCollector {
+	collectors := make(map[string]Collector, len(n.Collectors))
+	for name, c := range n.Collectors {
+		if !skipped[name] {
+			collectors[name] = c
+		}
+	}
+	return NodeCollector{Collectors: collectors, logger: n.logger}
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import "github.com/prometheus/procfs"
+
+// hostPressure returns the highest "some avg10" percentage of the CPU and
+// memory pressure of the host.
+func hostPressure() (float64, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return 0, err
+	}
+	pressure := 0.0
+	for _, resource := range []string{"cpu", "memory"} {
+		stats, err := fs.PSIStatsForResource(resource)
+		if err != nil {
+			return 0, err
+		}
+		if stats.Some != nil && stats.Some.Avg10 > pressure {
+			pressure = stats.Some.Avg10
+		}
+	}
+	return pressure, nil
+}
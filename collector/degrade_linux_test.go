@@ -0,0 +1,74 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPressureSkipped(t *testing.T) {
+	savedProcPath, savedThreshold, savedSkip := *procPath, *pressureThreshold, *pressureSkip
+	defer func() {
+		*procPath, *pressureThreshold, *pressureSkip = savedProcPath, savedThreshold, savedSkip
+	}()
+
+	*procPath = t.TempDir()
+	if err := os.Mkdir(filepath.Join(*procPath, "pressure"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writePressure := func(cpu, memory string) {
+		for resource, some := range map[string]string{"cpu": cpu, "memory": memory} {
+			psi := "some avg10=" + some + " avg60=1.00 avg300=0.50 total=1000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"
+			if err := os.WriteFile(filepath.Join(*procPath, "pressure", resource), []byte(psi), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	*pressureThreshold = 40
+	*pressureSkip = "hwmon, systemd"
+	n := NodeCollector{
+		Collectors: map[string]Collector{"cpu": nil, "hwmon": nil, "processes": nil},
+		logger:     log.NewNopLogger(),
+	}
+	skipped := func() map[string]bool {
+		ch := make(chan prometheus.Metric, 10)
+		s := n.pressureSkipped(ch)
+		close(ch)
+		// Only the enabled collectors which may be skipped are exposed.
+		if len(ch) != 1 {
+			t.Errorf("want 1 skipped metric, got %d", len(ch))
+		}
+		return s
+	}
+
+	writePressure("12.50", "3.00")
+	if s := skipped(); len(s) != 0 {
+		t.Errorf("want no collectors skipped below the threshold, got %v", s)
+	}
+
+	writePressure("12.50", "63.20")
+	s := skipped()
+	if len(s) != 1 || !s["hwmon"] {
+		t.Errorf("want hwmon skipped under memory pressure, got %v", s)
+	}
+	names := n.without(s).Collectors
+	if _, ok := names["hwmon"]; ok || len(names) != 2 {
+		t.Errorf("want hwmon removed, got %v", names)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nodiskpower
+// +build !nodiskpower
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	diskPowerSubsystem = "disk"
+
+	// Ioctls of linux/hdreg.h, implemented by libata for SATA disks.
+	hdioGetIdentity = 0x030d
+	hdioDriveCmd    = 0x031f
+
+	// ATA commands of CHECK POWER MODE, the second one for older disks.
+	ataCheckPowerMode1 = 0xe5
+	ataCheckPowerMode2 = 0x98
+)
+
+var diskPowerDeviceInclude = kingpin.Flag("collector.diskpower.device-include", "Regexp of block devices to expose the power state of.").Default("^sd[a-z]+$").String()
+
+// diskPowerModes are the power modes of ATA disks, by the sector count
+// returned by CHECK POWER MODE.
+var diskPowerModes = []string{"standby", "idle", "active"}
+
+type diskPowerCollector struct {
+	deviceFilter     deviceFilter
+	powerMode        *prometheus.Desc
+	apmLevel         *prometheus.Desc
+	runtimeSuspended *prometheus.Desc
+	runtimeTime      *prometheus.Desc
+	ata              ataDevice
+	logger           log.Logger
+}
+
+// ataDevice sends commands to ATA disks, to swap them out in tests.
+type ataDevice interface {
+	checkPowerMode(device string) (byte, error)
+	identify(device string) ([256]uint16, error)
+}
+
+func init() {
+	registerCollector("diskpower", defaultDisabled, NewDiskPowerCollector)
+}
+
+// NewDiskPowerCollector returns a new Collector exposing the power state of
+// disks.
+func NewDiskPowerCollector(logger log.Logger) (Collector, error) {
+	return &diskPowerCollector{
+		deviceFilter: newDeviceFilter("", *diskPowerDeviceInclude),
+		powerMode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskPowerSubsystem, "power_mode"),
+			"Power mode of an ATA disk, the disk is spun down in standby.",
+			[]string{"device", "mode"}, nil,
+		),
+		apmLevel: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskPowerSubsystem, "apm_level"),
+			"Advanced power management level of an ATA disk, levels up to 127 allow it to spin down. Absent if APM is disabled.",
+			[]string{"device"}, nil,
+		),
+		runtimeSuspended: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskPowerSubsystem, "runtime_pm_suspended"),
+			"Whether the disk is suspended by the runtime power management of the kernel.",
+			[]string{"device"}, nil,
+		),
+		runtimeTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, diskPowerSubsystem, "runtime_pm_seconds_total"),
+			"Time the disk spent in each runtime power management state.",
+			[]string{"device", "state"}, nil,
+		),
+		ata:    hdioDevice{},
+		logger: logger,
+	}, nil
+}
+
+func (c *diskPowerCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := os.ReadDir(sysFilePath("block"))
+	if err != nil {
+		return fmt.Errorf("couldn't list block devices: %w", err)
+	}
+	for _, d := range devices {
+		device := d.Name()
+		if c.deviceFilter.ignored(device) {
+			continue
+		}
+		if err := c.updateRuntimePM(ch, device); err != nil {
+			return fmt.Errorf("couldn't get runtime power management of %s: %w", device, err)
+		}
+		c.updateATA(ch, device)
+	}
+	return nil
+}
+
+func (c *diskPowerCollector) updateRuntimePM(ch chan<- prometheus.Metric, device string) error {
+	dir := sysFilePath(filepath.Join("block", device, "device/power"))
+	status, err := os.ReadFile(filepath.Join(dir, "runtime_status"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	// The status is "unsupported" if runtime power management isn't
+	// enabled for the disk.
+	switch strings.TrimSpace(string(status)) {
+	case "suspended":
+		ch <- prometheus.MustNewConstMetric(c.runtimeSuspended, prometheus.GaugeValue, 1, device)
+	case "active":
+		ch <- prometheus.MustNewConstMetric(c.runtimeSuspended, prometheus.GaugeValue, 0, device)
+	default:
+		return nil
+	}
+	for _, state := range []string{"active", "suspended"} {
+		ms, err := readUintFromFile(filepath.Join(dir, "runtime_"+state+"_time"))
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.runtimeTime, prometheus.CounterValue, float64(ms)/1000, device, state)
+	}
+	return nil
+}
+
+// updateATA exposes the power mode and APM level of ATA disks. These commands
+// are answered without spinning up the disk, but need CAP_SYS_RAWIO.
+func (c *diskPowerCollector) updateATA(ch chan<- prometheus.Metric, device string) {
+	mode, err := c.ata.checkPowerMode(device)
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "Couldn't check power mode, the disk may not be an ATA disk", "device", device, "err", err)
+		return
+	}
+	if m := ataPowerMode(mode); m != "" {
+		for _, name := range diskPowerModes {
+			ch <- prometheus.MustNewConstMetric(c.powerMode, prometheus.GaugeValue, boolToFloat(name == m), device, name)
+		}
+	}
+
+	id, err := c.ata.identify(device)
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "Couldn't identify disk", "device", device, "err", err)
+		return
+	}
+	if apm, ok := ataAPMLevel(id); ok {
+		ch <- prometheus.MustNewConstMetric(c.apmLevel, prometheus.GaugeValue, float64(apm), device)
+	}
+}
+
+// ataPowerMode returns the power mode of the sector count returned by CHECK
+// POWER MODE, or an empty string for the modes of NV caches.
+func ataPowerMode(count byte) string {
+	switch {
+	case count == 0x00:
+		return "standby"
+	case count >= 0x80 && count <= 0x83:
+		return "idle"
+	case count == 0xff:
+		// Active or idle, the disk doesn't tell.
+		return "active"
+	}
+	return ""
+}
+
+// ataAPMLevel returns the APM level of the IDENTIFY DEVICE data, if APM is
+// supported and enabled.
+func ataAPMLevel(id [256]uint16) (uint16, bool) {
+	if id[83]&(1<<3) == 0 || id[86]&(1<<3) == 0 {
+		return 0, false
+	}
+	return id[91] & 0xff, true
+}
+
+// hdioDevice sends the commands with the ioctls of the hd driver, which libata
+// translates.
+type hdioDevice struct{}
+
+func (hdioDevice) ioctl(device string, req uintptr, arg unsafe.Pointer) error {
+	fd, err := unix.Open(filepath.Join("/dev", device), unix.O_RDONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (d hdioDevice) checkPowerMode(device string) (byte, error) {
+	var err error
+	for _, cmd := range []byte{ataCheckPowerMode1, ataCheckPowerMode2} {
+		args := [4]byte{cmd}
+		if err = d.ioctl(device, hdioDriveCmd, unsafe.Pointer(&args)); err == nil {
+			return args[2], nil
+		}
+	}
+	return 0, err
+}
+
+func (d hdioDevice) identify(device string) ([256]uint16, error) {
+	var id [256]uint16
+	err := d.ioctl(device, hdioGetIdentity, unsafe.Pointer(&id))
+	return id, err
+}
@@ -0,0 +1,82 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nodiskpower
+// +build !nodiskpower
+
+package collector
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeATADevice map[string][256]uint16
+
+func (f fakeATADevice) checkPowerMode(device string) (byte, error) {
+	if _, ok := f[device]; !ok {
+		return 0, errors.New("not an ATA disk")
+	}
+	return 0x00, nil
+}
+
+func (f fakeATADevice) identify(device string) ([256]uint16, error) {
+	return f[device], nil
+}
+
+func TestDiskPower(t *testing.T) {
+	*sysPath = "fixtures/sys"
+
+	var id [256]uint16
+	id[83], id[86], id[91] = 1<<3, 1<<3, 0x4080
+	c, err := NewDiskPowerCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*diskPowerCollector).ata = fakeATADevice{"sda": id}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_disk_apm_level Advanced power management level of an ATA disk, levels up to 127 allow it to spin down. Absent if APM is disabled.
+# TYPE node_disk_apm_level gauge
+node_disk_apm_level{device="sda"} 128
+# HELP node_disk_power_mode Power mode of an ATA disk, the disk is spun down in standby.
+# TYPE node_disk_power_mode gauge
+node_disk_power_mode{device="sda",mode="active"} 0
+node_disk_power_mode{device="sda",mode="idle"} 0
+node_disk_power_mode{device="sda",mode="standby"} 1
+# HELP node_disk_runtime_pm_seconds_total Time the disk spent in each runtime power management state.
+# TYPE node_disk_runtime_pm_seconds_total counter
+node_disk_runtime_pm_seconds_total{device="sda",state="active"} 123.456
+node_disk_runtime_pm_seconds_total{device="sda",state="suspended"} 7890
+# HELP node_disk_runtime_pm_suspended Whether the disk is suspended by the runtime power management of the kernel.
+# TYPE node_disk_runtime_pm_suspended gauge
+node_disk_runtime_pm_suspended{device="sda"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestATAPowerMode(t *testing.T) {
+	for count, want := range map[byte]string{0x00: "standby", 0x80: "idle", 0x83: "idle", 0xff: "active", 0x40: ""} {
+		if got := ataPowerMode(count); got != want {
+			t.Errorf("ataPowerMode(%#x) = %q, want %q", count, got, want)
+		}
+	}
+}
@@ -17,9 +17,18 @@
 package collector
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
 )
@@ -28,21 +37,37 @@ const (
 	drmCollectorSubsystem = "drm"
 )
 
+var (
+	drmProcessInclude = kingpin.Flag("collector.drm.process-include", "Regexp of the names of the processes to expose the GPU memory of, per process. Processes aren't scanned if empty.").String()
+)
+
 type drmCollector struct {
 	fs                    sysfs.FS
 	logger                log.Logger
 	CardInfo              *prometheus.Desc
 	GPUBusyPercent        *prometheus.Desc
+	GPUClock              *prometheus.Desc
+	MemoryClock           *prometheus.Desc
 	MemoryGTTSize         *prometheus.Desc
 	MemoryGTTUsed         *prometheus.Desc
 	MemoryVisibleVRAMSize *prometheus.Desc
 	MemoryVisibleVRAMUsed *prometheus.Desc
 	MemoryVRAMSize        *prometheus.Desc
 	MemoryVRAMUsed        *prometheus.Desc
+	Power                 *prometheus.Desc
+	ProcessMemory         *prometheus.Desc
+
+	processPattern *regexp.Regexp
+}
+
+// drmProcessRegion identifies a memory region of a GPU.
+type drmProcessRegion struct {
+	pdev, region string
 }
 
 func init() {
 	registerCollector("drm", defaultDisabled, NewDrmCollector)
+	registerHardwareProbe("drm", "no DRM cards", sysfsProbe("class/drm/card[0-9]*"))
 }
 
 // NewDrmCollector returns a new Collector exposing /sys/class/drm/card?/device stats.
@@ -52,9 +77,18 @@ func NewDrmCollector(logger log.Logger) (Collector, error) {
 		return nil, fmt.Errorf("failed to open sysfs: %w", err)
 	}
 
+	var processPattern *regexp.Regexp
+	if *drmProcessInclude != "" {
+		processPattern, err = regexp.Compile(*drmProcessInclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --collector.drm.process-include: %w", err)
+		}
+	}
+
 	return &drmCollector{
-		fs:     fs,
-		logger: logger,
+		fs:             fs,
+		logger:         logger,
+		processPattern: processPattern,
 		CardInfo: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "card_info"),
 			"Card information",
@@ -65,6 +99,16 @@ func NewDrmCollector(logger log.Logger) (Collector, error) {
 			"How busy the GPU is as a percentage.",
 			[]string{"card"}, nil,
 		),
+		GPUClock: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "gpu_clock_hertz"),
+			"Current clock frequency of the GPU in hertz.",
+			[]string{"card"}, nil,
+		),
+		MemoryClock: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "memory_clock_hertz"),
+			"Current clock frequency of the GPU memory in hertz.",
+			[]string{"card"}, nil,
+		),
 		MemoryGTTSize: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "memory_gtt_size_bytes"),
 			"The size of the graphics translation table (GTT) block in bytes.",
@@ -95,11 +139,30 @@ func NewDrmCollector(logger log.Logger) (Collector, error) {
 			"The used amount of VRAM in bytes.",
 			[]string{"card"}, nil,
 		),
+		Power: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "power_watts"),
+			"Current power draw of the card in watts.",
+			[]string{"card"}, nil,
+		),
+		ProcessMemory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, drmCollectorSubsystem, "process_memory_bytes"),
+			"GPU memory allocated by a process, by memory region.",
+			[]string{"card", "pid", "process", "region"}, nil,
+		),
 	}, nil
 }
 
 func (c *drmCollector) Update(ch chan<- prometheus.Metric) error {
-	return c.updateAMDCards(ch)
+	if err := c.updateAMDCards(ch); err != nil {
+		return err
+	}
+	if err := c.updateIntelCards(ch); err != nil {
+		return err
+	}
+	if c.processPattern != nil {
+		return c.updateProcesses(ch)
+	}
+	return nil
 }
 
 func (c *drmCollector) updateAMDCards(ch chan<- prometheus.Metric) error {
@@ -110,6 +173,11 @@ func (c *drmCollector) updateAMDCards(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, s := range stats {
+		// procfs returns empty stats for cards bound to other drivers.
+		if sysfsDeviceDriver(sysFilePath(filepath.Join("class/drm", s.Name))) != "amdgpu" {
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.CardInfo, prometheus.GaugeValue, 1,
 			s.Name, s.MemoryVRAMVendor, s.PowerDPMForcePerformanceLevel, s.UniqueID, vendor)
@@ -134,7 +202,303 @@ func (c *drmCollector) updateAMDCards(ch chan<- prometheus.Metric) error {
 
 		ch <- prometheus.MustNewConstMetric(
 			c.MemoryVisibleVRAMUsed, prometheus.GaugeValue, float64(s.MemoryVisibleVRAMUsed), s.Name)
+
+		device := sysFilePath(filepath.Join("class/drm", s.Name, "device"))
+		if err := c.updateClock(ch, c.GPUClock, s.Name, filepath.Join(device, "pp_dpm_sclk")); err != nil {
+			return err
+		}
+		if err := c.updateClock(ch, c.MemoryClock, s.Name, filepath.Join(device, "pp_dpm_mclk")); err != nil {
+			return err
+		}
+		if err := c.updatePower(ch, s.Name, device); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *drmCollector) updateIntelCards(ch chan<- prometheus.Metric) error {
+	vendor := "intel"
+	cards, err := filepath.Glob(sysFilePath("class/drm/card[0-9]*"))
+	if err != nil {
+		return err
+	}
+
+	for _, card := range cards {
+		name := filepath.Base(card)
+		// Skip connectors like card0-DP-1.
+		if strings.Contains(name, "-") {
+			continue
+		}
+		if sysfsDeviceDriver(card) != "i915" {
+			continue
+		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.CardInfo, prometheus.GaugeValue, 1,
+			name, "", "", "", vendor)
+
+		if err := c.updateIntelClock(ch, c.GPUClock, name, filepath.Join(card, "gt_act_freq_mhz")); err != nil {
+			return err
+		}
+		if err := c.updateIntelClock(ch, c.MemoryClock, name, filepath.Join(card, "mem_act_freq_mhz")); err != nil {
+			return err
+		}
+
+		// Only discrete cards have local memory.
+		total, err := readUintFromFile(filepath.Join(card, "lmem_total_bytes"))
+		if err == nil {
+			avail, err := readUintFromFile(filepath.Join(card, "lmem_avail_bytes"))
+			if err != nil {
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.MemoryVRAMSize, prometheus.GaugeValue, float64(total), name)
+			ch <- prometheus.MustNewConstMetric(
+				c.MemoryVRAMUsed, prometheus.GaugeValue, float64(total)-float64(avail), name)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		if err := c.updatePower(ch, name, filepath.Join(card, "device")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateIntelClock exports the clock of an i915 *_freq_mhz file, if present.
+func (c *drmCollector) updateIntelClock(ch chan<- prometheus.Metric, desc *prometheus.Desc, card, path string) error {
+	mhz, err := readUintFromFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(mhz)*1e6, card)
+	return nil
+}
+
+// updateClock exports the currently selected clock level of an amdgpu
+// pp_dpm_* file, if present.
+func (c *drmCollector) updateClock(ch chan<- prometheus.Metric, desc *prometheus.Desc, card, path string) error {
+	clock, ok, err := readDPMCurrentClock(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if !ok {
+		return nil
 	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, clock, card)
+	return nil
+}
 
+// updatePower exports the power draw reported by the hwmon device of the
+// card, if present.
+func (c *drmCollector) updatePower(ch chan<- prometheus.Metric, card, device string) error {
+	for _, file := range []string{"power1_average", "power1_input"} {
+		paths, err := filepath.Glob(filepath.Join(device, "hwmon/hwmon*", file))
+		if err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		microwatts, err := readUintFromFile(paths[0])
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.Power, prometheus.GaugeValue, float64(microwatts)/1e6, card)
+		return nil
+	}
 	return nil
 }
+
+// updateProcesses exports the GPU memory of the processes whose name matches
+// --collector.drm.process-include, read from the fdinfo of their DRM files.
+func (c *drmCollector) updateProcesses(ch chan<- prometheus.Metric) error {
+	procs, err := filepath.Glob(procFilePath("[0-9]*"))
+	if err != nil {
+		return err
+	}
+	cards, err := drmCardsByPCIAddress()
+	if err != nil {
+		return err
+	}
+
+	for _, proc := range procs {
+		comm, err := os.ReadFile(filepath.Join(proc, "comm"))
+		if err != nil {
+			// The process exited since it was listed.
+			continue
+		}
+		name := strings.TrimSpace(string(comm))
+		if !c.processPattern.MatchString(name) {
+			continue
+		}
+		memory, err := readDRMProcessMemory(proc)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't read GPU memory of process", "pid", filepath.Base(proc), "err", err)
+			continue
+		}
+		for r, bytes := range memory {
+			card, ok := cards[r.pdev]
+			if !ok {
+				card = r.pdev
+			}
+			ch <- prometheus.MustNewConstMetric(
+				c.ProcessMemory, prometheus.GaugeValue, bytes,
+				card, filepath.Base(proc), name, r.region)
+		}
+	}
+	return nil
+}
+
+// drmCardsByPCIAddress returns the names of the DRM cards by the PCI address
+// of their device.
+func drmCardsByPCIAddress() (map[string]string, error) {
+	cards, err := filepath.Glob(sysFilePath("class/drm/card[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(cards))
+	for _, card := range cards {
+		if strings.Contains(filepath.Base(card), "-") {
+			continue
+		}
+		device, err := filepath.EvalSymlinks(filepath.Join(card, "device"))
+		if err != nil {
+			continue
+		}
+		names[filepath.Base(device)] = filepath.Base(card)
+	}
+	return names, nil
+}
+
+// readDRMProcessMemory sums the GPU memory of the DRM clients of a process
+// by region, from the fdinfo of its files opened under /dev/dri, see
+// https://docs.kernel.org/gpu/drm-usage-stats.html. Files sharing the same
+// client are counted once.
+func readDRMProcessMemory(proc string) (map[drmProcessRegion]float64, error) {
+	fds, err := os.ReadDir(filepath.Join(proc, "fd"))
+	if err != nil {
+		return nil, err
+	}
+	memory := map[drmProcessRegion]float64{}
+	clients := map[string]bool{}
+	for _, fd := range fds {
+		target, err := os.Readlink(filepath.Join(proc, "fd", fd.Name()))
+		if err != nil || !strings.HasPrefix(target, "/dev/dri/") {
+			continue
+		}
+		info, err := os.ReadFile(filepath.Join(proc, "fdinfo", fd.Name()))
+		if err != nil {
+			continue
+		}
+		pdev, client, regions, err := parseDRMFdinfo(string(info))
+		if err != nil {
+			return nil, err
+		}
+		if clients[pdev+"/"+client] {
+			continue
+		}
+		clients[pdev+"/"+client] = true
+		for region, bytes := range regions {
+			memory[drmProcessRegion{pdev: pdev, region: region}] += bytes
+		}
+	}
+	return memory, nil
+}
+
+// parseDRMFdinfo returns the device, the client ID and the memory by region
+// of the fdinfo of a DRM file. The drm-total-<region> keys are preferred over
+// the drm-memory-<region> ones amdgpu reported before them.
+func parseDRMFdinfo(info string) (string, string, map[string]float64, error) {
+	var pdev, client string
+	total, legacy := map[string]float64{}, map[string]float64{}
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case key == "drm-pdev":
+			pdev = value
+		case key == "drm-client-id":
+			client = value
+		case strings.HasPrefix(key, "drm-total-"):
+			bytes, err := parseDRMMemory(value)
+			if err != nil {
+				return "", "", nil, err
+			}
+			total[strings.TrimPrefix(key, "drm-total-")] = bytes
+		case strings.HasPrefix(key, "drm-memory-"):
+			bytes, err := parseDRMMemory(value)
+			if err != nil {
+				return "", "", nil, err
+			}
+			legacy[strings.TrimPrefix(key, "drm-memory-")] = bytes
+		}
+	}
+	if len(total) > 0 {
+		return pdev, client, total, nil
+	}
+	return pdev, client, legacy, nil
+}
+
+// parseDRMMemory parses a memory size of DRM fdinfo, e.g. "1024 KiB".
+func parseDRMMemory(value string) (float64, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, fmt.Errorf("invalid memory size %q", value)
+	}
+	bytes, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %q: %w", value, err)
+	}
+	if len(fields) == 1 {
+		return float64(bytes), nil
+	}
+	switch fields[1] {
+	case "KiB":
+		return float64(bytes) * 1024, nil
+	case "MiB":
+		return float64(bytes) * 1024 * 1024, nil
+	case "GiB":
+		return float64(bytes) * 1024 * 1024 * 1024, nil
+	}
+	return 0, fmt.Errorf("invalid memory size unit %q", value)
+}
+
+// readDPMCurrentClock returns the clock in hertz of the level marked as
+// active in an amdgpu pp_dpm_* file, e.g. "1: 1000Mhz *". It returns false
+// if no level is marked as active.
+func readDPMCurrentClock(path string) (float64, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 || fields[2] != "*" {
+			continue
+		}
+		mhz := strings.TrimSuffix(strings.ToLower(fields[1]), "mhz")
+		value, err := strconv.ParseFloat(mhz, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid clock %q in %s: %w", fields[1], path, err)
+		}
+		return value * 1e6, true, nil
+	}
+	return 0, false, scanner.Err()
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nogpu
+// +build !nogpu
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadDRMProcessMemory(t *testing.T) {
+	proc := t.TempDir()
+	for _, dir := range []string{"fd", "fdinfo"} {
+		if err := os.Mkdir(filepath.Join(proc, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const (
+		// amdgpu before drm-total-<region>.
+		amdgpuInfo = "pos:\t0\nflags:\t02100002\ndrm-driver:\tamdgpu\ndrm-pdev:\t0000:03:00.0\ndrm-client-id:\t12\ndrm-memory-vram:\t1024 KiB\ndrm-memory-gtt:\t2 MiB\n"
+		i915Info   = "drm-driver:\ti915\ndrm-pdev:\t0000:00:02.0\ndrm-client-id:\t7\ndrm-total-system0:\t4096\ndrm-resident-system0:\t4096\ndrm-memory-system:\t1 KiB\n"
+	)
+	for fd, file := range map[string]struct{ target, info string }{
+		"3": {"/dev/dri/renderD128", amdgpuInfo},
+		// A duplicate of the same client.
+		"4": {"/dev/dri/renderD128", amdgpuInfo},
+		"5": {"/dev/dri/card1", i915Info},
+		"6": {"/dev/null", "drm-pdev:\tnope\ndrm-total-vram:\t1\n"},
+	} {
+		if err := os.Symlink(file.target, filepath.Join(proc, "fd", fd)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(proc, "fdinfo", fd), []byte(file.info), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	memory, err := readDRMProcessMemory(proc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[drmProcessRegion]float64{
+		{pdev: "0000:03:00.0", region: "vram"}:    1024 * 1024,
+		{pdev: "0000:03:00.0", region: "gtt"}:     2 * 1024 * 1024,
+		{pdev: "0000:00:02.0", region: "system0"}: 4096,
+	}
+	if !reflect.DeepEqual(memory, want) {
+		t.Errorf("want %v, got %v", want, memory)
+	}
+}
+
+func TestReadDPMCurrentClock(t *testing.T) {
+	for _, tc := range []struct {
+		name, content string
+		want          float64
+		ok, err       bool
+	}{
+		{"active", "0: 852Mhz *\n1: 991Mhz \n", 852e6, true, false},
+		{"upper case", "0: 300MHz \n1: 1200MHz *\n", 1200e6, true, false},
+		{"no active level", "0: 852Mhz \n1: 991Mhz \n", 0, false, false},
+		{"invalid", "0: fastMhz *\n", 0, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pp_dpm_sclk")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, ok, err := readDPMCurrentClock(path)
+			if (err != nil) != tc.err {
+				t.Fatalf("want error %v, got %v", tc.err, err)
+			}
+			if got != tc.want || ok != tc.ok {
+				t.Errorf("want %v, %v, got %v, %v", tc.want, tc.ok, got, ok)
+			}
+		})
+	}
+}
@@ -44,6 +44,7 @@ type edacCollector struct {
 
 func init() {
 	registerCollector("edac", defaultEnabled, NewEdacCollector)
+	registerHardwareProbe("edac", "no EDAC memory controllers", sysfsProbe("devices/system/edac/mc/mc[0-9]*"))
 }
 
 // NewEdacCollector returns a new Collector exposing edac stats.
@@ -0,0 +1,181 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noenclosure
+// +build !noenclosure
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const enclosureCollectorSubsystem = "enclosure"
+
+// enclosureSlotTypes are the types of the components holding disks, see
+// enclosure_type in include/linux/enclosure.h.
+var enclosureSlotTypes = map[string]bool{"device": true, "array device": true}
+
+// enclosureLinks are the links of an enclosure device which aren't
+// components, the SCSI device also has a type attribute.
+var enclosureLinks = map[string]bool{"device": true, "power": true, "subsystem": true}
+
+type enclosureCollector struct {
+	info       *prometheus.Desc
+	components *prometheus.Desc
+	occupied   *prometheus.Desc
+	status     *prometheus.Desc
+	fault      *prometheus.Desc
+	locate     *prometheus.Desc
+	logger     log.Logger
+}
+
+func init() {
+	registerCollector(enclosureCollectorSubsystem, defaultDisabled, NewEnclosureCollector)
+}
+
+// NewEnclosureCollector returns a new Collector exposing the slots and
+// sensors of SCSI enclosures (SES).
+func NewEnclosureCollector(logger log.Logger) (Collector, error) {
+	return &enclosureCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "info"),
+			"Information about an enclosure, the id is its logical identifier.",
+			[]string{"enclosure", "id", "vendor", "model"}, nil,
+		),
+		components: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "components"),
+			"Number of components of the enclosure.",
+			[]string{"enclosure"}, nil,
+		),
+		occupied: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "slot_occupied"),
+			"Whether a disk is attached to the slot, device is the block device of the disk.",
+			[]string{"enclosure", "component", "slot", "device"}, nil,
+		),
+		status: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "component_status"),
+			"Status of a component as reported by the enclosure, e.g. OK, critical or not installed.",
+			[]string{"enclosure", "component", "type", "status"}, nil,
+		),
+		fault: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "component_fault"),
+			"Whether the fault LED of the component is on.",
+			[]string{"enclosure", "component", "type"}, nil,
+		),
+		locate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, enclosureCollectorSubsystem, "component_locate"),
+			"Whether the locate LED of the component is on.",
+			[]string{"enclosure", "component", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *enclosureCollector) Update(ch chan<- prometheus.Metric) error {
+	enclosures, err := filepath.Glob(sysFilePath("class/enclosure/*"))
+	if err != nil {
+		return err
+	}
+	if len(enclosures) == 0 {
+		return ErrNoData
+	}
+	for _, enclosure := range enclosures {
+		if err := c.updateEnclosure(ch, enclosure); err != nil {
+			return fmt.Errorf("couldn't get statistics of enclosure %s: %w", filepath.Base(enclosure), err)
+		}
+	}
+	return nil
+}
+
+func (c *enclosureCollector) updateEnclosure(ch chan<- prometheus.Metric, dir string) error {
+	name := filepath.Base(dir)
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, name,
+		enclosureAttribute(dir, "id"),
+		enclosureAttribute(dir, "device/vendor"),
+		enclosureAttribute(dir, "device/model"))
+	if components, err := readUintFromFile(filepath.Join(dir, "components")); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.components, prometheus.GaugeValue, float64(components), name)
+	}
+
+	// The components are the directories of the enclosure device, named
+	// by their descriptor or their number.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if enclosureLinks[e.Name()] {
+			continue
+		}
+		component := filepath.Join(dir, e.Name())
+		componentType := enclosureAttribute(component, "type")
+		if componentType == "" {
+			continue
+		}
+		c.updateComponent(ch, name, e.Name(), componentType, component)
+	}
+	return nil
+}
+
+func (c *enclosureCollector) updateComponent(ch chan<- prometheus.Metric, enclosure, name, componentType, dir string) {
+	if status := enclosureAttribute(dir, "status"); status != "" {
+		ch <- prometheus.MustNewConstMetric(c.status, prometheus.GaugeValue, 1, enclosure, name, componentType, status)
+	}
+	// Reading the LEDs fails if the enclosure doesn't support them.
+	if fault, err := readUintFromFile(filepath.Join(dir, "fault")); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.fault, prometheus.GaugeValue, boolToFloat(fault != 0), enclosure, name, componentType)
+	}
+	if locate, err := readUintFromFile(filepath.Join(dir, "locate")); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.locate, prometheus.GaugeValue, boolToFloat(locate != 0), enclosure, name, componentType)
+	}
+
+	if !enclosureSlotTypes[componentType] {
+		return
+	}
+	slot := enclosureAttribute(dir, "slot")
+	if slot == "" {
+		slot = name
+	}
+	device, occupied := enclosureSlotDevice(dir)
+	ch <- prometheus.MustNewConstMetric(c.occupied, prometheus.GaugeValue, boolToFloat(occupied), enclosure, name, slot, device)
+}
+
+// enclosureSlotDevice returns the block device of the disk in a slot and
+// whether a disk is attached to it at all.
+func enclosureSlotDevice(dir string) (string, bool) {
+	if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+		return "", false
+	}
+	blocks, err := os.ReadDir(filepath.Join(dir, "device/block"))
+	if err != nil || len(blocks) == 0 {
+		return "", true
+	}
+	return blocks[0].Name(), true
+}
+
+// enclosureAttribute returns the trimmed content of a sysfs attribute, or an
+// empty string if it can't be read.
+func enclosureAttribute(dir, attr string) string {
+	b, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
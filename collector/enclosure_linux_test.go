@@ -0,0 +1,67 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noenclosure
+// +build !noenclosure
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestEnclosure(t *testing.T) {
+	*sysPath = "fixtures/sys"
+
+	c, err := NewEnclosureCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_enclosure_component_fault Whether the fault LED of the component is on.
+# TYPE node_enclosure_component_fault gauge
+node_enclosure_component_fault{component="Cooling 00",enclosure="0:0:8:0",type="cooling"} 1
+node_enclosure_component_fault{component="Slot 00",enclosure="0:0:8:0",type="array device"} 0
+node_enclosure_component_fault{component="Slot 01",enclosure="0:0:8:0",type="array device"} 1
+# HELP node_enclosure_component_locate Whether the locate LED of the component is on.
+# TYPE node_enclosure_component_locate gauge
+node_enclosure_component_locate{component="Slot 00",enclosure="0:0:8:0",type="array device"} 1
+node_enclosure_component_locate{component="Slot 01",enclosure="0:0:8:0",type="array device"} 0
+# HELP node_enclosure_component_status Status of a component as reported by the enclosure, e.g. OK, critical or not installed.
+# TYPE node_enclosure_component_status gauge
+node_enclosure_component_status{component="Cooling 00",enclosure="0:0:8:0",status="critical",type="cooling"} 1
+node_enclosure_component_status{component="Slot 00",enclosure="0:0:8:0",status="OK",type="array device"} 1
+node_enclosure_component_status{component="Slot 01",enclosure="0:0:8:0",status="not installed",type="array device"} 1
+node_enclosure_component_status{component="Temperature 00",enclosure="0:0:8:0",status="OK",type="temperature sensor"} 1
+# HELP node_enclosure_components Number of components of the enclosure.
+# TYPE node_enclosure_components gauge
+node_enclosure_components{enclosure="0:0:8:0"} 4
+# HELP node_enclosure_info Information about an enclosure, the id is its logical identifier.
+# TYPE node_enclosure_info gauge
+node_enclosure_info{enclosure="0:0:8:0",id="0x500056b3a6b0bbfd",model="SAS2X28",vendor="LSI"} 1
+# HELP node_enclosure_slot_occupied Whether a disk is attached to the slot, device is the block device of the disk.
+# TYPE node_enclosure_slot_occupied gauge
+node_enclosure_slot_occupied{component="Slot 00",device="sdc",enclosure="0:0:8:0",slot="0"} 1
+node_enclosure_slot_occupied{component="Slot 01",device="",enclosure="0:0:8:0",slot="1"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
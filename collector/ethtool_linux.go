@@ -43,8 +43,19 @@ var (
 	ethtoolDeviceInclude   = kingpin.Flag("collector.ethtool.device-include", "Regexp of ethtool devices to include (mutually exclusive to device-exclude).").String()
 	ethtoolDeviceExclude   = kingpin.Flag("collector.ethtool.device-exclude", "Regexp of ethtool devices to exclude (mutually exclusive to device-include).").String()
 	ethtoolIncludedMetrics = kingpin.Flag("collector.ethtool.metrics-include", "Regexp of ethtool stats to include.").Default(".*").String()
+	ethtoolQueueLabel      = kingpin.Flag("collector.ethtool.queue-label", "Expose the per-queue stats of a device as one metric with a queue label.").Bool()
 	ethtoolReceivedRegex   = regexp.MustCompile(`(^|_)rx(_|$)`)
 	ethtoolTransmitRegex   = regexp.MustCompile(`(^|_)tx(_|$)`)
+
+	// ethtoolQueueRegexes match the names drivers give to per-queue stats,
+	// e.g. rx_queue_0_packets (virtio_net, ixgbe), rx0_packets (mlx5),
+	// tx-0.bytes (i40e) and queue_0_tx_cnt (ena).
+	ethtoolQueueRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`^(?P<dir>rx|tx)_queue_(?P<queue>\d+)_(?P<stat>.+)$`),
+		regexp.MustCompile(`^(?P<dir>rx|tx)(?P<queue>\d+)_(?P<stat>.+)$`),
+		regexp.MustCompile(`^(?P<dir>rx|tx)-(?P<queue>\d+)\.(?P<stat>.+)$`),
+		regexp.MustCompile(`^queue_(?P<queue>\d+)_(?P<dir>rx|tx)_(?P<stat>.+)$`),
+	}
 )
 
 type Ethtool interface {
@@ -74,6 +85,7 @@ func (e *ethtoolLibrary) LinkInfo(intf string) (ethtool.EthtoolCmd, error) {
 type ethtoolCollector struct {
 	fs             sysfs.FS
 	entries        map[string]*prometheus.Desc
+	queueEntries   map[string]*prometheus.Desc
 	entriesMutex   sync.Mutex
 	ethtool        Ethtool
 	deviceFilter   deviceFilter
@@ -103,6 +115,7 @@ func makeEthtoolCollector(logger log.Logger) (*ethtoolCollector, error) {
 		deviceFilter:   newDeviceFilter(*ethtoolDeviceExclude, *ethtoolDeviceInclude),
 		metricsPattern: regexp.MustCompile(*ethtoolIncludedMetrics),
 		logger:         logger,
+		queueEntries:   make(map[string]*prometheus.Desc),
 		entries: map[string]*prometheus.Desc{
 			"rx_bytes": prometheus.NewDesc(
 				prometheus.BuildFQName(namespace, "ethtool", "received_bytes_total"),
@@ -212,6 +225,21 @@ func buildEthtoolFQName(metric string) string {
 	return prometheus.BuildFQName(namespace, "ethtool", metricName)
 }
 
+// ethtoolQueueStat returns the name without the queue number and the queue of
+// a per-queue stat, e.g. rx_queue_packets and 0 for rx0_packets.
+func ethtoolQueueStat(metric string) (string, string, bool) {
+	for _, re := range ethtoolQueueRegexes {
+		m := re.FindStringSubmatch(metric)
+		if m == nil {
+			continue
+		}
+		dir := m[re.SubexpIndex("dir")]
+		stat := strings.TrimPrefix(m[re.SubexpIndex("stat")], dir+"_")
+		return dir + "_queue_" + stat, m[re.SubexpIndex("queue")], true
+	}
+	return "", "", false
+}
+
 // NewEthtoolCollector returns a new Collector exposing ethtool stats.
 func NewEthtoolCollector(logger log.Logger) (Collector, error) {
 	return makeEthtoolCollector(logger)
@@ -444,10 +472,23 @@ func (c *ethtoolCollector) Update(ch chan<- prometheus.Metric) error {
 
 		// Sanitizing the metric names can lead to duplicate metric names. Therefore check for clashes beforehand.
 		metricFQNames := make(map[string]string)
+		queueStats := make(map[string]map[string]uint64)
+		queueNames := make(map[string]string)
 		for metric := range stats {
 			if !c.metricsPattern.MatchString(metric) {
 				continue
 			}
+			if *ethtoolQueueLabel {
+				if name, queue, ok := ethtoolQueueStat(metric); ok {
+					metricFQName := buildEthtoolFQName(name)
+					if queueStats[metricFQName] == nil {
+						queueStats[metricFQName] = make(map[string]uint64)
+					}
+					queueStats[metricFQName][queue] = stats[metric]
+					queueNames[metricFQName] = name
+					continue
+				}
+			}
 			metricFQName := buildEthtoolFQName(metric)
 			existingMetric, exists := metricFQNames[metricFQName]
 			if exists {
@@ -481,6 +522,19 @@ func (c *ethtoolCollector) Update(ch chan<- prometheus.Metric) error {
 			ch <- prometheus.MustNewConstMetric(
 				entry, prometheus.UntypedValue, float64(val), device)
 		}
+
+		for metricFQName, queues := range queueStats {
+			if _, exists := metricFQNames[metricFQName]; exists {
+				level.Debug(c.logger).Log("msg", "dropping per-queue metric clashing with a device metric", "device", device,
+					"metricFQName", metricFQName)
+				continue
+			}
+			entry := c.queueEntryWithCreate(queueNames[metricFQName], metricFQName)
+			for queue, val := range queues {
+				ch <- prometheus.MustNewConstMetric(
+					entry, prometheus.UntypedValue, float64(val), device, queue)
+			}
+		}
 	}
 
 	return nil
@@ -501,6 +555,21 @@ func (c *ethtoolCollector) entryWithCreate(key, metricFQName string) *prometheus
 	return c.entries[key]
 }
 
+func (c *ethtoolCollector) queueEntryWithCreate(key, metricFQName string) *prometheus.Desc {
+	c.entriesMutex.Lock()
+	defer c.entriesMutex.Unlock()
+
+	if _, ok := c.queueEntries[key]; !ok {
+		c.queueEntries[key] = prometheus.NewDesc(
+			metricFQName,
+			fmt.Sprintf("Network interface %s, by queue", key),
+			[]string{"device", "queue"}, nil,
+		)
+	}
+
+	return c.queueEntries[key]
+}
+
 func (c *ethtoolCollector) entry(key string) *prometheus.Desc {
 	c.entriesMutex.Lock()
 	defer c.entriesMutex.Unlock()
@@ -281,6 +281,51 @@ func TestBuildEthtoolFQName(t *testing.T) {
 	}
 }
 
+func TestEthtoolQueueStat(t *testing.T) {
+	testcases := map[string][2]string{
+		"rx_queue_0_packets": {"rx_queue_packets", "0"},
+		"tx_queue_12_bytes":  {"tx_queue_bytes", "12"},
+		"rx3_packets":        {"rx_queue_packets", "3"},
+		"tx-1.tx_bytes":      {"tx_queue_bytes", "1"},
+		"queue_2_rx_cnt":     {"rx_queue_cnt", "2"},
+	}
+	for metric, expected := range testcases {
+		name, queue, ok := ethtoolQueueStat(metric)
+		if !ok || name != expected[0] || queue != expected[1] {
+			t.Errorf("%s: expected %v but got %s %s %v", metric, expected, name, queue, ok)
+		}
+	}
+	if _, _, ok := ethtoolQueueStat("rx_missed"); ok {
+		t.Error("rx_missed isn't a per-queue stat")
+	}
+}
+
+func TestEthToolCollectorQueueLabel(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	*ethtoolQueueLabel = true
+	defer func() { *ethtoolQueueLabel = false }()
+
+	c, err := NewEthtoolTestCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_ethtool_received_queue_drops Network interface rx_queue_drops, by queue
+# TYPE node_ethtool_received_queue_drops untyped
+node_ethtool_received_queue_drops{device="eth0",queue="0"} 12
+node_ethtool_received_queue_drops{device="eth0",queue="1"} 0
+# HELP node_ethtool_received_queue_packets Network interface rx_queue_packets, by queue
+# TYPE node_ethtool_received_queue_packets untyped
+node_ethtool_received_queue_packets{device="eth0",queue="0"} 630031
+node_ethtool_received_queue_packets{device="eth0",queue="1"} 630031
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_ethtool_received_queue_drops", "node_ethtool_received_queue_packets", "node_ethtool_received_queue_0_packets"); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestEthToolCollector(t *testing.T) {
 	testcase := `# HELP node_ethtool_align_errors Network interface align_errors
 # TYPE node_ethtool_align_errors untyped
@@ -303,6 +348,18 @@ node_ethtool_received_multicast{device="eth0"} 23973
 # HELP node_ethtool_received_packets_total Network interface packets received
 # TYPE node_ethtool_received_packets_total untyped
 node_ethtool_received_packets_total{device="eth0"} 1.260062e+06
+# HELP node_ethtool_received_queue_0_drops Network interface rx_queue_0_drops
+# TYPE node_ethtool_received_queue_0_drops untyped
+node_ethtool_received_queue_0_drops{device="eth0"} 12
+# HELP node_ethtool_received_queue_0_packets Network interface rx_queue_0_packets
+# TYPE node_ethtool_received_queue_0_packets untyped
+node_ethtool_received_queue_0_packets{device="eth0"} 630031
+# HELP node_ethtool_received_queue_1_drops Network interface rx_queue_1_drops
+# TYPE node_ethtool_received_queue_1_drops untyped
+node_ethtool_received_queue_1_drops{device="eth0"} 0
+# HELP node_ethtool_received_queue_1_packets Network interface rx_queue_1_packets
+# TYPE node_ethtool_received_queue_1_packets untyped
+node_ethtool_received_queue_1_packets{device="eth0"} 630031
 # HELP node_ethtool_received_unicast Network interface rx_unicast
 # TYPE node_ethtool_received_unicast untyped
 node_ethtool_received_unicast{device="eth0"} 1.230297e+06
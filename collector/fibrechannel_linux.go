@@ -19,6 +19,9 @@ package collector
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -29,14 +32,16 @@ import (
 const maxUint64 = ^uint64(0)
 
 type fibrechannelCollector struct {
-	fs          sysfs.FS
-	metricDescs map[string]*prometheus.Desc
-	logger      log.Logger
-	subsystem   string
+	fs              sysfs.FS
+	metricDescs     map[string]*prometheus.Desc
+	remotePortsDesc *prometheus.Desc
+	logger          log.Logger
+	subsystem       string
 }
 
 func init() {
 	registerCollector("fibrechannel", defaultEnabled, NewFibreChannelCollector)
+	registerHardwareProbe("fibrechannel", "no Fibre Channel hosts", sysfsProbe("class/fc_host/*"))
 }
 
 // NewFibreChannelCollector returns a new Collector exposing FibreChannel stats.
@@ -78,6 +83,8 @@ func NewFibreChannelCollector(logger log.Logger) (Collector, error) {
 		"dev_loss_tmo":                   "Device Loss Timeout in seconds",
 		"supported_classes":              "The FC classes supported",
 		"supported_speeds":               "The FC speeds supported",
+		"up":                             "Whether the port state of the host port is Online",
+		"speed_bits_per_second":          "Current operating speed in bits per second",
 	}
 
 	i.metricDescs = make(map[string]*prometheus.Desc)
@@ -91,6 +98,12 @@ func NewFibreChannelCollector(logger log.Logger) (Collector, error) {
 			nil,
 		)
 	}
+	i.remotePortsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, i.subsystem, "remote_ports"),
+		"Number of remote ports seen by the host port, by port state",
+		[]string{"fc_host", "port_state"},
+		nil,
+	)
 
 	return &i, nil
 }
@@ -143,7 +156,65 @@ func (c *fibrechannelCollector) Update(ch chan<- prometheus.Metric) error {
 		c.pushCounter(ch, "loss_of_signal_total", host.Counters.LossOfSignalCount, host.Name)
 		c.pushCounter(ch, "nos_total", host.Counters.NosCount, host.Name)
 		c.pushCounter(ch, "fcp_packet_aborts_total", host.Counters.FCPPacketAborts, host.Name)
+
+		up := uint64(0)
+		if host.PortState == "Online" {
+			up = 1
+		}
+		c.pushMetric(ch, "up", up, host.Name, prometheus.GaugeValue)
+		if speed, ok := parseFibreChannelSpeed(host.Speed); ok {
+			c.pushMetric(ch, "speed_bits_per_second", speed, host.Name, prometheus.GaugeValue)
+		}
 	}
 
+	return c.updateRemotePorts(ch)
+}
+
+// updateRemotePorts counts the remote ports, i.e. the targets and other
+// initiators, each host port sees per state.
+func (c *fibrechannelCollector) updateRemotePorts(ch chan<- prometheus.Metric) error {
+	rports, err := filepath.Glob(sysFilePath("class/fc_remote_ports/rport-*"))
+	if err != nil {
+		return err
+	}
+	counts := map[[2]string]float64{}
+	for _, rport := range rports {
+		// rport-<host>:<bus>-<id>
+		host, _, ok := strings.Cut(strings.TrimPrefix(filepath.Base(rport), "rport-"), ":")
+		if !ok {
+			continue
+		}
+		state, err := os.ReadFile(filepath.Join(rport, "port_state"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		counts[[2]string{"host" + host, strings.TrimSpace(string(state))}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.remotePortsDesc, prometheus.GaugeValue, count, k[0], k[1])
+	}
 	return nil
 }
+
+// parseFibreChannelSpeed parses speeds like "16 Gbit", it returns false for
+// "unknown".
+func parseFibreChannelSpeed(speed string) (uint64, bool) {
+	fields := strings.Fields(speed)
+	if len(fields) != 2 {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	switch fields[1] {
+	case "Gbit":
+		return value * 1e9, true
+	case "Mbit":
+		return value * 1e6, true
+	}
+	return 0, false
+}
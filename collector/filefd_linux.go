@@ -17,22 +17,42 @@
 package collector
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
 )
 
 const (
 	fileFDStatSubsystem = "filefd"
 )
 
+var fileFDTopProcesses = kingpin.Flag("collector.filefd.top-processes", "Number of processes with the most open file descriptors to expose with their limits, 0 to disable.").Default("0").Int()
+
 type fileFDStatCollector struct {
-	logger log.Logger
+	fs             procfs.FS
+	processOpenFDs *prometheus.Desc
+	processMaxFDs  *prometheus.Desc
+	logger         log.Logger
+}
+
+// fileFDProcess is a process with its number of open file descriptors.
+type fileFDProcess struct {
+	proc procfs.Proc
+	fds  int
 }
 
 func init() {
@@ -41,7 +61,27 @@ func init() {
 
 // NewFileFDStatCollector returns a new Collector exposing file-nr stats.
 func NewFileFDStatCollector(logger log.Logger) (Collector, error) {
-	return &fileFDStatCollector{logger}, nil
+	c := &fileFDStatCollector{
+		processOpenFDs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileFDStatSubsystem, "process_open_fds"),
+			"Number of open file descriptors of the processes with the most.",
+			[]string{"pid", "comm"}, nil,
+		),
+		processMaxFDs: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fileFDStatSubsystem, "process_max_fds"),
+			"Soft and hard limit of the number of open file descriptors of the processes with the most.",
+			[]string{"pid", "comm", "limit"}, nil,
+		),
+		logger: logger,
+	}
+	if *fileFDTopProcesses > 0 {
+		fs, err := procfs.NewFS(*procPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open procfs: %w", err)
+		}
+		c.fs = fs
+	}
+	return c, nil
 }
 
 func (c *fileFDStatCollector) Update(ch chan<- prometheus.Metric) error {
@@ -63,9 +103,104 @@ func (c *fileFDStatCollector) Update(ch chan<- prometheus.Metric) error {
 			prometheus.GaugeValue, v,
 		)
 	}
+	if *fileFDTopProcesses > 0 {
+		return c.updateTopProcesses(ch)
+	}
 	return nil
 }
 
+// updateTopProcesses exposes the processes with the most open file
+// descriptors, to find the one leaking them. Processes which can't be read
+// are skipped.
+func (c *fileFDStatCollector) updateTopProcesses(ch chan<- prometheus.Metric) error {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return fmt.Errorf("unable to list processes: %w", err)
+	}
+	var top []fileFDProcess
+	for _, p := range procs {
+		fds, err := p.FileDescriptorsLen()
+		if err != nil {
+			if !errors.Is(err, os.ErrPermission) && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("unable to count file descriptors of pid %d: %w", p.PID, err)
+			}
+			continue
+		}
+		top = append(top, fileFDProcess{proc: p, fds: fds})
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].fds > top[j].fds })
+	if len(top) > *fileFDTopProcesses {
+		top = top[:*fileFDTopProcesses]
+	}
+
+	for _, p := range top {
+		stat, err := p.proc.Stat()
+		if err != nil {
+			// The process exited since it was listed.
+			level.Debug(c.logger).Log("msg", "couldn't read process name", "pid", p.proc.PID, "err", err)
+			continue
+		}
+		pid, comm := strconv.Itoa(p.proc.PID), stat.Comm
+		ch <- prometheus.MustNewConstMetric(c.processOpenFDs, prometheus.GaugeValue, float64(p.fds), pid, comm)
+
+		soft, hard, err := readFileFDLimits(procFilePath(filepath.Join(pid, "limits")))
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't read process limits", "pid", pid, "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.processMaxFDs, prometheus.GaugeValue, soft, pid, comm, "soft")
+		ch <- prometheus.MustNewConstMetric(c.processMaxFDs, prometheus.GaugeValue, hard, pid, comm, "hard")
+	}
+	return nil
+}
+
+// readFileFDLimits returns the soft and hard limit of the open files from the
+// limits of a process, like
+// "Max open files            1024                 524288               files".
+// procfs only parses the soft limits.
+func readFileFDLimits(filename string) (float64, float64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) < 2 {
+			return 0, 0, fmt.Errorf("invalid open files limit in %q: %q", filename, line)
+		}
+		soft, err := parseFileFDLimit(fields[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		hard, err := parseFileFDLimit(fields[1])
+		if err != nil {
+			return 0, 0, err
+		}
+		return soft, hard, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+	return 0, 0, fmt.Errorf("no open files limit in %q", filename)
+}
+
+func parseFileFDLimit(value string) (float64, error) {
+	if value == "unlimited" {
+		return math.Inf(1), nil
+	}
+	limit, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid open files limit %q: %w", value, err)
+	}
+	return float64(limit), nil
+}
+
 func parseFileFDStats(filename string) (map[string]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -29,3 +29,13 @@ func TestFileFDStats(t *testing.T) {
 		t.Errorf("want filefd maximum %q, got %q", want, got)
 	}
 }
+
+func TestReadFileFDLimits(t *testing.T) {
+	soft, hard, err := readFileFDLimits("fixtures/proc/1/limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if soft != 1024 || hard != 524288 {
+		t.Errorf("want limits 1024 and 524288, got %v and %v", soft, hard)
+	}
+}
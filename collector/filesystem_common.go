@@ -60,7 +60,8 @@ var (
 		"Regexp of filesystem types to ignore for filesystem collector.",
 	).Hidden().String()
 
-	filesystemLabelNames = []string{"device", "mountpoint", "fstype"}
+	filesystemLabelNames     = []string{"device", "mountpoint", "fstype"}
+	filesystemInfoLabelNames = []string{"device", "mountpoint", "fstype", "options", "backing_device"}
 )
 
 type filesystemCollector struct {
@@ -69,11 +70,16 @@ type filesystemCollector struct {
 	sizeDesc, freeDesc, availDesc *prometheus.Desc
 	filesDesc, filesFreeDesc      *prometheus.Desc
 	roDesc, deviceErrorDesc       *prometheus.Desc
+	mountInfoDesc, roRemountDesc  *prometheus.Desc
 	logger                        log.Logger
 }
 
 type filesystemLabels struct {
 	device, mountPoint, fsType, options string
+	// backingDevice is the name of the block device and mountOptions the
+	// options of the mount itself without those of the filesystem, both
+	// only known on Linux.
+	backingDevice, mountOptions string
 }
 
 type filesystemStats struct {
@@ -81,6 +87,7 @@ type filesystemStats struct {
 	size, free, avail float64
 	files, filesFree  float64
 	ro, deviceError   float64
+	roRemount         float64
 }
 
 func init() {
@@ -155,6 +162,18 @@ func NewFilesystemCollector(logger log.Logger) (Collector, error) {
 		filesystemLabelNames, nil,
 	)
 
+	mountInfoDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "mount_info"),
+		"Mount options and backing block device of the filesystem.",
+		filesystemInfoLabelNames, nil,
+	)
+
+	roRemountDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "readonly_remount"),
+		"Whether the filesystem is read-only although mounted read-write, as after the kernel remounted it read-only on errors.",
+		filesystemLabelNames, nil,
+	)
+
 	return &filesystemCollector{
 		excludedMountPointsPattern: mountPointPattern,
 		excludedFSTypesPattern:     filesystemsTypesPattern,
@@ -165,6 +184,8 @@ func NewFilesystemCollector(logger log.Logger) (Collector, error) {
 		filesFreeDesc:              filesFreeDesc,
 		roDesc:                     roDesc,
 		deviceErrorDesc:            deviceErrorDesc,
+		mountInfoDesc:              mountInfoDesc,
+		roRemountDesc:              roRemountDesc,
 		logger:                     logger,
 	}, nil
 }
@@ -182,6 +203,10 @@ func (c *filesystemCollector) Update(ch chan<- prometheus.Metric) error {
 		}
 		seen[s.labels] = true
 
+		ch <- prometheus.MustNewConstMetric(
+			c.mountInfoDesc, prometheus.GaugeValue,
+			1, s.labels.device, s.labels.mountPoint, s.labels.fsType, s.labels.options, s.labels.backingDevice,
+		)
 		ch <- prometheus.MustNewConstMetric(
 			c.deviceErrorDesc, prometheus.GaugeValue,
 			s.deviceError, s.labels.device, s.labels.mountPoint, s.labels.fsType,
@@ -214,6 +239,12 @@ func (c *filesystemCollector) Update(ch chan<- prometheus.Metric) error {
 			c.roDesc, prometheus.GaugeValue,
 			s.ro, s.labels.device, s.labels.mountPoint, s.labels.fsType,
 		)
+		if s.labels.mountOptions != "" {
+			ch <- prometheus.MustNewConstMetric(
+				c.roRemountDesc, prometheus.GaugeValue,
+				s.roRemount, s.labels.device, s.labels.mountPoint, s.labels.fsType,
+			)
+		}
 	}
 	return nil
 }
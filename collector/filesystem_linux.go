@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -132,11 +133,14 @@ func (c *filesystemCollector) processStat(labels filesystemLabels) filesystemSta
 		}
 	}
 
-	var ro float64
-	for _, option := range strings.Split(labels.options, ",") {
-		if option == "ro" {
-			ro = 1
-			break
+	var ro, roRemount float64
+	if mountOptionSet(labels.options, "ro") {
+		ro = 1
+		// The options of /proc/mounts include the read-only flag of the
+		// superblock, which the kernel sets when it remounts the filesystem
+		// read-only on errors, but that of the mount itself stays unset.
+		if labels.mountOptions != "" && !mountOptionSet(labels.mountOptions, "ro") {
+			roRemount = 1
 		}
 	}
 	return filesystemStats{
@@ -147,9 +151,19 @@ func (c *filesystemCollector) processStat(labels filesystemLabels) filesystemSta
 		files:     float64(buf.Files),
 		filesFree: float64(buf.Ffree),
 		ro:        ro,
+		roRemount: roRemount,
 	}
 }
 
+func mountOptionSet(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
 // stuckMountWatcher listens on the given success channel and if the channel closes
 // then the watcher does nothing. If instead the timeout is reached, the
 // mount point that is being watched is marked as stuck.
@@ -185,7 +199,83 @@ func mountPointDetails(logger log.Logger) ([]filesystemLabels, error) {
 	}
 	defer file.Close()
 
-	return parseFilesystemLabels(file)
+	filesystems, err := parseFilesystemLabels(file)
+	if err != nil {
+		return nil, err
+	}
+	mounts, err := mountInfoDetails()
+	if err != nil {
+		level.Debug(logger).Log("msg", "Reading mountinfo failed, backing devices and read-only remounts are unknown", "err", err)
+		return filesystems, nil
+	}
+	for i, labels := range filesystems {
+		mount, ok := mounts[filesystemMountKey{device: labels.device, mountPoint: labels.mountPoint}]
+		if !ok {
+			continue
+		}
+		filesystems[i].mountOptions = mount.options
+		if target, err := os.Readlink(sysFilePath(filepath.Join("dev/block", mount.majorMinor))); err == nil {
+			filesystems[i].backingDevice = filepath.Base(target)
+		}
+	}
+	return filesystems, nil
+}
+
+type filesystemMountKey struct{ device, mountPoint string }
+
+// filesystemMount holds the details of a mount from mountinfo missing in
+// /proc/mounts.
+type filesystemMount struct {
+	majorMinor, options string
+}
+
+func mountInfoDetails() (map[filesystemMountKey]filesystemMount, error) {
+	file, err := os.Open(procFilePath("1/mountinfo"))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(procFilePath("self/mountinfo"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parseFilesystemMountInfo(file)
+}
+
+// parseFilesystemMountInfo returns the device number and the options of the
+// mount itself of the mounts in mountinfo, like
+// "36 35 98:0 /mnt1 /mnt2 rw,noatime master:1 - ext3 /dev/root rw,errors=continue".
+// Of mounts over the same mount point, the visible last one is returned.
+func parseFilesystemMountInfo(r io.Reader) (map[filesystemMountKey]filesystemMount, error) {
+	mounts := map[filesystemMountKey]filesystemMount{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		separator := -1
+		for i := 6; i < len(parts); i++ {
+			if parts[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 0 || len(parts) < separator+3 {
+			return nil, fmt.Errorf("malformed mountinfo: %q", scanner.Text())
+		}
+
+		key := filesystemMountKey{
+			device:     parts[separator+2],
+			mountPoint: rootfsStripPrefix(unescapeMountPoint(parts[4])),
+		}
+		mounts[key] = filesystemMount{majorMinor: parts[2], options: parts[5]}
+	}
+	return mounts, scanner.Err()
+}
+
+// unescapeMountPoint handles the translation of \040 and \011 as per
+// fstab(5).
+func unescapeMountPoint(mountPoint string) string {
+	mountPoint = strings.Replace(mountPoint, "\\040", " ", -1)
+	return strings.Replace(mountPoint, "\\011", "\t", -1)
 }
 
 func parseFilesystemLabels(r io.Reader) ([]filesystemLabels, error) {
@@ -199,14 +289,9 @@ func parseFilesystemLabels(r io.Reader) ([]filesystemLabels, error) {
 			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
 		}
 
-		// Ensure we handle the translation of \040 and \011
-		// as per fstab(5).
-		parts[1] = strings.Replace(parts[1], "\\040", " ", -1)
-		parts[1] = strings.Replace(parts[1], "\\011", "\t", -1)
-
 		filesystems = append(filesystems, filesystemLabels{
 			device:     parts[0],
-			mountPoint: rootfsStripPrefix(parts[1]),
+			mountPoint: rootfsStripPrefix(unescapeMountPoint(parts[1])),
 			fsType:     parts[2],
 			options:    parts[3],
 		})
@@ -15,6 +15,7 @@ package collector
 
 import (
 	"github.com/go-kit/log"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -41,6 +42,32 @@ func Test_parseFilesystemLabelsError(t *testing.T) {
 	}
 }
 
+func TestParseFilesystemMountInfo(t *testing.T) {
+	in := `21 0 253:2 / / rw,relatime shared:1 - ext4 /dev/dm-2 ro,relatime,errors=remount-ro
+25 21 8:3 / /boot ro,relatime shared:2 - ext2 /dev/sda3 ro
+26 21 0:22 / /run rw,nosuid shared:3 - tmpfs tmpfs rw,size=1617716k,mode=755
+27 21 0:23 / /run rw,nosuid shared:4 - tmpfs tmpfs rw,size=1024k
+28 21 8:0 / /mnt/a\040b rw,relatime - ext4 /dev/sda rw
+`
+	mounts, err := parseFilesystemMountInfo(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[filesystemMountKey]filesystemMount{
+		{device: "/dev/dm-2", mountPoint: "/"}:       {majorMinor: "253:2", options: "rw,relatime"},
+		{device: "/dev/sda3", mountPoint: "/boot"}:   {majorMinor: "8:3", options: "ro,relatime"},
+		{device: "tmpfs", mountPoint: "/run"}:        {majorMinor: "0:23", options: "rw,nosuid"},
+		{device: "/dev/sda", mountPoint: "/mnt/a b"}: {majorMinor: "8:0", options: "rw,relatime"},
+	}
+	if !reflect.DeepEqual(mounts, want) {
+		t.Errorf("want %v, got %v", want, mounts)
+	}
+
+	if _, err := parseFilesystemMountInfo(strings.NewReader("21 0 253:2 / / rw,relatime\n")); err == nil {
+		t.Error("missing separator: expected error")
+	}
+}
+
 func TestMountPointDetails(t *testing.T) {
 	if _, err := kingpin.CommandLine.Parse([]string{"--path.procfs", "./fixtures/proc"}); err != nil {
 		t.Fatal(err)
@@ -75,6 +102,9 @@ func TestMountPointDetails(t *testing.T) {
 		"/run/rpc_pipefs":                 "",
 		"/run/user/1000":                  "",
 		"/run/user/1000/gvfs":             "",
+		"/home":                           "",
+		"/home/alice":                     "",
+		"/home/bob":                       "",
 		"/var/lib/kubelet/plugins/kubernetes.io/vsphere-volume/mounts/[vsanDatastore] bafb9e5a-8856-7e6c-699c-801844e77a4a/kubernetes-dynamic-pvc-3eba5bba-48a3-11e8-89ab-005056b92113.vmdk": "",
 		"/var/lib/kubelet/plugins/kubernetes.io/vsphere-volume/mounts/[vsanDatastore]	bafb9e5a-8856-7e6c-699c-801844e77a4a/kubernetes-dynamic-pvc-3eba5bba-48a3-11e8-89ab-005056b92113.vmdk": "",
 	}
@@ -0,0 +1,143 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nofirmware
+// +build !nofirmware
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const firmwareSubsystem = "firmware"
+
+type firmwareCollector struct {
+	microcode *prometheus.Desc
+	biosDate  *prometheus.Desc
+	bmc       *prometheus.Desc
+	logger    log.Logger
+}
+
+func init() {
+	registerCollector(firmwareSubsystem, defaultEnabled, NewFirmwareCollector)
+}
+
+// NewFirmwareCollector returns a new Collector exposing the versions of the
+// CPU microcode and of the BIOS and BMC firmware.
+func NewFirmwareCollector(logger log.Logger) (Collector, error) {
+	return &firmwareCollector{
+		microcode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firmwareSubsystem, "microcode_info"),
+			"A metric with a constant '1' value labeled by each microcode version the CPUs run.",
+			[]string{"version"}, nil,
+		),
+		biosDate: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firmwareSubsystem, "bios_date_seconds"),
+			"Release date of the BIOS or UEFI firmware in seconds since the epoch.",
+			nil, nil,
+		),
+		bmc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, firmwareSubsystem, "bmc_info"),
+			"A metric with a constant '1' value labeled by the firmware revision, IPMI version, manufacturer and product ID of the BMC of an IPMI device.",
+			[]string{"device", "firmware_revision", "ipmi_version", "manufacturer_id", "product_id"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *firmwareCollector) Update(ch chan<- prometheus.Metric) error {
+	found := false
+
+	versions, err := readMicrocodeVersions()
+	if err != nil {
+		return fmt.Errorf("couldn't get microcode versions: %w", err)
+	}
+	for _, version := range versions {
+		ch <- prometheus.MustNewConstMetric(c.microcode, prometheus.GaugeValue, 1, version)
+		found = true
+	}
+
+	date, err := os.ReadFile(sysFilePath("class/dmi/id/bios_date"))
+	if err == nil {
+		// SMBIOS dates are mm/dd/yyyy, or mm/dd/yy before SMBIOS 2.3.
+		for _, layout := range []string{"01/02/2006", "01/02/06"} {
+			if t, err := time.Parse(layout, strings.TrimSpace(string(date))); err == nil {
+				ch <- prometheus.MustNewConstMetric(c.biosDate, prometheus.GaugeValue, float64(t.Unix()))
+				found = true
+				break
+			}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	bmcs, err := filepath.Glob(sysFilePath("class/ipmi/ipmi[0-9]*/device/bmc"))
+	if err != nil {
+		return err
+	}
+	for _, bmc := range bmcs {
+		device := filepath.Base(filepath.Dir(filepath.Dir(bmc)))
+		values := []string{device}
+		for _, attribute := range []string{"firmware_revision", "ipmi_version", "manufacturer_id", "product_id"} {
+			value, err := os.ReadFile(filepath.Join(bmc, attribute))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			values = append(values, strings.TrimSpace(string(value)))
+		}
+		ch <- prometheus.MustNewConstMetric(c.bmc, prometheus.GaugeValue, 1, values...)
+		found = true
+	}
+
+	if !found {
+		return ErrNoData
+	}
+	return nil
+}
+
+// readMicrocodeVersions returns the distinct microcode versions of the CPUs,
+// which only differ while an update is being applied.
+func readMicrocodeVersions() ([]string, error) {
+	paths, err := filepath.Glob(sysFilePath("devices/system/cpu/cpu[0-9]*/microcode/version"))
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, path := range paths {
+		version, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// The CPU went offline.
+				continue
+			}
+			return nil, err
+		}
+		seen[strings.TrimSpace(string(version))] = true
+	}
+	versions := make([]string, 0, len(seen))
+	for version := range seen {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nofslatency
+// +build !nofslatency
+
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	fsLatencySubsystem = "fslatency"
+	fsLatencyProbeSize = 4096
+)
+
+var (
+	fsLatencyMountPoints = kingpin.Flag("collector.fslatency.mount-point", "Mount point to probe, can be repeated.").Strings()
+	fsLatencyInterval    = kingpin.Flag("collector.fslatency.interval", "Interval between two probes of a mount point.").Default("30s").Duration()
+	fsLatencyTimeout     = kingpin.Flag("collector.fslatency.timeout", "Duration after which a probe still in progress is reported as failed.").Default("10s").Duration()
+)
+
+type fsLatencyCollector struct {
+	probers   []*fsLatencyProber
+	duration  *prometheus.Desc
+	success   *prometheus.Desc
+	timestamp *prometheus.Desc
+	failures  *prometheus.Desc
+	logger    log.Logger
+}
+
+// fsLatencyProber periodically writes, syncs and reads back a small file on
+// a mount point. Probes run in the background so that a hung mount can't
+// block scrapes.
+type fsLatencyProber struct {
+	mountPoint string
+	path       string
+	logger     log.Logger
+
+	mtx       sync.Mutex
+	started   time.Time // start of the probe in progress, zero if idle
+	completed time.Time
+	durations map[string]time.Duration
+	err       error
+	failures  uint64
+}
+
+var errFSLatencyMountPoints = errors.New("no mount points configured, use --collector.fslatency.mount-point")
+
+func init() {
+	registerCollector(fsLatencySubsystem, defaultDisabled, NewFSLatencyCollector)
+}
+
+// NewFSLatencyCollector returns a new Collector exposing the latency of
+// small synchronous I/O on the configured mount points.
+func NewFSLatencyCollector(logger log.Logger) (Collector, error) {
+	c := &fsLatencyCollector{
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsLatencySubsystem, "probe_duration_seconds"),
+			"Duration of each operation of the last completed probe.",
+			[]string{"mountpoint", "operation"}, nil,
+		),
+		success: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsLatencySubsystem, "probe_success"),
+			"Whether the last probe succeeded, 0 if a probe has been in progress for longer than the timeout.",
+			[]string{"mountpoint"}, nil,
+		),
+		timestamp: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsLatencySubsystem, "probe_timestamp_seconds"),
+			"Unix time the last probe completed.",
+			[]string{"mountpoint"}, nil,
+		),
+		failures: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsLatencySubsystem, "probe_failures_total"),
+			"Number of failed probes.",
+			[]string{"mountpoint"}, nil,
+		),
+		logger: logger,
+	}
+	for _, mp := range *fsLatencyMountPoints {
+		p := &fsLatencyProber{
+			mountPoint: mp,
+			path:       rootfsFilePath(mp),
+			logger:     log.With(logger, "mountpoint", mp),
+		}
+		c.probers = append(c.probers, p)
+		go p.run(*fsLatencyInterval)
+	}
+	return c, nil
+}
+
+// Validate implements Validator.
+func (c *fsLatencyCollector) Validate() error {
+	if len(c.probers) == 0 {
+		return errFSLatencyMountPoints
+	}
+	return nil
+}
+
+func (c *fsLatencyCollector) Update(ch chan<- prometheus.Metric) error {
+	if len(c.probers) == 0 {
+		// Failing to create the collector would fail every scrape.
+		return fmt.Errorf("%w: %s", ErrNoData, errFSLatencyMountPoints)
+	}
+	now := time.Now()
+	for _, p := range c.probers {
+		p.mtx.Lock()
+		stuck := !p.started.IsZero() && now.Sub(p.started) > *fsLatencyTimeout
+		completed, durations, err, failures := p.completed, p.durations, p.err, p.failures
+		p.mtx.Unlock()
+
+		if stuck || !completed.IsZero() {
+			success := 0.0
+			if !stuck && err == nil {
+				success = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.success, prometheus.GaugeValue, success, p.mountPoint)
+		}
+		if completed.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.timestamp, prometheus.GaugeValue, float64(completed.UnixNano())/1e9, p.mountPoint)
+		ch <- prometheus.MustNewConstMetric(c.failures, prometheus.CounterValue, float64(failures), p.mountPoint)
+		for op, d := range durations {
+			ch <- prometheus.MustNewConstMetric(c.duration, prometheus.GaugeValue, d.Seconds(), p.mountPoint, op)
+		}
+	}
+	return nil
+}
+
+func (p *fsLatencyProber) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.mtx.Lock()
+		p.started = time.Now()
+		p.mtx.Unlock()
+
+		durations, err := probeFSLatency(p.path)
+
+		p.mtx.Lock()
+		p.started = time.Time{}
+		p.completed = time.Now()
+		p.err = err
+		if err != nil {
+			p.failures++
+			level.Warn(p.logger).Log("msg", "filesystem latency probe failed", "err", err)
+		} else {
+			p.durations = durations
+		}
+		p.mtx.Unlock()
+
+		<-ticker.C
+	}
+}
+
+// probeFSLatency writes a small file to dir, syncs it to stable storage and
+// reads it back, returning the duration of each of these operations.
+func probeFSLatency(dir string) (map[string]time.Duration, error) {
+	f, err := os.CreateTemp(dir, ".node_exporter_fslatency-")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create probe file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	data := bytes.Repeat([]byte{0xa5}, fsLatencyProbeSize)
+	durations := map[string]time.Duration{}
+
+	begin := time.Now()
+	if _, err := f.Write(data); err != nil {
+		return nil, fmt.Errorf("couldn't write probe file: %w", err)
+	}
+	durations["write"] = time.Since(begin)
+
+	begin = time.Now()
+	if err := f.Sync(); err != nil {
+		return nil, fmt.Errorf("couldn't sync probe file: %w", err)
+	}
+	durations["fsync"] = time.Since(begin)
+
+	begin = time.Now()
+	buf := make([]byte, fsLatencyProbeSize)
+	if _, err := f.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("couldn't read probe file: %w", err)
+	}
+	durations["read"] = time.Since(begin)
+	if !bytes.Equal(buf, data) {
+		return nil, errors.New("probe file content doesn't match what was written")
+	}
+	return durations, nil
+}
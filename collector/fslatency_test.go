@@ -0,0 +1,64 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nofslatency
+// +build !nofslatency
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbeFSLatency(t *testing.T) {
+	dir := t.TempDir()
+	durations, err := probeFSLatency(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"write", "fsync", "read"} {
+		if _, ok := durations[op]; !ok {
+			t.Errorf("missing duration of %s", op)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("probe file wasn't removed: %v", entries)
+	}
+
+	if _, err := probeFSLatency(dir + "/missing"); err == nil {
+		t.Error("expected probing a missing directory to fail")
+	}
+}
+
+func TestFSLatencyWithoutMountPoints(t *testing.T) {
+	*fsLatencyMountPoints = nil
+	c, err := NewFSLatencyCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(Validator).Validate(); err == nil {
+		t.Error("validation without mount points: want error")
+	}
+	if err := c.Update(make(chan prometheus.Metric, 10)); !errors.Is(err, ErrNoData) {
+		t.Errorf("scrape without mount points: want ErrNoData, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nofsnotify
+// +build !nofsnotify
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const fsnotifySubsystem = "fsnotify"
+
+// fsnotifyAPIs are the anonymous inodes of the files of each API, and the
+// limits of the instances and watches per user in /proc/sys/fs. fanotify
+// calls its instances groups and its watches marks, the limits were added in
+// Linux 5.13.
+var fsnotifyAPIs = []struct {
+	api, target, maxInstances, maxWatches string
+}{
+	{"inotify", "anon_inode:inotify", "inotify/max_user_instances", "inotify/max_user_watches"},
+	{"fanotify", "anon_inode:[fanotify]", "fanotify/max_user_groups", "fanotify/max_user_marks"},
+}
+
+type fsnotifyUsage struct{ instances, watches float64 }
+
+type fsnotifyKey struct{ api, uid string }
+
+type fsnotifyCollector struct {
+	instances    *prometheus.Desc
+	watches      *prometheus.Desc
+	maxInstances *prometheus.Desc
+	maxWatches   *prometheus.Desc
+	logger       log.Logger
+}
+
+func init() {
+	registerCollector(fsnotifySubsystem, defaultDisabled, NewFsnotifyCollector)
+}
+
+// NewFsnotifyCollector returns a new Collector exposing the inotify and
+// fanotify instances and watches of each user and their limits.
+func NewFsnotifyCollector(logger log.Logger) (Collector, error) {
+	return &fsnotifyCollector{
+		instances: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsnotifySubsystem, "instances"),
+			"Number of inotify instances or fanotify groups of the processes of the user, by effective UID.",
+			[]string{"api", "uid"}, nil,
+		),
+		watches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsnotifySubsystem, "watches"),
+			"Number of inotify watches or fanotify marks of the processes of the user, by effective UID.",
+			[]string{"api", "uid"}, nil,
+		),
+		maxInstances: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsnotifySubsystem, "max_user_instances"),
+			"Maximum number of inotify instances or fanotify groups per user.",
+			[]string{"api"}, nil,
+		),
+		maxWatches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, fsnotifySubsystem, "max_user_watches"),
+			"Maximum number of inotify watches or fanotify marks per user.",
+			[]string{"api"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *fsnotifyCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, a := range fsnotifyAPIs {
+		for desc, file := range map[*prometheus.Desc]string{c.maxInstances: a.maxInstances, c.maxWatches: a.maxWatches} {
+			value, err := readUintFromFile(procFilePath(filepath.Join("sys/fs", file)))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), a.api)
+		}
+	}
+
+	usage, err := fsnotifyUsageByUser()
+	if err != nil {
+		return err
+	}
+	for key, u := range usage {
+		ch <- prometheus.MustNewConstMetric(c.instances, prometheus.GaugeValue, u.instances, key.api, key.uid)
+		ch <- prometheus.MustNewConstMetric(c.watches, prometheus.GaugeValue, u.watches, key.api, key.uid)
+	}
+	return nil
+}
+
+// fsnotifyUsageByUser sums the instances and watches of the files of all
+// processes. An instance shared by several processes, e.g. after a fork, is
+// counted for each of them. Without the permission to read the files of the
+// processes of other users, only the own ones are counted.
+func fsnotifyUsageByUser() (map[fsnotifyKey]*fsnotifyUsage, error) {
+	procs, err := filepath.Glob(procFilePath("[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	usage := map[fsnotifyKey]*fsnotifyUsage{}
+	for _, proc := range procs {
+		fds, err := os.ReadDir(filepath.Join(proc, "fd"))
+		if err != nil {
+			// The process exited since it was listed, or isn't readable.
+			continue
+		}
+		var uid string
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(proc, "fd", fd.Name()))
+			if err != nil {
+				continue
+			}
+			for _, a := range fsnotifyAPIs {
+				if target != a.target {
+					continue
+				}
+				if uid == "" {
+					if uid, err = procEffectiveUID(proc); err != nil {
+						break
+					}
+				}
+				watches, err := countFsnotifyWatches(filepath.Join(proc, "fdinfo", fd.Name()), a.api)
+				if err != nil {
+					break
+				}
+				key := fsnotifyKey{api: a.api, uid: uid}
+				if usage[key] == nil {
+					usage[key] = &fsnotifyUsage{}
+				}
+				usage[key].instances++
+				usage[key].watches += watches
+			}
+		}
+	}
+	return usage, nil
+}
+
+// countFsnotifyWatches returns the number of watches listed in the fdinfo of
+// an inotify or fanotify file, like
+// "inotify wd:1 ino:d41a1 sdev:fe00000 mask:fc6 ..." or
+// "fanotify ino:d41a1 sdev:fe00000 mflags:0 mask:1 ...". The "fanotify
+// flags:" line describes the group itself.
+func countFsnotifyWatches(path, api string) (float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	var watches float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, api+" ") && !strings.HasPrefix(line, "fanotify flags:") {
+			watches++
+		}
+	}
+	return watches, scanner.Err()
+}
+
+// procEffectiveUID returns the effective UID of a process from its status
+// file, the user the kernel charges the instances and watches to.
+func procEffectiveUID(proc string) (string, error) {
+	file, err := os.Open(filepath.Join(proc, "status"))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Uid: real effective saved filesystem
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 3 && fields[0] == "Uid:" {
+			return fields[2], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no Uid in %s/status", proc)
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nofsnotify
+// +build !nofsnotify
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFsnotifyUsageByUser(t *testing.T) {
+	proc := t.TempDir()
+	defer func(path string) { *procPath = path }(*procPath)
+	*procPath = proc
+
+	const (
+		inotifyInfo  = "pos:\t0\nflags:\t02004000\nmnt_id:\t15\nino:\t1057\ninotify wd:2 ino:1a2b sdev:fd00001 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:2b1a0000\ninotify wd:1 ino:2 sdev:fd00001 mask:fc6 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:02000000\n"
+		fanotifyInfo = "pos:\t0\nflags:\t02\nmnt_id:\t15\nino:\t1057\nfanotify flags:10 event-flags:0\nfanotify ino:1a2b sdev:fd00001 mflags:0 mask:1 ignored_mask:0 fhandle-bytes:8 fhandle-type:1 f_handle:2b1a0000\n"
+	)
+	for pid, p := range map[string]struct {
+		status string
+		fds    map[string]struct{ target, info string }
+	}{
+		"1": {"Name:\tsystemd\nUid:\t0\t0\t0\t0\n", map[string]struct{ target, info string }{
+			"3": {"anon_inode:inotify", inotifyInfo},
+			"4": {"anon_inode:[fanotify]", fanotifyInfo},
+			"5": {"/dev/null", ""},
+		}},
+		// The effective UID counts, not the real one.
+		"42": {"Name:\tsu\nUid:\t1000\t1001\t1001\t1001\n", map[string]struct{ target, info string }{
+			"7": {"anon_inode:inotify", inotifyInfo},
+			"8": {"anon_inode:inotify", "inotify wd:1 ino:2 sdev:fd00001 mask:fc6\n"},
+		}},
+		// A kernel thread without files.
+		"2": {"Name:\tkthreadd\nUid:\t0\t0\t0\t0\n", nil},
+	} {
+		for _, dir := range []string{"fd", "fdinfo"} {
+			if err := os.MkdirAll(filepath.Join(proc, pid, dir), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(proc, pid, "status"), []byte(p.status), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for fd, file := range p.fds {
+			if err := os.Symlink(file.target, filepath.Join(proc, pid, "fd", fd)); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(proc, pid, "fdinfo", fd), []byte(file.info), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// Not a process.
+	if err := os.Mkdir(filepath.Join(proc, "sys"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := fsnotifyUsageByUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[fsnotifyKey]*fsnotifyUsage{
+		{api: "inotify", uid: "0"}:    {instances: 1, watches: 2},
+		{api: "fanotify", uid: "0"}:   {instances: 1, watches: 1},
+		{api: "inotify", uid: "1001"}: {instances: 2, watches: 3},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		for key, u := range usage {
+			t.Logf("%v: %v", key, *u)
+		}
+		t.Errorf("unexpected usage")
+	}
+}
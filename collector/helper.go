@@ -15,6 +15,7 @@ package collector
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -32,6 +33,47 @@ func readUintFromFile(path string) (uint64, error) {
 	return value, nil
 }
 
+func readIntFromFile(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
+
+// blockDeviceName returns the kernel name of the block device with the given
+// "major:minor" number, or the number itself if it can't be resolved.
+func blockDeviceName(dev string) string {
+	target, err := os.Readlink(sysFilePath(filepath.Join("dev/block", dev)))
+	if err != nil {
+		return dev
+	}
+	return filepath.Base(target)
+}
+
+// sysfsDeviceDriver returns the name of the kernel driver bound to the device
+// of a sysfs class entry like /sys/class/drm/card0, or an empty string if
+// there is none.
+func sysfsDeviceDriver(entry string) string {
+	driver, err := os.Readlink(filepath.Join(entry, "device/driver"))
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(driver)
+}
+
+// boolToFloat returns 1 for true and 0 for false.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 var metricNameRegex = regexp.MustCompile(`_*[^0-9A-Za-z_]+_*`)
 
 // SanitizeMetricName sanitize the given metric name by replacing invalid characters by underscores.
@@ -0,0 +1,192 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nohugetlbfs
+// +build !nohugetlbfs
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const hugetlbfsSubsystem = "hugetlbfs"
+
+// hugetlbfsMount is a hugetlbfs mount and the limits given as mount options,
+// in bytes. The limits are -1 if not set.
+type hugetlbfsMount struct {
+	mountPoint string
+	size       int64
+	minSize    int64
+}
+
+type hugetlbfsCollector struct {
+	pagesUsed     *prometheus.Desc
+	pagesLimit    *prometheus.Desc
+	pagesReserved *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector(hugetlbfsSubsystem, defaultDisabled, NewHugetlbfsCollector)
+}
+
+// NewHugetlbfsCollector returns a new Collector exposing the huge pages used
+// by the files of each hugetlbfs mount.
+func NewHugetlbfsCollector(logger log.Logger) (Collector, error) {
+	labels := []string{"mountpoint", "pagesize"}
+	return &hugetlbfsCollector{
+		pagesUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hugetlbfsSubsystem, "pages_used"),
+			"Number of huge pages used by the files of the mount.",
+			labels, nil,
+		),
+		pagesLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hugetlbfsSubsystem, "pages_limit"),
+			"Maximum number of huge pages the files of the mount can use, set by the size mount option.",
+			labels, nil,
+		),
+		pagesReserved: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, hugetlbfsSubsystem, "pages_reserved"),
+			"Number of huge pages reserved for the mount, set by the min_size mount option.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *hugetlbfsCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("1/mounts"))
+	if errors.Is(err, os.ErrNotExist) {
+		// Fallback to `/proc/mounts` if `/proc/1/mounts` is missing due hidepid.
+		file, err = os.Open(procFilePath("mounts"))
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mounts, err := parseHugetlbfsMounts(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse mounts: %w", err)
+	}
+	if len(mounts) == 0 {
+		return ErrNoData
+	}
+	for _, m := range mounts {
+		if err := c.updateMount(ch, m); err != nil {
+			level.Debug(c.logger).Log("msg", "Couldn't get usage of hugetlbfs mount", "mountpoint", m.mountPoint, "err", err)
+		}
+	}
+	return nil
+}
+
+func (c *hugetlbfsCollector) updateMount(ch chan<- prometheus.Metric, m hugetlbfsMount) error {
+	path := rootfsFilePath(m.mountPoint)
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return err
+	}
+	// The block size of hugetlbfs is the huge page size.
+	pageSize := uint64(st.Bsize)
+	pageSizeLabel := fmt.Sprintf("%dkB", pageSize/1024)
+
+	used := st.Blocks - st.Bfree
+	if m.size < 0 {
+		// Without a size limit the kernel doesn't account the pages used
+		// by the mount, sum the ones of its files.
+		var err error
+		used, err = hugetlbfsPagesUsed(path, pageSize)
+		if err != nil {
+			return err
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.pagesUsed, prometheus.GaugeValue, float64(used), m.mountPoint, pageSizeLabel)
+	if m.size >= 0 {
+		ch <- prometheus.MustNewConstMetric(c.pagesLimit, prometheus.GaugeValue, float64(uint64(m.size)/pageSize), m.mountPoint, pageSizeLabel)
+	}
+	if m.minSize >= 0 {
+		ch <- prometheus.MustNewConstMetric(c.pagesReserved, prometheus.GaugeValue, float64(uint64(m.minSize)/pageSize), m.mountPoint, pageSizeLabel)
+	}
+	return nil
+}
+
+// hugetlbfsPagesUsed sums the huge pages allocated to the files below dir.
+func hugetlbfsPagesUsed(dir string, pageSize uint64) (uint64, error) {
+	var bytes uint64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			bytes += uint64(st.Blocks) * 512
+		}
+		return nil
+	})
+	return bytes / pageSize, err
+}
+
+// parseHugetlbfsMounts returns the hugetlbfs mounts of a mounts file.
+func parseHugetlbfsMounts(r io.Reader) ([]hugetlbfsMount, error) {
+	var mounts []hugetlbfsMount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 {
+			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
+		}
+		if parts[2] != "hugetlbfs" {
+			continue
+		}
+		m := hugetlbfsMount{
+			mountPoint: rootfsStripPrefix(strings.NewReplacer("\\040", " ", "\\011", "\t").Replace(parts[1])),
+			size:       -1,
+			minSize:    -1,
+		}
+		// The kernel shows the limits in bytes, the percentage of the pool
+		// they may have been given as is resolved at mount time.
+		for _, option := range strings.Split(parts[3], ",") {
+			key, value, _ := strings.Cut(option, "=")
+			var err error
+			switch key {
+			case "size":
+				m.size, err = strconv.ParseInt(value, 10, 64)
+			case "min_size":
+				m.minSize, err = strconv.ParseInt(value, 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid option %q of %s: %w", option, m.mountPoint, err)
+			}
+		}
+		mounts = append(mounts, m)
+	}
+	return mounts, scanner.Err()
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nohugetlbfs
+// +build !nohugetlbfs
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseHugetlbfsMounts(t *testing.T) {
+	file, err := os.Open("fixtures/proc/1/mounts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	mounts, err := parseHugetlbfsMounts(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []hugetlbfsMount{{mountPoint: "/dev/hugepages", size: -1, minSize: -1}}
+	if !reflect.DeepEqual(want, mounts) {
+		t.Errorf("want %v, got %v", want, mounts)
+	}
+
+	mounts, err = parseHugetlbfsMounts(strings.NewReader(
+		"none /mnt/huge\\0401G hugetlbfs rw,relatime,pagesize=1024M,size=8589934592,min_size=4294967296 0 0\n" +
+			"none /mnt/huge-2M hugetlbfs rw,relatime,pagesize=2M,min_size=104857600 0 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []hugetlbfsMount{
+		{mountPoint: "/mnt/huge 1G", size: 8589934592, minSize: 4294967296},
+		{mountPoint: "/mnt/huge-2M", size: -1, minSize: 104857600},
+	}
+	if !reflect.DeepEqual(want, mounts) {
+		t.Errorf("want %v, got %v", want, mounts)
+	}
+}
@@ -27,7 +27,6 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
-	"golang.org/x/sys/unix"
 )
 
 var (
@@ -44,16 +43,18 @@ var (
 
 func init() {
 	registerCollector("hwmon", defaultEnabled, NewHwMonCollector)
+	registerHardwareProbe("hwmon", "no hwmon chips", sysfsProbe("class/hwmon/*"))
 }
 
 type hwMonCollector struct {
+	reader fileReader
 	logger log.Logger
 }
 
 // NewHwMonCollector returns a new Collector exposing /sys/class/hwmon stats
 // (similar to lm-sensors).
 func NewHwMonCollector(logger log.Logger) (Collector, error) {
-	return &hwMonCollector{logger}, nil
+	return &hwMonCollector{newFileReader(logger), logger}, nil
 }
 
 func cleanMetricName(name string) string {
@@ -63,11 +64,7 @@ func cleanMetricName(name string) string {
 	return cleaned
 }
 
-func addValueFile(data map[string]map[string]string, sensor string, prop string, file string) {
-	raw, err := sysReadFile(file)
-	if err != nil {
-		return
-	}
+func addValue(data map[string]map[string]string, sensor string, prop string, raw []byte) {
 	value := strings.Trim(string(raw), "\n")
 
 	if _, ok := data[sensor]; !ok {
@@ -77,28 +74,6 @@ func addValueFile(data map[string]map[string]string, sensor string, prop string,
 	data[sensor][prop] = value
 }
 
-// sysReadFile is a simplified os.ReadFile that invokes syscall.Read directly.
-func sysReadFile(file string) ([]byte, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	// On some machines, hwmon drivers are broken and return EAGAIN.  This causes
-	// Go's os.ReadFile implementation to poll forever.
-	//
-	// Since we either want to read data or bail immediately, do the simplest
-	// possible read using system call directly.
-	b := make([]byte, 128)
-	n, err := unix.Read(int(f.Fd()), b)
-	if err != nil {
-		return nil, err
-	}
-
-	return b[:n], nil
-}
-
 // explodeSensorFilename splits a sensor name into <type><num>_<property>.
 func explodeSensorFilename(filename string) (ok bool, sensorType string, sensorNum int, sensorProperty string) {
 	matches := hwmonFilenameFormat.FindStringSubmatch(filename)
@@ -126,11 +101,12 @@ func explodeSensorFilename(filename string) (ok bool, sensorType string, sensorN
 	return true, sensorType, sensorNum, sensorProperty
 }
 
-func collectSensorData(dir string, data map[string]map[string]string) error {
+func collectSensorData(reader fileReader, dir string, data map[string]map[string]string) error {
 	sensorFiles, dirError := os.ReadDir(dir)
 	if dirError != nil {
 		return dirError
 	}
+	var sensors, props, files []string
 	for _, file := range sensorFiles {
 		filename := file.Name()
 		ok, sensorType, sensorNum, sensorProperty := explodeSensorFilename(filename)
@@ -140,11 +116,21 @@ func collectSensorData(dir string, data map[string]map[string]string) error {
 
 		for _, t := range hwmonSensorTypes {
 			if t == sensorType {
-				addValueFile(data, sensorType+strconv.Itoa(sensorNum), sensorProperty, filepath.Join(dir, file.Name()))
+				sensors = append(sensors, sensorType+strconv.Itoa(sensorNum))
+				props = append(props, sensorProperty)
+				files = append(files, filepath.Join(dir, file.Name()))
 				break
 			}
 		}
 	}
+
+	contents, errs := reader.readFiles(files, 128)
+	for i := range files {
+		if errs[i] != nil {
+			continue
+		}
+		addValue(data, sensors[i], props[i], contents[i])
+	}
 	return nil
 }
 
@@ -155,12 +141,12 @@ func (c *hwMonCollector) updateHwmon(ch chan<- prometheus.Metric, dir string) er
 	}
 
 	data := make(map[string]map[string]string)
-	err = collectSensorData(dir, data)
+	err = collectSensorData(c.reader, dir, data)
 	if err != nil {
 		return err
 	}
 	if _, err := os.Stat(filepath.Join(dir, "device")); err == nil {
-		err := collectSensorData(filepath.Join(dir, "device"), data)
+		err := collectSensorData(c.reader, filepath.Join(dir, "device"), data)
 		if err != nil {
 			return err
 		}
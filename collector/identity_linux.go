@@ -0,0 +1,96 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noidentity
+// +build !noidentity
+
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var identityRawMachineID = kingpin.Flag("collector.identity.raw-machine-id", "Expose the machine ID as is instead of its SHA-256 hash.").Bool()
+
+type identityCollector struct {
+	infoDesc *prometheus.Desc
+	logger   log.Logger
+}
+
+func init() {
+	registerCollector("identity", defaultDisabled, NewIdentityCollector)
+}
+
+// NewIdentityCollector returns a new Collector exposing the identity of the host.
+func NewIdentityCollector(logger log.Logger) (Collector, error) {
+	return &identityCollector{
+		infoDesc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "identity", "info"),
+			"A metric with a constant '1' value labeled by the hostname, the (hashed) machine ID and the product UUID of the host.",
+			[]string{"hostname", "machine_id", "product_uuid"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *identityCollector) Update(ch chan<- prometheus.Metric) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	machineID, err := readMachineID()
+	if err != nil {
+		return err
+	}
+	if machineID != "" && !*identityRawMachineID {
+		sum := sha256.Sum256([]byte(machineID))
+		machineID = hex.EncodeToString(sum[:])
+	}
+
+	// product_uuid is only readable by root on most systems.
+	productUUID, err := os.ReadFile(sysFilePath("class/dmi/id/product_uuid"))
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't read product UUID", "err", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.infoDesc, prometheus.GaugeValue, 1,
+		hostname, machineID, strings.TrimSpace(string(productUUID)))
+	return nil
+}
+
+// readMachineID returns the machine ID from /etc/machine-id or, on systems
+// without systemd, the D-Bus machine ID. It returns an empty string if
+// neither exists.
+func readMachineID() (string, error) {
+	for _, path := range []string{"etc/machine-id", "var/lib/dbus/machine-id"} {
+		data, err := os.ReadFile(rootfsFilePath(path))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return "", nil
+}
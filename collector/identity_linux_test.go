@@ -0,0 +1,60 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noidentity
+// +build !noidentity
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadMachineID(t *testing.T) {
+	defer func(path string) { *rootfsPath = path }(*rootfsPath)
+	for _, tc := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"systemd", map[string]string{"etc/machine-id": "4c4c4544004d3510804ac2c04f514d32\n"}, "4c4c4544004d3510804ac2c04f514d32"},
+		{"dbus", map[string]string{"var/lib/dbus/machine-id": "b08dfa6083e7567a1921a715000001fb\n"}, "b08dfa6083e7567a1921a715000001fb"},
+		{"both", map[string]string{
+			"etc/machine-id":          "4c4c4544004d3510804ac2c04f514d32\n",
+			"var/lib/dbus/machine-id": "b08dfa6083e7567a1921a715000001fb\n",
+		}, "4c4c4544004d3510804ac2c04f514d32"},
+		{"none", nil, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			*rootfsPath = t.TempDir()
+			for path, content := range tc.files {
+				file := filepath.Join(*rootfsPath, path)
+				if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := readMachineID()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
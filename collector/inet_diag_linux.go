@@ -0,0 +1,133 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/josharian/native"
+	"github.com/mdlayher/netlink"
+)
+
+// InetDiagSockID (inet_diag_sockid) contains the socket identity.
+// https://github.com/torvalds/linux/blob/v4.0/include/uapi/linux/inet_diag.h#L13
+type InetDiagSockID struct {
+	SourcePort [2]byte
+	DestPort   [2]byte
+	SourceIP   [4][4]byte
+	DestIP     [4][4]byte
+	Interface  uint32
+	Cookie     [2]uint32
+}
+
+// InetDiagReqV2 (inet_diag_req_v2) is used to request diagnostic data.
+// https://github.com/torvalds/linux/blob/v4.0/include/uapi/linux/inet_diag.h#L37
+type InetDiagReqV2 struct {
+	Family   uint8
+	Protocol uint8
+	Ext      uint8
+	Pad      uint8
+	States   uint32
+	ID       InetDiagSockID
+}
+
+const sizeOfDiagRequest = 0x38
+
+func (req *InetDiagReqV2) Serialize() []byte {
+	return (*(*[sizeOfDiagRequest]byte)(unsafe.Pointer(req)))[:]
+}
+
+func (req *InetDiagReqV2) Len() int {
+	return sizeOfDiagRequest
+}
+
+type InetDiagMsg struct {
+	Family  uint8
+	State   uint8
+	Timer   uint8
+	Retrans uint8
+	ID      InetDiagSockID
+	Expires uint32
+	RQueue  uint32
+	WQueue  uint32
+	UID     uint32
+	Inode   uint32
+}
+
+func parseInetDiagMsg(b []byte) *InetDiagMsg {
+	return (*InetDiagMsg)(unsafe.Pointer(&b[0]))
+}
+
+const (
+	// TCPF_ALL and TCPF_LISTEN, bitmasks of the socket states to dump.
+	tcpfAll    = 0xFFF
+	tcpfListen = 1 << 10
+
+	// INET_DIAG_SKMEMINFO, the attribute holding the SK_MEMINFO_* values.
+	inetDiagSkmeminfo = 7
+)
+
+// inetDiagDump lists the sockets of a family and protocol in the given states
+// with the inet_diag netlink interface. ext is a bitmask of the INET_DIAG_*
+// extensions to request as attributes.
+func inetDiagDump(family, protocol uint8, states uint32, ext uint8) ([]netlink.Message, error) {
+	const SockDiagByFamily = 20
+
+	conn, err := netlink.Dial(syscall.NETLINK_INET_DIAG, nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't connect netlink: %w", err)
+	}
+	defer conn.Close()
+
+	msg := netlink.Message{
+		Header: netlink.Header{
+			Type:  SockDiagByFamily,
+			Flags: syscall.NLM_F_REQUEST | syscall.NLM_F_DUMP,
+		},
+		Data: (&InetDiagReqV2{
+			Family:   family,
+			Protocol: protocol,
+			States:   states,
+			Ext:      ext,
+		}).Serialize(),
+	}
+
+	return conn.Execute(msg)
+}
+
+// inetDiagSkmem returns the SK_MEMINFO_* values of the INET_DIAG_SKMEMINFO
+// attribute of a socket, or nil if the message doesn't have it.
+func inetDiagSkmem(data []byte) []uint32 {
+	if len(data) < int(unsafe.Sizeof(InetDiagMsg{})) {
+		return nil
+	}
+	ad, err := netlink.NewAttributeDecoder(data[unsafe.Sizeof(InetDiagMsg{}):])
+	if err != nil {
+		return nil
+	}
+	for ad.Next() {
+		if ad.Type() != inetDiagSkmeminfo {
+			continue
+		}
+		b := ad.Bytes()
+		skmem := make([]uint32, len(b)/4)
+		for i := range skmem {
+			skmem[i] = native.Endian.Uint32(b[4*i:])
+		}
+		return skmem
+	}
+	return nil
+}
@@ -20,23 +20,31 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
 )
 
+var infinibandHWCounters = kingpin.Flag("collector.infiniband.hw-counters", "Expose the driver specific counters from /sys/class/infiniband/<device>/ports/<port>/hw_counters.").Bool()
+
 type infinibandCollector struct {
-	fs          sysfs.FS
-	metricDescs map[string]*prometheus.Desc
-	logger      log.Logger
-	subsystem   string
+	fs            sysfs.FS
+	metricDescs   map[string]*prometheus.Desc
+	portInfoDesc  *prometheus.Desc
+	hwCounterDesc *prometheus.Desc
+	logger        log.Logger
+	subsystem     string
 }
 
 func init() {
 	registerCollector("infiniband", defaultEnabled, NewInfiniBandCollector)
+	registerHardwareProbe("infiniband", "no InfiniBand devices", sysfsProbe("class/infiniband/*"))
 }
 
 // NewInfiniBandCollector returns a new Collector exposing InfiniBand stats.
@@ -98,6 +106,18 @@ func NewInfiniBandCollector(logger log.Logger) (Collector, error) {
 			nil,
 		)
 	}
+	i.portInfoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, i.subsystem, "port_info"),
+		"Non-numeric data from /sys/class/infiniband/<device>/ports/<port>, value is always 1.",
+		[]string{"device", "port", "link_layer"},
+		nil,
+	)
+	i.hwCounterDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, i.subsystem, "hw_counter_total"),
+		"Driver specific counter from /sys/class/infiniband/<device>/ports/<port>/hw_counters.",
+		[]string{"device", "port", "counter"},
+		nil,
+	)
 
 	return &i, nil
 }
@@ -169,8 +189,44 @@ func (c *infinibandCollector) Update(ch chan<- prometheus.Metric) error {
 			c.pushCounter(ch, "port_receive_switch_relay_errors_total", port.Counters.PortRcvSwitchRelayErrors, port.Name, portStr)
 			c.pushCounter(ch, "symbol_error_total", port.Counters.SymbolError, port.Name, portStr)
 			c.pushCounter(ch, "vl15_dropped_total", port.Counters.VL15Dropped, port.Name, portStr)
+
+			portPath := sysFilePath(filepath.Join("class/infiniband", device.Name, "ports", portStr))
+			if linkLayer, err := os.ReadFile(filepath.Join(portPath, "link_layer")); err == nil {
+				ch <- prometheus.MustNewConstMetric(c.portInfoDesc, prometheus.GaugeValue, 1, port.Name, portStr, strings.TrimSpace(string(linkLayer)))
+			}
+			if *infinibandHWCounters {
+				if err := c.updateHWCounters(ch, portPath, port.Name, portStr); err != nil {
+					return fmt.Errorf("error reading hw_counters of %s port %s: %w", port.Name, portStr, err)
+				}
+			}
 		}
 	}
 
 	return nil
 }
+
+// updateHWCounters exposes the counters the driver adds on top of the
+// standard port counters, e.g. congestion notifications of RoCE ports.
+func (c *infinibandCollector) updateHWCounters(ch chan<- prometheus.Metric, portPath, deviceName, port string) error {
+	files, err := os.ReadDir(filepath.Join(portPath, "hw_counters"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, f := range files {
+		// lifespan is the caching period of the counters in milliseconds.
+		if f.IsDir() || f.Name() == "lifespan" {
+			continue
+		}
+		value, err := readUintFromFile(filepath.Join(portPath, "hw_counters", f.Name()))
+		if err != nil {
+			// Some counters aren't supported by all firmware versions.
+			level.Debug(c.logger).Log("msg", "couldn't read hw counter", "device", deviceName, "port", port, "counter", f.Name(), "err", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.hwCounterDesc, prometheus.CounterValue, float64(value), deviceName, port, f.Name())
+	}
+	return nil
+}
@@ -22,14 +22,32 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 var (
 	interruptLabelNames = []string{"cpu", "type", "info", "devices"}
+
+	interruptsAggregateDevices = kingpin.Flag("collector.interrupts.aggregate-devices", "Expose the interrupts of numbered IRQs summed by device and CPU instead of by IRQ.").Bool()
+
+	interruptsDeviceDesc = typedDesc{prometheus.NewDesc(
+		namespace+"_interrupts_device_total",
+		"Interrupts of the numbered IRQs of a device.",
+		[]string{"cpu", "device"}, nil,
+	), prometheus.CounterValue}
+
+	// interruptsQueueSuffix matches the queue of the name of the IRQ of a
+	// device with one IRQ per queue, e.g. nvme0q1 or eth0-TxRx-3.
+	interruptsQueueSuffix = regexp.MustCompile(`(q|-TxRx-|-rx-|-tx-|-)[0-9]+$`)
+
+	// interruptsHWIRQ matches the hardware IRQ number and trigger type
+	// newer kernels show before the devices, e.g. "524288-edge".
+	interruptsHWIRQ = regexp.MustCompile(`^[0-9]+-(edge|level) +`)
 )
 
 func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
@@ -37,7 +55,21 @@ func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
 	if err != nil {
 		return fmt.Errorf("couldn't get interrupts: %w", err)
 	}
+	if *interruptsAggregateDevices {
+		devices, err := aggregateInterrupts(interrupts)
+		if err != nil {
+			return err
+		}
+		for device, values := range devices {
+			for cpuNo, value := range values {
+				ch <- interruptsDeviceDesc.mustNewConstMetric(value, strconv.Itoa(cpuNo), device)
+			}
+		}
+	}
 	for name, interrupt := range interrupts {
+		if _, err := strconv.Atoi(name); err == nil && *interruptsAggregateDevices {
+			continue
+		}
 		for cpuNo, value := range interrupt.values {
 			fv, err := strconv.ParseFloat(value, 64)
 			if err != nil {
@@ -49,6 +81,33 @@ func (c *interruptsCollector) Update(ch chan<- prometheus.Metric) (err error) {
 	return err
 }
 
+// aggregateInterrupts sums the interrupts of the numbered IRQs per CPU by
+// device, with the hardware IRQ and the queue of the IRQ removed from the
+// device name.
+func aggregateInterrupts(interrupts map[string]interrupt) (map[string][]float64, error) {
+	devices := map[string][]float64{}
+	for name, interrupt := range interrupts {
+		if _, err := strconv.Atoi(name); err != nil {
+			continue
+		}
+		device := interruptsHWIRQ.ReplaceAllString(interrupt.devices, "")
+		device = interruptsQueueSuffix.ReplaceAllString(device, "")
+		values, ok := devices[device]
+		if !ok {
+			values = make([]float64, len(interrupt.values))
+			devices[device] = values
+		}
+		for cpuNo, value := range interrupt.values {
+			fv, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %s in interrupts: %w", value, err)
+			}
+			values[cpuNo] += fv
+		}
+	}
+	return devices, nil
+}
+
 type interrupt struct {
 	info    string
 	devices string
@@ -15,6 +15,8 @@ package collector
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -67,3 +69,30 @@ func TestInterruptsArm(t *testing.T) {
 		t.Errorf("IPI0 label not found in interrupts")
 	}
 }
+
+func TestAggregateInterrupts(t *testing.T) {
+	interrupts, err := parseInterrupts(strings.NewReader(`           CPU0       CPU1
+  1:         10          0  IR-IO-APIC    1-edge      i8042
+ 24:          1          2  IR-PCI-MSI 524288-edge      nvme0q0
+ 25:         30          0  IR-PCI-MSI 524289-edge      nvme0q1
+ 26:          0         40  IR-PCI-MSI 524290-edge      nvme0q2
+ 27:          5          0  IR-PCI-MSI 1048576-edge      eth0-TxRx-0
+ 28:          0          6  IR-PCI-MSI 1048577-edge      eth0-TxRx-1
+NMI:          1          1   Non-maskable interrupts
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := aggregateInterrupts(interrupts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]float64{
+		"i8042": {10, 0},
+		"nvme0": {31, 42},
+		"eth0":  {5, 6},
+	}
+	if !reflect.DeepEqual(devices, want) {
+		t.Errorf("want %v, got %v", want, devices)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"golang.org/x/sys/unix"
+)
+
+var ioBackend = kingpin.Flag("runtime.io-backend", "Backend used by the hwmon collector to read sysfs files, one of std or uring. The experimental uring backend batches the reads with io_uring and falls back to std if it's unavailable.").Default("std").Enum("std", "uring")
+
+// fileReader reads small files, like sysfs attributes, with a single read of
+// at most size bytes each.
+type fileReader interface {
+	readFiles(paths []string, size int) ([][]byte, []error)
+}
+
+var (
+	fileReaderOnce   sync.Once
+	sharedFileReader fileReader
+)
+
+// newFileReader returns the reader of the backend selected with
+// --runtime.io-backend, which is shared by all collectors.
+func newFileReader(logger log.Logger) fileReader {
+	fileReaderOnce.Do(func() {
+		sharedFileReader = syscallFileReader{}
+		if *ioBackend != "uring" {
+			return
+		}
+		r, err := newUringFileReader(uringEntries)
+		if err != nil {
+			level.Warn(logger).Log("msg", "io_uring is unavailable, falling back to the std I/O backend", "err", err)
+			return
+		}
+		sharedFileReader = r
+	})
+	return sharedFileReader
+}
+
+// syscallFileReader reads the files one after the other.
+type syscallFileReader struct{}
+
+func (syscallFileReader) readFiles(paths []string, size int) ([][]byte, []error) {
+	contents := make([][]byte, len(paths))
+	errs := make([]error, len(paths))
+	for i, path := range paths {
+		contents[i], errs[i] = sysReadFile(path, size)
+	}
+	return contents, errs
+}
+
+// sysReadFile is a simplified os.ReadFile that invokes syscall.Read directly.
+func sysReadFile(file string, size int) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// On some machines, hwmon drivers are broken and return EAGAIN.  This causes
+	// Go's os.ReadFile implementation to poll forever.
+	//
+	// Since we either want to read data or bail immediately, do the simplest
+	// possible read using system call directly.
+	b := make([]byte, size)
+	n, err := unix.Read(int(f.Fd()), b)
+	if err != nil {
+		return nil, err
+	}
+
+	return b[:n], nil
+}
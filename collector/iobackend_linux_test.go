@@ -0,0 +1,53 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestUringFileReader(t *testing.T) {
+	r, err := newUringFileReader(8)
+	if err != nil {
+		t.Skipf("io_uring is unavailable: %s", err)
+	}
+
+	// More files than fit in a batch of the small queue.
+	paths, err := filepath.Glob("fixtures/proc/net/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = append(paths, "fixtures/proc/net/does-not-exist")
+
+	want, wantErrs := syscallFileReader{}.readFiles(paths, 128)
+	got, gotErrs := r.readFiles(paths, 128)
+	for i, path := range paths {
+		if (wantErrs[i] == nil) != (gotErrs[i] == nil) {
+			t.Errorf("%s: want error %v, got %v", path, wantErrs[i], gotErrs[i])
+		}
+		if !reflect.DeepEqual(want[i], got[i]) {
+			t.Errorf("%s: want %q, got %q", path, want[i], got[i])
+		}
+	}
+	if r.broken {
+		t.Error("want io_uring to be used, the reader fell back to reading files one by one")
+	}
+	if last := gotErrs[len(paths)-1]; !errors.Is(last, os.ErrNotExist) {
+		t.Errorf("want not exist error, got %v", last)
+	}
+}
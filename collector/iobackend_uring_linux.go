@@ -0,0 +1,282 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Constants of linux/io_uring.h.
+const (
+	ioringOpOpenat = 18
+	ioringOpClose  = 19
+	ioringOpRead   = 22
+
+	iosqeIOHardlink = 1 << 3
+
+	ioringEnterGetevents = 1 << 0
+	ioringFeatSingleMmap = 1 << 0
+
+	ioringOffSQRing = 0
+	ioringOffCQRing = 0x8000000
+	ioringOffSQEs   = 0x10000000
+)
+
+// uringEntries is the size of the submission queue, enough for the sysfs
+// attributes of most hwmon chips to be read in a single batch.
+const uringEntries = 128
+
+type ioSQRingOffsets struct {
+	head, tail, ringMask, ringEntries, flags, dropped, array, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type ioCQRingOffsets struct {
+	head, tail, ringMask, ringEntries, overflow, cqes, flags, resv1 uint32
+	userAddr                                                        uint64
+}
+
+type ioUringParams struct {
+	sqEntries, cqEntries, flags, sqThreadCPU, sqThreadIdle, features, wqFd uint32
+	resv                                                                   [3]uint32
+	sqOff                                                                  ioSQRingOffsets
+	cqOff                                                                  ioCQRingOffsets
+}
+
+type ioUringSQE struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	opFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	fileIndex   uint32
+	addr3       uint64
+	pad         uint64
+}
+
+type ioUringCQE struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// uringFileReader reads files with io_uring: the files of a batch are opened
+// with a single io_uring_enter call, then read and closed with another one.
+type uringFileReader struct {
+	mtx sync.Mutex
+	fd  int
+	// broken is set once io_uring_enter failed, the state of the rings is
+	// unknown after that and the files are read one by one.
+	broken bool
+
+	sqTail, cqHead, cqTail *uint32
+	sqMask, cqMask         uint32
+	sqArray                []uint32
+	sqes                   []ioUringSQE
+	cqes                   []ioUringCQE
+}
+
+func newUringFileReader(entries uint32) (*uringFileReader, error) {
+	var p ioUringParams
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("io_uring_setup: %w", errno)
+	}
+	r := &uringFileReader{fd: int(fd)}
+	if p.features&ioringFeatSingleMmap == 0 {
+		unix.Close(r.fd)
+		return nil, errors.New("kernel too old, io_uring doesn't support a single mmap")
+	}
+
+	ringSize := p.sqOff.array + p.sqEntries*4
+	if cqSize := p.cqOff.cqes + p.cqEntries*uint32(unsafe.Sizeof(ioUringCQE{})); cqSize > ringSize {
+		ringSize = cqSize
+	}
+	ring, err := unix.Mmap(r.fd, ioringOffSQRing, int(ringSize), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		unix.Close(r.fd)
+		return nil, fmt.Errorf("couldn't map rings: %w", err)
+	}
+	sqes, err := unix.Mmap(r.fd, ioringOffSQEs, int(p.sqEntries)*int(unsafe.Sizeof(ioUringSQE{})), unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_POPULATE)
+	if err != nil {
+		unix.Munmap(ring)
+		unix.Close(r.fd)
+		return nil, fmt.Errorf("couldn't map submission queue entries: %w", err)
+	}
+
+	r.sqTail = (*uint32)(unsafe.Pointer(&ring[p.sqOff.tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&ring[p.sqOff.ringMask]))
+	r.sqArray = unsafe.Slice((*uint32)(unsafe.Pointer(&ring[p.sqOff.array])), p.sqEntries)
+	r.sqes = unsafe.Slice((*ioUringSQE)(unsafe.Pointer(&sqes[0])), p.sqEntries)
+	r.cqHead = (*uint32)(unsafe.Pointer(&ring[p.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&ring[p.cqOff.tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&ring[p.cqOff.ringMask]))
+	r.cqes = unsafe.Slice((*ioUringCQE)(unsafe.Pointer(&ring[p.cqOff.cqes])), p.cqEntries)
+
+	// Kernels before 5.6 don't support the operations used, which fail
+	// with EINVAL.
+	errs := make([]error, 1)
+	err = r.readBatch([]string{os.DevNull}, 1, make([][]byte, 1), errs)
+	if err == nil {
+		err = errs[0]
+	}
+	if err != nil {
+		unix.Munmap(sqes)
+		unix.Munmap(ring)
+		unix.Close(r.fd)
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *uringFileReader) readFiles(paths []string, size int) ([][]byte, []error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	contents := make([][]byte, len(paths))
+	errs := make([]error, len(paths))
+	// Each file takes two entries of the queue to be read and closed.
+	batch := len(r.sqes) / 2
+	for start := 0; start < len(paths); start += batch {
+		end := start + batch
+		if end > len(paths) {
+			end = len(paths)
+		}
+		if r.broken {
+			c, e := syscallFileReader{}.readFiles(paths[start:end], size)
+			copy(contents[start:end], c)
+			copy(errs[start:end], e)
+			continue
+		}
+		if err := r.readBatch(paths[start:end], size, contents[start:end], errs[start:end]); err != nil {
+			r.broken = true
+			// Read the batch again, some files may have been read already.
+			start -= batch
+		}
+	}
+	return contents, errs
+}
+
+func (r *uringFileReader) readBatch(paths []string, size int, contents [][]byte, errs []error) error {
+	names := make([]*byte, len(paths))
+	sqes := make([]ioUringSQE, 0, 2*len(paths))
+	for i, path := range paths {
+		name, err := unix.BytePtrFromString(path)
+		if err != nil {
+			errs[i] = &os.PathError{Op: "open", Path: path, Err: err}
+			continue
+		}
+		names[i] = name
+		sqes = append(sqes, ioUringSQE{
+			opcode:   ioringOpOpenat,
+			fd:       unix.AT_FDCWD,
+			addr:     uint64(uintptr(unsafe.Pointer(name))),
+			opFlags:  unix.O_RDONLY | unix.O_CLOEXEC,
+			userData: uint64(i),
+		})
+	}
+	cqes, err := r.submit(sqes)
+	runtime.KeepAlive(names)
+	if err != nil {
+		return err
+	}
+
+	bufs := make([][]byte, len(paths))
+	sqes = sqes[:0]
+	for _, cqe := range cqes {
+		i := cqe.userData
+		if cqe.res < 0 {
+			errs[i] = &os.PathError{Op: "open", Path: paths[i], Err: unix.Errno(-cqe.res)}
+			continue
+		}
+		bufs[i] = make([]byte, size)
+		// The file is closed even if the read fails thanks to the hard link.
+		sqes = append(sqes, ioUringSQE{
+			opcode:   ioringOpRead,
+			flags:    iosqeIOHardlink,
+			fd:       cqe.res,
+			addr:     uint64(uintptr(unsafe.Pointer(&bufs[i][0]))),
+			len:      uint32(size),
+			userData: i,
+		}, ioUringSQE{
+			opcode:   ioringOpClose,
+			fd:       cqe.res,
+			userData: i | 1<<63,
+		})
+	}
+	cqes, err = r.submit(sqes)
+	runtime.KeepAlive(bufs)
+	if err != nil {
+		return err
+	}
+	for _, cqe := range cqes {
+		if cqe.userData&(1<<63) != 0 {
+			continue
+		}
+		i := cqe.userData
+		if cqe.res < 0 {
+			errs[i] = &os.PathError{Op: "read", Path: paths[i], Err: unix.Errno(-cqe.res)}
+			continue
+		}
+		contents[i] = bufs[i][:cqe.res]
+	}
+	return nil
+}
+
+// submit queues entries and waits for all of them to complete.
+func (r *uringFileReader) submit(sqes []ioUringSQE) ([]ioUringCQE, error) {
+	if len(sqes) == 0 {
+		return nil, nil
+	}
+	tail := atomic.LoadUint32(r.sqTail)
+	for i, sqe := range sqes {
+		idx := (tail + uint32(i)) & r.sqMask
+		r.sqes[idx] = sqe
+		r.sqArray[idx] = idx
+	}
+	atomic.StoreUint32(r.sqTail, tail+uint32(len(sqes)))
+
+	cqes := make([]ioUringCQE, 0, len(sqes))
+	toSubmit := len(sqes)
+	for len(cqes) < len(sqes) {
+		submitted, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(toSubmit), uintptr(len(sqes)-len(cqes)), ioringEnterGetevents, 0, 0)
+		switch errno {
+		case 0:
+			toSubmit -= int(submitted)
+		case unix.EINTR:
+		default:
+			return nil, fmt.Errorf("io_uring_enter: %w", errno)
+		}
+		head := atomic.LoadUint32(r.cqHead)
+		for cqTail := atomic.LoadUint32(r.cqTail); head != cqTail; head++ {
+			cqes = append(cqes, r.cqes[head&r.cqMask])
+		}
+		atomic.StoreUint32(r.cqHead, head)
+	}
+	return cqes, nil
+}
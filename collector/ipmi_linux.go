@@ -0,0 +1,524 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noipmi
+// +build !noipmi
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ipmiCollectorSubsystem = "ipmi"
+	ipmiCommandTimeout     = 5 * time.Second
+
+	// From linux/ipmi.h and linux/ipmi_msgdefs.h.
+	ipmiSystemInterfaceAddrType = 0x0c
+	ipmiBMCChannel              = 0x0f
+	ipmiResponseRecvType        = 1
+	ipmiMaxMsgLength            = 272
+
+	ipmiNetFnSensor  = 0x04
+	ipmiNetFnStorage = 0x0a
+
+	ipmiCmdGetSensorReading = 0x2d
+	ipmiCmdGetSDRRepoInfo   = 0x20
+	ipmiCmdReserveSDRRepo   = 0x22
+	ipmiCmdGetSDR           = 0x23
+
+	ipmiSDRTypeFull    = 0x01
+	ipmiSDRTypeCompact = 0x02
+	ipmiSDRHeaderSize  = 5
+	ipmiSDRChunkSize   = 16
+	ipmiBMCAddress     = 0x20
+
+	ipmiEventTypeThreshold = 0x01
+	ipmiSensorTypePSU      = 0x08
+)
+
+var ipmiDevicePath = kingpin.Flag("collector.ipmi.device", "Path of the OpenIPMI device.").Default("/dev/ipmi0").String()
+
+// ipmiUnitMetrics maps IPMI base unit type codes to the metric exposing
+// readings of sensors in that unit.
+var ipmiUnitMetrics = map[uint8]struct{ name, help string }{
+	1:  {"temperature_celsius", "Temperature reading of the IPMI sensor."},
+	4:  {"voltage_volts", "Voltage reading of the IPMI sensor."},
+	5:  {"current_amperes", "Current reading of the IPMI sensor."},
+	6:  {"power_watts", "Power reading of the IPMI sensor."},
+	18: {"fan_speed_rpm", "Fan speed reading of the IPMI sensor."},
+}
+
+// ipmiSensorTypes names the IPMI sensor type codes used in the type label.
+var ipmiSensorTypes = map[uint8]string{
+	0x01: "temperature",
+	0x02: "voltage",
+	0x03: "current",
+	0x04: "fan",
+	0x08: "power_supply",
+}
+
+type ipmiCollector struct {
+	readings map[uint8]*prometheus.Desc
+	state    *prometheus.Desc
+	logger   log.Logger
+
+	mtx         sync.Mutex
+	sensors     []ipmiSensor
+	sdrModified [8]byte
+}
+
+// ipmiSensor holds the part of a full or compact SDR needed to read and
+// convert a sensor.
+type ipmiSensor struct {
+	number     uint8
+	lun        uint8
+	name       string
+	sensorType uint8
+	eventType  uint8
+	baseUnit   uint8
+	full       bool
+
+	analogFormat  uint8
+	linearization uint8
+	m, b          int
+	bExp, rExp    int
+}
+
+func init() {
+	registerCollector(ipmiCollectorSubsystem, defaultDisabled, NewIPMICollector)
+}
+
+// NewIPMICollector returns a new Collector exposing IPMI sensor readings
+// from the BMC through the OpenIPMI driver.
+func NewIPMICollector(logger log.Logger) (Collector, error) {
+	readings := make(map[uint8]*prometheus.Desc, len(ipmiUnitMetrics))
+	for unit, m := range ipmiUnitMetrics {
+		readings[unit] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiCollectorSubsystem, m.name),
+			m.help, []string{"id", "name"}, nil,
+		)
+	}
+	return &ipmiCollector{
+		readings: readings,
+		state: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, ipmiCollectorSubsystem, "sensor_state"),
+			"State of the IPMI sensor: 0 for nominal, 1 for warning (non-critical threshold crossed or predictive failure), 2 for critical.",
+			[]string{"id", "name", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *ipmiCollector) Update(ch chan<- prometheus.Metric) error {
+	dev, err := openIPMIDevice(rootfsFilePath(*ipmiDevicePath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			level.Debug(c.logger).Log("msg", "IPMI device not found, is the ipmi_devintf module loaded?", "path", *ipmiDevicePath)
+			return ErrNoData
+		}
+		return err
+	}
+	defer dev.close()
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.refreshSensors(dev); err != nil {
+		return fmt.Errorf("couldn't read SDR repository: %w", err)
+	}
+
+	for _, s := range c.sensors {
+		typ, ok := ipmiSensorTypes[s.sensorType]
+		if !ok {
+			continue
+		}
+		resp, err := dev.command(ipmiNetFnSensor, ipmiCmdGetSensorReading, s.lun, []byte{s.number})
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't read IPMI sensor", "sensor", s.name, "err", err)
+			continue
+		}
+		id := strconv.Itoa(int(s.number))
+		value, state, ok := s.reading(resp)
+		if !ok {
+			continue
+		}
+		if desc, ok := c.readings[s.baseUnit]; ok && s.full && s.eventType == ipmiEventTypeThreshold {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, id, s.name)
+		}
+		ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, state, id, s.name, typ)
+	}
+	return nil
+}
+
+// refreshSensors reads the SDR repository unless it hasn't been modified
+// since it was last read.
+func (c *ipmiCollector) refreshSensors(dev *ipmiDevice) error {
+	info, err := dev.command(ipmiNetFnStorage, ipmiCmdGetSDRRepoInfo, 0, nil)
+	if err != nil {
+		return err
+	}
+	if len(info) < 14 {
+		return fmt.Errorf("short SDR repository info response")
+	}
+	// Bytes 5-12 are the most recent addition and erase timestamps.
+	var modified [8]byte
+	copy(modified[:], info[5:13])
+	if c.sensors != nil && modified == c.sdrModified {
+		return nil
+	}
+
+	records, err := dev.readSDRRepository()
+	if err != nil {
+		return err
+	}
+	sensors := []ipmiSensor{}
+	for _, record := range records {
+		if s, ok := parseIPMISDR(record); ok {
+			sensors = append(sensors, s)
+		}
+	}
+	c.sensors, c.sdrModified = sensors, modified
+	return nil
+}
+
+// parseIPMISDR parses a full or compact sensor record, other record types
+// and sensors not owned by the BMC are ignored.
+func parseIPMISDR(record []byte) (ipmiSensor, bool) {
+	if len(record) < ipmiSDRHeaderSize {
+		return ipmiSensor{}, false
+	}
+	var idOffset int
+	switch record[3] {
+	case ipmiSDRTypeFull:
+		idOffset = 47
+	case ipmiSDRTypeCompact:
+		idOffset = 31
+	default:
+		return ipmiSensor{}, false
+	}
+	if len(record) <= idOffset || record[5] != ipmiBMCAddress {
+		return ipmiSensor{}, false
+	}
+
+	s := ipmiSensor{
+		number:     record[7],
+		lun:        record[6] & 0x03,
+		sensorType: record[12],
+		eventType:  record[13],
+		baseUnit:   record[21],
+		full:       record[3] == ipmiSDRTypeFull,
+	}
+	idLen := int(record[idOffset] & 0x1f)
+	if end := idOffset + 1 + idLen; end <= len(record) {
+		s.name = strings.TrimRight(string(record[idOffset+1:end]), "\x00 ")
+	}
+	if s.full {
+		s.analogFormat = record[20] >> 6
+		s.linearization = record[23] & 0x7f
+		s.m = signExtend(int(record[24])|int(record[25]>>6)<<8, 10)
+		s.b = signExtend(int(record[26])|int(record[27]>>6)<<8, 10)
+		s.rExp = signExtend(int(record[29]>>4), 4)
+		s.bExp = signExtend(int(record[29]&0x0f), 4)
+	}
+	return s, true
+}
+
+// reading converts a Get Sensor Reading response to the sensor value and
+// state. It returns false if the reading is unavailable.
+func (s ipmiSensor) reading(resp []byte) (float64, float64, bool) {
+	if len(resp) < 3 || resp[1]&0x20 != 0 || resp[1]&0x40 == 0 {
+		return 0, 0, false
+	}
+	var value float64
+	if s.full {
+		value = s.convert(resp[0])
+	}
+
+	var state float64
+	switch {
+	case s.eventType == ipmiEventTypeThreshold:
+		// Bits of the threshold comparison status: lower non-critical,
+		// critical and non-recoverable, then the same for upper.
+		switch {
+		case resp[2]&0x36 != 0:
+			state = 2
+		case resp[2]&0x09 != 0:
+			state = 1
+		}
+	case s.sensorType == ipmiSensorTypePSU:
+		// Sensor specific offsets: failure detected, predictive failure,
+		// input lost.
+		switch {
+		case resp[2]&0x0a != 0:
+			state = 2
+		case resp[2]&0x04 != 0:
+			state = 1
+		}
+	}
+	return value, state, true
+}
+
+// convert applies the conversion formula of the SDR to a raw reading:
+// y = L[(M*x + B * 10^Bexp) * 10^Rexp].
+func (s ipmiSensor) convert(raw byte) float64 {
+	var x float64
+	switch s.analogFormat {
+	case 1: // One's complement.
+		if raw&0x80 != 0 {
+			x = -float64(^raw)
+		} else {
+			x = float64(raw)
+		}
+	case 2: // Two's complement.
+		x = float64(int8(raw))
+	default:
+		x = float64(raw)
+	}
+	y := (float64(s.m)*x + float64(s.b)*math.Pow10(s.bExp)) * math.Pow10(s.rExp)
+
+	switch s.linearization {
+	case 1:
+		y = math.Log(y)
+	case 2:
+		y = math.Log10(y)
+	case 3:
+		y = math.Log2(y)
+	case 4:
+		y = math.Exp(y)
+	case 5:
+		y = math.Pow(10, y)
+	case 6:
+		y = math.Exp2(y)
+	case 7:
+		y = 1 / y
+	case 8:
+		y = y * y
+	case 9:
+		y = y * y * y
+	case 10:
+		y = math.Sqrt(y)
+	case 11:
+		y = math.Cbrt(y)
+	}
+	return y
+}
+
+// signExtend interprets the lowest bits of v as a two's complement number.
+func signExtend(v, bits int) int {
+	if v&(1<<(bits-1)) != 0 {
+		return v - 1<<bits
+	}
+	return v
+}
+
+// ipmiDevice is a connection to the BMC through the OpenIPMI device.
+type ipmiDevice struct {
+	file  *os.File
+	msgID int
+}
+
+// Structures of linux/ipmi.h. The pointers are typed, so that the runtime
+// updates them if it moves what they point to with the stack, and keeps it
+// alive.
+type ipmiMsg struct {
+	NetFn   uint8
+	Cmd     uint8
+	DataLen uint16
+	Data    unsafe.Pointer
+}
+
+type ipmiReq struct {
+	Addr    unsafe.Pointer
+	AddrLen uint32
+	MsgID   int
+	Msg     ipmiMsg
+}
+
+type ipmiRecv struct {
+	RecvType int32
+	Addr     unsafe.Pointer
+	AddrLen  uint32
+	MsgID    int
+	Msg      ipmiMsg
+}
+
+type ipmiSystemInterfaceAddr struct {
+	AddrType int32
+	Channel  int16
+	LUN      uint8
+	_        uint8
+}
+
+var (
+	ipmiCtlReceiveMsgTrunc = ioctlNumber(3, 11, unsafe.Sizeof(ipmiRecv{}))
+	ipmiCtlSendCommand     = ioctlNumber(2, 13, unsafe.Sizeof(ipmiReq{}))
+)
+
+// ioctlNumber returns the number of an ioctl of the IPMI driver, dir is 1
+// for write, 2 for read and 3 for both.
+func ioctlNumber(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | uintptr('i')<<8 | nr
+}
+
+func openIPMIDevice(path string) (*ipmiDevice, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &ipmiDevice{file: file}, nil
+}
+
+func (d *ipmiDevice) close() error {
+	return d.file.Close()
+}
+
+// command sends a command to the BMC and returns the response data after
+// the completion code, which is checked.
+func (d *ipmiDevice) command(netFn, cmd, lun uint8, data []byte) ([]byte, error) {
+	d.msgID++
+	addr := &ipmiSystemInterfaceAddr{AddrType: ipmiSystemInterfaceAddrType, Channel: ipmiBMCChannel, LUN: lun}
+	req := &ipmiReq{
+		Addr:    unsafe.Pointer(addr),
+		AddrLen: uint32(unsafe.Sizeof(*addr)),
+		MsgID:   d.msgID,
+		Msg:     ipmiMsg{NetFn: netFn, Cmd: cmd, DataLen: uint16(len(data))},
+	}
+	if len(data) > 0 {
+		req.Msg.Data = unsafe.Pointer(&data[0])
+	}
+	if err := d.ioctl(ipmiCtlSendCommand, unsafe.Pointer(req)); err != nil {
+		return nil, fmt.Errorf("couldn't send IPMI command %#x/%#x: %w", netFn, cmd, err)
+	}
+
+	deadline := time.Now().Add(ipmiCommandTimeout)
+	for {
+		timeout := time.Until(deadline)
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout waiting for response to IPMI command %#x/%#x", netFn, cmd)
+		}
+		fds := []unix.PollFd{{Fd: int32(d.file.Fd()), Events: unix.POLLIN}}
+		if _, err := unix.Poll(fds, int(timeout.Milliseconds())); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return nil, err
+		}
+		if fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		var recvAddr ipmiSystemInterfaceAddr
+		buf := make([]byte, ipmiMaxMsgLength)
+		recv := &ipmiRecv{
+			Addr:    unsafe.Pointer(&recvAddr),
+			AddrLen: uint32(unsafe.Sizeof(recvAddr)),
+			Msg:     ipmiMsg{DataLen: uint16(len(buf)), Data: unsafe.Pointer(&buf[0])},
+		}
+		if err := d.ioctl(ipmiCtlReceiveMsgTrunc, unsafe.Pointer(recv)); err != nil {
+			return nil, fmt.Errorf("couldn't receive IPMI response: %w", err)
+		}
+		// Skip events and responses to earlier, timed out commands.
+		if recv.RecvType != ipmiResponseRecvType || recv.MsgID != d.msgID {
+			continue
+		}
+		resp := buf[:recv.Msg.DataLen]
+		if len(resp) == 0 {
+			return nil, fmt.Errorf("empty response to IPMI command %#x/%#x", netFn, cmd)
+		}
+		if resp[0] != 0 {
+			return nil, fmt.Errorf("IPMI command %#x/%#x failed with completion code %#x", netFn, cmd, resp[0])
+		}
+		return resp[1:], nil
+	}
+}
+
+func (d *ipmiDevice) ioctl(req uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, d.file.Fd(), req, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// readSDRRepository returns all records of the SDR repository. Records are
+// read in small chunks as many BMCs can't return them in one response.
+func (d *ipmiDevice) readSDRRepository() ([][]byte, error) {
+	resp, err := d.command(ipmiNetFnStorage, ipmiCmdReserveSDRRepo, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) < 2 {
+		return nil, errors.New("short reserve SDR repository response")
+	}
+	reservation := resp[:2]
+
+	var records [][]byte
+	for id := uint16(0); id != 0xffff; {
+		header, next, err := d.getSDR(reservation, id, 0, ipmiSDRHeaderSize)
+		if err != nil {
+			return nil, err
+		}
+		record := append([]byte{}, header...)
+		length := int(header[4])
+		for offset := 0; offset < length; offset += ipmiSDRChunkSize {
+			n := length - offset
+			if n > ipmiSDRChunkSize {
+				n = ipmiSDRChunkSize
+			}
+			chunk, _, err := d.getSDR(reservation, id, ipmiSDRHeaderSize+offset, n)
+			if err != nil {
+				return nil, err
+			}
+			record = append(record, chunk...)
+		}
+		records = append(records, record)
+		if next == id {
+			break
+		}
+		id = next
+	}
+	return records, nil
+}
+
+// getSDR reads size bytes of an SDR at the given offset, returning them and
+// the ID of the next record.
+func (d *ipmiDevice) getSDR(reservation []byte, id uint16, offset, size int) ([]byte, uint16, error) {
+	req := make([]byte, 6)
+	copy(req, reservation)
+	binary.LittleEndian.PutUint16(req[2:], id)
+	req[4], req[5] = byte(offset), byte(size)
+	resp, err := d.command(ipmiNetFnStorage, ipmiCmdGetSDR, 0, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(resp) < 2+size {
+		return nil, 0, fmt.Errorf("short SDR response for record %d", id)
+	}
+	return resp[2 : 2+size], binary.LittleEndian.Uint16(resp), nil
+}
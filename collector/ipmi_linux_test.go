@@ -0,0 +1,111 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noipmi
+// +build !noipmi
+
+package collector
+
+import (
+	"math"
+	"testing"
+)
+
+// fullSDR returns a full sensor record owned by the BMC.
+func fullSDR(number, sensorType, unit, m, mb, rb byte, name string) []byte {
+	record := make([]byte, 48+len(name))
+	record[3] = ipmiSDRTypeFull
+	record[4] = byte(len(record) - ipmiSDRHeaderSize)
+	record[5] = ipmiBMCAddress
+	record[7] = number
+	record[12] = sensorType
+	record[13] = ipmiEventTypeThreshold
+	record[21] = unit
+	record[24] = m
+	record[25] = mb
+	record[29] = rb
+	record[47] = 0xc0 | byte(len(name))
+	copy(record[48:], name)
+	return record
+}
+
+func TestIPMISensorReading(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		record []byte
+		resp   []byte
+		value  float64
+		state  float64
+	}{
+		{
+			name:   "CPU Temp",
+			record: fullSDR(1, 0x01, 1, 1, 0, 0, "CPU Temp"),
+			resp:   []byte{45, 0x40, 0x00},
+			value:  45,
+		},
+		{
+			// M = 78, R exponent = -4.
+			name:   "12V",
+			record: fullSDR(2, 0x02, 4, 78, 0, 0xc0, "12V"),
+			resp:   []byte{160, 0xc0, 0x08},
+			value:  1.248,
+			state:  1,
+		},
+		{
+			// M = -2 as 10 bit two's complement, B = 0.
+			name:   "FAN1",
+			record: fullSDR(3, 0x04, 18, 0xfe, 0xc0, 0, "FAN1"),
+			resp:   []byte{10, 0x40, 0x02},
+			value:  -20,
+			state:  2,
+		},
+	} {
+		s, ok := parseIPMISDR(tc.record)
+		if !ok {
+			t.Fatalf("%s: record not parsed", tc.name)
+		}
+		if s.name != tc.name {
+			t.Errorf("want name %q, got %q", tc.name, s.name)
+		}
+		value, state, ok := s.reading(tc.resp)
+		if !ok {
+			t.Fatalf("%s: reading unavailable", tc.name)
+		}
+		if math.Abs(value-tc.value) > 1e-9 || state != tc.state {
+			t.Errorf("%s: want %v (state %v), got %v (state %v)", tc.name, tc.value, tc.state, value, state)
+		}
+	}
+
+	s, _ := parseIPMISDR(fullSDR(4, 0x01, 1, 1, 0, 0, "Unavailable"))
+	if _, _, ok := s.reading([]byte{0, 0x60, 0}); ok {
+		t.Error("expected unavailable reading to be skipped")
+	}
+
+	// Compact records only carry the state, sensors owned by other
+	// controllers are ignored.
+	compact := make([]byte, 36)
+	compact[3], compact[5], compact[7], compact[12], compact[13] = ipmiSDRTypeCompact, ipmiBMCAddress, 5, ipmiSensorTypePSU, 0x6f
+	compact[31] = 0xc0 | 4
+	copy(compact[32:], "PSU1")
+	s, ok := parseIPMISDR(compact)
+	if !ok || s.name != "PSU1" || s.full {
+		t.Fatalf("unexpected compact sensor %+v", s)
+	}
+	if _, state, _ := s.reading([]byte{0, 0x40, 0x03}); state != 2 {
+		t.Errorf("want failed PSU to be critical, got state %v", state)
+	}
+	compact[5] = 0x2c
+	if _, ok := parseIPMISDR(compact); ok {
+		t.Error("expected sensor of another controller to be ignored")
+	}
+}
@@ -17,8 +17,12 @@
 package collector
 
 import (
+	"bufio"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"sort"
 	"strconv"
@@ -37,9 +41,19 @@ type ipvsCollector struct {
 	backendLabels                                                               []string
 	backendConnectionsActive, backendConnectionsInact, backendWeight            typedDesc
 	connections, incomingPackets, outgoingPackets, incomingBytes, outgoingBytes typedDesc
+	serviceInfo, servicePersistenceTimeout, serviceBackends                     typedDesc
+	serviceConnectionsActive, serviceConnectionsInact, serviceWeight            typedDesc
 	logger                                                                      log.Logger
 }
 
+// ipvsService is a virtual service from /proc/net/ip_vs.
+type ipvsService struct {
+	proto, localAddress, localPort, localMark string
+	scheduler                                 string
+	persistent                                bool
+	persistenceTimeout                        uint64
+}
+
 type ipvsBackendStatus struct {
 	ActiveConn uint64
 	InactConn  uint64
@@ -64,6 +78,12 @@ var (
 		ipvsLabelProto,
 		ipvsLabelLocalMark,
 	}
+	ipvsServiceLabels = []string{
+		ipvsLabelLocalAddress,
+		ipvsLabelLocalPort,
+		ipvsLabelProto,
+		ipvsLabelLocalMark,
+	}
 	ipvsLabels = kingpin.Flag("collector.ipvs.backend-labels", "Comma separated list for IPVS backend stats labels.").Default(strings.Join(fullIpvsBackendLabels, ",")).String()
 )
 
@@ -134,6 +154,36 @@ func newIPVSCollector(logger log.Logger) (*ipvsCollector, error) {
 		"The current backend weight by local and remote address.",
 		c.backendLabels, nil,
 	), prometheus.GaugeValue}
+	c.serviceInfo = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_info"),
+		"Information about a virtual service, like its scheduling algorithm.",
+		append(ipvsServiceLabels, "scheduler", "persistent"), nil,
+	), prometheus.GaugeValue}
+	c.servicePersistenceTimeout = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_persistence_timeout_seconds"),
+		"Timeout of the client affinity of a persistent virtual service.",
+		ipvsServiceLabels, nil,
+	), prometheus.GaugeValue}
+	c.serviceBackends = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_backends"),
+		"The number of backends of a virtual service.",
+		ipvsServiceLabels, nil,
+	), prometheus.GaugeValue}
+	c.serviceConnectionsActive = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_connections_active"),
+		"The current active connections of a virtual service, across its backends.",
+		ipvsServiceLabels, nil,
+	), prometheus.GaugeValue}
+	c.serviceConnectionsInact = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_connections_inactive"),
+		"The current inactive connections of a virtual service, across its backends.",
+		ipvsServiceLabels, nil,
+	), prometheus.GaugeValue}
+	c.serviceWeight = typedDesc{prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_weight"),
+		"The sum of the weights of the backends of a virtual service.",
+		ipvsServiceLabels, nil,
+	), prometheus.GaugeValue}
 
 	return &c, nil
 }
@@ -161,11 +211,26 @@ func (c *ipvsCollector) Update(ch chan<- prometheus.Metric) error {
 
 	sums := map[string]ipvsBackendStatus{}
 	labelValues := map[string][]string{}
+	serviceSums := map[string]ipvsBackendStatus{}
+	serviceBackends := map[string]int{}
 	for _, backend := range backendStats {
 		localAddress := ""
 		if backend.LocalAddress.String() != "<nil>" {
 			localAddress = backend.LocalAddress.String()
 		}
+		serviceKey := ipvsService{
+			proto:        backend.Proto,
+			localAddress: localAddress,
+			localPort:    strconv.FormatUint(uint64(backend.LocalPort), 10),
+			localMark:    backend.LocalMark,
+		}.key()
+		serviceStatus := serviceSums[serviceKey]
+		serviceStatus.ActiveConn += backend.ActiveConn
+		serviceStatus.InactConn += backend.InactConn
+		serviceStatus.Weight += backend.Weight
+		serviceSums[serviceKey] = serviceStatus
+		serviceBackends[serviceKey]++
+
 		kv := make([]string, len(c.backendLabels))
 		for i, label := range c.backendLabels {
 			var labelValue string
@@ -199,9 +264,104 @@ func (c *ipvsCollector) Update(ch chan<- prometheus.Metric) error {
 		ch <- c.backendConnectionsInact.mustNewConstMetric(float64(status.InactConn), kv...)
 		ch <- c.backendWeight.mustNewConstMetric(float64(status.Weight), kv...)
 	}
+
+	f, err := os.Open(procFilePath("net/ip_vs"))
+	if err != nil {
+		return fmt.Errorf("could not get virtual services: %w", err)
+	}
+	defer f.Close()
+	services, err := parseIPVSServices(f)
+	if err != nil {
+		return fmt.Errorf("could not get virtual services: %w", err)
+	}
+	for _, svc := range services {
+		labels := []string{svc.localAddress, svc.localPort, svc.proto, svc.localMark}
+		ch <- c.serviceInfo.mustNewConstMetric(1, append(labels, svc.scheduler, strconv.FormatBool(svc.persistent))...)
+		if svc.persistent {
+			ch <- c.servicePersistenceTimeout.mustNewConstMetric(float64(svc.persistenceTimeout), labels...)
+		}
+		status := serviceSums[svc.key()]
+		ch <- c.serviceBackends.mustNewConstMetric(float64(serviceBackends[svc.key()]), labels...)
+		ch <- c.serviceConnectionsActive.mustNewConstMetric(float64(status.ActiveConn), labels...)
+		ch <- c.serviceConnectionsInact.mustNewConstMetric(float64(status.InactConn), labels...)
+		ch <- c.serviceWeight.mustNewConstMetric(float64(status.Weight), labels...)
+	}
 	return nil
 }
 
+func (s ipvsService) key() string {
+	return strings.Join([]string{s.proto, s.localAddress, s.localPort, s.localMark}, "-")
+}
+
+// parseIPVSServices parses the virtual services of /proc/net/ip_vs, which
+// the procfs library skips. Their lines look like
+//
+//	TCP  C0A80016:0CEA wlc persistent 300 FFFFFFFF
+//	FWM  10001000 wlc
+func parseIPVSServices(r io.Reader) ([]ipvsService, error) {
+	var services []ipvsService
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		svc := ipvsService{proto: fields[0], localPort: "0", scheduler: fields[2]}
+		switch fields[0] {
+		case "TCP", "UDP":
+			addr, port, ok := strings.Cut(fields[1], "]:")
+			if ok {
+				addr = strings.TrimPrefix(addr, "[")
+			} else {
+				addr, port, ok = strings.Cut(fields[1], ":")
+			}
+			if !ok {
+				return nil, fmt.Errorf("unexpected address %q", fields[1])
+			}
+			ip, err := parseIPVSAddress(addr)
+			if err != nil {
+				return nil, err
+			}
+			p, err := strconv.ParseUint(port, 16, 16)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected port %q: %w", port, err)
+			}
+			svc.localAddress = ip.String()
+			svc.localPort = strconv.FormatUint(p, 10)
+		case "FWM":
+			svc.localMark = fields[1]
+		default:
+			continue
+		}
+		if len(fields) >= 5 && fields[3] == "persistent" {
+			timeout, err := strconv.ParseUint(fields[4], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected persistence timeout %q: %w", fields[4], err)
+			}
+			svc.persistent = true
+			svc.persistenceTimeout = timeout
+		}
+		services = append(services, svc)
+	}
+	return services, s.Err()
+}
+
+// parseIPVSAddress parses an IPv4 address printed as hex or an IPv6 address.
+func parseIPVSAddress(s string) (net.IP, error) {
+	if len(s) == 8 {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected address %q: %w", s, err)
+		}
+		return net.IP(b), nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("unexpected address %q", s)
+	}
+	return ip, nil
+}
+
 func (c *ipvsCollector) parseIpvsLabels(labelString string) ([]string, error) {
 	labels := strings.Split(labelString, ",")
 	labelSet := make(map[string]bool, len(labels))
@@ -0,0 +1,290 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nokmsg
+// +build !nokmsg
+
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const kmsgSubsystem = "kmsg"
+
+var (
+	kmsgDevice   = kingpin.Flag("collector.kmsg.device", "Device the kernel messages are read from.").Default("/dev/kmsg").String()
+	kmsgPatterns = kingpin.Flag("collector.kmsg.pattern", "Kernel messages to count as <name>=<regexp>, can be repeated. Replaces the default patterns.").Default(
+		`io_error=I/O error|critical medium error`,
+		`hung_task=blocked for more than [0-9]+ seconds`,
+		`link_down=[Ll]ink (is )?[Dd]own`,
+		`hardware_error=[Hh]ardware [Ee]rror|Machine check events logged`,
+	).Strings()
+)
+
+// kmsgLevels are the names of the syslog levels of the messages.
+var kmsgLevels = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// kmsgRecordSize is large enough for any record, reads of /dev/kmsg with a
+// smaller buffer fail.
+const kmsgRecordSize = 8192
+
+type kmsgPattern struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+type kmsgCollector struct {
+	messages *prometheus.Desc
+	matches  *prometheus.Desc
+	overruns *prometheus.Desc
+	patterns []kmsgPattern
+	logger   log.Logger
+
+	// boot identifies the boot of the node, to tell whether the records in
+	// the ring buffer were counted before a restart of the exporter.
+	boot float64
+
+	// The counters are updated by the reader in the background.
+	mtx           sync.Mutex
+	messageCounts [8]float64
+	matchCounts   []float64
+	overrunCount  float64
+	err           error
+	// sequence is the sequence number of the last record counted, if
+	// counted is set.
+	sequence uint64
+	counted  bool
+	changed  bool
+}
+
+func init() {
+	registerCollector(kmsgSubsystem, defaultDisabled, NewKmsgCollector)
+}
+
+// NewKmsgCollector returns a new Collector counting the kernel messages by
+// level and by pattern, read from /dev/kmsg in the background.
+func NewKmsgCollector(logger log.Logger) (Collector, error) {
+	c := &kmsgCollector{
+		messages: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kmsgSubsystem, "messages_total"),
+			"Number of kernel messages by level, since the oldest message in the ring buffer when the exporter started or, with a state file, first started.",
+			[]string{"level"}, nil,
+		),
+		matches: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kmsgSubsystem, "pattern_matches_total"),
+			"Number of kernel messages matching the pattern.",
+			[]string{"pattern"}, nil,
+		),
+		overruns: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kmsgSubsystem, "overruns_total"),
+			"Number of times messages were overwritten in the ring buffer before being read, and aren't counted.",
+			nil, nil,
+		),
+		logger: logger,
+	}
+	for _, value := range *kmsgPatterns {
+		name, expr, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid kmsg pattern %q, must be <name>=<regexp>", value)
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp of kmsg pattern %q: %w", name, err)
+		}
+		c.patterns = append(c.patterns, kmsgPattern{name: name, pattern: pattern})
+	}
+	c.matchCounts = make([]float64, len(c.patterns))
+	c.boot = kmsgBootID()
+	counters, err := restoreCounters(kmsgSubsystem)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Couldn't restore kernel message counts", "err", err)
+	}
+	c.restore(counters)
+
+	// Reading /dev/kmsg needs CAP_SYSLOG if kernel.dmesg_restrict is set,
+	// the failure is reported by every scrape rather than at startup.
+	f, err := os.Open(*kmsgDevice)
+	if err != nil {
+		c.err = err
+		return c, nil
+	}
+	go c.read(f)
+	return c, nil
+}
+
+func (c *kmsgCollector) Update(ch chan<- prometheus.Metric) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	// The counters stop increasing if the reader failed, don't expose them
+	// as if no messages were logged since.
+	if c.err != nil {
+		return fmt.Errorf("couldn't read kernel messages: %w", c.err)
+	}
+	for l, count := range c.messageCounts {
+		ch <- prometheus.MustNewConstMetric(c.messages, prometheus.CounterValue, count, kmsgLevels[l])
+	}
+	for i, p := range c.patterns {
+		ch <- prometheus.MustNewConstMetric(c.matches, prometheus.CounterValue, c.matchCounts[i], p.name)
+	}
+	ch <- prometheus.MustNewConstMetric(c.overruns, prometheus.CounterValue, c.overrunCount)
+	if c.changed {
+		c.changed = false
+		if err := persistCounters(kmsgSubsystem, c.counters()); err != nil {
+			level.Warn(c.logger).Log("msg", "Couldn't save kernel message counts", "err", err)
+		}
+	}
+	return nil
+}
+
+// counters returns the counts to save in the state file, c.mtx must be held.
+func (c *kmsgCollector) counters() map[string]float64 {
+	counters := map[string]float64{
+		"boot":     c.boot,
+		"sequence": float64(c.sequence),
+		"overruns": c.overrunCount,
+	}
+	for l, count := range c.messageCounts {
+		counters["messages_"+kmsgLevels[l]] = count
+	}
+	for i, p := range c.patterns {
+		counters["matches_"+p.name] = c.matchCounts[i]
+	}
+	return counters
+}
+
+// restore continues the counts saved in the state file. The records still
+// in the ring buffer are skipped up to the last one counted if the node
+// didn't reboot since.
+func (c *kmsgCollector) restore(counters map[string]float64) {
+	for l := range c.messageCounts {
+		c.messageCounts[l] = counters["messages_"+kmsgLevels[l]]
+	}
+	for i, p := range c.patterns {
+		c.matchCounts[i] = counters["matches_"+p.name]
+	}
+	c.overrunCount = counters["overruns"]
+	if boot, ok := counters["boot"]; ok && c.boot != 0 && boot == c.boot {
+		c.sequence, c.counted = uint64(counters["sequence"]), true
+	}
+}
+
+// kmsgBootID returns the first 52 bits of the boot ID of the kernel, which
+// a float64 holds exactly, 0 if it can't be read.
+func kmsgBootID() float64 {
+	content, err := os.ReadFile(procFilePath("sys/kernel/random/boot_id"))
+	if err != nil {
+		return 0
+	}
+	id := strings.ReplaceAll(strings.TrimSpace(string(content)), "-", "")
+	if len(id) < 13 {
+		return 0
+	}
+	boot, err := strconv.ParseUint(id[:13], 16, 64)
+	if err != nil {
+		return 0
+	}
+	return float64(boot)
+}
+
+// read counts the records of /dev/kmsg, each read returns one.
+func (c *kmsgCollector) read(f *os.File) {
+	defer f.Close()
+	buf := make([]byte, kmsgRecordSize)
+	for {
+		n, err := f.Read(buf)
+		switch {
+		case errors.Is(err, unix.EPIPE):
+			// The next read returns the oldest record left.
+			c.mtx.Lock()
+			c.overrunCount++
+			c.changed = true
+			c.mtx.Unlock()
+			continue
+		case errors.Is(err, unix.EINTR):
+			continue
+		case err != nil:
+			level.Error(c.logger).Log("msg", "Couldn't read kernel messages", "err", err)
+			c.mtx.Lock()
+			c.err = err
+			c.mtx.Unlock()
+			return
+		}
+		if err := c.record(buf[:n]); err != nil {
+			level.Debug(c.logger).Log("msg", "Invalid kernel message", "err", err)
+		}
+	}
+}
+
+// record counts a record, if it was logged by the kernel and not written to
+// /dev/kmsg by a process.
+func (c *kmsgCollector) record(record []byte) error {
+	facility, l, sequence, message, err := parseKmsgRecord(record)
+	if err != nil {
+		return err
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.counted && sequence <= c.sequence {
+		// Counted before the exporter restarted.
+		return nil
+	}
+	c.sequence, c.counted, c.changed = sequence, true, true
+	if facility != 0 {
+		return nil
+	}
+	c.messageCounts[l]++
+	for i, p := range c.patterns {
+		if p.pattern.MatchString(message) {
+			c.matchCounts[i]++
+		}
+	}
+	return nil
+}
+
+// parseKmsgRecord returns the facility, level, sequence number and text of a
+// record of /dev/kmsg like "6,339,5140900,-;NET: Registered protocol family
+// 10", without the key=value lines which may follow the text.
+func parseKmsgRecord(record []byte) (int, int, uint64, string, error) {
+	prefix, text, ok := bytes.Cut(record, []byte(";"))
+	if !ok {
+		return 0, 0, 0, "", fmt.Errorf("no ; in %q", record)
+	}
+	fields := bytes.SplitN(prefix, []byte(","), 3)
+	if len(fields) < 2 {
+		return 0, 0, 0, "", fmt.Errorf("invalid prefix %q", prefix)
+	}
+	p, err := strconv.Atoi(string(fields[0]))
+	if err != nil || p < 0 {
+		return 0, 0, 0, "", fmt.Errorf("invalid priority %q", fields[0])
+	}
+	sequence, err := strconv.ParseUint(string(fields[1]), 10, 64)
+	if err != nil {
+		return 0, 0, 0, "", fmt.Errorf("invalid sequence number %q", fields[1])
+	}
+	text, _, _ = bytes.Cut(text, []byte("\n"))
+	return p >> 3, p & 7, sequence, string(text), nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nokmsg
+// +build !nokmsg
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestKmsg(t *testing.T) {
+	*kmsgDevice = filepath.Join(t.TempDir(), "kmsg")
+	*kmsgPatterns = []string{`io_error=I/O error`, `link_down=[Ll]ink (is )?[Dd]own`}
+	c, err := NewKmsgCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	// Without the device, scrapes fail rather than expose stale counters.
+	if err := c.Update(make(chan prometheus.Metric, 16)); err == nil {
+		t.Error("missing device: expected error")
+	}
+
+	kc := c.(*kmsgCollector)
+	kc.err = nil
+	for _, record := range []string{
+		"6,339,5140900,-;NET: Registered protocol family 10",
+		"3,1024,90000000,-;blk_update_request: I/O error, dev sda, sector 2048\n SUBSYSTEM=block\n DEVICE=b8:0",
+		"6,1025,91000000,-;e1000e 0000:00:1f.6 eth0: NIC Link is Down",
+		"30,1026,92000000,-;systemd[1]: Link is down, but not logged by the kernel",
+		"garbage",
+	} {
+		kc.record([]byte(record))
+	}
+
+	want := `# HELP node_kmsg_messages_total Number of kernel messages by level, since the oldest message in the ring buffer when the exporter started or, with a state file, first started.
+# TYPE node_kmsg_messages_total counter
+node_kmsg_messages_total{level="alert"} 0
+node_kmsg_messages_total{level="crit"} 0
+node_kmsg_messages_total{level="debug"} 0
+node_kmsg_messages_total{level="emerg"} 0
+node_kmsg_messages_total{level="err"} 1
+node_kmsg_messages_total{level="info"} 2
+node_kmsg_messages_total{level="notice"} 0
+node_kmsg_messages_total{level="warning"} 0
+# HELP node_kmsg_pattern_matches_total Number of kernel messages matching the pattern.
+# TYPE node_kmsg_pattern_matches_total counter
+node_kmsg_pattern_matches_total{pattern="io_error"} 1
+node_kmsg_pattern_matches_total{pattern="link_down"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_kmsg_messages_total", "node_kmsg_pattern_matches_total"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKmsgStateFile(t *testing.T) {
+	dir := t.TempDir()
+	*procPath = dir
+	*stateFile = filepath.Join(dir, "state.json")
+	*kmsgDevice = filepath.Join(dir, "kmsg")
+	*kmsgPatterns = []string{`io_error=I/O error`}
+	restart := func() {
+		counterState.loaded = false
+		counterState.counters = nil
+	}
+	defer func() {
+		*procPath = "fixtures/proc"
+		*stateFile = ""
+		restart()
+	}()
+	if err := os.MkdirAll(filepath.Join(dir, "sys/kernel/random"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setBoot := func(id string) {
+		if err := os.WriteFile(filepath.Join(dir, "sys/kernel/random/boot_id"), []byte(id+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := func() *kmsgCollector {
+		restart()
+		c, err := NewKmsgCollector(log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		kc := c.(*kmsgCollector)
+		kc.err = nil
+		return kc
+	}
+	records := []string{
+		"3,10,1000,-;blk_update_request: I/O error, dev sda, sector 2048",
+		"6,11,2000,-;NET: Registered protocol family 10",
+	}
+	scrape := func(kc *kmsgCollector, want string) {
+		t.Helper()
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(collectorAdapter{kc})
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_kmsg_pattern_matches_total"); err != nil {
+			t.Error(err)
+		}
+	}
+	matches := func(n string) string {
+		return `# HELP node_kmsg_pattern_matches_total Number of kernel messages matching the pattern.
+# TYPE node_kmsg_pattern_matches_total counter
+node_kmsg_pattern_matches_total{pattern="io_error"} ` + n + "\n"
+	}
+
+	setBoot("6ae7a5a3-3b40-4d1c-9a61-0c5e2b8f1d47")
+	kc := start()
+	for _, r := range records {
+		kc.record([]byte(r))
+	}
+	scrape(kc, matches("1"))
+
+	// After a restart, the records still in the ring buffer are skipped.
+	kc = start()
+	for _, r := range append(records, "3,12,3000,-;blk_update_request: I/O error, dev sdb, sector 0") {
+		kc.record([]byte(r))
+	}
+	scrape(kc, matches("2"))
+
+	// After a reboot, the sequence numbers start again.
+	setBoot("0b9d2f4e-8c1a-4e7b-b5d3-2f6a9c0e7b18")
+	kc = start()
+	kc.record([]byte(records[0]))
+	scrape(kc, matches("3"))
+}
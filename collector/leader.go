@@ -0,0 +1,94 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	haLockFile   = kingpin.Flag("collector.ha.lock-file", "Lock file shared by the exporters of an HA pair, e.g. on shared storage. The exporter holding an exclusive lock on it is the leader.").Default("").String()
+	haStateFile  = kingpin.Flag("collector.ha.state-file", "File with the VRRP state of the node, written by a keepalived notify script. The exporter is the leader while it is MASTER. Takes precedence over --collector.ha.lock-file.").Default("").String()
+	haLeaderOnly = kingpin.Flag("collector.ha.leader-only", "Comma separated list of the collectors only run by the leader of an HA pair, e.g. the ones reading shared storage.").Default("").String()
+
+	leaderDesc = prometheus.NewDesc(
+		prometheus.BuildFQName("node_exporter", "", "leader"),
+		"Whether this exporter is the leader of its HA pair and runs the collectors given with --collector.ha.leader-only.",
+		nil, nil,
+	)
+
+	// leaderLock is the lock file while this exporter holds the lock.
+	leaderLock    *os.File
+	leaderLockMtx sync.Mutex
+)
+
+// haEnabled returns whether the exporter coordinates with the other exporter
+// of an HA pair.
+func haEnabled() bool {
+	return *haLockFile != "" || *haStateFile != ""
+}
+
+// leaderSkipped returns the collectors to skip for this scrape because this
+// exporter isn't the leader, and exposes whether it is.
+func (n NodeCollector) leaderSkipped(ch chan<- prometheus.Metric) map[string]bool {
+	if !haEnabled() {
+		return nil
+	}
+	leader := isLeader(n.logger)
+	ch <- prometheus.MustNewConstMetric(leaderDesc, prometheus.GaugeValue, boolToFloat(leader))
+	if leader {
+		return nil
+	}
+	skipped := map[string]bool{}
+	for _, name := range strings.Split(*haLeaderOnly, ",") {
+		skipped[strings.TrimSpace(name)] = true
+	}
+	return skipped
+}
+
+// isLeader returns whether this exporter is the leader, a standby takes over
+// the lock on the first scrape after the leader released it.
+func isLeader(logger log.Logger) bool {
+	if *haStateFile != "" {
+		state, err := os.ReadFile(*haStateFile)
+		if err != nil {
+			level.Warn(logger).Log("msg", "Couldn't read the VRRP state, acting as standby", "file", *haStateFile, "err", err)
+			return false
+		}
+		return strings.TrimSpace(string(state)) == "MASTER"
+	}
+
+	leaderLockMtx.Lock()
+	defer leaderLockMtx.Unlock()
+	if leaderLock != nil {
+		return true
+	}
+	f, err := tryLock(*haLockFile)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Couldn't lock the HA lock file, acting as standby", "file", *haLockFile, "err", err)
+		return false
+	}
+	if f != nil {
+		level.Info(logger).Log("msg", "Acquired the HA lock, acting as leader", "file", *haLockFile)
+		leaderLock = f
+	}
+	return f != nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	leader, err := tryLock(path)
+	if err != nil || leader == nil {
+		t.Fatalf("first lock: got %v, %v, want the file", leader, err)
+	}
+	// Locks are held per open file, so this acts like another exporter.
+	if standby, err := tryLock(path); err != nil || standby != nil {
+		t.Fatalf("second lock: got %v, %v, want nil while the first is held", standby, err)
+	}
+	leader.Close()
+	standby, err := tryLock(path)
+	if err != nil || standby == nil {
+		t.Fatalf("lock after release: got %v, %v, want the file", standby, err)
+	}
+	standby.Close()
+}
+
+func TestLeaderSkipped(t *testing.T) {
+	savedStateFile, savedLeaderOnly := *haStateFile, *haLeaderOnly
+	defer func() {
+		*haStateFile, *haLeaderOnly = savedStateFile, savedLeaderOnly
+	}()
+
+	*haStateFile = filepath.Join(t.TempDir(), "vrrp_state")
+	*haLeaderOnly = "diskstats, mountstats"
+	n := NodeCollector{
+		Collectors: map[string]Collector{"cpu": nil, "diskstats": nil, "mountstats": nil},
+		logger:     log.NewNopLogger(),
+	}
+	skipped := func() map[string]bool {
+		ch := make(chan prometheus.Metric, 1)
+		s := n.leaderSkipped(ch)
+		if len(ch) != 1 {
+			t.Errorf("want the leader metric, got %d metrics", len(ch))
+		}
+		return s
+	}
+
+	for _, state := range []string{"BACKUP", "FAULT"} {
+		if err := os.WriteFile(*haStateFile, []byte(state+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names := n.without(skipped()).Collectors
+		if _, ok := names["cpu"]; !ok || len(names) != 1 {
+			t.Errorf("%s: want only cpu to run, got %v", state, names)
+		}
+	}
+
+	if err := os.WriteFile(*haStateFile, []byte("MASTER\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if s := skipped(); len(s) != 0 {
+		t.Errorf("MASTER: want no collectors skipped, got %v", s)
+	}
+}
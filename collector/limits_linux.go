@@ -0,0 +1,400 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolimits
+// +build !nolimits
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const limitsSubsystem = "limits"
+
+var limitsUsers = kingpin.Flag("collector.limits.user", "User to expose the limits configured for in /etc/security/limits.conf and limits.d, can be repeated.").Strings()
+
+// limitsResources maps the resources exposed to their rlimit and their name
+// in /proc/[pid]/limits. The names are the ones used by limits.conf.
+var limitsResources = []struct {
+	name     string
+	resource int
+	procName string
+}{
+	{"nofile", unix.RLIMIT_NOFILE, "Max open files"},
+	{"nproc", unix.RLIMIT_NPROC, "Max processes"},
+}
+
+var procLimitsRE = regexp.MustCompile(`^(Max [a-z ]+?)\s{2,}(\S+)\s+(\S+)`)
+
+type limitsCollector struct {
+	exporterLimit *prometheus.Desc
+	initLimit     *prometheus.Desc
+	pamLimit      *prometheus.Desc
+	logger        log.Logger
+}
+
+// limitsValue is a soft and hard limit, unlimited is +Inf.
+type limitsValue struct {
+	soft, hard float64
+}
+
+func init() {
+	registerCollector(limitsSubsystem, defaultDisabled, NewLimitsCollector)
+}
+
+// NewLimitsCollector returns a new Collector exposing the resource limits of
+// the exporter, of init and configured for users through pam_limits.
+func NewLimitsCollector(logger log.Logger) (Collector, error) {
+	return &limitsCollector{
+		exporterLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, limitsSubsystem, "exporter"),
+			"Resource limit of the node_exporter process, +Inf if unlimited.",
+			[]string{"resource", "type"}, nil,
+		),
+		initLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, limitsSubsystem, "init"),
+			"Resource limit of PID 1, which services inherit by default, +Inf if unlimited.",
+			[]string{"resource", "type"}, nil,
+		),
+		pamLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, limitsSubsystem, "pam"),
+			"Resource limit configured for a user in the pam_limits configuration, +Inf if unlimited.",
+			[]string{"user", "resource", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *limitsCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, r := range limitsResources {
+		var rlimit unix.Rlimit
+		if err := unix.Getrlimit(r.resource, &rlimit); err != nil {
+			return fmt.Errorf("couldn't get %s limit: %w", r.name, err)
+		}
+		c.emit(ch, c.exporterLimit, limitsValue{rlimitFloat(rlimit.Cur), rlimitFloat(rlimit.Max)}, r.name)
+	}
+
+	f, err := os.Open(procFilePath("1/limits"))
+	if err != nil {
+		return fmt.Errorf("couldn't open limits of PID 1: %w", err)
+	}
+	initLimits, err := parseProcLimits(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("couldn't parse limits of PID 1: %w", err)
+	}
+	for _, r := range limitsResources {
+		if v, ok := initLimits[r.procName]; ok {
+			c.emit(ch, c.initLimit, v, r.name)
+		}
+	}
+
+	if len(*limitsUsers) == 0 {
+		return nil
+	}
+	entries, err := readPAMLimits(c.logger)
+	if err != nil {
+		return err
+	}
+	for _, name := range *limitsUsers {
+		u, err := lookupLimitsUser(name)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't look up user", "user", name, "err", err)
+			continue
+		}
+		limits := pamLimitsForUser(entries, u)
+		for _, r := range limitsResources {
+			// Limits not configured are inherited from the process starting
+			// the session.
+			for limitType, v := range limits[r.name] {
+				ch <- prometheus.MustNewConstMetric(c.pamLimit, prometheus.GaugeValue, v, name, r.name, limitType)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *limitsCollector) emit(ch chan<- prometheus.Metric, desc *prometheus.Desc, v limitsValue, labels ...string) {
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v.soft, append(labels, "soft")...)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v.hard, append(labels, "hard")...)
+}
+
+func rlimitFloat(v uint64) float64 {
+	if v == unix.RLIM_INFINITY {
+		return math.Inf(1)
+	}
+	return float64(v)
+}
+
+// parseProcLimits parses /proc/[pid]/limits into soft and hard limits by the
+// name of the limit.
+func parseProcLimits(r io.Reader) (map[string]limitsValue, error) {
+	limits := map[string]limitsValue{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		m := procLimitsRE.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		soft, err := parseLimitValue(m[2])
+		if err != nil {
+			return nil, err
+		}
+		hard, err := parseLimitValue(m[3])
+		if err != nil {
+			return nil, err
+		}
+		limits[m[1]] = limitsValue{soft, hard}
+	}
+	return limits, s.Err()
+}
+
+func parseLimitValue(s string) (float64, error) {
+	switch s {
+	case "unlimited", "infinity", "-1":
+		return math.Inf(1), nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit %q: %w", s, err)
+	}
+	return float64(v), nil
+}
+
+// pamLimitsEntry is a line of limits.conf.
+type pamLimitsEntry struct {
+	domain    string
+	limitType string
+	item      string
+	value     float64
+}
+
+// limitsUser is a user as far as matching pam_limits domains is concerned.
+type limitsUser struct {
+	name   string
+	uid    uint64
+	groups []string
+	gids   []uint64
+}
+
+func lookupLimitsUser(name string) (limitsUser, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return limitsUser{}, err
+	}
+	lu := limitsUser{name: name}
+	if lu.uid, err = strconv.ParseUint(u.Uid, 10, 32); err != nil {
+		return limitsUser{}, err
+	}
+	gids, err := u.GroupIds()
+	if err != nil {
+		return limitsUser{}, err
+	}
+	for _, gid := range gids {
+		if id, err := strconv.ParseUint(gid, 10, 32); err == nil {
+			lu.gids = append(lu.gids, id)
+		}
+		if g, err := user.LookupGroupId(gid); err == nil {
+			lu.groups = append(lu.groups, g.Name)
+		}
+	}
+	return lu, nil
+}
+
+// readPAMLimits reads limits.conf followed by limits.d/*.conf, in the order
+// pam_limits applies them.
+func readPAMLimits(logger log.Logger) ([]pamLimitsEntry, error) {
+	files := []string{rootfsFilePath("/etc/security/limits.conf")}
+	dropIns, err := filepath.Glob(rootfsFilePath("/etc/security/limits.d/*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dropIns)
+	files = append(files, dropIns...)
+
+	var entries []pamLimitsEntry
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		e, err := parsePAMLimits(log.With(logger, "file", file), f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse %s: %w", file, err)
+		}
+		entries = append(entries, e...)
+	}
+	return entries, nil
+}
+
+// parsePAMLimits parses a limits.conf file, see limits.conf(5). Malformed
+// lines are skipped like pam_limits does.
+func parsePAMLimits(logger log.Logger, r io.Reader) ([]pamLimitsEntry, error) {
+	var entries []pamLimitsEntry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			level.Debug(logger).Log("msg", "skipping malformed limits line", "line", s.Text())
+			continue
+		}
+		value, err := parseLimitValue(fields[3])
+		if err != nil {
+			// Some items like priority take negative values, which none of the
+			// resources exposed do.
+			continue
+		}
+		entries = append(entries, pamLimitsEntry{
+			domain:    fields[0],
+			limitType: fields[1],
+			item:      fields[2],
+			value:     value,
+		})
+	}
+	return entries, s.Err()
+}
+
+// Precedence of the pam_limits domains, higher wins.
+const (
+	pamLimitsNoMatch = iota
+	pamLimitsDefault
+	pamLimitsGroup
+	pamLimitsUser
+)
+
+// pamLimitsForUser returns the soft and hard limits by item that apply to
+// the user. Entries for the user override the ones for its groups, which
+// override the default ones, and among entries of the same precedence the
+// last one wins.
+func pamLimitsForUser(entries []pamLimitsEntry, u limitsUser) map[string]map[string]float64 {
+	type limit struct {
+		value      float64
+		precedence int
+	}
+	found := map[string]map[string]limit{}
+	set := func(item, limitType string, value float64, precedence int) {
+		if found[item] == nil {
+			found[item] = map[string]limit{}
+		}
+		if precedence >= found[item][limitType].precedence {
+			found[item][limitType] = limit{value, precedence}
+		}
+	}
+	for _, e := range entries {
+		p := pamLimitsPrecedence(e.domain, u)
+		if p == pamLimitsNoMatch {
+			continue
+		}
+		if e.limitType == "soft" || e.limitType == "-" {
+			set(e.item, "soft", e.value, p)
+		}
+		if e.limitType == "hard" || e.limitType == "-" {
+			set(e.item, "hard", e.value, p)
+		}
+	}
+
+	limits := make(map[string]map[string]float64, len(found))
+	for item, types := range found {
+		limits[item] = make(map[string]float64, len(types))
+		for limitType, l := range types {
+			limits[item][limitType] = l.value
+		}
+	}
+	return limits
+}
+
+func pamLimitsPrecedence(domain string, u limitsUser) int {
+	switch {
+	case domain == "*":
+		return pamLimitsDefault
+	case strings.HasPrefix(domain, "%"):
+		// Only applies to maxlogins.
+		return pamLimitsNoMatch
+	case strings.HasPrefix(domain, "@"):
+		group := domain[1:]
+		if strings.Contains(group, ":") {
+			for _, gid := range u.gids {
+				if matchIDRange(group, gid) {
+					return pamLimitsGroup
+				}
+			}
+			return pamLimitsNoMatch
+		}
+		for _, g := range u.groups {
+			if g == group {
+				return pamLimitsGroup
+			}
+		}
+		return pamLimitsNoMatch
+	case strings.Contains(domain, ":"):
+		if !matchIDRange(domain, u.uid) {
+			return pamLimitsNoMatch
+		}
+		// A single UID counts as the user, a range like a group.
+		if min, max, _ := strings.Cut(domain, ":"); min != "" && min == max {
+			return pamLimitsUser
+		}
+		return pamLimitsGroup
+	case domain == u.name:
+		return pamLimitsUser
+	}
+	return pamLimitsNoMatch
+}
+
+// matchIDRange reports whether id is in a min:max range, either bound may be
+// omitted.
+func matchIDRange(r string, id uint64) bool {
+	minStr, maxStr, _ := strings.Cut(r, ":")
+	if minStr != "" {
+		min, err := strconv.ParseUint(minStr, 10, 32)
+		if err != nil || id < min {
+			return false
+		}
+	}
+	if maxStr != "" {
+		max, err := strconv.ParseUint(maxStr, 10, 32)
+		if err != nil || id > max {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolimits
+// +build !nolimits
+
+package collector
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestParseProcLimits(t *testing.T) {
+	limits, err := parseProcLimits(strings.NewReader(`Limit                     Soft Limit           Hard Limit           Units
+Max cpu time              unlimited            unlimited            seconds
+Max processes             63438                63438                processes
+Max open files            1024                 524288               files
+Max locked memory         8388608              8388608              bytes
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]limitsValue{
+		"Max cpu time":      {math.Inf(1), math.Inf(1)},
+		"Max processes":     {63438, 63438},
+		"Max open files":    {1024, 524288},
+		"Max locked memory": {8388608, 8388608},
+	}
+	if !reflect.DeepEqual(limits, want) {
+		t.Errorf("want %v, got %v", want, limits)
+	}
+}
+
+func TestPAMLimitsForUser(t *testing.T) {
+	entries, err := parsePAMLimits(log.NewNopLogger(), strings.NewReader(`# /etc/security/limits.conf
+*               soft    nofile          1024
+*               soft    nofile          # malformed, skipped
+*               hard    nofile          4096
+@dba            -       nofile          65536
+postgres        hard    nofile          unlimited
+1000:           soft    nproc           2048
+:0              -       nproc           -1
+%admins         -       maxlogins       4
+*               -       priority        -5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user limitsUser
+		want map[string]map[string]float64
+	}{
+		{
+			user: limitsUser{name: "alice", uid: 1001, groups: []string{"alice"}, gids: []uint64{1001}},
+			want: map[string]map[string]float64{
+				"nofile": {"soft": 1024, "hard": 4096},
+				"nproc":  {"soft": 2048},
+			},
+		},
+		{
+			user: limitsUser{name: "postgres", uid: 113, groups: []string{"postgres", "dba"}, gids: []uint64{120, 121}},
+			want: map[string]map[string]float64{
+				"nofile": {"soft": 65536, "hard": math.Inf(1)},
+			},
+		},
+		{
+			user: limitsUser{name: "root", uid: 0, groups: []string{"root"}, gids: []uint64{0}},
+			want: map[string]map[string]float64{
+				"nofile": {"soft": 1024, "hard": 4096},
+				"nproc":  {"soft": math.Inf(1), "hard": math.Inf(1)},
+			},
+		},
+	} {
+		if got := pamLimitsForUser(entries, tc.user); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.user.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolistenqueue
+// +build !nolistenqueue
+
+package collector
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/go-kit/log"
+	"github.com/mdlayher/netlink"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	listenQueueSubsystem = "listen_queue"
+
+	// SK_MEMINFO_DROPS, the index of the drops in the SK_MEMINFO_* values.
+	skMeminfoDrops = 8
+)
+
+// listenQueue is the accept queue of the listening sockets of a port, summed
+// over the sockets bound to it, e.g. with SO_REUSEPORT or for both families.
+type listenQueue struct {
+	sockets float64
+	length  float64
+	max     float64
+	drops   float64
+}
+
+type listenQueueCollector struct {
+	sockets *prometheus.Desc
+	length  *prometheus.Desc
+	max     *prometheus.Desc
+	drops   *prometheus.Desc
+	logger  log.Logger
+}
+
+func init() {
+	registerCollector("listenqueue", defaultDisabled, NewListenQueueCollector)
+}
+
+// NewListenQueueCollector returns a new Collector exposing the accept queues
+// of the listening TCP sockets by local port.
+func NewListenQueueCollector(logger log.Logger) (Collector, error) {
+	return &listenQueueCollector{
+		sockets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, listenQueueSubsystem, "sockets"),
+			"Number of listening TCP sockets bound to the port.",
+			[]string{"port"}, nil,
+		),
+		length: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, listenQueueSubsystem, "length"),
+			"Number of established connections waiting to be accepted on the port.",
+			[]string{"port"}, nil,
+		),
+		max: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, listenQueueSubsystem, "max"),
+			"Backlog of the listening sockets of the port, capped by net.core.somaxconn.",
+			[]string{"port"}, nil,
+		),
+		drops: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, listenQueueSubsystem, "drops_total"),
+			"Connections dropped by the listening sockets of the port because the SYN or accept queue overflowed, or on other errors.",
+			[]string{"port"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *listenQueueCollector) Update(ch chan<- prometheus.Metric) error {
+	msgs, err := inetDiagDump(syscall.AF_INET, syscall.IPPROTO_TCP, tcpfListen, 1<<(inetDiagSkmeminfo-1))
+	if err != nil {
+		return fmt.Errorf("couldn't get listening sockets: %w", err)
+	}
+
+	// if enabled ipv6 system
+	if _, hasIPv6 := os.Stat(procFilePath("net/tcp6")); hasIPv6 == nil {
+		msgs6, err := inetDiagDump(syscall.AF_INET6, syscall.IPPROTO_TCP, tcpfListen, 1<<(inetDiagSkmeminfo-1))
+		if err != nil {
+			return fmt.Errorf("couldn't get IPv6 listening sockets: %w", err)
+		}
+		msgs = append(msgs, msgs6...)
+	}
+
+	for port, q := range parseListenQueues(msgs) {
+		p := strconv.Itoa(int(port))
+		ch <- prometheus.MustNewConstMetric(c.sockets, prometheus.GaugeValue, q.sockets, p)
+		ch <- prometheus.MustNewConstMetric(c.length, prometheus.GaugeValue, q.length, p)
+		ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, q.max, p)
+		ch <- prometheus.MustNewConstMetric(c.drops, prometheus.CounterValue, q.drops, p)
+	}
+	return nil
+}
+
+// parseListenQueues sums the queues of listening sockets by local port. For
+// those sockets the kernel reports the length of the accept queue as receive
+// queue and the backlog as send queue.
+func parseListenQueues(msgs []netlink.Message) map[uint16]listenQueue {
+	queues := map[uint16]listenQueue{}
+	for _, m := range msgs {
+		msg := parseInetDiagMsg(m.Data)
+		port := binary.BigEndian.Uint16(msg.ID.SourcePort[:])
+		q := queues[port]
+		q.sockets++
+		q.length += float64(msg.RQueue)
+		q.max += float64(msg.WQueue)
+		if skmem := inetDiagSkmem(m.Data); len(skmem) > skMeminfoDrops {
+			q.drops += float64(skmem[skMeminfoDrops])
+		}
+		queues[port] = q
+	}
+	return queues
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolistenqueue
+// +build !nolistenqueue
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/josharian/native"
+	"github.com/mdlayher/netlink"
+)
+
+func TestParseListenQueues(t *testing.T) {
+	socket := func(port uint16, length, max uint32, drops uint32) netlink.Message {
+		id := InetDiagSockID{}
+		binary.BigEndian.PutUint16(id.SourcePort[:], port)
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, native.Endian, InetDiagMsg{Family: syscall.AF_INET, ID: id, RQueue: length, WQueue: max}); err != nil {
+			t.Fatal(err)
+		}
+		ae := netlink.NewAttributeEncoder()
+		ae.Do(inetDiagSkmeminfo, func() ([]byte, error) {
+			var b bytes.Buffer
+			err := binary.Write(&b, native.Endian, []uint32{0, 0, 0, 0, 0, 0, 0, 0, drops})
+			return b.Bytes(), err
+		})
+		attrs, err := ae.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return netlink.Message{Data: append(buf.Bytes(), attrs...)}
+	}
+
+	queues := parseListenQueues([]netlink.Message{
+		socket(443, 3, 4096, 12),
+		socket(443, 1, 4096, 0),
+		socket(22, 0, 128, 0),
+	})
+
+	want := map[uint16]listenQueue{
+		443: {sockets: 2, length: 4, max: 8192, drops: 12},
+		22:  {sockets: 1, length: 0, max: 128, drops: 0},
+	}
+	if len(queues) != len(want) {
+		t.Fatalf("want %v, got %v", want, queues)
+	}
+	for port, q := range want {
+		if got := queues[port]; got != q {
+			t.Errorf("want queue %+v for port %d, got %+v", q, port, got)
+		}
+	}
+}
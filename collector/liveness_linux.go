@@ -0,0 +1,183 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noliveness
+// +build !noliveness
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+const livenessSubsystem = "liveness"
+
+var (
+	livenessPidfiles  = kingpin.Flag("collector.liveness.pidfile", "Pidfile of a process to check, can be repeated. The process is named after the file name without the .pid extension.").Strings()
+	livenessProcesses = kingpin.Flag("collector.liveness.process", "Name of a process to check, as shown in /proc/[pid]/stat, can be repeated.").Strings()
+)
+
+type livenessCollector struct {
+	fs        procfs.FS
+	up        *prometheus.Desc
+	startTime *prometheus.Desc
+	logger    log.Logger
+}
+
+func init() {
+	registerCollector(livenessSubsystem, defaultDisabled, NewLivenessCollector)
+}
+
+// NewLivenessCollector returns a new Collector exposing whether the
+// configured processes are running, identified by pidfile or name.
+func NewLivenessCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	return &livenessCollector{
+		fs: fs,
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, livenessSubsystem, "up"),
+			"Whether the process is running.",
+			[]string{"name", "source"}, nil,
+		),
+		startTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, livenessSubsystem, "start_time_seconds"),
+			"Unix time the process started, of the oldest one if several match.",
+			[]string{"name", "source"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *livenessCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, path := range *livenessPidfiles {
+		name := strings.TrimSuffix(filepath.Base(path), ".pid")
+		start, err := c.pidfileStartTime(rootfsFilePath(path))
+		if err != nil {
+			return fmt.Errorf("couldn't check process of pidfile %s: %w", path, err)
+		}
+		c.emit(ch, name, "pidfile", start)
+	}
+
+	if len(*livenessProcesses) == 0 {
+		return nil
+	}
+	starts, err := c.processStartTimes(*livenessProcesses)
+	if err != nil {
+		return err
+	}
+	for _, name := range *livenessProcesses {
+		c.emit(ch, name, "process", starts[name])
+	}
+	return nil
+}
+
+// emit exposes the liveness of a process, start is 0 if it isn't running.
+func (c *livenessCollector) emit(ch chan<- prometheus.Metric, name, source string, start float64) {
+	if start == 0 {
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0, name, source)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1, name, source)
+	ch <- prometheus.MustNewConstMetric(c.startTime, prometheus.GaugeValue, start, name, source)
+}
+
+// pidfileStartTime returns the start time of the process whose PID is stored
+// in path, or 0 if the pidfile or the process doesn't exist.
+func (c *livenessCollector) pidfileStartTime(path string) (float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			level.Debug(c.logger).Log("msg", "pidfile not found", "path", path)
+			return 0, nil
+		}
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid: %w", err)
+	}
+	proc, err := c.fs.Proc(pid)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	stat, err := proc.Stat()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return livenessStartTime(stat)
+}
+
+// processStartTimes returns the start time of the oldest running process
+// with each of the given names.
+func (c *livenessCollector) processStartTimes(names []string) (map[string]float64, error) {
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list processes: %w", err)
+	}
+	starts := map[string]float64{}
+	for _, proc := range procs {
+		stat, err := proc.Stat()
+		if err != nil {
+			// The process may have exited since it was listed.
+			continue
+		}
+		if !wanted[stat.Comm] {
+			continue
+		}
+		start, err := livenessStartTime(stat)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := starts[stat.Comm]; start != 0 && (!ok || start < s) {
+			starts[stat.Comm] = start
+		}
+	}
+	return starts, nil
+}
+
+// livenessStartTime returns the start time of the process, or 0 if it is a
+// zombie.
+func livenessStartTime(stat procfs.ProcStat) (float64, error) {
+	if stat.State == "Z" {
+		return 0, nil
+	}
+	start, err := stat.StartTime()
+	if err != nil {
+		return 0, fmt.Errorf("couldn't get start time of pid %d: %w", stat.PID, err)
+	}
+	return start, nil
+}
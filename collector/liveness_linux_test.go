@@ -0,0 +1,63 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noliveness
+// +build !noliveness
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestLivenessStartTimes(t *testing.T) {
+	*procPath = "fixtures/proc"
+	collector, err := NewLivenessCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := collector.(*livenessCollector)
+
+	// pid 1 started 29 ticks after boot.
+	const want = 1418183276.29
+
+	dir := t.TempDir()
+	for pid, want := range map[string]float64{"1\n": want, "4242": 0} {
+		path := filepath.Join(dir, "test.pid")
+		if err := os.WriteFile(path, []byte(pid), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		start, err := c.pidfileStartTime(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start != want {
+			t.Errorf("pid %q: want start time %v, got %v", pid, want, start)
+		}
+	}
+	if start, err := c.pidfileStartTime(filepath.Join(dir, "missing.pid")); err != nil || start != 0 {
+		t.Errorf("missing pidfile: want 0, got %v (err %v)", start, err)
+	}
+
+	starts, err := c.processStartTimes([]string{"systemd", "sshd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starts) != 1 || starts["systemd"] != want {
+		t.Errorf("want start time %v for systemd only, got %v", want, starts)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nologind && journal && cgo
+// +build !nologind,journal,cgo
+
+package collector
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/coreos/go-systemd/v22/sdjournal"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+var (
+	sshAuthFailureRE      = regexp.MustCompile(`^Failed (\S+) for `)
+	sshAuthFailureMethods = []string{"other", "password", "publickey", "keyboard-interactive/pam", "hostbased", "none"}
+)
+
+// watchSSHAuthFailures follows the journal for failed authentications logged
+// by sshd from now on, and returns a function returning the number of
+// failures per method seen so far.
+func watchSSHAuthFailures(logger log.Logger) (func() map[string]uint64, error) {
+	j, err := sdjournal.NewJournal()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open journal: %w", err)
+	}
+	// Newer OpenSSH versions log from a separate sshd-session binary.
+	for i, identifier := range []string{"sshd", "sshd-session"} {
+		if i > 0 {
+			if err := j.AddDisjunction(); err != nil {
+				j.Close()
+				return nil, err
+			}
+		}
+		if err := j.AddMatch(sdjournal.SD_JOURNAL_FIELD_SYSLOG_IDENTIFIER + "=" + identifier); err != nil {
+			j.Close()
+			return nil, err
+		}
+	}
+	if err := j.SeekTail(); err != nil {
+		j.Close()
+		return nil, err
+	}
+	// SeekTail positions after the last entry, step back so that the next
+	// call to Next returns the first new one.
+	if _, err := j.Previous(); err != nil {
+		j.Close()
+		return nil, err
+	}
+
+	var (
+		mtx      sync.Mutex
+		failures = map[string]uint64{}
+	)
+	go func() {
+		defer j.Close()
+		for {
+			n, err := j.Next()
+			if err != nil {
+				level.Error(logger).Log("msg", "failed to read journal, no longer counting SSH authentication failures", "err", err)
+				return
+			}
+			if n == 0 {
+				j.Wait(sdjournal.IndefiniteWait)
+				continue
+			}
+			message, err := j.GetDataValue(sdjournal.SD_JOURNAL_FIELD_MESSAGE)
+			if err != nil {
+				continue
+			}
+			m := sshAuthFailureRE.FindStringSubmatch(message)
+			if m == nil {
+				continue
+			}
+			mtx.Lock()
+			failures[knownStringOrOther(m[1], sshAuthFailureMethods)]++
+			mtx.Unlock()
+		}
+	}()
+
+	return func() map[string]uint64 {
+		mtx.Lock()
+		defer mtx.Unlock()
+		counts := make(map[string]uint64, len(failures))
+		for method, count := range failures {
+			counts[method] = count
+		}
+		return counts
+	}, nil
+}
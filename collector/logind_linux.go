@@ -21,7 +21,9 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/godbus/dbus/v5"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -43,10 +45,22 @@ var (
 		prometheus.BuildFQName(namespace, logindSubsystem, "sessions"),
 		"Number of sessions registered in logind.", []string{"seat", "remote", "type", "class"}, nil,
 	)
+	userSessionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, logindSubsystem, "user_sessions"),
+		"Number of sessions registered in logind per user and type, SSH sessions have the type ssh.", []string{"user", "type"}, nil,
+	)
+	sshAuthFailuresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, logindSubsystem, "ssh_auth_failures_total"),
+		"Number of failed SSH authentications logged to the journal by sshd since the exporter started.", []string{"method"}, nil,
+	)
+
+	logindSSHAuthFailures = kingpin.Flag("collector.logind.ssh-auth-failures", "Count failed SSH authentications logged to the journal by sshd.").Bool()
 )
 
 type logindCollector struct {
-	logger log.Logger
+	logger             log.Logger
+	sshAuthFailures    func() map[string]uint64
+	sshAuthFailuresErr error
 }
 
 type logindDbus struct {
@@ -65,6 +79,12 @@ type logindSession struct {
 	remote      string
 	sessionType string
 	class       string
+	service     string
+}
+
+type logindUserSession struct {
+	user        string
+	sessionType string
 }
 
 // Struct elements must be public for the reflection magic of godbus to work.
@@ -87,10 +107,35 @@ func init() {
 
 // NewLogindCollector returns a new Collector exposing logind statistics.
 func NewLogindCollector(logger log.Logger) (Collector, error) {
-	return &logindCollector{logger}, nil
+	c := &logindCollector{logger: logger}
+	if *logindSSHAuthFailures {
+		failures, err := watchSSHAuthFailures(logger)
+		if err != nil {
+			// Failing to create the collector would fail every scrape,
+			// the sessions are still exposed.
+			c.sshAuthFailuresErr = fmt.Errorf("unable to watch the journal for SSH authentication failures: %w", err)
+			level.Error(logger).Log("msg", "SSH authentication failures aren't counted", "err", c.sshAuthFailuresErr)
+		}
+		c.sshAuthFailures = failures
+	}
+	return c, nil
+}
+
+// Validate implements Validator.
+func (lc *logindCollector) Validate() error {
+	if lc.sshAuthFailuresErr != nil {
+		return lc.sshAuthFailuresErr
+	}
+	return checkSystemBus()
 }
 
 func (lc *logindCollector) Update(ch chan<- prometheus.Metric) error {
+	if lc.sshAuthFailures != nil {
+		for method, count := range lc.sshAuthFailures() {
+			ch <- prometheus.MustNewConstMetric(sshAuthFailuresDesc, prometheus.CounterValue, float64(count), method)
+		}
+	}
+
 	c, err := newDbus()
 	if err != nil {
 		return fmt.Errorf("unable to connect to dbus: %w", err)
@@ -112,19 +157,32 @@ func collectMetrics(ch chan<- prometheus.Metric, c logindInterface) error {
 	}
 
 	sessions := make(map[logindSession]float64)
+	userSessions := make(map[logindUserSession]float64)
 
 	for _, s := range sessionList {
 		session := c.getSession(s)
 		if session != nil {
-			sessions[*session]++
+			// The service only distinguishes SSH sessions per user.
+			key := *session
+			key.service = ""
+			sessions[key]++
+			sessionType := session.sessionType
+			if session.service == "sshd" {
+				sessionType = "ssh"
+			}
+			userSessions[logindUserSession{s.UserName, sessionType}]++
 		}
 	}
 
+	for s, count := range userSessions {
+		ch <- prometheus.MustNewConstMetric(userSessionsDesc, prometheus.GaugeValue, count, s.user, s.sessionType)
+	}
+
 	for _, remote := range attrRemoteValues {
 		for _, sessionType := range attrTypeValues {
 			for _, class := range attrClassValues {
 				for _, seat := range seats {
-					count := sessions[logindSession{seat, remote, sessionType, class}]
+					count := sessions[logindSession{seat: seat, remote: remote, sessionType: sessionType, class: class}]
 
 					ch <- prometheus.MustNewConstMetric(
 						sessionsDesc, prometheus.GaugeValue, count,
@@ -262,10 +320,18 @@ func (c *logindDbus) getSession(session logindSessionEntry) *logindSession {
 		return nil
 	}
 
+	// Service is only used to tell SSH sessions apart, older logind
+	// versions don't have it.
+	var serviceStr string
+	if service, err := object.GetProperty(dbusObject + ".Session.Service"); err == nil {
+		serviceStr, _ = service.Value().(string)
+	}
+
 	return &logindSession{
 		seat:        session.SeatID,
 		remote:      remote.String(),
 		sessionType: knownStringOrOther(sessionTypeStr, attrTypeValues),
 		class:       knownStringOrOther(classStr, attrClassValues),
+		service:     serviceStr,
 	}
 }
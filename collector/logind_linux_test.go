@@ -32,15 +32,15 @@ func (c *testLogindInterface) listSessions() ([]logindSessionEntry, error) {
 	return []logindSessionEntry{
 		{
 			SessionID:         "1",
-			UserID:            0,
-			UserName:          "",
+			UserID:            1000,
+			UserName:          "alice",
 			SeatID:            "",
 			SessionObjectPath: dbus.ObjectPath("/org/freedesktop/login1/session/1"),
 		},
 		{
 			SessionID:         "2",
 			UserID:            0,
-			UserName:          "",
+			UserName:          "gdm",
 			SeatID:            "seat0",
 			SessionObjectPath: dbus.ObjectPath("/org/freedesktop/login1/session/2"),
 		},
@@ -54,12 +54,14 @@ func (c *testLogindInterface) getSession(session logindSessionEntry) *logindSess
 			remote:      "true",
 			sessionType: knownStringOrOther("tty", attrTypeValues),
 			class:       knownStringOrOther("user", attrClassValues),
+			service:     "sshd",
 		},
 		dbus.ObjectPath("/org/freedesktop/login1/session/2"): {
 			seat:        session.SeatID,
 			remote:      "false",
 			sessionType: knownStringOrOther("x11", attrTypeValues),
 			class:       knownStringOrOther("greeter", attrClassValues),
+			service:     "gdm-launch-environment",
 		},
 	}
 
@@ -95,7 +97,9 @@ func TestLogindCollectorCollectMetrics(t *testing.T) {
 		count++
 	}
 
-	expected := len(testSeats) * len(attrRemoteValues) * len(attrTypeValues) * len(attrClassValues)
+	// One user_sessions metric per user and type on top of the sessions
+	// matrix.
+	expected := len(testSeats)*len(attrRemoteValues)*len(attrTypeValues)*len(attrClassValues) + 2
 	if count != expected {
 		t.Errorf("collectMetrics did not generate the expected number of metrics: got %d, expected %d.", count, expected)
 	}
@@ -0,0 +1,28 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nologind && !(journal && cgo)
+// +build !nologind
+// +build !journal !cgo
+
+package collector
+
+import (
+	"errors"
+
+	"github.com/go-kit/log"
+)
+
+func watchSSHAuthFailures(logger log.Logger) (func() map[string]uint64, error) {
+	return nil, errors.New("node_exporter was built without the journal build tag")
+}
@@ -0,0 +1,36 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nologind && !(journal && cgo)
+// +build !nologind
+// +build !journal !cgo
+
+package collector
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestLogindSSHAuthFailuresWithoutJournal(t *testing.T) {
+	*logindSSHAuthFailures = true
+	defer func() { *logindSSHAuthFailures = false }()
+	c, err := NewLogindCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("the collector must be created without the journal: %v", err)
+	}
+	if err := c.(Validator).Validate(); err == nil {
+		t.Error("validation without the journal: want error")
+	}
+}
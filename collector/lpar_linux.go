@@ -0,0 +1,250 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolpar
+// +build !nolpar
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const lparSubsystem = "lpar"
+
+type lparCollector struct {
+	info                *prometheus.Desc
+	cpus                *prometheus.Desc
+	entitledCPUs        *prometheus.Desc
+	capped              *prometheus.Desc
+	capacityWeight      *prometheus.Desc
+	poolCPUs            *prometheus.Desc
+	poolIdleSeconds     *prometheus.Desc
+	physicalCPUSeconds  *prometheus.Desc
+	dispatches          *prometheus.Desc
+	dispatchDispersions *prometheus.Desc
+	logger              log.Logger
+}
+
+func init() {
+	registerCollector(lparSubsystem, defaultDisabled, NewLPARCollector)
+	registerHardwareProbe(lparSubsystem, "not a POWER or IBM Z LPAR", func() bool {
+		return procfsProbe("powerpc/lparcfg")() || procfsProbe("sysinfo")()
+	})
+}
+
+// NewLPARCollector returns a new Collector exposing the CPU capacity of the
+// logical partition on POWER and IBM Z.
+func NewLPARCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, lparSubsystem, name), help, labels, nil)
+	}
+	return &lparCollector{
+		info:                desc("info", "Number, name and processor mode, shared or dedicated, of the logical partition.", "number", "name", "mode"),
+		cpus:                desc("cpus", "Number of CPUs of the logical partition by state.", "state"),
+		entitledCPUs:        desc("entitled_cpus", "Processor capacity the logical partition is entitled to, in CPUs."),
+		capped:              desc("capped", "Whether the logical partition can't use more than its entitled capacity."),
+		capacityWeight:      desc("capacity_weight", "Weight of the logical partition when sharing the unused capacity of the pool."),
+		poolCPUs:            desc("pool_cpus", "Number of physical CPUs in the shared processor pool."),
+		poolIdleSeconds:     desc("pool_idle_seconds_total", "Idle time of the physical CPUs of the shared processor pool, 0 unless the partition may collect pool information."),
+		physicalCPUSeconds:  desc("physical_cpu_seconds_total", "Physical processor time used by the logical partition, from its PURR."),
+		dispatches:          desc("dispatches_total", "Number of times the virtual CPUs of the logical partition were dispatched by the hypervisor."),
+		dispatchDispersions: desc("dispatch_dispersions_total", "Number of times the virtual CPUs were dispatched on another physical CPU than before."),
+		logger:              logger,
+	}, nil
+}
+
+func (c *lparCollector) Update(ch chan<- prometheus.Metric) error {
+	err := c.updatePOWER(ch)
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	err = c.updateIBMZ(ch)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoData
+	}
+	return err
+}
+
+// updatePOWER exposes /proc/powerpc/lparcfg.
+func (c *lparCollector) updatePOWER(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("powerpc/lparcfg"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	lparcfg, err := parseLparcfg(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse lparcfg: %w", err)
+	}
+
+	mode := "dedicated"
+	if lparcfg["shared_processor_mode"] == 1 {
+		mode = "shared"
+	}
+	name, err := os.ReadFile(sysFilePath("firmware/devicetree/base/ibm,partition-name"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+		strconv.FormatUint(lparcfg["partition_id"], 10), strings.TrimRight(string(name), "\x00\n"), mode)
+
+	for state, key := range map[string]string{
+		"active":    "partition_active_processors",
+		"potential": "partition_potential_processors",
+	} {
+		if value, ok := lparcfg[key]; ok {
+			ch <- prometheus.MustNewConstMetric(c.cpus, prometheus.GaugeValue, float64(value), state)
+		}
+	}
+	// The capacities are in hundredths of a CPU.
+	for desc, key := range map[*prometheus.Desc]string{
+		c.entitledCPUs: "partition_entitled_capacity",
+		c.poolCPUs:     "pool_capacity",
+	} {
+		if value, ok := lparcfg[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value)/100)
+		}
+	}
+	for desc, key := range map[*prometheus.Desc]string{
+		c.capped:         "capped",
+		c.capacityWeight: "capacity_weight",
+	} {
+		if value, ok := lparcfg[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+		}
+	}
+	for desc, key := range map[*prometheus.Desc]string{
+		c.dispatches:          "dispatches",
+		c.dispatchDispersions: "dispatch_dispersions",
+	} {
+		if value, ok := lparcfg[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value))
+		}
+	}
+
+	// The times are in ticks of the time base.
+	timebase, err := readPOWERTimebase()
+	if err != nil {
+		return fmt.Errorf("couldn't get time base frequency: %w", err)
+	}
+	for desc, key := range map[*prometheus.Desc]string{
+		c.poolIdleSeconds:    "pool_idle_time",
+		c.physicalCPUSeconds: "purr",
+	} {
+		if value, ok := lparcfg[key]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value)/timebase)
+		}
+	}
+	return nil
+}
+
+// updateIBMZ exposes the LPAR fields of /proc/sysinfo.
+func (c *lparCollector) updateIBMZ(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("sysinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	sysinfo, err := parseSysinfo(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse sysinfo: %w", err)
+	}
+	number, ok := sysinfo["LPAR Number"]
+	if !ok {
+		// Not running in an LPAR.
+		return os.ErrNotExist
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1,
+		number, sysinfo["LPAR Name"], strings.ToLower(sysinfo["LPAR Characteristics"]))
+	for _, state := range []string{"Total", "Configured", "Standby", "Reserved", "Dedicated", "Shared"} {
+		value, ok := sysinfo["LPAR CPUs "+state]
+		if !ok {
+			continue
+		}
+		cpus, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid LPAR CPUs %s %q: %w", state, value, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.cpus, prometheus.GaugeValue, cpus, strings.ToLower(state))
+	}
+	return nil
+}
+
+// parseLparcfg returns the numeric fields of /proc/powerpc/lparcfg.
+func parseLparcfg(r io.Reader) (map[string]uint64, error) {
+	lparcfg := map[string]uint64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		// Some fields like backing_memory have a unit.
+		value, _, _ = strings.Cut(value, " ")
+		if n, err := strconv.ParseUint(value, 10, 64); err == nil {
+			lparcfg[key] = n
+		}
+	}
+	return lparcfg, scanner.Err()
+}
+
+// parseSysinfo returns the fields of /proc/sysinfo by name.
+func parseSysinfo(r io.Reader) (map[string]string, error) {
+	sysinfo := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		sysinfo[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sysinfo, scanner.Err()
+}
+
+// readPOWERTimebase returns the frequency of the time base register from
+// /proc/cpuinfo.
+func readPOWERTimebase() (float64, error) {
+	file, err := os.Open(procFilePath("cpuinfo"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(key) != "timebase" {
+			continue
+		}
+		timebase, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || timebase <= 0 {
+			return 0, fmt.Errorf("invalid timebase %q", value)
+		}
+		return timebase, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, ErrNoData
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nolpar
+// +build !nolpar
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLPAR(t *testing.T) {
+	savedProcPath, savedSysPath := *procPath, *sysPath
+	defer func() { *procPath, *sysPath = savedProcPath, savedSysPath }()
+
+	for _, test := range []struct {
+		platform string
+		want     string
+	}{
+		{
+			platform: "ppc64le",
+			want: `# HELP node_lpar_capacity_weight Weight of the logical partition when sharing the unused capacity of the pool.
+# TYPE node_lpar_capacity_weight gauge
+node_lpar_capacity_weight 128
+# HELP node_lpar_capped Whether the logical partition can't use more than its entitled capacity.
+# TYPE node_lpar_capped gauge
+node_lpar_capped 0
+# HELP node_lpar_cpus Number of CPUs of the logical partition by state.
+# TYPE node_lpar_cpus gauge
+node_lpar_cpus{state="active"} 4
+node_lpar_cpus{state="potential"} 8
+# HELP node_lpar_dispatch_dispersions_total Number of times the virtual CPUs were dispatched on another physical CPU than before.
+# TYPE node_lpar_dispatch_dispersions_total counter
+node_lpar_dispatch_dispersions_total 789
+# HELP node_lpar_dispatches_total Number of times the virtual CPUs of the logical partition were dispatched by the hypervisor.
+# TYPE node_lpar_dispatches_total counter
+node_lpar_dispatches_total 123456
+# HELP node_lpar_entitled_cpus Processor capacity the logical partition is entitled to, in CPUs.
+# TYPE node_lpar_entitled_cpus gauge
+node_lpar_entitled_cpus 1.5
+# HELP node_lpar_info Number, name and processor mode, shared or dedicated, of the logical partition.
+# TYPE node_lpar_info gauge
+node_lpar_info{mode="shared",name="lpar-db01",number="5"} 1
+# HELP node_lpar_physical_cpu_seconds_total Physical processor time used by the logical partition, from its PURR.
+# TYPE node_lpar_physical_cpu_seconds_total counter
+node_lpar_physical_cpu_seconds_total 2000
+# HELP node_lpar_pool_cpus Number of physical CPUs in the shared processor pool.
+# TYPE node_lpar_pool_cpus gauge
+node_lpar_pool_cpus 40
+# HELP node_lpar_pool_idle_seconds_total Idle time of the physical CPUs of the shared processor pool, 0 unless the partition may collect pool information.
+# TYPE node_lpar_pool_idle_seconds_total counter
+node_lpar_pool_idle_seconds_total 6000
+`,
+		},
+		{
+			platform: "s390x",
+			want: `# HELP node_lpar_cpus Number of CPUs of the logical partition by state.
+# TYPE node_lpar_cpus gauge
+node_lpar_cpus{state="configured"} 16
+node_lpar_cpus{state="dedicated"} 0
+node_lpar_cpus{state="reserved"} 0
+node_lpar_cpus{state="shared"} 16
+node_lpar_cpus{state="standby"} 16
+node_lpar_cpus{state="total"} 32
+# HELP node_lpar_info Number, name and processor mode, shared or dedicated, of the logical partition.
+# TYPE node_lpar_info gauge
+node_lpar_info{mode="shared",name="Z05",number="47"} 1
+`,
+		},
+	} {
+		*procPath = "fixtures/lpar/" + test.platform + "/proc"
+		*sysPath = "fixtures/lpar/" + test.platform + "/sys"
+		c, err := NewLPARCollector(log.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(collectorAdapter{c})
+		if err := testutil.GatherAndCompare(reg, strings.NewReader(test.want)); err != nil {
+			t.Errorf("%s: %v", test.platform, err)
+		}
+	}
+}
@@ -56,12 +56,18 @@ func NewMeminfoCollector(logger log.Logger) (Collector, error) {
 // memory metrics.
 // 定义了一个名为Update的方法，该方法接受一个类型为chan<- prometheus.Metric的通道ch，并返回一个error。它用于更新内存指标
 func (c *meminfoCollector) Update(ch chan<- prometheus.Metric) error {
-	var metricType prometheus.ValueType //定义变量metricType
 	//调用getMemInfo方法，获取特定平台的内存指标信息，并将结果存储在memInfo变量中。如果有错误发生，将返回err
 	memInfo, err := c.getMemInfo()
 	if err != nil {
 		return fmt.Errorf("couldn't get meminfo: %w", err)
 	}
+	c.updateMemInfo(ch, memInfo)
+	return nil
+}
+
+// updateMemInfo exports the given memory metrics.
+func (c *meminfoCollector) updateMemInfo(ch chan<- prometheus.Metric, memInfo map[string]float64) {
+	var metricType prometheus.ValueType //定义变量metricType
 	level.Debug(c.logger).Log("msg", "Set node_mem", "memInfo", memInfo)
 	for k, v := range memInfo { //遍历memInfo映射中的键值对。其中k表示字段名称，v表示对应的值
 		//检查字段名称k是否以"_total"结尾，如果是，则将metricType设置为prometheus.CounterValue，表示计数器类型的指标；
@@ -85,5 +91,4 @@ func (c *meminfoCollector) Update(ch chan<- prometheus.Metric) error {
 			metricType, v,
 		)
 	}
-	return nil //返回空值，表示没有发生错误
 }
@@ -19,12 +19,15 @@ package collector //定义了一个名为collector的Go包
 //导入了一些Go标准库，以及在其他文件中定义的一些内部包
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 //定义了一个名为reParens的正则表达式变量，用于匹配括号中的内容
@@ -46,6 +49,20 @@ func (c *meminfoCollector) getMemInfo() (map[string]float64, error) {
 	return parseMemInfo(file) //调用parseMemInfo函数，将打开的文件file作为参数进行解析
 }
 
+// updateFromSnapshot implements snapshotCollector.
+func (c *meminfoCollector) updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error {
+	data, err := s.MemInfo()
+	if err != nil {
+		return fmt.Errorf("couldn't get meminfo: %w", err)
+	}
+	memInfo, err := parseMemInfo(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("couldn't get meminfo: %w", err)
+	}
+	c.updateMemInfo(ch, memInfo)
+	return nil
+}
+
 //定义了parseMemInfo函数，它接收一个io.Reader类型的参数r，并返回一个map[string]float64类型的值和一个error类型的值。
 //该函数用于解析从meminfo文件读取的内容，并将其转换为内存信息的键值对
 func parseMemInfo(r io.Reader) (map[string]float64, error) {
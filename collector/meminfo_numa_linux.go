@@ -18,6 +18,8 @@ package collector
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -25,9 +27,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"golang.org/x/sys/unix"
 )
 
 //定义了一个常量 memInfoNumaSubsystem，它的值是字符串 "memory_numa"。
@@ -42,6 +48,34 @@ const (
 //并提取出节点号（在这个例子中是数字 1）
 var meminfoNodeRE = regexp.MustCompile(`.*devices/system/node/node([0-9]*)`)
 
+// meminfoParenRE matches the parenthesized part of field names like
+// Active(anon).
+var meminfoParenRE = regexp.MustCompile(`\((.*)\)`)
+
+// numaReclaimFields are the fields of the per node vmstat file related to
+// reclaim. The kernel counts kswapd wakeups and allocation stalls only for the
+// whole system, they are exposed by the vmstat collector.
+var numaReclaimFields = map[string]bool{
+	"pgscan_kswapd":               true,
+	"pgscan_direct":               true,
+	"pgscan_khugepaged":           true,
+	"pgsteal_kswapd":              true,
+	"pgsteal_direct":              true,
+	"pgsteal_khugepaged":          true,
+	"pgdemote_kswapd":             true,
+	"pgdemote_direct":             true,
+	"pgdemote_khugepaged":         true,
+	"pgdemote_proactive":          true,
+	"pgpromote_success":           true,
+	"workingset_nodereclaim":      true,
+	"nr_vmscan_write":             true,
+	"nr_vmscan_immediate_reclaim": true,
+}
+
+// numaNodeUeventPrefix is the start of the devpath of uevents sent when a
+// NUMA node is hotplugged, after the action and the '@'.
+var numaNodeUeventPrefix = []byte("/devices/system/node/node")
+
 //定义了一个名为 meminfoMetric 的结构体类型。
 //这个结构体用于存储内存指标的相关信息，包括指标名称、指标类型、节点号和数值
 type meminfoMetric struct {
@@ -54,8 +88,32 @@ type meminfoMetric struct {
 //定义了一个名为 meminfoNumaCollector 的结构体类型。
 //这个结构体表示一个内存统计收集器，包含了存储指标描述符的映射和一个日志记录器
 type meminfoNumaCollector struct {
-	metricDescs map[string]*prometheus.Desc
-	logger      log.Logger
+	metricDescs       map[string]*prometheus.Desc
+	watermarkBreached *prometheus.Desc
+	hugepages         *prometheus.Desc
+	hugepagesFree     *prometheus.Desc
+	hugepagesSurplus  *prometheus.Desc
+	nodes             *numaNodeCache
+	fs                procfs.FS
+	logger            log.Logger
+}
+
+// numaNode is a NUMA node directory in sysfs and the number of the node.
+type numaNode struct {
+	path   string
+	number string
+}
+
+// numaNodeCache caches the NUMA nodes found in sysfs. The list is only
+// refreshed after the kernel announced the hotplug of a node, or on every
+// scrape if uevents can't be received.
+type numaNodeCache struct {
+	once     sync.Once
+	mtx      sync.Mutex
+	watching bool
+	stale    bool
+	nodes    []numaNode
+	logger   log.Logger
 }
 
 //这是一个初始化函数 init，它在包被导入时自动执行。
@@ -63,7 +121,8 @@ type meminfoNumaCollector struct {
 //将收集器的名称、默认禁用状态和 NewMeminfoNumaCollector 函数作为参数传递给它。
 //这个函数的作用是注册内存统计收集器
 func init() {
-	registerCollector("meminfo_numa", defaultDisabled, NewMeminfoNumaCollector)
+	registerCollector("meminfo_numa", defaultEnabled, NewMeminfoNumaCollector)
+	registerHardwareProbe("meminfo_numa", "no NUMA nodes", sysfsProbe("devices/system/node/node[0-9]*"))
 }
 
 //这是一个构造函数 NewMeminfoNumaCollector，它返回一个新的内存统计收集器。
@@ -72,9 +131,35 @@ func init() {
 //其中的 metricDescs 字段被初始化为空的映射，而 logger 字段则被设置为传入的日志记录器
 // NewMeminfoNumaCollector returns a new Collector exposing memory stats.
 func NewMeminfoNumaCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
 	return &meminfoNumaCollector{
 		metricDescs: map[string]*prometheus.Desc{},
-		logger:      logger,
+		watermarkBreached: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memInfoNumaSubsystem, "zone_watermark_breached"),
+			"Whether the free pages of a memory zone are below a watermark, kswapd is woken up below the low one and allocations reclaim directly below the min one.",
+			[]string{"node", "zone", "watermark"}, nil,
+		),
+		hugepages: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memInfoNumaSubsystem, "hugepages_pages"),
+			"Number of huge pages in the pool of the node, by page size in bytes.",
+			[]string{"node", "size"}, nil,
+		),
+		hugepagesFree: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memInfoNumaSubsystem, "hugepages_free_pages"),
+			"Number of huge pages of the node not allocated yet, by page size in bytes.",
+			[]string{"node", "size"}, nil,
+		),
+		hugepagesSurplus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, memInfoNumaSubsystem, "hugepages_surplus_pages"),
+			"Number of huge pages of the node allocated above the size of the pool, by page size in bytes.",
+			[]string{"node", "size"}, nil,
+		),
+		nodes:  &numaNodeCache{stale: true, logger: logger},
+		fs:     fs,
+		logger: logger,
 	}, nil
 }
 
@@ -82,8 +167,16 @@ func NewMeminfoNumaCollector(logger log.Logger) (Collector, error) {
 //它实现了 Collector 接口中的 Update 方法。
 //这个方法用于更新收集器中的指标，并将其发送到传入的通道 ch 中。
 func (c *meminfoNumaCollector) Update(ch chan<- prometheus.Metric) error {
-	metrics, err := getMemInfoNuma() //调用 getMemInfoNuma 函数获取内存统计信息，并将结果保存在 metrics 变量中。
+	nodes, err := c.nodes.get()
+	if err != nil {
+		return fmt.Errorf("couldn't list NUMA nodes: %w", err)
+	}
+	metrics, err := getMemInfoNuma(nodes) //调用 getMemInfoNuma 函数获取内存统计信息，并将结果保存在 metrics 变量中。
 	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// A node went away since the list was cached.
+			c.nodes.invalidate()
+		}
 		return fmt.Errorf("couldn't get NUMA meminfo: %w", err)
 	}
 	for _, v := range metrics { //遍历指标
@@ -100,75 +193,202 @@ func (c *meminfoNumaCollector) Update(ch chan<- prometheus.Metric) error {
 		//使用 desc 和指标的类型、数值和节点号创建一个常量指标，并将其发送到通道 ch 中
 		ch <- prometheus.MustNewConstMetric(desc, v.metricType, v.value, v.numaNode)
 	}
+	if err := c.updateHugepages(ch, nodes); err != nil {
+		return fmt.Errorf("couldn't get NUMA huge pages: %w", err)
+	}
+
+	zones, err := c.fs.Zoneinfo()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("couldn't get zoneinfo: %w", err)
+	}
+	for _, zone := range zones {
+		// Zones without memory, like Movable on most systems, have all
+		// watermarks at 0.
+		if zone.NrFreePages == nil || zone.Managed == nil || *zone.Managed == 0 {
+			continue
+		}
+		for watermark, pages := range map[string]*int64{"min": zone.Min, "low": zone.Low, "high": zone.High} {
+			if pages == nil {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.watermarkBreached, prometheus.GaugeValue, boolToFloat(*zone.NrFreePages < *pages), zone.Node, zone.Zone, watermark)
+		}
+	}
 	return nil
 }
 
-//这是 getMemInfoNuma 函数，用于获取内存统计信息。
-//该函数返回一个[]meminfoMetric类型的切片和一个error类型的错误对象
-func getMemInfoNuma() ([]meminfoMetric, error) {
-	//声明一个名为metrics的空切片，用于存储内存信息
-	var (
-		metrics []meminfoMetric
-	)
+// updateHugepages exposes the huge page pools of the nodes by page size. The
+// meminfo file of nodes only has the pool of the default size.
+func (c *meminfoNumaCollector) updateHugepages(ch chan<- prometheus.Metric, nodes []numaNode) error {
+	for _, node := range nodes {
+		// The directories are absent without CONFIG_HUGETLBFS.
+		pools, err := filepath.Glob(filepath.Join(node.path, "hugepages/hugepages-*kB"))
+		if err != nil {
+			return err
+		}
+		for _, pool := range pools {
+			sizeKB, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(pool), "hugepages-"), "kB"), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid huge page size of %s: %w", pool, err)
+			}
+			size := strconv.FormatUint(sizeKB*1024, 10)
+			for desc, file := range map[*prometheus.Desc]string{
+				c.hugepages:        "nr_hugepages",
+				c.hugepagesFree:    "free_hugepages",
+				c.hugepagesSurplus: "surplus_hugepages",
+			} {
+				value, err := readUintFromFile(filepath.Join(pool, file))
+				if err != nil {
+					return err
+				}
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), node.number, size)
+			}
+		}
+	}
+	return nil
+}
+
+// get returns the cached NUMA nodes, listing them again if a node was
+// hotplugged since the last call.
+func (c *numaNodeCache) get() ([]numaNode, error) {
+	c.once.Do(c.watch)
 
-        //使用 filepath.Glob 函数查找匹配模式 "devices/system/node/node[0-9]*" 的文件路径
-	//该模式用于找到NUMA节点。如果发生错误，则返回nil和错误对象
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.stale && c.watching {
+		return c.nodes, nil
+	}
 	nodes, err := filepath.Glob(sysFilePath("devices/system/node/node[0-9]*"))
 	if err != nil {
 		return nil, err
 	}
+	// Callers keep using the returned slice without the lock, so a fresh one
+	// replaces it instead of reusing its backing array.
+	list := make([]numaNode, 0, len(nodes))
 	for _, node := range nodes {
-		//打开每个节点的 meminfo 文件，将其赋值给meminfoFile变量
-		//如果发生错误，则返回nil和错误对象
-		meminfoFile, err := os.Open(filepath.Join(node, "meminfo"))
-		if err != nil {
-			return nil, err
+		nodeNumber := meminfoNodeRE.FindStringSubmatch(node)
+		if nodeNumber == nil {
+			return nil, fmt.Errorf("device node string didn't match regexp: %s", node)
+		}
+		list = append(list, numaNode{path: node, number: nodeNumber[1]})
+	}
+	c.nodes = list
+	c.stale = false
+	return c.nodes, nil
+}
+
+// invalidate makes the next call to get list the nodes again.
+func (c *numaNodeCache) invalidate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.stale = true
+}
+
+// watch subscribes to the kernel uevents to invalidate the cache when a node
+// is hotplugged. If that fails, the nodes are listed on every call to get.
+func (c *numaNodeCache) watch() {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "Couldn't subscribe to uevents, listing NUMA nodes on every scrape", "err", err)
+		return
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: 1}); err != nil {
+		unix.Close(fd)
+		level.Debug(c.logger).Log("msg", "Couldn't subscribe to uevents, listing NUMA nodes on every scrape", "err", err)
+		return
+	}
+
+	c.mtx.Lock()
+	c.watching = true
+	c.mtx.Unlock()
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, os.Getpagesize())
+		for {
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			switch {
+			case errors.Is(err, unix.EINTR):
+				continue
+			case errors.Is(err, unix.ENOBUFS):
+				// Uevents were dropped, one of them may have been about a node.
+				c.invalidate()
+				continue
+			case err != nil:
+				level.Warn(c.logger).Log("msg", "Couldn't receive uevents, listing NUMA nodes on every scrape", "err", err)
+				c.mtx.Lock()
+				c.watching = false
+				c.mtx.Unlock()
+				return
+			}
+			if isNumaNodeUevent(buf[:n]) {
+				c.invalidate()
+			}
 		}
-		//使用defer关键字延迟关闭meminfoFile，确保它在后续代码执行完毕后被关闭
-		defer meminfoFile.Close()
+	}()
+}
 
-		//调用parseMemInfoNuma()函数，传递meminfoFile作为参数，以解析文件中的内存信息。
-		//将返回值赋给numaInfo变量。如果发生错误，则返回nil和错误对象
-		numaInfo, err := parseMemInfoNuma(meminfoFile)
+// isNumaNodeUevent returns whether a uevent message, which starts with a
+// header like "add@/devices/system/node/node1", is about a NUMA node.
+func isNumaNodeUevent(msg []byte) bool {
+	header, _, _ := bytes.Cut(msg, []byte{0})
+	_, devpath, ok := bytes.Cut(header, []byte("@"))
+	return ok && bytes.HasPrefix(devpath, numaNodeUeventPrefix)
+}
+
+//这是 getMemInfoNuma 函数，用于获取内存统计信息。
+//该函数返回一个[]meminfoMetric类型的切片和一个error类型的错误对象
+func getMemInfoNuma(nodes []numaNode) ([]meminfoMetric, error) {
+	//声明一个名为metrics的空切片，用于存储内存信息
+	var (
+		metrics []meminfoMetric
+	)
+
+	for _, node := range nodes {
+		numaInfo, err := readMemInfoNumaFile(filepath.Join(node.path, "meminfo"), parseMemInfoNuma)
 		if err != nil {
 			return nil, err
 		}
-		
 		//使用append()函数将numaInfo中的所有元素追加到metrics切片中
 		metrics = append(metrics, numaInfo...)
 
-		//打开每个节点的 numastat 文件，并将其赋值给numastatFile变量。
-		//如果发生错误，则返回nil和错误对象
-		numastatFile, err := os.Open(filepath.Join(node, "numastat"))
+		numaStat, err := readMemInfoNumaFile(filepath.Join(node.path, "numastat"), func(r io.Reader) ([]meminfoMetric, error) {
+			return parseMemInfoNumaStat(r, node.number)
+		})
 		if err != nil {
 			return nil, err
 		}
-		//使用defer关键字延迟关闭numastatFile
-		defer numastatFile.Close()
-
-		//使用正则表达式（meminfoNodeRE.FindStringSubmatch(node)）从当前节点路径中提取节点编号。
-		//将提取的节点编号存储在nodeNumber变量中。
-		//如果提取失败（nodeNumber == nil），则返回错误对象，指示设备节点字符串与正则表达式不匹配
-		nodeNumber := meminfoNodeRE.FindStringSubmatch(node)
-		if nodeNumber == nil {
-			return nil, fmt.Errorf("device node string didn't match regexp: %s", node)
-		}
+		//使用append()函数将numaStat中的所有元素追加到metrics切片中
+		metrics = append(metrics, numaStat...)
 
-		//调用parseMemInfoNumaStat()函数，传递numastatFile和提取的节点编号nodeNumber[1]作为参数，
-		//以解析文件中的NUMA统计信息。将返回值赋给numaStat变量。
-		//如果发生错误，则返回nil和错误对象
-		numaStat, err := parseMemInfoNumaStat(numastatFile, nodeNumber[1])
-		if err != nil {
+		// The vmstat file of nodes was added in Linux 2.6.37.
+		numaVmstat, err := readMemInfoNumaFile(filepath.Join(node.path, "vmstat"), func(r io.Reader) ([]meminfoMetric, error) {
+			return parseMemInfoNumaVmstat(r, node.number)
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return nil, err
 		}
-		//使用append()函数将numaStat中的所有元素追加到metrics切片中
-		metrics = append(metrics, numaStat...)
+		metrics = append(metrics, numaVmstat...)
 	}
 
 	//在遍历完所有NUMA节点后，返回包含所有内存信息的metrics切片，并返回nil错误，表示成功
 	return metrics, nil
 }
 
+// readMemInfoNumaFile parses a file of a node, closing it before returning
+// rather than after all nodes were read.
+func readMemInfoNumaFile(path string, parse func(io.Reader) ([]meminfoMetric, error)) ([]meminfoMetric, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parse(f)
+}
+
 //这是 parseMemInfoNuma 函数，用于解析 meminfo 文件的内容。
 //它接收一个实现了 io.Reader 接口的参数 r，并返回解析得到的指标信息。
 func parseMemInfoNuma(r io.Reader) ([]meminfoMetric, error) {
@@ -176,7 +396,6 @@ func parseMemInfoNuma(r io.Reader) ([]meminfoMetric, error) {
 		memInfo []meminfoMetric //创建一个空的指标切片 memInfo
 		//使用 bufio.NewScanner 函数创建一个bufio.Scanner对象，用于逐行读取输入的内容
 		scanner = bufio.NewScanner(r)
-		re      = regexp.MustCompile(`\((.*)\)`) //定义了一个正则表达式对象re，用于匹配括号中的内容
 	)
 
 	for scanner.Scan() { //通过scanner.Scan()循环读取每一行的内容,逐行扫描输入
@@ -205,7 +424,7 @@ func parseMemInfoNuma(r io.Reader) ([]meminfoMetric, error) {
 
 		// Active(anon) -> Active_anon
 		//使用正则表达式re替换metric中的括号内容，将括号内的内容替换为_${1}。
-		metric = re.ReplaceAllString(metric, "_${1}")
+		metric = meminfoParenRE.ReplaceAllString(metric, "_${1}")
 		//将metric、prometheus.GaugeValue、parts[1]和fv作为字段值，创建一个meminfoMetric结构体，
 		//并将其追加到memInfo切片中
 		memInfo = append(memInfo, meminfoMetric{metric, prometheus.GaugeValue, parts[1], fv})
@@ -251,3 +470,24 @@ func parseMemInfoNumaStat(r io.Reader, nodeNumber string) ([]meminfoMetric, erro
 	//返回存储解析后的NUMA统计信息的numaStat切片，并返回scanner.Err()，表示解析过程中的错误（如果有）
 	return numaStat, scanner.Err()
 }
+
+// parseMemInfoNumaVmstat parses the reclaim related counters of the vmstat
+// file of a node.
+func parseMemInfoNumaVmstat(r io.Reader, nodeNumber string) ([]meminfoMetric, error) {
+	var (
+		vmstat  []meminfoMetric
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 || !numaReclaimFields[parts[0]] {
+			continue
+		}
+		fv, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in vmstat: %w", err)
+		}
+		vmstat = append(vmstat, meminfoMetric{parts[0] + "_total", prometheus.CounterValue, nodeNumber, fv})
+	}
+	return vmstat, scanner.Err()
+}
@@ -105,3 +105,67 @@ func TestMemInfoNumaStat(t *testing.T) {
 		t.Errorf("want numa stat other_node %f, got %f", want, got)
 	}
 }
+
+func TestIsNumaNodeUevent(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"online@/devices/system/node/node1\x00ACTION=online\x00DEVPATH=/devices/system/node/node1\x00SUBSYSTEM=node": true,
+		"add@/devices/system/node/node12\x00ACTION=add":                                                              true,
+		"online@/devices/system/memory/memory32\x00ACTION=online\x00SUBSYSTEM=memory":                                false,
+		"add@/devices/virtual/net/veth0\x00DEVPATH=/devices/system/node/node1":                                       false,
+		"libudev\x00\xfe\xed\xca\xfe":                                                                                false,
+	} {
+		if got := isNumaNodeUevent([]byte(msg)); got != want {
+			t.Errorf("isNumaNodeUevent(%q) = %t, want %t", msg, got, want)
+		}
+	}
+}
+
+func TestNumaNodeCacheGet(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	c := &numaNodeCache{stale: true}
+	// Don't subscribe to uevents, so every call lists the nodes again.
+	c.once.Do(func() {})
+
+	first, err := c.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) == 0 {
+		t.Fatal("no NUMA nodes found")
+	}
+	want := first[0]
+	second, err := c.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &first[0] == &second[0] {
+		t.Error("listing the nodes again reused the slice returned before")
+	}
+	if first[0] != want {
+		t.Errorf("listing the nodes again changed the slice returned before to %v, want %v", first[0], want)
+	}
+}
+
+func TestMemInfoNumaVmstat(t *testing.T) {
+	file, err := os.Open("fixtures/sys/devices/system/node/node0/vmstat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	vmstat, err := parseMemInfoNumaVmstat(file, "0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the reclaim related fields are kept.
+	if want, got := 10, len(vmstat); want != got {
+		t.Fatalf("want %d fields, got %d", want, got)
+	}
+	if want, got := "pgscan_kswapd_total", vmstat[3].metricName; want != got {
+		t.Errorf("want metric %s, got %s", want, got)
+	}
+	if want, got := 58241.0, vmstat[3].value; want != got {
+		t.Errorf("want pgscan_kswapd %f, got %f", want, got)
+	}
+}
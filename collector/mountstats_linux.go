@@ -32,6 +32,7 @@ var (
 
 type mountStatsCollector struct {
 	// General statistics
+	NFSMountInfo       *prometheus.Desc
 	NFSAgeSecondsTotal *prometheus.Desc
 
 	// Byte statistics
@@ -106,6 +107,15 @@ type nfsDeviceIdentifier struct {
 	MountAddress string
 }
 
+// used to expose each mount point of an NFS mount once
+type nfsMountPoint struct {
+	Device       string
+	Protocol     string
+	MountAddress string
+	MountPoint   string
+	Version      string
+}
+
 func init() {
 	registerCollector("mountstats", defaultDisabled, NewMountStatsCollector)
 }
@@ -133,6 +143,12 @@ func NewMountStatsCollector(logger log.Logger) (Collector, error) {
 	)
 
 	return &mountStatsCollector{
+		NFSMountInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "mount_info"),
+			"Mount points of an NFS export, mounts of the same export share their statistics.",
+			[]string{"export", "protocol", "mountaddr", "mountpoint", "version"},
+			nil,
+		),
 		NFSAgeSecondsTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, subsystem, "age_seconds_total"),
 			"The age of the NFS mount in seconds.",
@@ -522,6 +538,7 @@ func (c *mountStatsCollector) Update(ch chan<- prometheus.Metric) error {
 
 	// store all seen nfsDeviceIdentifiers for deduplication
 	deviceList := make(map[nfsDeviceIdentifier]bool)
+	mountPoints := make(map[nfsMountPoint]bool)
 
 	for idx, m := range mounts {
 		// For the time being, only NFS statistics are available via this mechanism
@@ -531,11 +548,19 @@ func (c *mountStatsCollector) Update(ch chan<- prometheus.Metric) error {
 			continue
 		}
 
-		var mountAddress string
+		var mountAddress, version string
 		if idx < len(mountsInfo) {
 			// The mount entry order in the /proc/self/mountstats and /proc/self/mountinfo is the same.
 			miStats := mountsInfo[idx]
 			mountAddress = miStats.SuperOptions["addr"]
+			version = miStats.SuperOptions["vers"]
+		}
+
+		mountPoint := nfsMountPoint{m.Device, stats.Transport.Protocol, mountAddress, m.Mount, version}
+		if !mountPoints[mountPoint] {
+			mountPoints[mountPoint] = true
+			ch <- prometheus.MustNewConstMetric(c.NFSMountInfo, prometheus.GaugeValue, 1,
+				m.Device, stats.Transport.Protocol, mountAddress, m.Mount, version)
 		}
 
 		deviceIdentifier := nfsDeviceIdentifier{m.Device, stats.Transport.Protocol, mountAddress}
@@ -0,0 +1,161 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noneighbor
+// +build !noneighbor
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/jsimonetti/rtnetlink"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const neighborSubsystem = "neighbor"
+
+var (
+	neighborDeviceInclude = kingpin.Flag("collector.neighbor.device-include", "Regexp of devices to include (mutually exclusive to device-exclude).").String()
+	neighborDeviceExclude = kingpin.Flag("collector.neighbor.device-exclude", "Regexp of devices to exclude (mutually exclusive to device-include).").String()
+)
+
+// neighborStates are the NUD_* states of neighbor entries, see rtnetlink(7).
+var neighborStates = []struct {
+	bit  uint16
+	name string
+}{
+	{unix.NUD_INCOMPLETE, "incomplete"},
+	{unix.NUD_REACHABLE, "reachable"},
+	{unix.NUD_STALE, "stale"},
+	{unix.NUD_DELAY, "delay"},
+	{unix.NUD_PROBE, "probe"},
+	{unix.NUD_FAILED, "failed"},
+	{unix.NUD_NOARP, "noarp"},
+	{unix.NUD_PERMANENT, "permanent"},
+}
+
+// neighborFamilies are the neighbor tables by address family, with their
+// directory in /proc/sys/net.
+var neighborFamilies = map[uint16]string{
+	unix.AF_INET:  "ipv4",
+	unix.AF_INET6: "ipv6",
+}
+
+type neighborCollector struct {
+	deviceFilter deviceFilter
+	entries      *prometheus.Desc
+	gcThresh     *prometheus.Desc
+	logger       log.Logger
+}
+
+// neighborKey identifies the neighbor entries of a device and an address
+// family.
+type neighborKey struct {
+	device, family string
+}
+
+func init() {
+	registerCollector(neighborSubsystem, defaultDisabled, NewNeighborCollector)
+}
+
+// NewNeighborCollector returns a new Collector exposing the size of the ARP
+// and NDP neighbor tables.
+func NewNeighborCollector(logger log.Logger) (Collector, error) {
+	return &neighborCollector{
+		deviceFilter: newDeviceFilter(*neighborDeviceExclude, *neighborDeviceInclude),
+		entries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, neighborSubsystem, "entries"),
+			"Number of neighbor table entries by device, address family and state.",
+			[]string{"device", "family", "state"}, nil,
+		),
+		gcThresh: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, neighborSubsystem, "gc_thresh"),
+			"Garbage collection thresholds of the neighbor table, no entries are added once gc_thresh3 is reached.",
+			[]string{"family", "threshold"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *neighborCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := rtnetlink.Dial(nil)
+	if err != nil {
+		return fmt.Errorf("couldn't connect rtnetlink: %w", err)
+	}
+	defer conn.Close()
+
+	links, err := conn.Link.List()
+	if err != nil {
+		return fmt.Errorf("couldn't get links: %w", err)
+	}
+	names := make(map[uint32]string, len(links))
+	for _, link := range links {
+		names[link.Index] = link.Attributes.Name
+	}
+	neighs, err := conn.Neigh.List()
+	if err != nil {
+		return fmt.Errorf("couldn't get neighbors: %w", err)
+	}
+
+	for key, states := range countNeighbors(neighs, names) {
+		if c.deviceFilter.ignored(key.device) {
+			continue
+		}
+		for i, s := range neighborStates {
+			ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(states[i]), key.device, key.family, s.name)
+		}
+	}
+
+	for _, family := range neighborFamilies {
+		for _, threshold := range []string{"gc_thresh1", "gc_thresh2", "gc_thresh3"} {
+			v, err := readUintFromFile(procFilePath(filepath.Join("sys/net", family, "neigh/default", threshold)))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return err
+			}
+			ch <- prometheus.MustNewConstMetric(c.gcThresh, prometheus.GaugeValue, float64(v), family, threshold)
+		}
+	}
+	return nil
+}
+
+// countNeighbors counts the ARP and NDP entries by device and state, the
+// counts are in the order of neighborStates.
+func countNeighbors(neighs []rtnetlink.NeighMessage, names map[uint32]string) map[neighborKey][]uint64 {
+	counts := make(map[neighborKey][]uint64)
+	for _, n := range neighs {
+		family, ok := neighborFamilies[n.Family]
+		if !ok {
+			continue
+		}
+		key := neighborKey{names[n.Index], family}
+		if counts[key] == nil {
+			counts[key] = make([]uint64, len(neighborStates))
+		}
+		for i, s := range neighborStates {
+			if n.State&s.bit != 0 {
+				counts[key][i]++
+			}
+		}
+	}
+	return counts
+}
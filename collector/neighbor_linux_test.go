@@ -0,0 +1,48 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noneighbor
+// +build !noneighbor
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jsimonetti/rtnetlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestCountNeighbors(t *testing.T) {
+	names := map[uint32]string{1: "lo", 2: "eth0"}
+	neighs := []rtnetlink.NeighMessage{
+		{Family: unix.AF_INET, Index: 2, State: unix.NUD_REACHABLE},
+		{Family: unix.AF_INET, Index: 2, State: unix.NUD_STALE},
+		{Family: unix.AF_INET, Index: 2, State: unix.NUD_STALE},
+		{Family: unix.AF_INET, Index: 2, State: unix.NUD_FAILED},
+		{Family: unix.AF_INET6, Index: 2, State: unix.NUD_PERMANENT},
+		{Family: unix.AF_INET6, Index: 1, State: unix.NUD_NOARP},
+		// Bridge FDB entries aren't neighbors of an IP protocol.
+		{Family: unix.AF_BRIDGE, Index: 2, State: unix.NUD_PERMANENT},
+	}
+
+	want := map[neighborKey][]uint64{
+		{"eth0", "ipv4"}: {0, 1, 2, 0, 0, 1, 0, 0},
+		{"eth0", "ipv6"}: {0, 0, 0, 0, 0, 0, 0, 1},
+		{"lo", "ipv6"}:   {0, 0, 0, 0, 0, 0, 1, 0},
+	}
+	if got := countNeighbors(neighs, names); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
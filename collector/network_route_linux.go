@@ -17,10 +17,14 @@
 package collector
 
 import (
+	"errors"
 	"fmt"
 	"golang.org/x/sys/unix"
+	"io"
 	"net"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/go-kit/log"
 	"github.com/jsimonetti/rtnetlink"
@@ -28,9 +32,26 @@ import (
 )
 
 type networkRouteCollector struct {
-	routeInfoDesc *prometheus.Desc
-	routesDesc    *prometheus.Desc
-	logger        log.Logger
+	routeInfoDesc     *prometheus.Desc
+	routesDesc        *prometheus.Desc
+	tableRoutesDesc   *prometheus.Desc
+	ipv6FIBStatsDescs []*prometheus.Desc
+	logger            log.Logger
+}
+
+// networkRouteIPv6FIBStats are the fields of /proc/net/rt6_stats, see
+// rt6_stats_seq_show in net/ipv6/route.c. Empty names are fields which aren't
+// exposed.
+var networkRouteIPv6FIBStats = []struct {
+	name, help string
+}{
+	{"fib_nodes", "Number of nodes of the IPv6 FIB tree."},
+	{"fib_route_nodes", "Number of nodes of the IPv6 FIB tree holding routes."},
+	{"", ""},
+	{"fib_route_entries", "Number of routes in the IPv6 FIB."},
+	{"fib_route_cache_entries", "Number of cached routes in the IPv6 FIB, e.g. for PMTU exceptions."},
+	{"dst_entries", "Number of IPv6 destination cache entries."},
+	{"fib_discarded_routes", "Number of IPv6 routes discarded while the FIB was dumped."},
 }
 
 func init() {
@@ -50,15 +71,31 @@ func NewNetworkRouteCollector(logger log.Logger) (Collector, error) {
 		"network routes by interface", []string{"device"}, nil,
 	)
 
+	tableRoutesDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "table_routes"),
+		"network routes by routing table and address family", []string{"table", "family"}, nil,
+	)
+	var ipv6FIBStatsDescs []*prometheus.Desc
+	for _, stat := range networkRouteIPv6FIBStats {
+		var desc *prometheus.Desc
+		if stat.name != "" {
+			desc = prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "ipv6_"+stat.name), stat.help, nil, nil)
+		}
+		ipv6FIBStatsDescs = append(ipv6FIBStatsDescs, desc)
+	}
+
 	return &networkRouteCollector{
-		routeInfoDesc: routeInfoDesc,
-		routesDesc:    routesDesc,
-		logger:        logger,
+		routeInfoDesc:     routeInfoDesc,
+		routesDesc:        routesDesc,
+		tableRoutesDesc:   tableRoutesDesc,
+		ipv6FIBStatsDescs: ipv6FIBStatsDescs,
+		logger:            logger,
 	}, nil
 }
 
 func (n networkRouteCollector) Update(ch chan<- prometheus.Metric) error {
 	deviceRoutes := make(map[string]int)
+	tableRoutes := make(map[[2]string]int)
 
 	conn, err := rtnetlink.Dial(nil)
 	if err != nil {
@@ -77,6 +114,13 @@ func (n networkRouteCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, route := range routes {
+		// Tables with IDs above 255 are only given as attribute.
+		table := uint32(route.Table)
+		if route.Attributes.Table != 0 {
+			table = route.Attributes.Table
+		}
+		tableRoutes[[2]string{networkRouteTableToString(table), networkRouteFamilyToString(route.Family)}]++
+
 		if route.Type != unix.RTA_DST {
 			continue
 		}
@@ -128,10 +172,78 @@ func (n networkRouteCollector) Update(ch chan<- prometheus.Metric) error {
 	for dev, total := range deviceRoutes {
 		ch <- prometheus.MustNewConstMetric(n.routesDesc, prometheus.GaugeValue, float64(total), dev)
 	}
+	for key, total := range tableRoutes {
+		ch <- prometheus.MustNewConstMetric(n.tableRoutesDesc, prometheus.GaugeValue, float64(total), key[0], key[1])
+	}
 
+	return n.updateIPv6FIBStats(ch)
+}
+
+func (n networkRouteCollector) updateIPv6FIBStats(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("net/rt6_stats"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// IPv6 is disabled.
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	stats, err := parseIPv6FIBStats(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse rt6_stats: %w", err)
+	}
+	for i, v := range stats {
+		if i < len(n.ipv6FIBStatsDescs) && n.ipv6FIBStatsDescs[i] != nil {
+			ch <- prometheus.MustNewConstMetric(n.ipv6FIBStatsDescs[i], prometheus.GaugeValue, float64(v))
+		}
+	}
 	return nil
 }
 
+// parseIPv6FIBStats parses the hexadecimal fields of /proc/net/rt6_stats.
+func parseIPv6FIBStats(r io.Reader) ([]uint64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var stats []uint64
+	for _, field := range strings.Fields(string(b)) {
+		v, err := strconv.ParseUint(field, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, v)
+	}
+	return stats, nil
+}
+
+func networkRouteTableToString(table uint32) string {
+	// from linux kernel 'include/uapi/linux/rtnetlink.h'
+	switch table {
+	case unix.RT_TABLE_DEFAULT:
+		return "default"
+	case unix.RT_TABLE_MAIN:
+		return "main"
+	case unix.RT_TABLE_LOCAL:
+		return "local"
+	}
+	return strconv.FormatUint(uint64(table), 10)
+}
+
+func networkRouteFamilyToString(family uint8) string {
+	switch family {
+	case unix.AF_INET:
+		return "ipv4"
+	case unix.AF_INET6:
+		return "ipv6"
+	case unix.AF_MPLS:
+		return "mpls"
+	}
+	return strconv.FormatUint(uint64(family), 10)
+}
+
 func networkRouteIPWithPrefixToString(ip net.IP, len uint8) string {
 	if len == 0 {
 		return "default"
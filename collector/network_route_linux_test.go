@@ -0,0 +1,46 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nonetworkroute
+// +build !nonetworkroute
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseIPv6FIBStats(t *testing.T) {
+	got, err := parseIPv6FIBStats(strings.NewReader("0009 0006 0000 0007 0002 001a 0000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint64{9, 6, 0, 7, 2, 26, 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if _, err := parseIPv6FIBStats(strings.NewReader("0009 zz\n")); err == nil {
+		t.Error("expected error for invalid field")
+	}
+}
+
+func TestNetworkRouteTableToString(t *testing.T) {
+	for table, want := range map[uint32]string{254: "main", 255: "local", 253: "default", 100: "100", 1000: "1000"} {
+		if got := networkRouteTableToString(table); got != want {
+			t.Errorf("table %d: want %s, got %s", table, want, got)
+		}
+	}
+}
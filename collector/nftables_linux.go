@@ -0,0 +1,171 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nonftables
+// +build !nonftables
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/google/nftables"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const nftablesSubsystem = "nftables"
+
+// nftablesFamilies are the names nft uses for the table families.
+var nftablesFamilies = map[nftables.TableFamily]string{
+	nftables.TableFamilyINet:   "inet",
+	nftables.TableFamilyIPv4:   "ip",
+	nftables.TableFamilyIPv6:   "ip6",
+	nftables.TableFamilyARP:    "arp",
+	nftables.TableFamilyNetdev: "netdev",
+	nftables.TableFamilyBridge: "bridge",
+}
+
+// nftablesLister is the part of *nftables.Conn used by the collector, to swap
+// it out in tests.
+type nftablesLister interface {
+	ListTables() ([]*nftables.Table, error)
+	ListChains() ([]*nftables.Chain, error)
+	GetRules(*nftables.Table, *nftables.Chain) ([]*nftables.Rule, error)
+	GetObjects(*nftables.Table) ([]nftables.Obj, error)
+	GetSets(*nftables.Table) ([]*nftables.Set, error)
+	GetSetElements(*nftables.Set) ([]nftables.SetElement, error)
+}
+
+type nftablesCollector struct {
+	counterPackets *prometheus.Desc
+	counterBytes   *prometheus.Desc
+	setElements    *prometheus.Desc
+	chainRules     *prometheus.Desc
+	newLister      func() (nftablesLister, error)
+	logger         log.Logger
+}
+
+func init() {
+	registerCollector(nftablesSubsystem, defaultDisabled, NewNftablesCollector)
+}
+
+// NewNftablesCollector returns a new Collector exposing the named counters,
+// set sizes and chain sizes of nftables.
+func NewNftablesCollector(logger log.Logger) (Collector, error) {
+	return &nftablesCollector{
+		counterPackets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nftablesSubsystem, "counter_packets_total"),
+			"Packets counted by a named nftables counter.",
+			[]string{"family", "table", "counter"}, nil,
+		),
+		counterBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nftablesSubsystem, "counter_bytes_total"),
+			"Bytes counted by a named nftables counter.",
+			[]string{"family", "table", "counter"}, nil,
+		),
+		setElements: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nftablesSubsystem, "set_elements"),
+			"Number of elements in a named nftables set or map.",
+			[]string{"family", "table", "set", "type"}, nil,
+		),
+		chainRules: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nftablesSubsystem, "chain_rules"),
+			"Number of rules in an nftables chain.",
+			[]string{"family", "table", "chain"}, nil,
+		),
+		newLister: func() (nftablesLister, error) {
+			return nftables.New()
+		},
+		logger: logger,
+	}, nil
+}
+
+func (c *nftablesCollector) Update(ch chan<- prometheus.Metric) error {
+	conn, err := c.newLister()
+	if err != nil {
+		return fmt.Errorf("couldn't open netlink connection: %w", err)
+	}
+	tables, err := conn.ListTables()
+	if err != nil {
+		// Listing requires CAP_NET_ADMIN.
+		return fmt.Errorf("couldn't list tables: %w", expectedPermissionError(err))
+	}
+
+	for _, table := range tables {
+		family := nftablesFamily(table.Family)
+		objs, err := conn.GetObjects(table)
+		if err != nil {
+			return fmt.Errorf("couldn't get objects of table %s %s: %w", family, table.Name, err)
+		}
+		for _, obj := range objs {
+			counter, ok := obj.(*nftables.CounterObj)
+			if !ok {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(c.counterPackets, prometheus.CounterValue, float64(counter.Packets), family, table.Name, counter.Name)
+			ch <- prometheus.MustNewConstMetric(c.counterBytes, prometheus.CounterValue, float64(counter.Bytes), family, table.Name, counter.Name)
+		}
+
+		sets, err := conn.GetSets(table)
+		if err != nil {
+			return fmt.Errorf("couldn't get sets of table %s %s: %w", family, table.Name, err)
+		}
+		for _, set := range sets {
+			if set.Anonymous {
+				continue
+			}
+			elements, err := conn.GetSetElements(set)
+			if err != nil {
+				return fmt.Errorf("couldn't get elements of set %s: %w", set.Name, err)
+			}
+			setType := "set"
+			if set.IsMap {
+				setType = "map"
+			}
+			ch <- prometheus.MustNewConstMetric(c.setElements, prometheus.GaugeValue, float64(nftablesCountElements(elements)), family, table.Name, set.Name, setType)
+		}
+	}
+
+	chains, err := conn.ListChains()
+	if err != nil {
+		return fmt.Errorf("couldn't list chains: %w", err)
+	}
+	for _, chain := range chains {
+		rules, err := conn.GetRules(chain.Table, chain)
+		if err != nil {
+			return fmt.Errorf("couldn't get rules of chain %s: %w", chain.Name, err)
+		}
+		ch <- prometheus.MustNewConstMetric(c.chainRules, prometheus.GaugeValue, float64(len(rules)), nftablesFamily(chain.Table.Family), chain.Table.Name, chain.Name)
+	}
+	return nil
+}
+
+func nftablesFamily(f nftables.TableFamily) string {
+	if name, ok := nftablesFamilies[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", f)
+}
+
+// nftablesCountElements counts the elements of a set. The ranges of interval
+// sets are made of two elements, the one marking the end isn't counted.
+func nftablesCountElements(elements []nftables.SetElement) int {
+	n := 0
+	for _, e := range elements {
+		if !e.IntervalEnd {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nonftables
+// +build !nonftables
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/google/nftables"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeNftables struct {
+	tables   []*nftables.Table
+	chains   []*nftables.Chain
+	rules    map[string][]*nftables.Rule
+	objs     map[string][]nftables.Obj
+	sets     map[string][]*nftables.Set
+	elements map[string][]nftables.SetElement
+}
+
+func (f *fakeNftables) ListTables() ([]*nftables.Table, error) { return f.tables, nil }
+func (f *fakeNftables) ListChains() ([]*nftables.Chain, error) { return f.chains, nil }
+func (f *fakeNftables) GetRules(_ *nftables.Table, c *nftables.Chain) ([]*nftables.Rule, error) {
+	return f.rules[c.Name], nil
+}
+func (f *fakeNftables) GetObjects(t *nftables.Table) ([]nftables.Obj, error) {
+	return f.objs[t.Name], nil
+}
+func (f *fakeNftables) GetSets(t *nftables.Table) ([]*nftables.Set, error) {
+	return f.sets[t.Name], nil
+}
+func (f *fakeNftables) GetSetElements(s *nftables.Set) ([]nftables.SetElement, error) {
+	return f.elements[s.Name], nil
+}
+
+func TestNftablesCollector(t *testing.T) {
+	filter := &nftables.Table{Name: "filter", Family: nftables.TableFamilyINet}
+	input := &nftables.Chain{Name: "input", Table: filter}
+	fake := &fakeNftables{
+		tables: []*nftables.Table{filter},
+		chains: []*nftables.Chain{input},
+		rules:  map[string][]*nftables.Rule{"input": {{}, {}, {}}},
+		objs: map[string][]nftables.Obj{"filter": {
+			&nftables.CounterObj{Table: filter, Name: "ssh", Packets: 12, Bytes: 3400},
+		}},
+		sets: map[string][]*nftables.Set{"filter": {
+			{Table: filter, Name: "blocklist", Interval: true},
+			{Table: filter, Name: "ports", IsMap: true},
+			{Table: filter, Name: "__set0", Anonymous: true},
+		}},
+		elements: map[string][]nftables.SetElement{
+			"blocklist": {{}, {IntervalEnd: true}, {}, {IntervalEnd: true}},
+			"ports":     {{}},
+			"__set0":    {{}, {}},
+		},
+	}
+	c, err := NewNftablesCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(*nftablesCollector).newLister = func() (nftablesLister, error) { return fake, nil }
+
+	want := `# HELP node_nftables_chain_rules Number of rules in an nftables chain.
+# TYPE node_nftables_chain_rules gauge
+node_nftables_chain_rules{chain="input",family="inet",table="filter"} 3
+# HELP node_nftables_counter_bytes_total Bytes counted by a named nftables counter.
+# TYPE node_nftables_counter_bytes_total counter
+node_nftables_counter_bytes_total{counter="ssh",family="inet",table="filter"} 3400
+# HELP node_nftables_counter_packets_total Packets counted by a named nftables counter.
+# TYPE node_nftables_counter_packets_total counter
+node_nftables_counter_packets_total{counter="ssh",family="inet",table="filter"} 12
+# HELP node_nftables_set_elements Number of elements in a named nftables set or map.
+# TYPE node_nftables_set_elements gauge
+node_nftables_set_elements{family="inet",set="blocklist",table="filter",type="set"} 2
+node_nftables_set_elements{family="inet",set="ports",table="filter",type="map"} 1
+`
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
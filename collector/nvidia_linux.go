@@ -0,0 +1,356 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build nvml && cgo
+// +build nvml,cgo
+
+package collector
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+
+// The subset of nvml.h used by the collector. NVML is loaded at runtime so
+// neither the headers nor the library are needed at build time.
+typedef int nvmlReturn_t;
+typedef struct nvmlDevice_st* nvmlDevice_t;
+typedef struct {
+	unsigned int gpu;
+	unsigned int memory;
+} nvmlUtilization_t;
+typedef struct {
+	unsigned long long total;
+	unsigned long long free;
+	unsigned long long used;
+} nvmlMemory_t;
+typedef struct {
+	unsigned int pid;
+	unsigned long long usedGpuMemory;
+} nvmlProcessInfo_v1_t;
+
+#define NVML_SUCCESS 0
+#define NVML_ERROR_NOT_SUPPORTED 3
+#define NVML_ERROR_INSUFFICIENT_SIZE 7
+#define NVML_ERROR_LIBRARY_NOT_FOUND 12
+#define NVML_ERROR_FUNCTION_NOT_FOUND 13
+#define NVML_TEMPERATURE_GPU 0
+#define NVML_MEMORY_ERROR_TYPE_CORRECTED 0
+#define NVML_MEMORY_ERROR_TYPE_UNCORRECTED 1
+#define NVML_AGGREGATE_ECC 1
+
+static void *nvml;
+
+static nvmlReturn_t nvml_init(void) {
+	nvml = dlopen("libnvidia-ml.so.1", RTLD_LAZY | RTLD_GLOBAL);
+	if (!nvml) return NVML_ERROR_LIBRARY_NOT_FOUND;
+	nvmlReturn_t (*f)(void) = dlsym(nvml, "nvmlInit_v2");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f();
+}
+
+static const char *nvml_error_string(nvmlReturn_t ret) {
+	const char *(*f)(nvmlReturn_t) = nvml ? dlsym(nvml, "nvmlErrorString") : NULL;
+	if (!f) return "unknown NVML error";
+	return f(ret);
+}
+
+static nvmlReturn_t nvml_device_count(unsigned int *count) {
+	nvmlReturn_t (*f)(unsigned int *) = dlsym(nvml, "nvmlDeviceGetCount_v2");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(count);
+}
+
+static nvmlReturn_t nvml_device_handle(unsigned int index, nvmlDevice_t *dev) {
+	nvmlReturn_t (*f)(unsigned int, nvmlDevice_t *) = dlsym(nvml, "nvmlDeviceGetHandleByIndex_v2");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(index, dev);
+}
+
+static nvmlReturn_t nvml_device_string(const char *name, nvmlDevice_t dev, char *buf, unsigned int len) {
+	nvmlReturn_t (*f)(nvmlDevice_t, char *, unsigned int) = dlsym(nvml, name);
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, buf, len);
+}
+
+static nvmlReturn_t nvml_device_uint(const char *name, nvmlDevice_t dev, unsigned int *value) {
+	nvmlReturn_t (*f)(nvmlDevice_t, unsigned int *) = dlsym(nvml, name);
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, value);
+}
+
+static nvmlReturn_t nvml_device_utilization(nvmlDevice_t dev, nvmlUtilization_t *util) {
+	nvmlReturn_t (*f)(nvmlDevice_t, nvmlUtilization_t *) = dlsym(nvml, "nvmlDeviceGetUtilizationRates");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, util);
+}
+
+static nvmlReturn_t nvml_device_memory(nvmlDevice_t dev, nvmlMemory_t *mem) {
+	nvmlReturn_t (*f)(nvmlDevice_t, nvmlMemory_t *) = dlsym(nvml, "nvmlDeviceGetMemoryInfo");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, mem);
+}
+
+static nvmlReturn_t nvml_device_temperature(nvmlDevice_t dev, unsigned int *temp) {
+	nvmlReturn_t (*f)(nvmlDevice_t, int, unsigned int *) = dlsym(nvml, "nvmlDeviceGetTemperature");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, NVML_TEMPERATURE_GPU, temp);
+}
+
+static nvmlReturn_t nvml_device_ecc_errors(nvmlDevice_t dev, int type, unsigned long long *count) {
+	nvmlReturn_t (*f)(nvmlDevice_t, int, int, unsigned long long *) = dlsym(nvml, "nvmlDeviceGetTotalEccErrors");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, type, NVML_AGGREGATE_ECC, count);
+}
+
+static nvmlReturn_t nvml_device_processes(nvmlDevice_t dev, unsigned int *count, nvmlProcessInfo_v1_t *infos) {
+	nvmlReturn_t (*f)(nvmlDevice_t, unsigned int *, nvmlProcessInfo_v1_t *) = dlsym(nvml, "nvmlDeviceGetComputeRunningProcesses");
+	if (!f) return NVML_ERROR_FUNCTION_NOT_FOUND;
+	return f(dev, count, infos);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"unsafe"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const nvidiaCollectorSubsystem = "nvidia"
+
+var nvidiaProcesses = kingpin.Flag("collector.nvidia.processes", "Expose the GPU memory used by each process.").Bool()
+
+type nvidiaCollector struct {
+	logger log.Logger
+
+	info              *prometheus.Desc
+	gpuUtilization    *prometheus.Desc
+	memoryUtilization *prometheus.Desc
+	memoryTotal       *prometheus.Desc
+	memoryUsed        *prometheus.Desc
+	temperature       *prometheus.Desc
+	power             *prometheus.Desc
+	eccErrors         *prometheus.Desc
+	processMemory     *prometheus.Desc
+}
+
+var (
+	nvmlInitOnce sync.Once
+	nvmlInitErr  error
+)
+
+func init() {
+	registerCollector(nvidiaCollectorSubsystem, defaultDisabled, NewNvidiaCollector)
+}
+
+type nvmlError C.nvmlReturn_t
+
+func (e nvmlError) Error() string {
+	if e == C.NVML_ERROR_LIBRARY_NOT_FOUND {
+		return "libnvidia-ml.so.1 not found"
+	}
+	return C.GoString(C.nvml_error_string(C.nvmlReturn_t(e)))
+}
+
+func nvmlCheck(ret C.nvmlReturn_t) error {
+	if ret != C.NVML_SUCCESS {
+		return nvmlError(ret)
+	}
+	return nil
+}
+
+func isNVMLNotSupported(err error) bool {
+	e, ok := err.(nvmlError)
+	return ok && (e == C.NVML_ERROR_NOT_SUPPORTED || e == C.NVML_ERROR_FUNCTION_NOT_FOUND)
+}
+
+// NewNvidiaCollector returns a new Collector exposing NVIDIA GPU statistics
+// from NVML.
+func NewNvidiaCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, nvidiaCollectorSubsystem, name),
+			help, append([]string{"gpu"}, labels...), nil,
+		)
+	}
+	return &nvidiaCollector{
+		logger:            logger,
+		info:              desc("info", "Non-numeric data about the GPU, value is always 1.", "name", "uuid"),
+		gpuUtilization:    desc("gpu_utilization_ratio", "Ratio of time over the past sample period during which kernels were executing on the GPU."),
+		memoryUtilization: desc("memory_utilization_ratio", "Ratio of time over the past sample period during which device memory was being read or written."),
+		memoryTotal:       desc("memory_total_bytes", "Total device memory."),
+		memoryUsed:        desc("memory_used_bytes", "Allocated device memory."),
+		temperature:       desc("temperature_celsius", "Temperature of the GPU die in degrees Celsius."),
+		power:             desc("power_watts", "Power draw of the GPU and its associated circuitry."),
+		eccErrors:         desc("ecc_errors_total", "Number of ECC errors since the last driver reload.", "type"),
+		processMemory:     desc("process_memory_used_bytes", "Device memory used by a compute process.", "pid"),
+	}, nil
+}
+
+func (c *nvidiaCollector) Update(ch chan<- prometheus.Metric) error {
+	// NVML is initialized on first use and never shut down, so hosts without
+	// the driver only fail the collector rather than the exporter.
+	nvmlInitOnce.Do(func() {
+		nvmlInitErr = nvmlCheck(C.nvml_init())
+	})
+	if nvmlInitErr != nil {
+		return fmt.Errorf("failed to initialize NVML: %w", nvmlInitErr)
+	}
+
+	var count C.uint
+	if err := nvmlCheck(C.nvml_device_count(&count)); err != nil {
+		return fmt.Errorf("couldn't get NVIDIA device count: %w", err)
+	}
+	if count == 0 {
+		return ErrNoData
+	}
+
+	for i := C.uint(0); i < count; i++ {
+		var dev C.nvmlDevice_t
+		if err := nvmlCheck(C.nvml_device_handle(i, &dev)); err != nil {
+			return fmt.Errorf("couldn't get handle of NVIDIA device %d: %w", i, err)
+		}
+		if err := c.updateDevice(ch, strconv.Itoa(int(i)), dev); err != nil {
+			return fmt.Errorf("couldn't get stats of NVIDIA device %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (c *nvidiaCollector) updateDevice(ch chan<- prometheus.Metric, gpu string, dev C.nvmlDevice_t) error {
+	name, err := nvmlDeviceString("nvmlDeviceGetName", dev)
+	if err != nil {
+		return err
+	}
+	uuid, err := nvmlDeviceString("nvmlDeviceGetUUID", dev)
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, gpu, name, uuid)
+
+	// Not all metrics are supported by all GPUs, so unsupported ones are
+	// skipped silently.
+	var util C.nvmlUtilization_t
+	if ok, err := c.supported(C.nvml_device_utilization(dev, &util)); err != nil {
+		return err
+	} else if ok {
+		ch <- prometheus.MustNewConstMetric(c.gpuUtilization, prometheus.GaugeValue, float64(util.gpu)/100, gpu)
+		ch <- prometheus.MustNewConstMetric(c.memoryUtilization, prometheus.GaugeValue, float64(util.memory)/100, gpu)
+	}
+
+	var mem C.nvmlMemory_t
+	if ok, err := c.supported(C.nvml_device_memory(dev, &mem)); err != nil {
+		return err
+	} else if ok {
+		ch <- prometheus.MustNewConstMetric(c.memoryTotal, prometheus.GaugeValue, float64(mem.total), gpu)
+		ch <- prometheus.MustNewConstMetric(c.memoryUsed, prometheus.GaugeValue, float64(mem.used), gpu)
+	}
+
+	var temp C.uint
+	if ok, err := c.supported(C.nvml_device_temperature(dev, &temp)); err != nil {
+		return err
+	} else if ok {
+		ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, float64(temp), gpu)
+	}
+
+	var milliwatts C.uint
+	if ok, err := c.supported(nvmlDeviceUint("nvmlDeviceGetPowerUsage", dev, &milliwatts)); err != nil {
+		return err
+	} else if ok {
+		ch <- prometheus.MustNewConstMetric(c.power, prometheus.GaugeValue, float64(milliwatts)/1000, gpu)
+	}
+
+	for _, e := range []struct {
+		typ  C.int
+		name string
+	}{
+		{C.NVML_MEMORY_ERROR_TYPE_CORRECTED, "corrected"},
+		{C.NVML_MEMORY_ERROR_TYPE_UNCORRECTED, "uncorrected"},
+	} {
+		var errors C.ulonglong
+		if ok, err := c.supported(C.nvml_device_ecc_errors(dev, e.typ, &errors)); err != nil {
+			return err
+		} else if ok {
+			ch <- prometheus.MustNewConstMetric(c.eccErrors, prometheus.CounterValue, float64(errors), gpu, e.name)
+		}
+	}
+
+	if *nvidiaProcesses {
+		return c.updateProcesses(ch, gpu, dev)
+	}
+	return nil
+}
+
+func (c *nvidiaCollector) updateProcesses(ch chan<- prometheus.Metric, gpu string, dev C.nvmlDevice_t) error {
+	// Query the number of processes first, the list may grow between the
+	// calls so leave some headroom.
+	var count C.uint
+	ret := C.nvml_device_processes(dev, &count, nil)
+	if ret != C.NVML_SUCCESS && ret != C.NVML_ERROR_INSUFFICIENT_SIZE {
+		if ok, err := c.supported(ret); !ok {
+			return err
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+	count += 8
+	infos := make([]C.nvmlProcessInfo_v1_t, count)
+	if err := nvmlCheck(C.nvml_device_processes(dev, &count, &infos[0])); err != nil {
+		return err
+	}
+	for _, info := range infos[:count] {
+		ch <- prometheus.MustNewConstMetric(c.processMemory, prometheus.GaugeValue,
+			float64(info.usedGpuMemory), gpu, strconv.Itoa(int(info.pid)))
+	}
+	return nil
+}
+
+// supported returns false if ret indicates that the queried metric isn't
+// supported by the device, and the error for any other failure.
+func (c *nvidiaCollector) supported(ret C.nvmlReturn_t) (bool, error) {
+	err := nvmlCheck(ret)
+	if err == nil {
+		return true, nil
+	}
+	if isNVMLNotSupported(err) {
+		level.Debug(c.logger).Log("msg", "metric not supported by NVIDIA device", "err", err)
+		return false, nil
+	}
+	return false, err
+}
+
+func nvmlDeviceString(function string, dev C.nvmlDevice_t) (string, error) {
+	cFunction := C.CString(function)
+	defer C.free(unsafe.Pointer(cFunction))
+
+	var buf [96]C.char
+	if err := nvmlCheck(C.nvml_device_string(cFunction, dev, &buf[0], C.uint(len(buf)))); err != nil {
+		return "", err
+	}
+	return C.GoString(&buf[0]), nil
+}
+
+func nvmlDeviceUint(function string, dev C.nvmlDevice_t, value *C.uint) C.nvmlReturn_t {
+	cFunction := C.CString(function)
+	defer C.free(unsafe.Pointer(cFunction))
+
+	return C.nvml_device_uint(cFunction, dev, value)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build nvml && cgo
+// +build nvml,cgo
+
+package collector
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNVMLError(t *testing.T) {
+	// Test files can't use cgo, the NVML return codes are given as numbers.
+	for _, tc := range []struct {
+		name         string
+		ret          int
+		notSupported bool
+		message      string
+	}{
+		{"not supported", 3, true, ""},
+		{"function not found", 13, true, ""},
+		{"library not found", 12, false, "libnvidia-ml.so.1 not found"},
+		// Without the library the message can't be looked up.
+		{"insufficient size", 7, false, "unknown NVML error"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := nvmlError(tc.ret)
+			if got := isNVMLNotSupported(err); got != tc.notSupported {
+				t.Errorf("want not supported %v, got %v", tc.notSupported, got)
+			}
+			if tc.message != "" && err.Error() != tc.message {
+				t.Errorf("want message %q, got %q", tc.message, err.Error())
+			}
+		})
+	}
+	if isNVMLNotSupported(errors.New("not supported")) {
+		t.Error("other errors must not be taken for unsupported metrics")
+	}
+}
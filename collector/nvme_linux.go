@@ -17,23 +17,135 @@
 package collector
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs/sysfs"
+	"golang.org/x/sys/unix"
 )
 
+const (
+	// NVME_IOCTL_ADMIN_CMD from linux/nvme_ioctl.h.
+	nvmeIoctlAdminCmd = 0xc0484e41
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeAdminIdentify   = 0x06
+	nvmeLogSMART        = 0x02
+	nvmeLogSMARTSize    = 512
+	nvmeIdentifySize    = 4096
+	nvmeNSIDAll         = 0xffffffff
+
+	// Controller or Namespace Structure of the Identify command.
+	nvmeIdentifyNamespace  = 0x00
+	nvmeIdentifyController = 0x01
+)
+
+var nvmeSMART = kingpin.Flag("collector.nvme.smart", "Expose metrics from the NVMe SMART/health information log page (requires CAP_SYS_ADMIN).").Bool()
+
+// nvmeControllerStates are the states of a controller, see nvme_ctrl_state in
+// drivers/nvme/host/nvme.h.
+var nvmeControllerStates = []string{"new", "live", "resetting", "connecting", "deleting", "deleting (no IO)", "dead"}
+
+// nvmeAdminCmd mirrors struct nvme_admin_cmd from linux/nvme_ioctl.h.
+type nvmeAdminCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMs   uint32
+	result      uint32
+}
+
+// nvmeSMARTLog contains the fields of the SMART/health information log page
+// as defined in the NVMe base specification, converted to base units.
+type nvmeSMARTLog struct {
+	CriticalWarning         uint8
+	TemperatureCelsius      float64
+	AvailableSpare          float64
+	AvailableSpareThreshold float64
+	PercentageUsed          float64
+	DataReadBytes           float64
+	DataWrittenBytes        float64
+	HostReadCommands        float64
+	HostWriteCommands       float64
+	ControllerBusySeconds   float64
+	PowerCycles             float64
+	PowerOnSeconds          float64
+	UnsafeShutdowns         float64
+	MediaErrors             float64
+	ErrorLogEntries         float64
+}
+
 type nvmeCollector struct {
 	fs     sysfs.FS
 	logger log.Logger
+
+	criticalWarning         *prometheus.Desc
+	temperature             *prometheus.Desc
+	availableSpare          *prometheus.Desc
+	availableSpareThreshold *prometheus.Desc
+	percentageUsed          *prometheus.Desc
+	dataRead                *prometheus.Desc
+	dataWritten             *prometheus.Desc
+	hostReadCommands        *prometheus.Desc
+	hostWriteCommands       *prometheus.Desc
+	controllerBusy          *prometheus.Desc
+	powerCycles             *prometheus.Desc
+	powerOn                 *prometheus.Desc
+	unsafeShutdowns         *prometheus.Desc
+	mediaErrors             *prometheus.Desc
+	errorLogEntries         *prometheus.Desc
+
+	namespaceSize              *prometheus.Desc
+	namespaceCapacity          *prometheus.Desc
+	namespaceUsed              *prometheus.Desc
+	namespaceDataRead          *prometheus.Desc
+	namespaceDataWritten       *prometheus.Desc
+	namespaceHostReadCommands  *prometheus.Desc
+	namespaceHostWriteCommands *prometheus.Desc
+
+	fabricsInfo          *prometheus.Desc
+	fabricsState         *prometheus.Desc
+	fabricsQueues        *prometheus.Desc
+	fabricsReconnects    *prometheus.Desc
+	fabricsCtrlLossTmo   *prometheus.Desc
+	fabricsReconnectWait *prometheus.Desc
+
+	// The kernel doesn't count reconnects, they are counted when a
+	// controller is seen connecting after it was seen in another state.
+	mtx        sync.Mutex
+	lastStates map[string]string
+	reconnects map[string]uint64
 }
 
 func init() {
 	registerCollector("nvme", defaultEnabled, NewNVMeCollector)
+	registerHardwareProbe("nvme", "no NVMe controllers", sysfsProbe("class/nvme/*"))
 }
 
 // NewNVMeCollector returns a new Collector exposing NVMe stats.
@@ -43,9 +155,64 @@ func NewNVMeCollector(logger log.Logger) (Collector, error) {
 		return nil, fmt.Errorf("failed to open sysfs: %w", err)
 	}
 
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nvme", name),
+			help, []string{"device"}, nil,
+		)
+	}
+	namespaceDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nvme", "namespace_"+name),
+			help, []string{"device", "namespace"}, nil,
+		)
+	}
+
 	return &nvmeCollector{
 		fs:     fs,
 		logger: logger,
+
+		criticalWarning:         desc("critical_warning", "Bitmap of critical warnings reported by the controller."),
+		temperature:             desc("temperature_celsius", "Composite temperature of the controller in degrees Celsius."),
+		availableSpare:          desc("available_spare_ratio", "Remaining spare capacity available as a ratio."),
+		availableSpareThreshold: desc("available_spare_threshold_ratio", "Available spare ratio below which the controller reports a critical warning."),
+		percentageUsed:          desc("percentage_used_ratio", "Vendor specific estimate of the used life of the device as a ratio, may exceed 1."),
+		dataRead:                desc("data_read_bytes_total", "Amount of data read from the device by the host."),
+		dataWritten:             desc("data_written_bytes_total", "Amount of data written to the device by the host."),
+		hostReadCommands:        desc("host_read_commands_total", "Number of read commands completed by the controller."),
+		hostWriteCommands:       desc("host_write_commands_total", "Number of write commands completed by the controller."),
+		controllerBusy:          desc("controller_busy_seconds_total", "Time the controller was busy with I/O commands."),
+		powerCycles:             desc("power_cycles_total", "Number of power cycles."),
+		powerOn:                 desc("power_on_seconds_total", "Time the device has been powered on."),
+		unsafeShutdowns:         desc("unsafe_shutdowns_total", "Number of unsafe shutdowns."),
+		mediaErrors:             desc("media_errors_total", "Number of unrecovered data integrity errors detected by the controller."),
+		errorLogEntries:         desc("error_log_entries_total", "Number of error information log entries over the life of the controller."),
+
+		namespaceSize:              namespaceDesc("size_bytes", "Size of the namespace."),
+		namespaceCapacity:          namespaceDesc("capacity_bytes", "Maximum amount of storage the namespace can allocate, less than its size if thin provisioned."),
+		namespaceUsed:              namespaceDesc("used_bytes", "Amount of storage currently allocated by the namespace."),
+		namespaceDataRead:          namespaceDesc("data_read_bytes_total", "Amount of data read from the namespace by the host, if the controller keeps SMART logs per namespace."),
+		namespaceDataWritten:       namespaceDesc("data_written_bytes_total", "Amount of data written to the namespace by the host, if the controller keeps SMART logs per namespace."),
+		namespaceHostReadCommands:  namespaceDesc("host_read_commands_total", "Number of read commands to the namespace completed by the controller, if it keeps SMART logs per namespace."),
+		namespaceHostWriteCommands: namespaceDesc("host_write_commands_total", "Number of write commands to the namespace completed by the controller, if it keeps SMART logs per namespace."),
+
+		fabricsInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nvme", "fabrics_info"),
+			"Information about an NVMe over Fabrics controller, address is the transport address of the target.",
+			[]string{"device", "transport", "address", "subsysnqn", "hostnqn"}, nil,
+		),
+		fabricsState: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "nvme", "fabrics_state"),
+			"State of an NVMe over Fabrics controller, only live controllers can do I/O.",
+			[]string{"device", "state"}, nil,
+		),
+		fabricsQueues:        desc("fabrics_queues", "Number of queues of an NVMe over Fabrics controller, including the admin queue."),
+		fabricsReconnects:    desc("fabrics_reconnects_total", "Number of times an NVMe over Fabrics controller was seen reconnecting, reconnects between scrapes are missed."),
+		fabricsCtrlLossTmo:   desc("fabrics_ctrl_loss_timeout_seconds", "Time after which an NVMe over Fabrics controller stops reconnecting and is removed, absent if it reconnects forever."),
+		fabricsReconnectWait: desc("fabrics_reconnect_delay_seconds", "Delay between reconnect attempts of an NVMe over Fabrics controller."),
+
+		lastStates: make(map[string]string),
+		reconnects: make(map[string]uint64),
 	}, nil
 }
 
@@ -68,7 +235,282 @@ func (c *nvmeCollector) Update(ch chan<- prometheus.Metric) error {
 		)
 		infoValue := 1.0
 		ch <- prometheus.MustNewConstMetric(infoDesc, prometheus.GaugeValue, infoValue, device.Name, device.FirmwareRevision, device.Model, device.Serial, device.State)
+		c.updateFabrics(ch, device.Name)
+
+		namespaces, err := readNVMeNamespaces(device.Name)
+		if err != nil {
+			return fmt.Errorf("couldn't list namespaces of %s: %w", device.Name, err)
+		}
+		for _, ns := range namespaces {
+			ch <- prometheus.MustNewConstMetric(c.namespaceSize, prometheus.GaugeValue, ns.sizeBytes, device.Name, ns.name)
+		}
+
+		if !*nvmeSMART {
+			continue
+		}
+		if err := c.updateAdmin(ch, device.Name, namespaces); err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+				level.Debug(c.logger).Log("msg", "couldn't read NVMe SMART log", "device", device.Name, "err", err)
+				continue
+			}
+			return fmt.Errorf("couldn't read NVMe SMART log for %s: %w", device.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// updateAdmin exposes the SMART log of the controller, and the allocation and
+// SMART logs of the namespaces, read with admin commands.
+func (c *nvmeCollector) updateAdmin(ch chan<- prometheus.Metric, device string, namespaces []nvmeNamespace) error {
+	f, err := os.Open(rootfsFilePath(filepath.Join("/dev", device)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	admin := nvmeAdmin{f}
+
+	buf, err := admin.getLogPage(nvmeNSIDAll, nvmeLogSMART, nvmeLogSMARTSize)
+	if err != nil {
+		return err
+	}
+	smart, err := parseNVMeSMARTLog(buf)
+	if err != nil {
+		return err
 	}
+	c.updateSMART(ch, device, smart)
 
+	id, err := admin.identify(0, nvmeIdentifyController)
+	if err != nil {
+		return err
+	}
+	// Bit 0 of the Log Page Attributes is set if the controller keeps
+	// SMART logs per namespace, others return the log of the controller.
+	perNamespace := id[261]&1 != 0
+	for _, ns := range namespaces {
+		id, err := admin.identify(ns.nsid, nvmeIdentifyNamespace)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't identify NVMe namespace", "device", device, "namespace", ns.name, "err", err)
+			continue
+		}
+		if capacity, used, ok := parseNVMeIdentifyNamespace(id); ok {
+			ch <- prometheus.MustNewConstMetric(c.namespaceCapacity, prometheus.GaugeValue, capacity, device, ns.name)
+			ch <- prometheus.MustNewConstMetric(c.namespaceUsed, prometheus.GaugeValue, used, device, ns.name)
+		}
+		if !perNamespace {
+			continue
+		}
+		buf, err := admin.getLogPage(ns.nsid, nvmeLogSMART, nvmeLogSMARTSize)
+		if err != nil {
+			level.Debug(c.logger).Log("msg", "couldn't read NVMe namespace SMART log", "device", device, "namespace", ns.name, "err", err)
+			continue
+		}
+		// Only the data units and commands are kept per namespace.
+		s, err := parseNVMeSMARTLog(buf)
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(c.namespaceDataRead, prometheus.CounterValue, s.DataReadBytes, device, ns.name)
+		ch <- prometheus.MustNewConstMetric(c.namespaceDataWritten, prometheus.CounterValue, s.DataWrittenBytes, device, ns.name)
+		ch <- prometheus.MustNewConstMetric(c.namespaceHostReadCommands, prometheus.CounterValue, s.HostReadCommands, device, ns.name)
+		ch <- prometheus.MustNewConstMetric(c.namespaceHostWriteCommands, prometheus.CounterValue, s.HostWriteCommands, device, ns.name)
+	}
 	return nil
 }
+
+func (c *nvmeCollector) updateSMART(ch chan<- prometheus.Metric, device string, s nvmeSMARTLog) {
+	ch <- prometheus.MustNewConstMetric(c.criticalWarning, prometheus.GaugeValue, float64(s.CriticalWarning), device)
+	ch <- prometheus.MustNewConstMetric(c.temperature, prometheus.GaugeValue, s.TemperatureCelsius, device)
+	ch <- prometheus.MustNewConstMetric(c.availableSpare, prometheus.GaugeValue, s.AvailableSpare, device)
+	ch <- prometheus.MustNewConstMetric(c.availableSpareThreshold, prometheus.GaugeValue, s.AvailableSpareThreshold, device)
+	ch <- prometheus.MustNewConstMetric(c.percentageUsed, prometheus.GaugeValue, s.PercentageUsed, device)
+	ch <- prometheus.MustNewConstMetric(c.dataRead, prometheus.CounterValue, s.DataReadBytes, device)
+	ch <- prometheus.MustNewConstMetric(c.dataWritten, prometheus.CounterValue, s.DataWrittenBytes, device)
+	ch <- prometheus.MustNewConstMetric(c.hostReadCommands, prometheus.CounterValue, s.HostReadCommands, device)
+	ch <- prometheus.MustNewConstMetric(c.hostWriteCommands, prometheus.CounterValue, s.HostWriteCommands, device)
+	ch <- prometheus.MustNewConstMetric(c.controllerBusy, prometheus.CounterValue, s.ControllerBusySeconds, device)
+	ch <- prometheus.MustNewConstMetric(c.powerCycles, prometheus.CounterValue, s.PowerCycles, device)
+	ch <- prometheus.MustNewConstMetric(c.powerOn, prometheus.CounterValue, s.PowerOnSeconds, device)
+	ch <- prometheus.MustNewConstMetric(c.unsafeShutdowns, prometheus.CounterValue, s.UnsafeShutdowns, device)
+	ch <- prometheus.MustNewConstMetric(c.mediaErrors, prometheus.CounterValue, s.MediaErrors, device)
+	ch <- prometheus.MustNewConstMetric(c.errorLogEntries, prometheus.CounterValue, s.ErrorLogEntries, device)
+}
+
+// updateFabrics exposes the session of NVMe over Fabrics controllers, it does
+// nothing for PCIe controllers.
+func (c *nvmeCollector) updateFabrics(ch chan<- prometheus.Metric, device string) {
+	dir := sysFilePath(filepath.Join("class/nvme", device))
+	attr := func(a string) string {
+		b, err := os.ReadFile(filepath.Join(dir, a))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(b))
+	}
+	transport := attr("transport")
+	if transport == "" || transport == "pcie" {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.fabricsInfo, prometheus.GaugeValue, 1,
+		device, transport, attr("address"), attr("subsysnqn"), attr("hostnqn"))
+	state := attr("state")
+	for _, s := range nvmeControllerStates {
+		ch <- prometheus.MustNewConstMetric(c.fabricsState, prometheus.GaugeValue, boolToFloat(s == state), device, s)
+	}
+	ch <- prometheus.MustNewConstMetric(c.fabricsReconnects, prometheus.CounterValue, float64(c.countReconnect(device, state)), device)
+
+	if queues, err := strconv.ParseUint(attr("queue_count"), 10, 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.fabricsQueues, prometheus.GaugeValue, float64(queues), device)
+	}
+	// ctrl_loss_tmo is "off" if the controller reconnects forever.
+	if tmo, err := strconv.ParseUint(attr("ctrl_loss_tmo"), 10, 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.fabricsCtrlLossTmo, prometheus.GaugeValue, float64(tmo), device)
+	}
+	if delay, err := strconv.ParseUint(attr("reconnect_delay"), 10, 64); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.fabricsReconnectWait, prometheus.GaugeValue, float64(delay), device)
+	}
+}
+
+// countReconnect records the state of a controller and returns the number of
+// reconnects seen so far.
+func (c *nvmeCollector) countReconnect(device, state string) uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if last, ok := c.lastStates[device]; ok && last != state && state == "connecting" {
+		c.reconnects[device]++
+	}
+	c.lastStates[device] = state
+	return c.reconnects[device]
+}
+
+// nvmeNamespace is a namespace of a controller, named like its block device.
+type nvmeNamespace struct {
+	name      string
+	nsid      uint32
+	sizeBytes float64
+}
+
+// nvmeMultipathController matches the controller in the names of the
+// namespaces of multipath controllers, like nvme0c1n1 for the block device
+// nvme0n1.
+var nvmeMultipathController = regexp.MustCompile(`c[0-9]+n`)
+
+// readNVMeNamespaces returns the namespaces of a controller from sysfs.
+func readNVMeNamespaces(device string) ([]nvmeNamespace, error) {
+	dirs, err := filepath.Glob(sysFilePath(filepath.Join("class/nvme", device, "nvme*n*")))
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []nvmeNamespace
+	for _, dir := range dirs {
+		nsid, err := readUintFromFile(filepath.Join(dir, "nsid"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		// The size is in 512 byte sectors whatever the block size.
+		sectors, err := readUintFromFile(filepath.Join(dir, "size"))
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, nvmeNamespace{
+			name:      nvmeMultipathController.ReplaceAllString(filepath.Base(dir), "n"),
+			nsid:      uint32(nsid),
+			sizeBytes: float64(sectors) * 512,
+		})
+	}
+	return namespaces, nil
+}
+
+// nvmeAdmin sends admin commands to the character device of a controller.
+type nvmeAdmin struct {
+	file *os.File
+}
+
+func (a nvmeAdmin) getLogPage(nsid uint32, page uint8, size int) ([]byte, error) {
+	// Number of dwords to read (zero based) and the log page identifier.
+	return a.command(nvmeAdminGetLogPage, nsid, uint32(size/4-1)<<16|uint32(page), size)
+}
+
+func (a nvmeAdmin) identify(nsid uint32, cns uint8) ([]byte, error) {
+	return a.command(nvmeAdminIdentify, nsid, uint32(cns), nvmeIdentifySize)
+}
+
+// command sends an admin command returning size bytes of data.
+func (a nvmeAdmin) command(opcode uint8, nsid, cdw10 uint32, size int) ([]byte, error) {
+	// The kernel gets the address of the data buffer inside the command,
+	// which the runtime wouldn't update if it moved a buffer on the stack,
+	// so it is mapped outside of the memory managed by Go.
+	data, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Munmap(data)
+	cmd := nvmeAdminCmd{
+		opcode:  opcode,
+		nsid:    nsid,
+		addr:    uint64(uintptr(unsafe.Pointer(&data[0]))),
+		dataLen: uint32(size),
+		cdw10:   cdw10,
+	}
+	status, _, errno := unix.Syscall(unix.SYS_IOCTL, a.file.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	if errno != 0 {
+		return nil, errno
+	}
+	// The ioctl returns the status of failed commands.
+	if status != 0 {
+		return nil, fmt.Errorf("NVMe admin command %#x failed with status %#x", opcode, status)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// parseNVMeIdentifyNamespace returns the capacity and utilization of a
+// namespace from its Identify Namespace data structure.
+func parseNVMeIdentifyNamespace(id []byte) (float64, float64, bool) {
+	if len(id) < nvmeIdentifySize {
+		return 0, 0, false
+	}
+	// The sizes are in logical blocks of the format selected by the low
+	// bits of FLBAS, among the LBA formats from byte 128.
+	format := int(id[26] & 0x0f)
+	blockSize := float64(uint64(1) << id[128+4*format+2])
+	capacity := float64(binary.LittleEndian.Uint64(id[8:])) * blockSize
+	used := float64(binary.LittleEndian.Uint64(id[16:])) * blockSize
+	return capacity, used, blockSize > 1
+}
+
+func parseNVMeSMARTLog(buf []byte) (nvmeSMARTLog, error) {
+	if len(buf) < nvmeLogSMARTSize {
+		return nvmeSMARTLog{}, fmt.Errorf("short SMART log page: %d bytes", len(buf))
+	}
+	// 128 bit little endian counters, which can't be represented exactly
+	// as float64 anyway.
+	u128 := func(offset int) float64 {
+		lo := binary.LittleEndian.Uint64(buf[offset:])
+		hi := binary.LittleEndian.Uint64(buf[offset+8:])
+		return float64(hi)*math.Pow(2, 64) + float64(lo)
+	}
+	return nvmeSMARTLog{
+		CriticalWarning: buf[0],
+		// Reported in Kelvin.
+		TemperatureCelsius:      float64(binary.LittleEndian.Uint16(buf[1:])) - 273.15,
+		AvailableSpare:          float64(buf[3]) / 100,
+		AvailableSpareThreshold: float64(buf[4]) / 100,
+		PercentageUsed:          float64(buf[5]) / 100,
+		// Data units are thousands of 512 byte blocks.
+		DataReadBytes:     u128(32) * 512000,
+		DataWrittenBytes:  u128(48) * 512000,
+		HostReadCommands:  u128(64),
+		HostWriteCommands: u128(80),
+		// Busy time is reported in minutes, power on time in hours.
+		ControllerBusySeconds: u128(96) * 60,
+		PowerCycles:           u128(112),
+		PowerOnSeconds:        u128(128) * 3600,
+		UnsafeShutdowns:       u128(144),
+		MediaErrors:           u128(160),
+		ErrorLogEntries:       u128(176),
+	}, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nonvme
+// +build !nonvme
+
+package collector
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestParseNVMeSMARTLog(t *testing.T) {
+	buf := make([]byte, nvmeLogSMARTSize)
+	buf[0] = 0x04
+	binary.LittleEndian.PutUint16(buf[1:], 310)
+	buf[3] = 100
+	buf[4] = 10
+	buf[5] = 3
+	binary.LittleEndian.PutUint64(buf[32:], 2000)
+	binary.LittleEndian.PutUint64(buf[48:], 1000)
+	binary.LittleEndian.PutUint64(buf[96:], 15)
+	binary.LittleEndian.PutUint64(buf[128:], 2)
+	binary.LittleEndian.PutUint64(buf[152:], 1)
+	binary.LittleEndian.PutUint64(buf[160:], 7)
+
+	s, err := parseNVMeSMARTLog(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name string
+		want float64
+		got  float64
+	}{
+		{"critical warning", 4, float64(s.CriticalWarning)},
+		{"temperature", 36.85, s.TemperatureCelsius},
+		{"available spare", 1, s.AvailableSpare},
+		{"available spare threshold", 0.1, s.AvailableSpareThreshold},
+		{"percentage used", 0.03, s.PercentageUsed},
+		{"data read", 1024000000, s.DataReadBytes},
+		{"data written", 512000000, s.DataWrittenBytes},
+		{"controller busy", 900, s.ControllerBusySeconds},
+		{"power on", 7200, s.PowerOnSeconds},
+		{"unsafe shutdowns", 18446744073709551616, s.UnsafeShutdowns},
+		{"media errors", 7, s.MediaErrors},
+	} {
+		if diff := c.got - c.want; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("%s: want %v, got %v", c.name, c.want, c.got)
+		}
+	}
+
+	if _, err := parseNVMeSMARTLog(buf[:100]); err == nil {
+		t.Error("expected error for short log page")
+	}
+}
+
+func TestParseNVMeIdentifyNamespace(t *testing.T) {
+	id := make([]byte, nvmeIdentifySize)
+	binary.LittleEndian.PutUint64(id[0:], 1000)
+	binary.LittleEndian.PutUint64(id[8:], 800)
+	binary.LittleEndian.PutUint64(id[16:], 200)
+	// The second LBA format with 4096 byte blocks is selected.
+	id[26] = 1
+	id[128+2] = 9
+	id[132+2] = 12
+
+	capacity, used, ok := parseNVMeIdentifyNamespace(id)
+	if !ok || capacity != 800*4096 || used != 200*4096 {
+		t.Errorf("want capacity %d and used %d, got %v, %v (%t)", 800*4096, 200*4096, capacity, used, ok)
+	}
+
+	// The selected format isn't available.
+	id[26] = 2
+	if _, _, ok := parseNVMeIdentifyNamespace(id); ok {
+		t.Error("unavailable LBA format: expected no sizes")
+	}
+}
+
+func TestReadNVMeNamespaces(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	namespaces, err := readNVMeNamespaces("nvme0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []nvmeNamespace{{name: "nvme0n1", nsid: 1, sizeBytes: 1000215216 * 512}}
+	if !reflect.DeepEqual(namespaces, want) {
+		t.Errorf("want %v, got %v", want, namespaces)
+	}
+	if got := nvmeMultipathController.ReplaceAllString("nvme0c1n2", "n"); got != "nvme0n2" {
+		t.Errorf("multipath namespace: want nvme0n2, got %s", got)
+	}
+}
+
+func TestNVMeFabricsReconnects(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	c, err := NewNVMeCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nc := c.(*nvmeCollector)
+
+	for _, step := range []struct {
+		state string
+		want  uint64
+	}{
+		{"connecting", 0},
+		{"live", 0},
+		{"live", 0},
+		{"connecting", 1},
+		{"connecting", 1},
+		{"resetting", 1},
+		{"connecting", 2},
+	} {
+		if got := nc.countReconnect("nvme1", step.state); got != step.want {
+			t.Errorf("%s: want %d reconnects, got %d", step.state, step.want, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nooom
+// +build !nooom
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const oomSubsystem = "oom"
+
+type oomCollector struct {
+	lastKill *prometheus.Desc
+	logger   log.Logger
+
+	// The time of the last kill is when a scrape first saw the counter
+	// increase.
+	mtx      sync.Mutex
+	seen     float64
+	lastTime float64
+	started  bool
+}
+
+func init() {
+	registerCollector(oomSubsystem, defaultEnabled, NewOOMCollector)
+}
+
+// NewOOMCollector returns a new Collector exposing the time of the last OOM
+// kill. The number of kills is node_vmstat_oom_kill.
+func NewOOMCollector(logger log.Logger) (Collector, error) {
+	c := &oomCollector{
+		lastKill: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, oomSubsystem, "last_kill_timestamp_seconds"),
+			"Time of the scrape which first saw the last OOM kill, 0 if none was seen.",
+			nil, nil,
+		),
+		logger: logger,
+	}
+	counters, err := restoreCounters(oomSubsystem)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Couldn't restore the time of the last OOM kill", "err", err)
+	}
+	if kills, ok := counters["kills"]; ok {
+		c.seen, c.lastTime, c.started = kills, counters["last_kill"], true
+	}
+	return c, nil
+}
+
+func (c *oomCollector) Update(ch chan<- prometheus.Metric) error {
+	kills, err := readOOMKills()
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	switch {
+	case !c.started:
+		// Kills before the first scrape happened at an unknown time.
+		c.started = true
+		c.seen = kills
+		c.persist()
+	case kills > c.seen:
+		c.seen, c.lastTime = kills, float64(time.Now().Unix())
+		c.persist()
+	case kills < c.seen:
+		// The node rebooted while the exporter wasn't running.
+		c.seen = kills
+		c.persist()
+	}
+	ch <- prometheus.MustNewConstMetric(c.lastKill, prometheus.GaugeValue, c.lastTime)
+	return nil
+}
+
+// persist saves the kills seen and the time of the last one in the state
+// file, c.mtx must be held.
+func (c *oomCollector) persist() {
+	if err := persistCounters(oomSubsystem, map[string]float64{"kills": c.seen, "last_kill": c.lastTime}); err != nil {
+		level.Warn(c.logger).Log("msg", "Couldn't save the time of the last OOM kill", "err", err)
+	}
+}
+
+// readOOMKills returns the oom_kill field of /proc/vmstat, added in Linux
+// 4.13.
+func readOOMKills() (float64, error) {
+	file, err := os.Open(procFilePath("vmstat"))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || name != "oom_kill" {
+			continue
+		}
+		kills, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid oom_kill %q: %w", value, err)
+		}
+		return kills, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, ErrNoData
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nooom
+// +build !nooom
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestOOMLastKill(t *testing.T) {
+	dir := t.TempDir()
+	*procPath = dir
+	*stateFile = filepath.Join(dir, "state.json")
+	restart := func() {
+		counterState.loaded = false
+		counterState.counters = nil
+	}
+	defer func() {
+		*procPath = "fixtures/proc"
+		*stateFile = ""
+		restart()
+	}()
+	setKills := func(kills int) {
+		if err := os.WriteFile(filepath.Join(dir, "vmstat"), []byte(fmt.Sprintf("pgfault 100\noom_kill %d\n", kills)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastKill := func(c Collector) float64 {
+		ch := make(chan prometheus.Metric, 1)
+		if err := c.Update(ch); err != nil {
+			t.Fatal(err)
+		}
+		var m dto.Metric
+		if err := (<-ch).Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	restart()
+	c, err := NewOOMCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Kills before the first scrape have no time.
+	setKills(2)
+	if got := lastKill(c); got != 0 {
+		t.Errorf("first scrape: want 0, got %v", got)
+	}
+	setKills(3)
+	before := float64(time.Now().Unix())
+	killed := lastKill(c)
+	if killed < before {
+		t.Errorf("after kill: want at least %v, got %v", before, killed)
+	}
+
+	// The time is restored after a restart, and kept after a reboot.
+	restart()
+	setKills(0)
+	c, err = NewOOMCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := lastKill(c); got != killed {
+		t.Errorf("after restart: want %v, got %v", killed, got)
+	}
+}
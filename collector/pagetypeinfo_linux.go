@@ -0,0 +1,172 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nopagetypeinfo
+// +build !nopagetypeinfo
+
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pagetypeinfoSubsystem = "pagetypeinfo"
+
+// pagetypeinfoCounts are the counts of a migrate type of a zone, by order for
+// the free blocks.
+type pagetypeinfoCounts struct {
+	node, zone, migrateType string
+	counts                  []float64
+}
+
+type pagetypeinfo struct {
+	pagesPerBlock float64
+	orders        []string
+	free          []pagetypeinfoCounts
+	blocks        []pagetypeinfoCounts
+}
+
+type pagetypeinfoCollector struct {
+	pagesPerBlock *prometheus.Desc
+	freeBlocks    *prometheus.Desc
+	pageBlocks    *prometheus.Desc
+	logger        log.Logger
+}
+
+func init() {
+	registerCollector(pagetypeinfoSubsystem, defaultDisabled, NewPagetypeinfoCollector)
+}
+
+// NewPagetypeinfoCollector returns a new Collector exposing the free blocks
+// and page blocks of each zone by migrate type.
+func NewPagetypeinfoCollector(logger log.Logger) (Collector, error) {
+	return &pagetypeinfoCollector{
+		pagesPerBlock: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pagetypeinfoSubsystem, "pages_per_block"),
+			"Number of pages of the page blocks the migrate types are assigned to.",
+			nil, nil,
+		),
+		freeBlocks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pagetypeinfoSubsystem, "free_blocks"),
+			"Number of free blocks of 2^order pages of the zone, by migrate type.",
+			[]string{"node", "zone", "type", "order"}, nil,
+		),
+		pageBlocks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, pagetypeinfoSubsystem, "page_blocks"),
+			"Number of page blocks of the zone assigned to the migrate type.",
+			[]string{"node", "zone", "type"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *pagetypeinfoCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("pagetypeinfo"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := parsePagetypeinfo(file)
+	if err != nil {
+		return fmt.Errorf("couldn't parse pagetypeinfo: %w", err)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pagesPerBlock, prometheus.GaugeValue, info.pagesPerBlock)
+	for _, free := range info.free {
+		for i, count := range free.counts {
+			ch <- prometheus.MustNewConstMetric(c.freeBlocks, prometheus.GaugeValue, count, free.node, free.zone, free.migrateType, info.orders[i])
+		}
+	}
+	for _, blocks := range info.blocks {
+		ch <- prometheus.MustNewConstMetric(c.pageBlocks, prometheus.GaugeValue, blocks.counts[0], blocks.node, blocks.zone, blocks.migrateType)
+	}
+	return nil
+}
+
+// parsePagetypeinfo parses /proc/pagetypeinfo. The orders and the migrate
+// types, which depend on the kernel configuration, are read from the headers
+// of the tables.
+func parsePagetypeinfo(r io.Reader) (*pagetypeinfo, error) {
+	var (
+		info    = &pagetypeinfo{}
+		types   []string
+		section string
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		switch {
+		case len(fields) == 0:
+			continue
+		case strings.HasPrefix(line, "Pages per block:"):
+			v, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pages per block: %w", err)
+			}
+			info.pagesPerBlock = v
+		case strings.HasPrefix(line, "Free pages count per migrate type at order"):
+			section = "free"
+			info.orders = fields[8:]
+		case strings.HasPrefix(line, "Number of blocks type"):
+			section = "blocks"
+			types = fields[4:]
+		case strings.HasPrefix(line, "Number of "):
+			// Like the mixed blocks with CONFIG_PAGE_OWNER.
+			section = ""
+		case fields[0] == "Node" && section == "free":
+			// Node 0, zone DMA32, type Movable 23339 12664 ...
+			if len(fields) != 6+len(info.orders) || fields[2] != "zone" || fields[4] != "type" {
+				return nil, fmt.Errorf("invalid line: %q", line)
+			}
+			counts, err := parsePagetypeinfoCounts(fields[6:])
+			if err != nil {
+				return nil, err
+			}
+			info.free = append(info.free, pagetypeinfoCounts{fields[1], fields[3], fields[5], counts})
+		case fields[0] == "Node" && section == "blocks":
+			// Node 0, zone DMA32 10 1475 43 0 0
+			if len(fields) != 4+len(types) || fields[2] != "zone" {
+				return nil, fmt.Errorf("invalid line: %q", line)
+			}
+			counts, err := parsePagetypeinfoCounts(fields[4:])
+			if err != nil {
+				return nil, err
+			}
+			for i, count := range counts {
+				info.blocks = append(info.blocks, pagetypeinfoCounts{fields[1], fields[3], types[i], []float64{count}})
+			}
+		}
+	}
+	return info, scanner.Err()
+}
+
+func parsePagetypeinfoCounts(fields []string) ([]float64, error) {
+	counts := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid count %q: %w", field, err)
+		}
+		counts[i] = v
+	}
+	return counts, nil
+}
@@ -0,0 +1,54 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nopagetypeinfo
+// +build !nopagetypeinfo
+
+package collector
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParsePagetypeinfo(t *testing.T) {
+	file, err := os.Open("fixtures/proc/pagetypeinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := parsePagetypeinfo(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.pagesPerBlock != 512 {
+		t.Errorf("want 512 pages per block, got %v", info.pagesPerBlock)
+	}
+	if len(info.orders) != 11 || len(info.free) != 10 {
+		t.Fatalf("want 11 orders of 10 migrate types, got %d of %d", len(info.orders), len(info.free))
+	}
+	want := pagetypeinfoCounts{"0", "Normal", "Movable", []float64{4048, 3198, 2602, 7, 9, 0, 0, 0, 0, 0, 0}}
+	if got := info.free[6]; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	// The mixed blocks aren't counted as page blocks.
+	if len(info.blocks) != 10 {
+		t.Fatalf("want 10 page block counts, got %d", len(info.blocks))
+	}
+	want = pagetypeinfoCounts{"0", "DMA32", "Movable", []float64{1475}}
+	if got := info.blocks[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -17,7 +17,10 @@
 package collector
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -191,6 +194,13 @@ type perfCollector struct {
 	desc                map[string]*prometheus.Desc
 	logger              log.Logger
 	tracepointCollector *perfTracepointCollector
+	cpuPMUs             map[int]perfCPUPMU
+}
+
+// perfCPUPMU is the core PMU of a CPU and the cluster of the CPU, on systems
+// with several kinds of cores like ARM big.LITTLE or hybrid x86 CPUs.
+type perfCPUPMU struct {
+	pmu, cluster string
 }
 
 type perfTracepointCollector struct {
@@ -676,13 +686,74 @@ func NewPerfCollector(logger log.Logger) (Collector, error) {
 			[]string{"cpu"},
 			nil,
 		),
+		"cpu_pmu_info": prometheus.NewDesc(
+			prometheus.BuildFQName(
+				namespace,
+				perfSubsystem,
+				"cpu_pmu_info",
+			),
+			"Core PMU counting the hardware events of a CPU, and the cluster of the CPU",
+			[]string{"cpu", "pmu", "cluster"},
+			nil,
+		),
+	}
+
+	collector.cpuPMUs, err = perfCPUPMUs(cpus)
+	if err != nil {
+		level.Debug(logger).Log("msg", "couldn't map CPUs to PMUs", "err", err)
 	}
 
 	return collector, nil
 }
 
+// perfCPUPMUs returns the core PMU and cluster of the given CPUs. Only
+// systems with several core PMUs list the CPUs of each, others return none.
+func perfCPUPMUs(cpus []int) (map[int]perfCPUPMU, error) {
+	paths, err := filepath.Glob(sysFilePath("bus/event_source/devices/*/cpus"))
+	if err != nil {
+		return nil, err
+	}
+	pmus := map[int]string{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pmuCPUs, err := perfCPUFlagToCPUs(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list in %s: %w", path, err)
+		}
+		for _, cpu := range pmuCPUs {
+			pmus[cpu] = filepath.Base(filepath.Dir(path))
+		}
+	}
+
+	cpuPMUs := map[int]perfCPUPMU{}
+	for _, cpu := range cpus {
+		pmu, ok := pmus[cpu]
+		if !ok {
+			continue
+		}
+		// cluster_id exists since Linux 5.16.
+		cluster, err := os.ReadFile(sysFilePath(fmt.Sprintf("devices/system/cpu/cpu%d/topology/cluster_id", cpu)))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		cpuPMUs[cpu] = perfCPUPMU{pmu: pmu, cluster: strings.TrimSpace(string(cluster))}
+	}
+	return cpuPMUs, nil
+}
+
 // Update implements the Collector interface and will collect metrics per CPU.
 func (c *perfCollector) Update(ch chan<- prometheus.Metric) error {
+	for cpu, pmu := range c.cpuPMUs {
+		ch <- prometheus.MustNewConstMetric(
+			c.desc["cpu_pmu_info"],
+			prometheus.GaugeValue, 1,
+			strconv.Itoa(cpu), pmu.pmu, pmu.cluster,
+		)
+	}
+
 	if err := c.updateHardwareStats(ch); err != nil {
 		return err
 	}
@@ -18,6 +18,7 @@ package collector
 
 import (
 	"os"
+	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
@@ -255,3 +256,20 @@ func TestPerfTracepointFlagToTracepoints(t *testing.T) {
 		})
 	}
 }
+
+func TestPerfCPUPMUs(t *testing.T) {
+	*sysPath = "fixtures/sys"
+	cpuPMUs, err := perfCPUPMUs([]int{0, 1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]perfCPUPMU{
+		0: {pmu: "armv8_cortex_a55", cluster: "0"},
+		1: {pmu: "armv8_cortex_a55", cluster: "0"},
+		2: {pmu: "armv8_cortex_a76", cluster: "1"},
+		3: {pmu: "armv8_cortex_a76", cluster: "1"},
+	}
+	if !reflect.DeepEqual(cpuPMUs, want) {
+		t.Errorf("want %v, got %v", want, cpuPMUs)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"syscall"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
@@ -30,15 +31,21 @@ import (
 
 var (
 	psiResources = []string{"cpu", "io", "memory"}
+
+	pressureAverages = kingpin.Flag("collector.pressure.averages", "Expose the kernel's 10s, 60s and 300s averages of the ratio of time tasks waited and stalled.").Bool()
 )
 
 type pressureStatsCollector struct {
 	cpu     *prometheus.Desc
+	cpuFull *prometheus.Desc
 	io      *prometheus.Desc
 	ioFull  *prometheus.Desc
 	mem     *prometheus.Desc
 	memFull *prometheus.Desc
 
+	waitingRatio *prometheus.Desc
+	stalledRatio *prometheus.Desc
+
 	fs procfs.FS
 
 	logger log.Logger
@@ -61,6 +68,11 @@ func NewPressureStatsCollector(logger log.Logger) (Collector, error) {
 			"Total time in seconds that processes have waited for CPU time",
 			nil, nil,
 		),
+		cpuFull: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "cpu_stalled_seconds_total"),
+			"Total time in seconds no process could make progress due to CPU congestion, on Linux 5.13+",
+			nil, nil,
+		),
 		io: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "pressure", "io_waiting_seconds_total"),
 			"Total time in seconds that processes have waited due to IO congestion",
@@ -81,6 +93,16 @@ func NewPressureStatsCollector(logger log.Logger) (Collector, error) {
 			"Total time in seconds no process could make progress due to memory congestion",
 			nil, nil,
 		),
+		waitingRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "waiting_ratio"),
+			"Average ratio of time some processes waited for the resource over the window",
+			[]string{"resource", "window"}, nil,
+		),
+		stalledRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "pressure", "stalled_ratio"),
+			"Average ratio of time no process could make progress due to congestion of the resource over the window",
+			[]string{"resource", "window"}, nil,
+		),
 		fs:     fs,
 		logger: logger,
 	}, nil
@@ -105,6 +127,9 @@ func (c *pressureStatsCollector) Update(ch chan<- prometheus.Metric) error {
 		switch res {
 		case "cpu":
 			ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, float64(vals.Some.Total)/1000.0/1000.0)
+			if vals.Full != nil {
+				ch <- prometheus.MustNewConstMetric(c.cpuFull, prometheus.CounterValue, float64(vals.Full.Total)/1000.0/1000.0)
+			}
 		case "io":
 			ch <- prometheus.MustNewConstMetric(c.io, prometheus.CounterValue, float64(vals.Some.Total)/1000.0/1000.0)
 			ch <- prometheus.MustNewConstMetric(c.ioFull, prometheus.CounterValue, float64(vals.Full.Total)/1000.0/1000.0)
@@ -114,7 +139,22 @@ func (c *pressureStatsCollector) Update(ch chan<- prometheus.Metric) error {
 		default:
 			level.Debug(c.logger).Log("msg", "did not account for resource", "resource", res)
 		}
+		if *pressureAverages {
+			c.updateAverages(ch, res, c.waitingRatio, vals.Some)
+			c.updateAverages(ch, res, c.stalledRatio, vals.Full)
+		}
 	}
 
 	return nil
 }
+
+// updateAverages exposes the averages of a line of a pressure file, which are
+// percentages.
+func (c *pressureStatsCollector) updateAverages(ch chan<- prometheus.Metric, res string, desc *prometheus.Desc, line *procfs.PSILine) {
+	if line == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, line.Avg10/100, res, "10s")
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, line.Avg60/100, res, "60s")
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, line.Avg300/100, res, "300s")
+}
@@ -21,25 +21,30 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
 )
 
+var processesWchanTop = kingpin.Flag("collector.processes.wchan-top", "Number of wait channels to break down threads in uninterruptible sleep by, the others are summed up as other. 0 disables the breakdown.").Default("0").Int()
+
 type processCollector struct {
-	fs           procfs.FS
-	threadAlloc  *prometheus.Desc
-	threadLimit  *prometheus.Desc
-	threadsState *prometheus.Desc
-	procsState   *prometheus.Desc
-	pidUsed      *prometheus.Desc
-	pidMax       *prometheus.Desc
-	logger       log.Logger
+	fs              procfs.FS
+	threadAlloc     *prometheus.Desc
+	threadLimit     *prometheus.Desc
+	threadsState    *prometheus.Desc
+	procsState      *prometheus.Desc
+	pidUsed         *prometheus.Desc
+	pidMax          *prometheus.Desc
+	uninterruptible *prometheus.Desc
+	logger          log.Logger
 }
 
 func init() {
@@ -81,11 +86,19 @@ func NewProcessStatCollector(logger log.Logger) (Collector, error) {
 		pidMax: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "max_processes"),
 			"Number of max PIDs limit", nil, nil,
 		),
+		uninterruptible: prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, "uninterruptible"),
+			"Number of threads in uninterruptible sleep (D state) by the kernel function they wait in.",
+			[]string{"wchan"}, nil,
+		),
 		logger: logger,
 	}, nil
 }
 func (c *processCollector) Update(ch chan<- prometheus.Metric) error {
-	pids, states, threads, threadStates, err := c.getAllocatedThreads()
+	var wchans map[string]int
+	if *processesWchanTop > 0 {
+		wchans = map[string]int{}
+	}
+	pids, states, threads, threadStates, err := c.getAllocatedThreads(wchans)
 	if err != nil {
 		return fmt.Errorf("unable to retrieve number of allocated threads: %w", err)
 	}
@@ -112,10 +125,19 @@ func (c *processCollector) Update(ch chan<- prometheus.Metric) error {
 	ch <- prometheus.MustNewConstMetric(c.pidUsed, prometheus.GaugeValue, float64(pids))
 	ch <- prometheus.MustNewConstMetric(c.pidMax, prometheus.GaugeValue, float64(pidM))
 
+	if wchans != nil {
+		for wchan, count := range topWchans(wchans, *processesWchanTop) {
+			ch <- prometheus.MustNewConstMetric(c.uninterruptible, prometheus.GaugeValue, float64(count), wchan)
+		}
+	}
+
 	return nil
 }
 
-func (c *processCollector) getAllocatedThreads() (int, map[string]int32, int, map[string]int32, error) {
+// getAllocatedThreads counts the processes and threads by state. If wchans
+// isn't nil, the threads in uninterruptible sleep are also counted in it by
+// wait channel.
+func (c *processCollector) getAllocatedThreads(wchans map[string]int) (int, map[string]int32, int, map[string]int32, error) {
 	p, err := c.fs.AllProcs()
 	if err != nil {
 		return 0, nil, 0, nil, fmt.Errorf("unable to list all processes: %w", err)
@@ -139,7 +161,7 @@ func (c *processCollector) getAllocatedThreads() (int, map[string]int32, int, ma
 		pids++
 		procStates[stat.State]++
 		thread += stat.NumThreads
-		err = c.getThreadStates(pid.PID, stat, threadStates)
+		err = c.getThreadStates(pid.PID, stat, threadStates, wchans)
 		if err != nil {
 			return 0, nil, 0, nil, err
 		}
@@ -147,7 +169,7 @@ func (c *processCollector) getAllocatedThreads() (int, map[string]int32, int, ma
 	return pids, procStates, thread, threadStates, nil
 }
 
-func (c *processCollector) getThreadStates(pid int, pidStat procfs.ProcStat, threadStates map[string]int32) error {
+func (c *processCollector) getThreadStates(pid int, pidStat procfs.ProcStat, threadStates map[string]int32, wchans map[string]int) error {
 	fs, err := procfs.NewFS(procFilePath(path.Join(strconv.Itoa(pid), "task")))
 	if err != nil {
 		if c.isIgnoredError(err) {
@@ -168,24 +190,67 @@ func (c *processCollector) getThreadStates(pid int, pidStat procfs.ProcStat, thr
 	}
 
 	for _, thread := range t {
-		if pid == thread.PID {
-			threadStates[pidStat.State]++
+		state := pidStat.State
+		if pid != thread.PID {
+			threadStat, err := thread.Stat()
+			if err != nil {
+				if c.isIgnoredError(err) {
+					level.Debug(c.logger).Log("msg", "file not found when retrieving stats for thread", "pid", pid, "threadId", thread.PID, "err", err)
+					continue
+				}
+				level.Debug(c.logger).Log("msg", "error reading stat for thread", "pid", pid, "threadId", thread.PID, "err", err)
+				return fmt.Errorf("error reading stat for pid:%d thread:%d err:%w", pid, thread.PID, err)
+			}
+			state = threadStat.State
+		}
+		threadStates[state]++
+		if wchans == nil || state != "D" {
 			continue
 		}
-		threadStat, err := thread.Stat()
+		wchan, err := thread.Wchan()
 		if err != nil {
 			if c.isIgnoredError(err) {
-				level.Debug(c.logger).Log("msg", "file not found when retrieving stats for thread", "pid", pid, "threadId", thread.PID, "err", err)
 				continue
 			}
-			level.Debug(c.logger).Log("msg", "error reading stat for thread", "pid", pid, "threadId", thread.PID, "err", err)
-			return fmt.Errorf("error reading stat for pid:%d thread:%d err:%w", pid, thread.PID, err)
+			return fmt.Errorf("error reading wchan for pid:%d thread:%d err:%w", pid, thread.PID, err)
 		}
-		threadStates[threadStat.State]++
+		if wchan == "" {
+			// The thread woke up in the meantime, or its wait channel is
+			// hidden from us.
+			wchan = "unknown"
+		}
+		wchans[wchan]++
 	}
 	return nil
 }
 
+// topWchans keeps the n wait channels with the most threads and sums up the
+// others as other, to bound the cardinality.
+func topWchans(wchans map[string]int, n int) map[string]int {
+	if len(wchans) <= n {
+		return wchans
+	}
+	names := make([]string, 0, len(wchans))
+	for wchan := range wchans {
+		names = append(names, wchan)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if wchans[names[i]] != wchans[names[j]] {
+			return wchans[names[i]] > wchans[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	top := make(map[string]int, n+1)
+	for i, wchan := range names {
+		if i < n {
+			top[wchan] = wchans[wchan]
+		} else {
+			top["other"] += wchans[wchan]
+		}
+	}
+	return top
+}
+
 func (c *processCollector) isIgnoredError(err error) bool {
 	if errors.Is(err, os.ErrNotExist) || strings.Contains(err.Error(), syscall.ESRCH.Error()) {
 		return true
@@ -17,6 +17,10 @@
 package collector
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/alecthomas/kingpin/v2"
@@ -34,7 +38,7 @@ func TestReadProcessStatus(t *testing.T) {
 		t.Errorf("failed to open procfs: %v", err)
 	}
 	c := processCollector{fs: fs, logger: log.NewNopLogger()}
-	pids, states, threads, _, err := c.getAllocatedThreads()
+	pids, states, threads, _, err := c.getAllocatedThreads(nil)
 	if err != nil {
 		t.Fatalf("Cannot retrieve data from procfs getAllocatedThreads function: %v ", err)
 	}
@@ -53,3 +57,76 @@ func TestReadProcessStatus(t *testing.T) {
 		t.Fatalf("Total running pids cannot be greater than %d or equals to 0", maxPid)
 	}
 }
+
+func TestUninterruptibleWchans(t *testing.T) {
+	dir := t.TempDir()
+	*procPath = dir
+	defer func() { *procPath = "fixtures/proc" }()
+	// Threads by process with their state and wait channel.
+	for pid, threads := range map[int]map[int][2]string{
+		100: {100: {"S", "do_epoll_wait"}, 101: {"D", "nfs_wait_on_request"}, 102: {"D", "nfs_wait_on_request"}},
+		200: {200: {"D", "io_schedule"}, 201: {"D", ""}},
+		300: {300: {"R", "0"}},
+	} {
+		for tid, thread := range threads {
+			task := filepath.Join(dir, fmt.Sprint(pid), "task", fmt.Sprint(tid))
+			if err := os.MkdirAll(task, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			stat := fmt.Sprintf("%d (test) %s 1 0 0 0 -1 4194560 0 0 0 0 0 0 0 0 20 0 %d 0 24 0 0 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n", tid, thread[0], len(threads))
+			files := map[string]string{
+				filepath.Join(task, "stat"):  stat,
+				filepath.Join(task, "wchan"): thread[1],
+			}
+			if tid == pid {
+				files[filepath.Join(dir, fmt.Sprint(pid), "stat")] = stat
+			}
+			for file, content := range files {
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	fs, err := procfs.NewFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := processCollector{fs: fs, logger: log.NewNopLogger()}
+	wchans := map[string]int{}
+	_, _, _, threadStates, err := c.getAllocatedThreads(wchans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int32{"S": 1, "D": 4, "R": 1}; !reflect.DeepEqual(threadStates, want) {
+		t.Errorf("thread states: want %v, got %v", want, threadStates)
+	}
+	if want := map[string]int{"nfs_wait_on_request": 2, "io_schedule": 1, "unknown": 1}; !reflect.DeepEqual(wchans, want) {
+		t.Errorf("wait channels: want %v, got %v", want, wchans)
+	}
+}
+
+func TestTopWchans(t *testing.T) {
+	wchans := map[string]int{
+		"rpc_wait_bit_killable": 40,
+		"io_schedule":           12,
+		"wait_on_page_bit":      12,
+		"jbd2_log_wait_commit":  3,
+		"unknown":               1,
+	}
+
+	got := topWchans(wchans, 2)
+	want := map[string]int{
+		"rpc_wait_bit_killable": 40,
+		"io_schedule":           12,
+		"other":                 16,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if got := topWchans(wchans, 10); !reflect.DeepEqual(got, wchans) {
+		t.Errorf("want %v, got %v", wchans, got)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noprocessgroup
+// +build !noprocessgroup
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+const (
+	processGroupSubsystem = "processgroup"
+	// processGroupUserHZ is USER_HZ, the unit of the CPU times in
+	// /proc/[pid]/stat, which is 100 on all architectures procfs supports.
+	processGroupUserHZ = 100
+)
+
+var (
+	processGroupNames    = kingpin.Flag("collector.processgroup.name", "Group of processes whose name, as shown in /proc/[pid]/stat, matches a regexp, in the form <group>=<regexp>, can be repeated.").Strings()
+	processGroupCmdlines = kingpin.Flag("collector.processgroup.cmdline", "Group of processes whose command line, with arguments separated by spaces, matches a regexp, in the form <group>=<regexp>, can be repeated.").Strings()
+)
+
+// processGroupMatcher assigns the processes matching pattern to group,
+// matching their name or, if cmdline is set, their command line.
+type processGroupMatcher struct {
+	group   string
+	pattern *regexp.Regexp
+	cmdline bool
+}
+
+// processKey identifies a process across scrapes, as PIDs are reused.
+type processKey struct {
+	pid       int
+	starttime uint64
+}
+
+// processCPU is the CPU time a process of group used up to the last scrape.
+type processCPU struct {
+	group        string
+	user, system float64
+}
+
+type processGroupStats struct {
+	processes, threads, fds int
+	rss                     int
+	user, system            float64
+}
+
+type processGroupCollector struct {
+	fs       procfs.FS
+	matchers []processGroupMatcher
+	groups   []string
+
+	processes *prometheus.Desc
+	threads   *prometheus.Desc
+	cpu       *prometheus.Desc
+	rss       *prometheus.Desc
+	fds       *prometheus.Desc
+	logger    log.Logger
+
+	// mtx protects the state which keeps the CPU counters of the groups
+	// from going down when their processes exit.
+	mtx    sync.Mutex
+	seen   map[processKey]processCPU
+	exited map[string]*processGroupStats
+}
+
+func init() {
+	registerCollector(processGroupSubsystem, defaultDisabled, NewProcessGroupCollector)
+}
+
+// NewProcessGroupCollector returns a new Collector exposing the summed
+// resource usage of the processes matched by name or command line.
+func NewProcessGroupCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	c := &processGroupCollector{
+		fs: fs,
+		processes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, processGroupSubsystem, "processes"),
+			"Number of processes in the group.",
+			[]string{"group"}, nil,
+		),
+		threads: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, processGroupSubsystem, "threads"),
+			"Number of threads of the processes in the group.",
+			[]string{"group"}, nil,
+		),
+		cpu: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, processGroupSubsystem, "cpu_seconds_total"),
+			"Seconds the processes in the group spent in each mode, including exited ones.",
+			[]string{"group", "mode"}, nil,
+		),
+		rss: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, processGroupSubsystem, "resident_memory_bytes"),
+			"Resident memory of the processes in the group in bytes.",
+			[]string{"group"}, nil,
+		),
+		fds: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, processGroupSubsystem, "open_fds"),
+			"Number of open file descriptors of the processes in the group which can be read.",
+			[]string{"group"}, nil,
+		),
+		logger: logger,
+		seen:   map[processKey]processCPU{},
+		exited: map[string]*processGroupStats{},
+	}
+	for _, flag := range []struct {
+		values  []string
+		cmdline bool
+	}{{*processGroupNames, false}, {*processGroupCmdlines, true}} {
+		for _, value := range flag.values {
+			m, err := parseProcessGroupMatcher(value, flag.cmdline)
+			if err != nil {
+				return nil, err
+			}
+			c.matchers = append(c.matchers, m)
+			if _, ok := c.exited[m.group]; !ok {
+				c.exited[m.group] = &processGroupStats{}
+				c.groups = append(c.groups, m.group)
+			}
+		}
+	}
+
+	counters, err := restoreCounters(processGroupSubsystem)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Couldn't restore the CPU time of exited processes", "err", err)
+	}
+	for group, s := range c.exited {
+		s.user, s.system = counters[group+"/user"], counters[group+"/system"]
+	}
+	return c, nil
+}
+
+func parseProcessGroupMatcher(value string, cmdline bool) (processGroupMatcher, error) {
+	group, expr, ok := strings.Cut(value, "=")
+	if !ok || group == "" {
+		return processGroupMatcher{}, fmt.Errorf("invalid process group %q, must be <group>=<regexp>", value)
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return processGroupMatcher{}, fmt.Errorf("invalid regexp of process group %q: %w", group, err)
+	}
+	return processGroupMatcher{group: group, pattern: pattern, cmdline: cmdline}, nil
+}
+
+func (c *processGroupCollector) Update(ch chan<- prometheus.Metric) error {
+	if len(c.matchers) == 0 {
+		return ErrNoData
+	}
+	stats, err := c.groupStats()
+	if err != nil {
+		return err
+	}
+	for _, group := range c.groups {
+		s := stats[group]
+		ch <- prometheus.MustNewConstMetric(c.processes, prometheus.GaugeValue, float64(s.processes), group)
+		ch <- prometheus.MustNewConstMetric(c.threads, prometheus.GaugeValue, float64(s.threads), group)
+		ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, s.user, group, "user")
+		ch <- prometheus.MustNewConstMetric(c.cpu, prometheus.CounterValue, s.system, group, "system")
+		ch <- prometheus.MustNewConstMetric(c.rss, prometheus.GaugeValue, float64(s.rss), group)
+		ch <- prometheus.MustNewConstMetric(c.fds, prometheus.GaugeValue, float64(s.fds), group)
+	}
+	return nil
+}
+
+// groupStats sums the usage of the running processes of each group and adds
+// the CPU time of the processes which exited since they were first seen.
+func (c *processGroupCollector) groupStats() (map[string]*processGroupStats, error) {
+	procs, err := c.fs.AllProcs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list processes: %w", err)
+	}
+	stats := make(map[string]*processGroupStats, len(c.groups))
+	for _, group := range c.groups {
+		stats[group] = &processGroupStats{}
+	}
+	seen := map[processKey]processCPU{}
+	for _, p := range procs {
+		stat, err := p.Stat()
+		if err != nil {
+			// The process exited since it was listed.
+			continue
+		}
+		group, ok := c.match(p, stat.Comm)
+		if !ok {
+			continue
+		}
+		s := stats[group]
+		s.processes++
+		s.threads += stat.NumThreads
+		s.rss += stat.ResidentMemory()
+		cpu := processCPU{
+			group:  group,
+			user:   float64(stat.UTime) / processGroupUserHZ,
+			system: float64(stat.STime) / processGroupUserHZ,
+		}
+		s.user += cpu.user
+		s.system += cpu.system
+		seen[processKey{pid: p.PID, starttime: stat.Starttime}] = cpu
+
+		fds, err := p.FileDescriptorsLen()
+		if err != nil {
+			if !errors.Is(err, os.ErrPermission) && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("unable to count file descriptors of pid %d: %w", p.PID, err)
+			}
+			level.Debug(c.logger).Log("msg", "couldn't count file descriptors", "pid", p.PID, "err", err)
+			continue
+		}
+		s.fds += fds
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	exited := false
+	for key, cpu := range c.seen {
+		if _, ok := seen[key]; !ok {
+			c.exited[cpu.group].user += cpu.user
+			c.exited[cpu.group].system += cpu.system
+			exited = true
+		}
+	}
+	c.seen = seen
+	if exited {
+		c.persistExited()
+	}
+	for group, s := range stats {
+		s.user += c.exited[group].user
+		s.system += c.exited[group].system
+	}
+	return stats, nil
+}
+
+// persistExited saves the CPU time of the exited processes of each group in
+// the state file, c.mtx must be held.
+func (c *processGroupCollector) persistExited() {
+	counters := make(map[string]float64, 2*len(c.exited))
+	for group, s := range c.exited {
+		counters[group+"/user"], counters[group+"/system"] = s.user, s.system
+	}
+	if err := persistCounters(processGroupSubsystem, counters); err != nil {
+		level.Warn(c.logger).Log("msg", "Couldn't save the CPU time of exited processes", "err", err)
+	}
+}
+
+// match returns the group of the first matcher matching the process.
+func (c *processGroupCollector) match(p procfs.Proc, comm string) (string, bool) {
+	var cmdline *string
+	for _, m := range c.matchers {
+		if !m.cmdline {
+			if m.pattern.MatchString(comm) {
+				return m.group, true
+			}
+			continue
+		}
+		if cmdline == nil {
+			// Kernel threads and zombies have an empty command line.
+			args, err := p.CmdLine()
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "couldn't read command line", "pid", p.PID, "err", err)
+			}
+			joined := strings.Join(args, " ")
+			cmdline = &joined
+		}
+		if m.pattern.MatchString(*cmdline) {
+			return m.group, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,99 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noprocessgroup
+// +build !noprocessgroup
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestProcessGroup(t *testing.T) {
+	*procPath = "fixtures/proc"
+	*processGroupNames = []string{"init=^systemd$", "kernel=^(khungtaskd|rcu_)"}
+	// pid 1 is already in the init group, the first match wins.
+	*processGroupCmdlines = []string{"boot=splash"}
+	defer func() {
+		*processGroupNames = nil
+		*processGroupCmdlines = nil
+	}()
+
+	collector, err := NewProcessGroupCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := collector.(*processGroupCollector)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	expected := func(kernelUser, kernelSystem float64) string {
+		return fmt.Sprintf(`# HELP node_processgroup_cpu_seconds_total Seconds the processes in the group spent in each mode, including exited ones.
+# TYPE node_processgroup_cpu_seconds_total counter
+node_processgroup_cpu_seconds_total{group="boot",mode="system"} 0
+node_processgroup_cpu_seconds_total{group="boot",mode="user"} 0
+node_processgroup_cpu_seconds_total{group="init",mode="system"} 0.98
+node_processgroup_cpu_seconds_total{group="init",mode="user"} 0.36
+node_processgroup_cpu_seconds_total{group="kernel",mode="system"} %v
+node_processgroup_cpu_seconds_total{group="kernel",mode="user"} %v
+# HELP node_processgroup_open_fds Number of open file descriptors of the processes in the group which can be read.
+# TYPE node_processgroup_open_fds gauge
+node_processgroup_open_fds{group="boot"} 0
+node_processgroup_open_fds{group="init"} 4
+node_processgroup_open_fds{group="kernel"} 0
+# HELP node_processgroup_processes Number of processes in the group.
+# TYPE node_processgroup_processes gauge
+node_processgroup_processes{group="boot"} 0
+node_processgroup_processes{group="init"} 1
+node_processgroup_processes{group="kernel"} 2
+# HELP node_processgroup_resident_memory_bytes Resident memory of the processes in the group in bytes.
+# TYPE node_processgroup_resident_memory_bytes gauge
+node_processgroup_resident_memory_bytes{group="boot"} 0
+node_processgroup_resident_memory_bytes{group="init"} %d
+node_processgroup_resident_memory_bytes{group="kernel"} 0
+# HELP node_processgroup_threads Number of threads of the processes in the group.
+# TYPE node_processgroup_threads gauge
+node_processgroup_threads{group="boot"} 0
+node_processgroup_threads{group="init"} 1
+node_processgroup_threads{group="kernel"} 2
+`, kernelSystem, kernelUser, 2507*os.Getpagesize())
+	}
+
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected(0.14, 3.46))); err != nil {
+		t.Fatal(err)
+	}
+
+	// The CPU time of a process which exited since the last scrape stays in
+	// the counters of its group.
+	exitedUser, exitedSystem := 1.0, 2.0
+	c.seen[processKey{pid: 4242, starttime: 1}] = processCPU{group: "kernel", user: exitedUser, system: exitedSystem}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected(0.14+exitedUser, 3.46+exitedSystem))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessGroupInvalid(t *testing.T) {
+	for _, value := range []string{"nogroup", "=^systemd$", "broken=("} {
+		if _, err := parseProcessGroupMatcher(value, false); err == nil {
+			t.Errorf("%q: expected error", value)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noprojectquota
+// +build !noprojectquota
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	projectQuotaSubsystem = "project_quota"
+
+	// Quota commands of linux/quota.h.
+	qGetNextQuota = 0x800009
+	prjQuota      = 2
+	// Size of the blocks of the limits in struct if_nextdqblk.
+	qifDqblkSize = 1024
+)
+
+var projectQuotaProjidFile = kingpin.Flag("collector.projectquota.projid-file", "File mapping project names to IDs, in the format of /etc/projid.").Default("/etc/projid").String()
+
+// ifNextDqblk mirrors struct if_nextdqblk of linux/quota.h.
+type ifNextDqblk struct {
+	bHardLimit uint64
+	bSoftLimit uint64
+	curSpace   uint64
+	iHardLimit uint64
+	iSoftLimit uint64
+	curInodes  uint64
+	bTime      uint64
+	iTime      uint64
+	valid      uint32
+	id         uint32
+}
+
+// projectQuotaMount is a filesystem mounted with project quotas.
+type projectQuotaMount struct {
+	device, mountPoint string
+}
+
+type projectQuotaCollector struct {
+	usedBytes      *prometheus.Desc
+	bytesHardLimit *prometheus.Desc
+	bytesSoftLimit *prometheus.Desc
+	usedInodes     *prometheus.Desc
+	inodesLimit    *prometheus.Desc
+	logger         log.Logger
+}
+
+func init() {
+	registerCollector("projectquota", defaultDisabled, NewProjectQuotaCollector)
+}
+
+// NewProjectQuotaCollector returns a new Collector exposing the usage and
+// limits of the project quotas of XFS and ext4 filesystems.
+func NewProjectQuotaCollector(logger log.Logger) (Collector, error) {
+	labels := []string{"mountpoint", "project_id", "project"}
+	return &projectQuotaCollector{
+		usedBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, projectQuotaSubsystem, "used_bytes"),
+			"Space used by the files of the project.",
+			labels, nil,
+		),
+		bytesHardLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, projectQuotaSubsystem, "hard_limit_bytes"),
+			"Space the files of the project can't exceed, 0 if unlimited.",
+			labels, nil,
+		),
+		bytesSoftLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, projectQuotaSubsystem, "soft_limit_bytes"),
+			"Space the files of the project can only exceed for the grace period, 0 if unlimited.",
+			labels, nil,
+		),
+		usedInodes: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, projectQuotaSubsystem, "used_inodes"),
+			"Number of inodes used by the project.",
+			labels, nil,
+		),
+		inodesLimit: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, projectQuotaSubsystem, "hard_limit_inodes"),
+			"Number of inodes the project can't exceed, 0 if unlimited.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *projectQuotaCollector) Update(ch chan<- prometheus.Metric) error {
+	file, err := os.Open(procFilePath("1/mounts"))
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open(procFilePath("mounts"))
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	mounts, err := parseProjectQuotaMounts(file)
+	if err != nil {
+		return err
+	}
+	if len(mounts) == 0 {
+		return ErrNoData
+	}
+
+	// The names are optional, kubelet for one only uses the IDs.
+	names := map[uint32]string{}
+	if f, err := os.Open(rootfsFilePath(*projectQuotaProjidFile)); err == nil {
+		names, err = parseProjid(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("couldn't parse %s: %w", *projectQuotaProjidFile, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, m := range mounts {
+		err := readProjectQuotas(rootfsFilePath(m.device), func(q ifNextDqblk) {
+			c.updateProject(ch, m.mountPoint, names, q)
+		})
+		if err != nil {
+			// Reading them requires CAP_SYS_ADMIN.
+			return fmt.Errorf("couldn't read project quotas of %s: %w", m.mountPoint, expectedPermissionError(err))
+		}
+	}
+	return nil
+}
+
+func (c *projectQuotaCollector) updateProject(ch chan<- prometheus.Metric, mountPoint string, names map[uint32]string, q ifNextDqblk) {
+	labels := []string{mountPoint, strconv.FormatUint(uint64(q.id), 10), names[q.id]}
+	ch <- prometheus.MustNewConstMetric(c.usedBytes, prometheus.GaugeValue, float64(q.curSpace), labels...)
+	ch <- prometheus.MustNewConstMetric(c.bytesHardLimit, prometheus.GaugeValue, float64(q.bHardLimit*qifDqblkSize), labels...)
+	ch <- prometheus.MustNewConstMetric(c.bytesSoftLimit, prometheus.GaugeValue, float64(q.bSoftLimit*qifDqblkSize), labels...)
+	ch <- prometheus.MustNewConstMetric(c.usedInodes, prometheus.GaugeValue, float64(q.curInodes), labels...)
+	ch <- prometheus.MustNewConstMetric(c.inodesLimit, prometheus.GaugeValue, float64(q.iHardLimit), labels...)
+}
+
+// readProjectQuotas calls fn with the quota of each project of the filesystem
+// on device, in the order of their IDs.
+func readProjectQuotas(device string, fn func(ifNextDqblk)) error {
+	dev, err := unix.BytePtrFromString(device)
+	if err != nil {
+		return err
+	}
+	for id := uint64(0); id <= 0xffffffff; {
+		var q ifNextDqblk
+		_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, qGetNextQuota<<8|prjQuota,
+			uintptr(unsafe.Pointer(dev)), uintptr(id), uintptr(unsafe.Pointer(&q)), 0, 0)
+		if errno == unix.ENOENT {
+			// No project with an ID at least id.
+			return nil
+		}
+		if errno != 0 {
+			return errno
+		}
+		fn(q)
+		id = uint64(q.id) + 1
+	}
+	return nil
+}
+
+// parseProjectQuotaMounts returns the XFS and ext4 filesystems of a mounts
+// file which have project quota accounting enabled.
+func parseProjectQuotaMounts(r io.Reader) ([]projectQuotaMount, error) {
+	var mounts []projectQuotaMount
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) < 4 {
+			return nil, fmt.Errorf("malformed mount point information: %q", scanner.Text())
+		}
+		if parts[2] != "xfs" && parts[2] != "ext4" {
+			continue
+		}
+		for _, option := range strings.Split(parts[3], ",") {
+			// XFS shows pqnoenforce if the quotas are only accounted.
+			if option == "prjquota" || option == "pquota" || option == "pqnoenforce" {
+				mounts = append(mounts, projectQuotaMount{
+					device:     parts[0],
+					mountPoint: rootfsStripPrefix(strings.NewReplacer("\\040", " ", "\\011", "\t").Replace(parts[1])),
+				})
+				break
+			}
+		}
+	}
+	return mounts, scanner.Err()
+}
+
+// parseProjid parses the name:id lines of a projid file.
+func parseProjid(r io.Reader) (map[uint32]string, error) {
+	names := make(map[uint32]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, id, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed line: %q", line)
+		}
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID of project %s: %w", name, err)
+		}
+		names[uint32(n)] = name
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,70 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noprojectquota
+// +build !noprojectquota
+
+package collector
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestParseProjectQuotaMounts(t *testing.T) {
+	mounts := `/dev/sda1 / ext4 rw,relatime 0 0
+/dev/sdb1 /var/lib/kubelet xfs rw,relatime,attr2,inode64,logbufs=8,logbsize=32k,prjquota 0 0
+/dev/sdc1 /srv/volumes ext4 rw,relatime,prjquota 0 0
+/dev/sdd1 /srv/scratch xfs rw,relatime,pqnoenforce 0 0
+tmpfs /tmp tmpfs rw,prjquota 0 0
+`
+	got, err := parseProjectQuotaMounts(strings.NewReader(mounts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []projectQuotaMount{
+		{"/dev/sdb1", "/var/lib/kubelet"},
+		{"/dev/sdc1", "/srv/volumes"},
+		{"/dev/sdd1", "/srv/scratch"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestParseProjid(t *testing.T) {
+	projid := `# projects of the build farm
+cache:10
+artifacts:1048577
+`
+	got, err := parseProjid(strings.NewReader(projid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32]string{10: "cache", 1048577: "artifacts"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if _, err := parseProjid(strings.NewReader("cache\n")); err == nil {
+		t.Error("expected error for line without ID")
+	}
+}
+
+func TestIfNextDqblkSize(t *testing.T) {
+	if size := unsafe.Sizeof(ifNextDqblk{}); size != 72 {
+		t.Errorf("struct if_nextdqblk is 72 bytes, got %d", size)
+	}
+}
@@ -31,6 +31,7 @@ import (
 type qdiscStatCollector struct {
 	logger       log.Logger
 	deviceFilter deviceFilter
+	children     bool
 	bytes        typedDesc
 	packets      typedDesc
 	drops        typedDesc
@@ -44,6 +45,7 @@ var (
 	collectorQdisc              = kingpin.Flag("collector.qdisc.fixtures", "test fixtures to use for qdisc collector end-to-end testing").Default("").String()
 	collectorQdiskDeviceInclude = kingpin.Flag("collector.qdisk.device-include", "Regexp of qdisk devices to include (mutually exclusive to device-exclude).").String()
 	collectorQdiskDeviceExclude = kingpin.Flag("collector.qdisk.device-exclude", "Regexp of qdisk devices to exclude (mutually exclusive to device-include).").String()
+	collectorQdiscChildren      = kingpin.Flag("collector.qdisc.children", "Also expose the statistics of child qdiscs, like the leaves of HTB or the queues of mq, adding their handle and parent as labels.").Bool()
 )
 
 func init() {
@@ -56,44 +58,50 @@ func NewQdiscStatCollector(logger log.Logger) (Collector, error) {
 		return nil, fmt.Errorf("collector.qdisk.device-include and collector.qdisk.device-exclude are mutaly exclusive")
 	}
 
+	labels := []string{"device", "kind"}
+	if *collectorQdiscChildren {
+		labels = append(labels, "handle", "parent")
+	}
+
 	return &qdiscStatCollector{
 		bytes: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "bytes_total"),
 			"Number of bytes sent.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.CounterValue},
 		packets: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "packets_total"),
 			"Number of packets sent.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.CounterValue},
 		drops: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "drops_total"),
 			"Number of packets dropped.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.CounterValue},
 		requeues: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "requeues_total"),
 			"Number of packets dequeued, not transmitted, and requeued.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.CounterValue},
 		overlimits: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "overlimits_total"),
 			"Number of overlimit packets.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.CounterValue},
 		qlength: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "current_queue_length"),
 			"Number of packets currently in queue to be sent.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.GaugeValue},
 		backlog: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "qdisc", "backlog"),
 			"Number of bytes currently in queue to be sent.",
-			[]string{"device", "kind"}, nil,
+			labels, nil,
 		), prometheus.GaugeValue},
 		logger:       logger,
-		deviceFilter: newDeviceFilter(*collectorQdiskDeviceExclude, *collectorQdiskDeviceExclude),
+		deviceFilter: newDeviceFilter(*collectorQdiskDeviceExclude, *collectorQdiskDeviceInclude),
+		children:     *collectorQdiscChildren,
 	}, nil
 }
 
@@ -126,8 +134,8 @@ func (c *qdiscStatCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, msg := range msgs {
-		// Only report root qdisc information.
-		if msg.Parent != 0 {
+		// Only report root qdisc information, unless asked otherwise.
+		if msg.Parent != 0 && !c.children {
 			continue
 		}
 
@@ -135,14 +143,32 @@ func (c *qdiscStatCollector) Update(ch chan<- prometheus.Metric) error {
 			continue
 		}
 
-		ch <- c.bytes.mustNewConstMetric(float64(msg.Bytes), msg.IfaceName, msg.Kind)
-		ch <- c.packets.mustNewConstMetric(float64(msg.Packets), msg.IfaceName, msg.Kind)
-		ch <- c.drops.mustNewConstMetric(float64(msg.Drops), msg.IfaceName, msg.Kind)
-		ch <- c.requeues.mustNewConstMetric(float64(msg.Requeues), msg.IfaceName, msg.Kind)
-		ch <- c.overlimits.mustNewConstMetric(float64(msg.Overlimits), msg.IfaceName, msg.Kind)
-		ch <- c.qlength.mustNewConstMetric(float64(msg.Qlen), msg.IfaceName, msg.Kind)
-		ch <- c.backlog.mustNewConstMetric(float64(msg.Backlog), msg.IfaceName, msg.Kind)
+		labels := []string{msg.IfaceName, msg.Kind}
+		if c.children {
+			parent := "root"
+			if msg.Parent != 0 {
+				parent = tcHandle(msg.Parent)
+			}
+			labels = append(labels, tcHandle(msg.Handle), parent)
+		}
+
+		ch <- c.bytes.mustNewConstMetric(float64(msg.Bytes), labels...)
+		ch <- c.packets.mustNewConstMetric(float64(msg.Packets), labels...)
+		ch <- c.drops.mustNewConstMetric(float64(msg.Drops), labels...)
+		ch <- c.requeues.mustNewConstMetric(float64(msg.Requeues), labels...)
+		ch <- c.overlimits.mustNewConstMetric(float64(msg.Overlimits), labels...)
+		ch <- c.qlength.mustNewConstMetric(float64(msg.Qlen), labels...)
+		ch <- c.backlog.mustNewConstMetric(float64(msg.Backlog), labels...)
 	}
 
 	return nil
 }
+
+// tcHandle formats a qdisc or class handle like tc does, as "major:minor" in
+// hexadecimal with the minor omitted if it's 0.
+func tcHandle(h uint32) string {
+	if h&0xffff == 0 {
+		return fmt.Sprintf("%x:", h>>16)
+	}
+	return fmt.Sprintf("%x:%x", h>>16, h&0xffff)
+}
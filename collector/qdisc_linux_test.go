@@ -0,0 +1,56 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noqdisc
+// +build !noqdisc
+
+package collector
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestQdiscChildren(t *testing.T) {
+	savedFixtures, savedChildren, savedInclude := *collectorQdisc, *collectorQdiscChildren, *collectorQdiskDeviceInclude
+	defer func() {
+		*collectorQdisc, *collectorQdiscChildren, *collectorQdiskDeviceInclude = savedFixtures, savedChildren, savedInclude
+	}()
+	*collectorQdisc = "fixtures/qdisc/"
+	*collectorQdiscChildren = true
+	*collectorQdiskDeviceInclude = "^eth1$"
+
+	c, err := NewQdiscStatCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_qdisc_backlog Number of bytes currently in queue to be sent.
+# TYPE node_qdisc_backlog gauge
+node_qdisc_backlog{device="eth1",handle="1:",kind="htb",parent="root"} 0
+node_qdisc_backlog{device="eth1",handle="8001:",kind="fq_codel",parent="1:10"} 1514
+# HELP node_qdisc_drops_total Number of packets dropped.
+# TYPE node_qdisc_drops_total counter
+node_qdisc_drops_total{device="eth1",handle="1:",kind="htb",parent="root"} 0
+node_qdisc_drops_total{device="eth1",handle="8001:",kind="fq_codel",parent="1:10"} 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_qdisc_backlog", "node_qdisc_drops_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -40,6 +40,7 @@ type raplCollector struct {
 
 func init() {
 	registerCollector(raplCollectorSubsystem, defaultEnabled, NewRaplCollector)
+	registerHardwareProbe(raplCollectorSubsystem, "no RAPL domains", sysfsProbe("class/powercap/intel-rapl*"))
 }
 
 var (
@@ -77,21 +78,13 @@ func (c *raplCollector) Update(ch chan<- prometheus.Metric) error {
 			level.Debug(c.logger).Log("msg", "Platform doesn't have powercap files present", "err", err)
 			return ErrNoData
 		}
-		if errors.Is(err, os.ErrPermission) {
-			level.Debug(c.logger).Log("msg", "Can't access powercap files", "err", err)
-			return ErrNoData
-		}
-		return fmt.Errorf("failed to retrieve rapl stats: %w", err)
+		return fmt.Errorf("failed to retrieve rapl stats: %w", expectedPermissionError(err))
 	}
 
 	for _, rz := range zones {
 		microJoules, err := rz.GetEnergyMicrojoules()
 		if err != nil {
-			if errors.Is(err, os.ErrPermission) {
-				level.Debug(c.logger).Log("msg", "Can't access energy_uj file", "zone", rz, "err", err)
-				return ErrNoData
-			}
-			return err
+			return fmt.Errorf("can't access energy_uj file of zone %s: %w", rz.Name, expectedPermissionError(err))
 		}
 
 		joules := float64(microJoules) / 1000000.0
@@ -0,0 +1,219 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noras
+// +build !noras
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const rasSubsystem = "ras"
+
+var (
+	rasDatabase     = kingpin.Flag("collector.ras.database", "Path of the SQLite database of rasdaemon.").Default("/var/lib/rasdaemon/ras-mc_event.db").String()
+	rasAddressRange = kingpin.Flag("collector.ras.address-range-bytes", "Size of the aligned physical address ranges memory errors are counted by.").Default("1073741824").Uint64()
+)
+
+// mceStatusUC is the bit of MCi_STATUS set for uncorrected errors.
+const mceStatusUC = 1 << 61
+
+type rasMCEKey struct{ cpu, errorType string }
+
+type rasMemoryKey struct{ errorType, addressRange string }
+
+type rasCollector struct {
+	mce    *prometheus.Desc
+	memory *prometheus.Desc
+	logger log.Logger
+
+	// The counts are only read again if the database changed.
+	mtx          sync.Mutex
+	modTime      time.Time
+	size         int64
+	mceCounts    map[rasMCEKey]float64
+	memoryCounts map[rasMemoryKey]float64
+}
+
+func init() {
+	registerCollector(rasSubsystem, defaultDisabled, NewRASCollector)
+}
+
+// NewRASCollector returns a new Collector exposing the hardware errors
+// recorded by rasdaemon.
+func NewRASCollector(logger log.Logger) (Collector, error) {
+	if *rasAddressRange == 0 {
+		return nil, errors.New("--collector.ras.address-range-bytes must be positive")
+	}
+	return &rasCollector{
+		mce: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rasSubsystem, "machine_check_errors_total"),
+			"Number of machine check exceptions recorded by rasdaemon, by CPU and whether they were corrected.",
+			[]string{"cpu", "type"}, nil,
+		),
+		memory: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, rasSubsystem, "memory_errors_total"),
+			"Number of memory controller errors recorded by rasdaemon, by type and physical address range.",
+			[]string{"type", "address_range"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *rasCollector) Update(ch chan<- prometheus.Metric) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.read(); err != nil {
+		return err
+	}
+	for key, count := range c.mceCounts {
+		ch <- prometheus.MustNewConstMetric(c.mce, prometheus.CounterValue, count, key.cpu, key.errorType)
+	}
+	for key, count := range c.memoryCounts {
+		ch <- prometheus.MustNewConstMetric(c.memory, prometheus.CounterValue, count, key.errorType, key.addressRange)
+	}
+	return nil
+}
+
+// read counts the errors in the database if it changed since the last call.
+func (c *rasCollector) read() error {
+	f, err := os.Open(*rasDatabase)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoData
+		}
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(c.modTime) && info.Size() == c.size {
+		return nil
+	}
+
+	unlock, err := sqliteReadLock(f)
+	if err != nil {
+		if errors.Is(err, errSQLiteBusy) && !c.modTime.IsZero() {
+			// The counts of the last read are exposed until rasdaemon
+			// committed its transaction.
+			level.Debug(c.logger).Log("msg", "Keeping counts of the last read", "err", err)
+			return nil
+		}
+		return err
+	}
+	defer unlock()
+	db, err := openSQLite(f, info.Size())
+	if err != nil {
+		return err
+	}
+	mceCounts, err := rasMCECounts(db)
+	if err != nil && !errors.Is(err, ErrNoData) {
+		return fmt.Errorf("couldn't read machine check errors: %w", err)
+	}
+	memoryCounts, err := rasMemoryCounts(db, *rasAddressRange)
+	if err != nil && !errors.Is(err, ErrNoData) {
+		return fmt.Errorf("couldn't read memory errors: %w", err)
+	}
+	// Writers can't change the file while it's locked, unless they ignore
+	// the locks.
+	if changed, err := db.changed(); err != nil || changed {
+		if err == nil {
+			err = errors.New("database changed while it was read")
+		}
+		return err
+	}
+	c.modTime, c.size = info.ModTime(), info.Size()
+	c.mceCounts, c.memoryCounts = mceCounts, memoryCounts
+	return nil
+}
+
+// rasColumns returns the index of each column of a table by name.
+func rasColumns(db *sqliteDB, table string) (uint32, map[string]int, error) {
+	root, names, err := db.tableColumns(table)
+	if err != nil {
+		return 0, nil, err
+	}
+	columns := make(map[string]int, len(names))
+	for i, name := range names {
+		columns[name] = i
+	}
+	return root, columns, nil
+}
+
+// rasValue returns the value of a column of a record, nil if the table has
+// no such column or the record predates it.
+func rasValue(record []interface{}, columns map[string]int, name string) interface{} {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return nil
+	}
+	return record[i]
+}
+
+func rasMCECounts(db *sqliteDB) (map[rasMCEKey]float64, error) {
+	root, columns, err := rasColumns(db, "mce_record")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[rasMCEKey]float64{}
+	err = db.scan(root, func(_ int64, record []interface{}) error {
+		cpu, _ := rasValue(record, columns, "cpu").(int64)
+		status, _ := rasValue(record, columns, "status").(int64)
+		errorType := "corrected"
+		if uint64(status)&mceStatusUC != 0 {
+			errorType = "uncorrected"
+		}
+		counts[rasMCEKey{cpu: strconv.FormatInt(cpu, 10), errorType: errorType}]++
+		return nil
+	})
+	return counts, err
+}
+
+func rasMemoryCounts(db *sqliteDB, addressRange uint64) (map[rasMemoryKey]float64, error) {
+	root, columns, err := rasColumns(db, "mc_event")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[rasMemoryKey]float64{}
+	err = db.scan(root, func(_ int64, record []interface{}) error {
+		errorType, _ := rasValue(record, columns, "err_type").(string)
+		address, _ := rasValue(record, columns, "address").(int64)
+		count, ok := rasValue(record, columns, "err_count").(int64)
+		if !ok {
+			count = 1
+		}
+		start := uint64(address) / addressRange * addressRange
+		key := rasMemoryKey{
+			errorType:    strings.ToLower(errorType),
+			addressRange: fmt.Sprintf("0x%x", start),
+		}
+		counts[key] += float64(count)
+		return nil
+	})
+	return counts, err
+}
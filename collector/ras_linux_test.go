@@ -0,0 +1,282 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noras
+// +build !noras
+
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"golang.org/x/sys/unix"
+)
+
+// The fixture database was created by sqlite3 with the schema of rasdaemon.
+// Its 201 machine check records span several pages and the last one has an
+// error message in overflow pages.
+const rasFixture = "fixtures/ras/ras-mc_event.db"
+
+// The same schema with 512 byte pages, created by sqlite3 as well.
+const rasFixtureSmallPages = "fixtures/ras/ras-mc_event-512.db"
+
+// A database left by a writer which crashed during a transaction, with the
+// hot journal SQLite would roll it back with.
+const rasFixtureHotJournal = "fixtures/ras/ras-hot.db"
+
+func TestRAS(t *testing.T) {
+	*rasDatabase = rasFixture
+	*rasAddressRange = 1 << 30
+	c, err := NewRASCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_ras_machine_check_errors_total Number of machine check exceptions recorded by rasdaemon, by CPU and whether they were corrected.
+# TYPE node_ras_machine_check_errors_total counter
+node_ras_machine_check_errors_total{cpu="1",type="corrected"} 200
+node_ras_machine_check_errors_total{cpu="3",type="uncorrected"} 1
+# HELP node_ras_memory_errors_total Number of memory controller errors recorded by rasdaemon, by type and physical address range.
+# TYPE node_ras_memory_errors_total counter
+node_ras_memory_errors_total{address_range="0x0",type="corrected"} 3
+node_ras_memory_errors_total{address_range="0x840000000",type="uncorrected"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLiteScan(t *testing.T) {
+	for _, tc := range []struct {
+		path        string
+		rows        int64
+		lastMessage string
+	}{
+		{rasFixture, 201, "Uncorrected memory scrubbing error " + strings.Repeat("y", 6000)},
+		// A table b-tree of three levels, the last message spans several
+		// overflow pages.
+		{rasFixtureSmallPages, 401, "Uncorrected memory scrubbing error " + strings.Repeat("y", 2000)},
+	} {
+		t.Run(filepath.Base(tc.path), func(t *testing.T) {
+			data, err := os.ReadFile(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, err := openSQLite(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, columns, err := rasColumns(db, "mce_record")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rows int64
+			var lastMessage string
+			err = db.scan(root, func(rowid int64, record []interface{}) error {
+				if rowid != rows+1 {
+					t.Errorf("want rowid %d, got %d", rows+1, rowid)
+				}
+				rows = rowid
+				lastMessage, _ = rasValue(record, columns, "error_msg").(string)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rows != tc.rows {
+				t.Errorf("want %d rows, got %d", tc.rows, rows)
+			}
+			if lastMessage != tc.lastMessage {
+				t.Errorf("overflowing message: want %d bytes, got %d", len(tc.lastMessage), len(lastMessage))
+			}
+
+			if _, _, err := rasColumns(db, "aer_event"); err == nil {
+				t.Error("missing table: expected error")
+			}
+		})
+	}
+}
+
+func TestRASSmallPages(t *testing.T) {
+	*rasDatabase = rasFixtureSmallPages
+	*rasAddressRange = 1 << 30
+	c, err := NewRASCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_ras_machine_check_errors_total Number of machine check exceptions recorded by rasdaemon, by CPU and whether they were corrected.
+# TYPE node_ras_machine_check_errors_total counter
+node_ras_machine_check_errors_total{cpu="0",type="corrected"} 200
+node_ras_machine_check_errors_total{cpu="1",type="corrected"} 200
+node_ras_machine_check_errors_total{cpu="2",type="uncorrected"} 1
+# HELP node_ras_memory_errors_total Number of memory controller errors recorded by rasdaemon, by type and physical address range.
+# TYPE node_ras_memory_errors_total counter
+node_ras_memory_errors_total{address_range="0x0",type="corrected"} 4
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRASHotJournal(t *testing.T) {
+	f, err := os.Open(rasFixtureHotJournal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := sqliteReadLock(f); err == nil || errors.Is(err, errSQLiteBusy) {
+		t.Errorf("hot journal: want hot journal error, got %v", err)
+	}
+
+	*rasDatabase = rasFixtureHotJournal
+	c, err := NewRASCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(make(chan prometheus.Metric, 100)); err == nil {
+		t.Error("hot journal: want error from Update")
+	}
+}
+
+// FuzzSQLite checks that corrupt databases are rejected without panicking or
+// hanging the scrape.
+func FuzzSQLite(f *testing.F) {
+	for _, path := range []string{rasFixture, rasFixtureSmallPages, rasFixtureHotJournal} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		db, err := openSQLite(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		rasMCECounts(db)
+		rasMemoryCounts(db, 1<<30)
+	})
+}
+
+// copyRASFixture returns the path of a writable copy of the fixture database.
+func copyRASFixture(t *testing.T) string {
+	data, err := os.ReadFile(rasFixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ras-mc_event.db")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSQLiteReadLock(t *testing.T) {
+	path := copyRASFixture(t)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	unlock, err := sqliteReadLock(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Open file description locks conflict with the POSIX locks of the same
+	// process, unlike other POSIX locks.
+	writer, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	exclusive := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart, Start: sqliteSharedFirst, Len: sqliteSharedSize}
+	if err := unix.FcntlFlock(writer.Fd(), unix.F_OFD_SETLK, &exclusive); err != nil {
+		t.Skipf("open file description locks unsupported: %v", err)
+	}
+	if _, err := sqliteReadLock(f); !errors.Is(err, errSQLiteBusy) {
+		t.Errorf("exclusive lock held: want errSQLiteBusy, got %v", err)
+	}
+	exclusive.Type = unix.F_UNLCK
+	if err := unix.FcntlFlock(writer.Fd(), unix.F_OFD_SETLK, &exclusive); err != nil {
+		t.Fatal(err)
+	}
+
+	// A journal without the RESERVED lock held is hot.
+	if err := os.WriteFile(path+"-journal", []byte{0xd9, 0xd5, 0x05, 0xf9}, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sqliteReadLock(f); err == nil {
+		t.Error("hot journal: want error")
+	}
+	reserved := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart, Start: sqliteReservedByte, Len: 1}
+	if err := unix.FcntlFlock(writer.Fd(), unix.F_OFD_SETLK, &reserved); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = sqliteReadLock(f)
+	if err != nil {
+		t.Fatalf("journal of a writer holding the RESERVED lock: %v", err)
+	}
+	unlock()
+}
+
+func TestSQLiteChanged(t *testing.T) {
+	path := copyRASFixture(t)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := openSQLite(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := db.changed(); err != nil || changed {
+		t.Fatalf("unchanged database: got changed %t, err %v", changed, err)
+	}
+	if _, err := f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 24); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := db.changed(); err != nil || !changed {
+		t.Errorf("changed database: got changed %t, err %v", changed, err)
+	}
+
+	// Write-ahead log mode.
+	if _, err := f.WriteAt([]byte{2, 2}, 18); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openSQLite(f, info.Size()); err == nil {
+		t.Error("database in write-ahead log mode: want error")
+	}
+}
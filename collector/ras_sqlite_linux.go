@@ -0,0 +1,436 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noras
+// +build !noras
+
+package collector
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// sqliteDB reads the rows of tables of an SQLite 3 database file, as
+// documented in https://www.sqlite.org/fileformat2.html. It only supports
+// what rasdaemon's database uses: UTF-8 tables with rowids and a rollback
+// journal. Databases in write-ahead log mode are rejected, as the rows in
+// the log wouldn't be seen.
+//
+// The file should be locked with sqliteReadLock while it's read, so that
+// writers can't change it in between.
+type sqliteDB struct {
+	r        io.ReaderAt
+	pageSize int
+	// usable is the page size without the space reserved for extensions.
+	usable int
+	// pages is the number of pages of the file.
+	pages uint32
+	// changeCounter is incremented by every transaction changing the file.
+	changeCounter uint32
+}
+
+// The locks SQLite takes on Unix, byte ranges of the database file locked
+// with POSIX advisory locks as described in
+// https://www.sqlite.org/lockingv3.html and os_unix.c.
+const (
+	sqlitePendingByte  = 0x40000000
+	sqliteReservedByte = sqlitePendingByte + 1
+	sqliteSharedFirst  = sqlitePendingByte + 2
+	sqliteSharedSize   = 510
+)
+
+// errSQLiteBusy is returned if a writer holds a lock of the database.
+var errSQLiteBusy = errors.New("SQLite database is locked by a writer")
+
+// sqliteReadLock takes the SHARED lock of an SQLite database like SQLite
+// does: a read lock of the shared range is taken while holding one of the
+// pending byte, which fails while a writer waits for or holds the EXCLUSIVE
+// lock. A hot journal left by a writer which crashed during a transaction
+// has to be rolled back by SQLite first, the database is inconsistent until
+// then. The returned function releases the lock.
+func sqliteReadLock(f *os.File) (func() error, error) {
+	lock := func(typ int16, start, length int64) error {
+		return unix.FcntlFlock(f.Fd(), unix.F_SETLK, &unix.Flock_t{Type: typ, Whence: io.SeekStart, Start: start, Len: length})
+	}
+	if err := lock(unix.F_RDLCK, sqlitePendingByte, 1); err != nil {
+		return nil, sqliteLockError(err)
+	}
+	err := lock(unix.F_RDLCK, sqliteSharedFirst, sqliteSharedSize)
+	if uerr := lock(unix.F_UNLCK, sqlitePendingByte, 1); err == nil {
+		err = uerr
+	}
+	if err != nil {
+		return nil, sqliteLockError(err)
+	}
+	unlock := func() error { return lock(unix.F_UNLCK, sqliteSharedFirst, sqliteSharedSize) }
+
+	hot, err := sqliteHotJournal(f)
+	if err != nil || hot {
+		unlock()
+		if err == nil {
+			err = errors.New("SQLite database has a hot journal to be rolled back by a writer")
+		}
+		return nil, err
+	}
+	return unlock, nil
+}
+
+func sqliteLockError(err error) error {
+	if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+		return errSQLiteBusy
+	}
+	return fmt.Errorf("couldn't lock SQLite database: %w", err)
+}
+
+// sqliteHotJournal reports whether the rollback journal of the database is
+// hot: it exists, isn't zeroed and no writer holds the RESERVED lock.
+func sqliteHotJournal(f *os.File) (bool, error) {
+	journal, err := os.Open(f.Name() + "-journal")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer journal.Close()
+	magic := make([]byte, 1)
+	if _, err := journal.ReadAt(magic, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	if magic[0] == 0 {
+		return false, nil
+	}
+	reserved := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart, Start: sqliteReservedByte, Len: 1}
+	if err := unix.FcntlFlock(f.Fd(), unix.F_GETLK, &reserved); err != nil {
+		return false, err
+	}
+	return reserved.Type == unix.F_UNLCK, nil
+}
+
+// openSQLite opens the database in r, a file of the given size.
+func openSQLite(r io.ReaderAt, size int64) (*sqliteDB, error) {
+	header := make([]byte, 100)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: couldn't read SQLite header: %s", ErrParse, err)
+	}
+	if string(header[:16]) != "SQLite format 3\x00" {
+		return nil, fmt.Errorf("%w: not an SQLite 3 database", ErrParse)
+	}
+	pageSize := int(binary.BigEndian.Uint16(header[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("%w: invalid SQLite page size %d", ErrParse, pageSize)
+	}
+	if header[18] == 2 || header[19] == 2 {
+		return nil, errors.New("SQLite databases in write-ahead log mode aren't supported")
+	}
+	if encoding := binary.BigEndian.Uint32(header[56:60]); encoding != 1 {
+		return nil, fmt.Errorf("%w: unsupported SQLite text encoding %d", ErrParse, encoding)
+	}
+	// The usable size of a page is at least 480 bytes.
+	if usable := pageSize - int(header[20]); usable < 480 {
+		return nil, fmt.Errorf("%w: invalid SQLite usable page size %d", ErrParse, usable)
+	}
+	// The pages of the file bound the pages visited and the size of
+	// payloads, so that a corrupt file can't hang a scrape.
+	pages := size / int64(pageSize)
+	if pages == 0 || pages > math.MaxUint32 {
+		return nil, fmt.Errorf("%w: invalid SQLite database size %d", ErrParse, size)
+	}
+	return &sqliteDB{
+		r:             r,
+		pageSize:      pageSize,
+		usable:        pageSize - int(header[20]),
+		pages:         uint32(pages),
+		changeCounter: binary.BigEndian.Uint32(header[24:28]),
+	}, nil
+}
+
+// changed reports whether the database was changed since it was opened.
+func (db *sqliteDB) changed() (bool, error) {
+	counter := make([]byte, 4)
+	if _, err := db.r.ReadAt(counter, 24); err != nil {
+		return false, fmt.Errorf("%w: couldn't read SQLite header: %s", ErrParse, err)
+	}
+	return binary.BigEndian.Uint32(counter) != db.changeCounter, nil
+}
+
+func (db *sqliteDB) page(number uint32) ([]byte, error) {
+	if number == 0 || number > db.pages {
+		return nil, fmt.Errorf("%w: invalid SQLite page number %d", ErrParse, number)
+	}
+	page := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(page, int64(number-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("%w: couldn't read SQLite page %d: %s", ErrParse, number, err)
+	}
+	return page, nil
+}
+
+// tableColumns returns the root page and the column names of a table, from
+// its definition in the schema table.
+func (db *sqliteDB) tableColumns(table string) (uint32, []string, error) {
+	var root uint32
+	var columns []string
+	err := db.scan(1, func(_ int64, record []interface{}) error {
+		if len(record) < 5 || record[0] != "table" || record[1] != table {
+			return nil
+		}
+		page, ok := record[3].(int64)
+		sql, _ := record[4].(string)
+		if !ok || page <= 0 || page > math.MaxUint32 {
+			return fmt.Errorf("%w: invalid root page of table %s", ErrParse, table)
+		}
+		root, columns = uint32(page), sqliteColumnNames(sql)
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if root == 0 {
+		return 0, nil, fmt.Errorf("%w: no table %s", ErrNoData, table)
+	}
+	return root, columns, nil
+}
+
+// sqliteColumnNames returns the columns of a CREATE TABLE statement whose
+// column definitions don't contain commas, like the ones of rasdaemon.
+func sqliteColumnNames(sql string) []string {
+	start, end := strings.Index(sql, "("), strings.LastIndex(sql, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+	var names []string
+	for _, def := range strings.Split(sql[start+1:end], ",") {
+		if fields := strings.Fields(def); len(fields) > 0 {
+			names = append(names, strings.Trim(fields[0], "\"`[]"))
+		}
+	}
+	return names
+}
+
+// scan calls fn with the rowid and the values of each row of the table
+// b-tree rooted at the given page. Values are nil, int64, float64, string or
+// []byte.
+func (db *sqliteDB) scan(root uint32, fn func(rowid int64, record []interface{}) error) error {
+	pages := []uint32{root}
+	visited := map[uint32]bool{}
+	for len(pages) > 0 {
+		number := pages[len(pages)-1]
+		pages = pages[:len(pages)-1]
+		if visited[number] {
+			return fmt.Errorf("%w: SQLite page %d is referenced twice", ErrParse, number)
+		}
+		visited[number] = true
+		page, err := db.page(number)
+		if err != nil {
+			return err
+		}
+		header := page
+		if number == 1 {
+			header = page[100:]
+		}
+		cells := int(binary.BigEndian.Uint16(header[3:5]))
+		switch header[0] {
+		case 0x05: // Interior table page.
+			cellPointers := header[12:]
+			if len(cellPointers) < 2*cells {
+				return fmt.Errorf("%w: invalid SQLite page %d", ErrParse, number)
+			}
+			// Visit the children in order, the right-most one last.
+			pages = append(pages, binary.BigEndian.Uint32(header[8:12]))
+			for i := cells - 1; i >= 0; i-- {
+				offset := int(binary.BigEndian.Uint16(cellPointers[2*i:]))
+				if offset+4 > len(page) {
+					return fmt.Errorf("%w: invalid SQLite cell in page %d", ErrParse, number)
+				}
+				pages = append(pages, binary.BigEndian.Uint32(page[offset:]))
+			}
+		case 0x0d: // Leaf table page.
+			cellPointers := header[8:]
+			if len(cellPointers) < 2*cells {
+				return fmt.Errorf("%w: invalid SQLite page %d", ErrParse, number)
+			}
+			for i := 0; i < cells; i++ {
+				offset := int(binary.BigEndian.Uint16(cellPointers[2*i:]))
+				rowid, payload, err := db.leafCell(page, offset, visited)
+				if err != nil {
+					return fmt.Errorf("%w in page %d", err, number)
+				}
+				record, err := sqliteRecord(payload)
+				if err != nil {
+					return fmt.Errorf("%w in page %d", err, number)
+				}
+				if err := fn(rowid, record); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("%w: unexpected SQLite page type %#x of page %d", ErrParse, header[0], number)
+		}
+	}
+	return nil
+}
+
+// leafCell returns the rowid and payload of the cell of a leaf table page at
+// offset, reading the overflow pages of large payloads. Like the pages of
+// the b-tree, each overflow page is only read once per scan.
+func (db *sqliteDB) leafCell(page []byte, offset int, visited map[uint32]bool) (int64, []byte, error) {
+	if offset >= len(page) {
+		return 0, nil, fmt.Errorf("%w: invalid SQLite cell offset", ErrParse)
+	}
+	size, n := sqliteVarint(page[offset:])
+	offset += n
+	rowid, n := sqliteVarint(page[offset:])
+	offset += n
+	if n == 0 || size < 0 || size > int64(db.pages)*int64(db.pageSize) {
+		return 0, nil, fmt.Errorf("%w: invalid SQLite cell", ErrParse)
+	}
+	total := int(size)
+
+	// The part of the payload stored on the page, see "Cell Payload
+	// Overflow Pages" of the file format documentation.
+	local := total
+	if maxLocal := db.usable - 35; total > maxLocal {
+		minLocal := (db.usable-12)*32/255 - 23
+		local = minLocal + (total-minLocal)%(db.usable-4)
+		if local > maxLocal {
+			local = minLocal
+		}
+	}
+	if offset+local > len(page) {
+		return 0, nil, fmt.Errorf("%w: SQLite cell exceeds page", ErrParse)
+	}
+	payload := make([]byte, 0, total)
+	payload = append(payload, page[offset:offset+local]...)
+	if local == total {
+		return rowid, payload, nil
+	}
+	if offset+local+4 > len(page) {
+		return 0, nil, fmt.Errorf("%w: SQLite cell exceeds page", ErrParse)
+	}
+	next := binary.BigEndian.Uint32(page[offset+local:])
+	for len(payload) < total {
+		if next == 0 {
+			return 0, nil, fmt.Errorf("%w: truncated SQLite overflow chain", ErrParse)
+		}
+		if visited[next] {
+			return 0, nil, fmt.Errorf("%w: SQLite page %d is referenced twice", ErrParse, next)
+		}
+		visited[next] = true
+		overflow, err := db.page(next)
+		if err != nil {
+			return 0, nil, err
+		}
+		next = binary.BigEndian.Uint32(overflow)
+		chunk := overflow[4:db.usable]
+		if rest := total - len(payload); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		payload = append(payload, chunk...)
+	}
+	return rowid, payload, nil
+}
+
+// sqliteRecord decodes the values of a record.
+func sqliteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize < int64(n) || headerSize > int64(len(payload)) {
+		return nil, fmt.Errorf("%w: invalid SQLite record header", ErrParse)
+	}
+	header, body := payload[n:headerSize], payload[headerSize:]
+	var values []interface{}
+	for len(header) > 0 {
+		serialType, n := sqliteVarint(header)
+		if n == 0 {
+			return nil, fmt.Errorf("%w: invalid SQLite record header", ErrParse)
+		}
+		header = header[n:]
+
+		size := 0
+		switch {
+		case serialType >= 1 && serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6 || serialType == 7:
+			size = 8
+		case serialType >= 12:
+			size = int((serialType - 12) / 2)
+		}
+		if size > len(body) {
+			return nil, fmt.Errorf("%w: truncated SQLite record", ErrParse)
+		}
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType <= 6:
+			// Big-endian two's complement integers.
+			var v int64
+			if size > 0 && value[0]&0x80 != 0 {
+				v = -1
+			}
+			for _, b := range value {
+				v = v<<8 | int64(b)
+			}
+			values = append(values, v)
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, append([]byte(nil), value...))
+		case serialType >= 13:
+			values = append(values, string(value))
+		default:
+			return nil, fmt.Errorf("%w: reserved SQLite serial type %d", ErrParse, serialType)
+		}
+	}
+	return values, nil
+}
+
+// sqliteVarint decodes a big-endian variable-length integer of up to 9 bytes
+// and returns it along with its length, 0 if b is too short.
+func sqliteVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return int64(v<<8 | uint64(b[i])), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	return 0, 0
+}
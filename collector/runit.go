@@ -73,6 +73,11 @@ func NewRunitCollector(logger log.Logger) (Collector, error) {
 	}, nil
 }
 
+// Validate implements Validator.
+func (c *runitCollector) Validate() error {
+	return checkReadableDir(*runitServiceDir)
+}
+
 func (c *runitCollector) Update(ch chan<- prometheus.Metric) error {
 	services, err := runit.GetServices(*runitServiceDir)
 	if err != nil {
@@ -0,0 +1,253 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	scheduleFile = kingpin.Flag("collector.schedule-file", "YAML file restricting when collectors run to cron-like schedules, in local time. Between their runs, scrapes return the result of the last one.").Default("").String()
+
+	scrapeLastRunDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "scrape", "collector_last_run_timestamp_seconds"),
+		"node_exporter: Unix time a scheduled collector last ran, its metrics are from then.",
+		[]string{"collector"},
+		nil,
+	)
+)
+
+// scheduleConfig is the content of the file given with
+// --collector.schedule-file.
+type scheduleConfig struct {
+	Schedules []struct {
+		Collectors []string `yaml:"collectors"`
+		// Cron is the minute, hour, day of month, month and day of week
+		// fields of a crontab line. The collectors run on the scrapes
+		// during the minutes it matches.
+		Cron string `yaml:"cron"`
+	} `yaml:"schedules"`
+}
+
+// loadSchedules returns the schedule of each collector in the file given
+// with --collector.schedule-file, nil if there is none.
+func loadSchedules() (map[string]*cronSchedule, error) {
+	if *scheduleFile == "" {
+		return nil, nil
+	}
+	content, err := os.ReadFile(*scheduleFile)
+	if err != nil {
+		return nil, err
+	}
+	return parseSchedules(content)
+}
+
+func parseSchedules(content []byte) (map[string]*cronSchedule, error) {
+	var c scheduleConfig
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return nil, err
+	}
+	schedules := map[string]*cronSchedule{}
+	for _, s := range c.Schedules {
+		schedule, err := parseCron(s.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", s.Cron, err)
+		}
+		for _, name := range s.Collectors {
+			if _, ok := collectorState[name]; !ok {
+				return nil, fmt.Errorf("schedule of unknown collector %s", name)
+			}
+			if _, ok := schedules[name]; ok {
+				return nil, fmt.Errorf("collector %s has several schedules", name)
+			}
+			schedules[name] = schedule
+		}
+	}
+	return schedules, nil
+}
+
+// cronSchedule holds the values each field of a crontab line matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	// domAny and dowAny are set if the day fields are "*", as cron matches
+	// the days matching either of them otherwise.
+	domAny, dowAny bool
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("want 5 fields, got %d", len(fields))
+	}
+	var s cronSchedule
+	var err error
+	for i, f := range []struct {
+		values   *map[int]bool
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dom, 1, 31},
+		{&s.month, 1, 12},
+		// 7 is Sunday too.
+		{&s.dow, 0, 7},
+	} {
+		if *f.values, err = parseCronField(fields[i], f.min, f.max); err != nil {
+			return nil, err
+		}
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	s.domAny, s.dowAny = fields[2] == "*", fields[4] == "*"
+	return &s, nil
+}
+
+// parseCronField parses a comma separated list of "*", values and ranges,
+// each with an optional "/step".
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepStr); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+		from, to := min, max
+		if rng != "*" {
+			lo, hi, isRange := strings.Cut(rng, "-")
+			var err error
+			if from, err = strconv.Atoi(lo); err != nil {
+				return nil, fmt.Errorf("invalid value %q", lo)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(hi); err != nil {
+					return nil, fmt.Errorf("invalid value %q", hi)
+				}
+			} else if hasStep {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// matches returns whether the collectors run at t.
+func (s *cronSchedule) matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// scheduledCollector runs a collector only when its schedule matches and
+// replays the result of its last run otherwise.
+type scheduledCollector struct {
+	name      string
+	collector Collector
+	schedule  *cronSchedule
+	now       func() time.Time
+
+	mtx     sync.Mutex
+	lastRun time.Time
+	metrics []prometheus.Metric
+	err     error
+}
+
+func newScheduledCollector(name string, c Collector, schedule *cronSchedule) *scheduledCollector {
+	return &scheduledCollector{name: name, collector: c, schedule: schedule, now: time.Now}
+}
+
+// Update implements Collector.
+func (c *scheduledCollector) Update(ch chan<- prometheus.Metric) error {
+	return c.update(ch, nil)
+}
+
+// updateFromSnapshot implements snapshotCollector, the snapshot is passed on
+// to the wrapped collector if it implements it too.
+func (c *scheduledCollector) updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error {
+	return c.update(ch, s)
+}
+
+func (c *scheduledCollector) update(ch chan<- prometheus.Metric, snapshot *procSnapshot) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if now := c.now(); c.schedule.matches(now) {
+		c.run(snapshot)
+		c.lastRun = now
+	}
+	if c.lastRun.IsZero() {
+		return fmt.Errorf("%w: scheduled collector hasn't run yet", ErrNoData)
+	}
+	for _, m := range c.metrics {
+		ch <- m
+	}
+	ch <- prometheus.MustNewConstMetric(scrapeLastRunDesc, prometheus.GaugeValue, float64(c.lastRun.UnixNano())/1e9, c.name)
+	return c.err
+}
+
+// run updates the wrapped collector and keeps its metrics and error.
+func (c *scheduledCollector) run(snapshot *procSnapshot) {
+	metricCh := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		for m := range metricCh {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+	if sc, ok := c.collector.(snapshotCollector); ok && snapshot != nil {
+		c.err = sc.updateFromSnapshot(metricCh, snapshot)
+	} else {
+		c.err = c.collector.Update(metricCh)
+	}
+	close(metricCh)
+	<-done
+	c.metrics = metrics
+}
+
+// unwrapCollector returns the collector a scheduled collector runs.
+func unwrapCollector(c Collector) Collector {
+	if sc, ok := c.(*scheduledCollector); ok {
+		return sc.collector
+	}
+	return c
+}
@@ -0,0 +1,179 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCronSchedule(t *testing.T) {
+	// 2023-06-14 was a Wednesday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"* 0-5 * * *", at(14, 3, 30), true},
+		{"* 0-5 * * *", at(14, 6, 0), false},
+		{"*/15 * * * *", at(14, 12, 45), true},
+		{"*/15 * * * *", at(14, 12, 46), false},
+		{"0,30 22 * * *", at(14, 22, 30), true},
+		{"* * * * 0,6", at(14, 1, 0), false},
+		{"* * * * 1-5", at(14, 1, 0), true},
+		{"* * * * 7", at(18, 1, 0), true},
+		// cron matches either of the day fields if both are restricted.
+		{"* * 1 * 3", at(14, 1, 0), true},
+		{"* * 1 * 4", at(14, 1, 0), false},
+		{"* * 14 6 *", at(14, 1, 0), true},
+	} {
+		s, err := parseCron(tc.spec)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.spec, err)
+		}
+		if got := s.matches(tc.t); got != tc.want {
+			t.Errorf("%q at %s: want %t, got %t", tc.spec, tc.t, tc.want, got)
+		}
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* 5-2 * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+type countingCollector struct {
+	runs int
+	err  error
+}
+
+func (c *countingCollector) Update(ch chan<- prometheus.Metric) error {
+	c.runs++
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("test_runs", "Runs.", nil, nil), prometheus.GaugeValue, float64(c.runs))
+	return c.err
+}
+
+func TestScheduledCollector(t *testing.T) {
+	schedule, err := parseCron("* 0-5 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &countingCollector{}
+	c := newScheduledCollector("test", inner, schedule)
+	update := func(now time.Time) (int, error) {
+		c.now = func() time.Time { return now }
+		ch := make(chan prometheus.Metric, 10)
+		err := c.Update(ch)
+		close(ch)
+		return len(ch), err
+	}
+
+	day := time.Date(2023, time.June, 14, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2023, time.June, 15, 2, 0, 0, 0, time.UTC)
+	if _, err := update(day); !errors.Is(err, ErrNoData) {
+		t.Errorf("before the first run: want ErrNoData, got %v", err)
+	}
+	if n, err := update(night); err != nil || n != 2 {
+		t.Errorf("at night: want 2 metrics, got %d (err %v)", n, err)
+	}
+	inner.err = errors.New("failed")
+	if n, err := update(day.AddDate(0, 0, 1)); err != nil || n != 2 {
+		t.Errorf("replayed: want 2 metrics, got %d (err %v)", n, err)
+	}
+	if inner.runs != 1 {
+		t.Errorf("want 1 run, got %d", inner.runs)
+	}
+	if _, err := update(night.Add(time.Hour)); err == nil {
+		t.Error("want error of the last run")
+	}
+}
+
+type snapshotCountingCollector struct {
+	countingCollector
+	snapshots int
+}
+
+func (c *snapshotCountingCollector) updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error {
+	c.snapshots++
+	return c.Update(ch)
+}
+
+func TestScheduledCollectorSnapshot(t *testing.T) {
+	schedule, err := parseCron("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &snapshotCountingCollector{}
+	var c Collector = newScheduledCollector("test", inner, schedule)
+	sc, ok := c.(snapshotCollector)
+	if !ok {
+		t.Fatal("scheduled collector doesn't implement snapshotCollector")
+	}
+	ch := make(chan prometheus.Metric, 10)
+	if err := sc.updateFromSnapshot(ch, &procSnapshot{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(ch); err != nil {
+		t.Fatal(err)
+	}
+	if inner.runs != 2 || inner.snapshots != 1 {
+		t.Errorf("want 2 runs with 1 from the snapshot, got %d runs with %d from the snapshot", inner.runs, inner.snapshots)
+	}
+
+	var last int
+	close(ch)
+	for m := range ch {
+		if isScrapeMetric(m) {
+			last++
+		}
+	}
+	if last != 2 {
+		t.Errorf("want 2 scrape metrics, got %d", last)
+	}
+}
+
+func TestParseSchedules(t *testing.T) {
+	collectorState["scheduled"] = new(bool)
+	defer delete(collectorState, "scheduled")
+
+	schedules, err := parseSchedules([]byte(`
+schedules:
+  - collectors: [scheduled]
+    cron: "0 2 * * *"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schedules["scheduled"]; !ok || len(schedules) != 1 {
+		t.Errorf("unexpected schedules %v", schedules)
+	}
+
+	for _, content := range []string{
+		"schedules:\n  - collectors: [nonexistent]\n    cron: \"* * * * *\"\n",
+		"schedules:\n  - collectors: [scheduled]\n    cron: \"* * *\"\n",
+		"schedules:\n  - collectors: [scheduled]\n    cron: \"* * * * *\"\n  - collectors: [scheduled]\n    cron: \"* * * * *\"\n",
+		"schedules:\n  - collectors: [scheduled]\n    crontab: \"* * * * *\"\n",
+	} {
+		if _, err := parseSchedules([]byte(content)); err == nil {
+			t.Errorf("expected error for %q", content)
+		}
+	}
+}
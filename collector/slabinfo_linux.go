@@ -17,18 +17,28 @@
 package collector
 
 import (
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/procfs"
 )
 
+var (
+	slabNameInclude = kingpin.Flag("collector.slabinfo.slabs-include", "Regexp of slab caches to include, e.g. to bound the cardinality to dentry and inode caches (mutually exclusive to slabs-exclude).").String()
+	slabNameExclude = kingpin.Flag("collector.slabinfo.slabs-exclude", "Regexp of slab caches to exclude (mutually exclusive to slabs-include).").String()
+)
+
 type slabinfoCollector struct {
-	fs        procfs.FS
-	logger    log.Logger
-	subsystem string
-	labels    []string
+	fs         procfs.FS
+	logger     log.Logger
+	subsystem  string
+	labels     []string
+	slabFilter deviceFilter
+	pageSize   int64
 }
 
 func init() {
@@ -36,15 +46,20 @@ func init() {
 }
 
 func NewSlabinfoCollector(logger log.Logger) (Collector, error) {
+	if *slabNameExclude != "" && *slabNameInclude != "" {
+		return nil, errors.New("--collector.slabinfo.slabs-exclude and --collector.slabinfo.slabs-include are mutually exclusive")
+	}
 	fs, err := procfs.NewFS(*procPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open procfs: %w", err)
 	}
 
 	return &slabinfoCollector{logger: logger,
-		fs:        fs,
-		subsystem: "slabinfo",
-		labels:    []string{"slab"},
+		fs:         fs,
+		subsystem:  "slabinfo",
+		labels:     []string{"slab"},
+		slabFilter: newDeviceFilter(*slabNameExclude, *slabNameInclude),
+		pageSize:   int64(os.Getpagesize()),
 	}, nil
 }
 
@@ -55,11 +70,15 @@ func (c *slabinfoCollector) Update(ch chan<- prometheus.Metric) error {
 	}
 
 	for _, slab := range slabinfo.Slabs {
+		if c.slabFilter.ignored(slab.Name) {
+			continue
+		}
 		ch <- c.activeObjects(slab.Name, slab.ObjActive)
 		ch <- c.objects(slab.Name, slab.ObjNum)
 		ch <- c.objectSizeBytes(slab.Name, slab.ObjSize)
 		ch <- c.objectsPerSlab(slab.Name, slab.ObjPerSlab)
 		ch <- c.pagesPerSlab(slab.Name, slab.PagesPerSlab)
+		ch <- c.sizeBytes(slab.Name, slab.SlabNum*slab.PagesPerSlab*c.pageSize)
 	}
 
 	return nil
@@ -119,3 +138,14 @@ func (c *slabinfoCollector) pagesPerSlab(label string, val int64) prometheus.Met
 		desc, prometheus.GaugeValue, float64(val), label,
 	)
 }
+
+func (c *slabinfoCollector) sizeBytes(label string, val int64) prometheus.Metric {
+	desc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, c.subsystem, "size_bytes"),
+		"The memory used by the slabs of this cache, in bytes.",
+		c.labels, nil)
+
+	return prometheus.MustNewConstMetric(
+		desc, prometheus.GaugeValue, float64(val), label,
+	)
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+var consistentSnapshot = kingpin.Flag(
+	"collector.consistent-snapshot",
+	"Read /proc/stat and /proc/meminfo once at the start of each scrape and derive the cpu, stat and meminfo metrics from these readings.",
+).Bool()
+
+// snapshotCollector is implemented by collectors which can derive their
+// metrics from a procSnapshot instead of reading /proc themselves.
+type snapshotCollector interface {
+	updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error
+}
+
+// procSnapshot holds readings of /proc/stat and /proc/meminfo taken
+// back to back, so that ratios computed across collectors aren't skewed by
+// the time between their individual reads.
+type procSnapshot struct {
+	stat       procfs.Stat
+	statErr    error
+	memInfo    []byte
+	memInfoErr error
+}
+
+// snapshot returns a procSnapshot for the scrape, nil if consistent snapshots
+// are disabled or none of the collectors would use it.
+func (n NodeCollector) snapshot() *procSnapshot {
+	if !*consistentSnapshot {
+		return nil
+	}
+	for _, c := range n.Collectors {
+		if _, ok := unwrapCollector(c).(snapshotCollector); ok {
+			return newProcSnapshot()
+		}
+	}
+	return nil
+}
+
+func newProcSnapshot() *procSnapshot {
+	s := &procSnapshot{}
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		s.statErr = err
+	} else {
+		s.stat, s.statErr = fs.Stat()
+	}
+	s.memInfo, s.memInfoErr = os.ReadFile(procFilePath("meminfo"))
+	return s
+}
+
+// Stat returns the snapshot of /proc/stat.
+func (s *procSnapshot) Stat() (procfs.Stat, error) {
+	return s.stat, s.statErr
+}
+
+// MemInfo returns the raw contents of the /proc/meminfo snapshot.
+func (s *procSnapshot) MemInfo() ([]byte, error) {
+	return s.memInfo, s.memInfoErr
+}
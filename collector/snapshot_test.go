@@ -0,0 +1,78 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProcSnapshot(t *testing.T) {
+	*procPath = "fixtures/proc"
+	s := newProcSnapshot()
+	stat, err := s.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.BootTime != 1418183276 || stat.IRQTotal != 8885917 {
+		t.Errorf("want boot time 1418183276 and 8885917 interrupts, got %d and %d", stat.BootTime, stat.IRQTotal)
+	}
+	memInfo, err := s.MemInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(memInfo, []byte("MemTotal:")) {
+		t.Errorf("unexpected meminfo %q", memInfo)
+	}
+
+	*procPath = t.TempDir()
+	defer func() { *procPath = "fixtures/proc" }()
+	s = newProcSnapshot()
+	if _, err := s.Stat(); err == nil {
+		t.Error("missing stat: expected error")
+	}
+	if _, err := s.MemInfo(); err == nil {
+		t.Error("missing meminfo: expected error")
+	}
+}
+
+func TestNodeCollectorSnapshot(t *testing.T) {
+	schedule, err := parseCron("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { *consistentSnapshot = false }()
+	for _, tc := range []struct {
+		name       string
+		enabled    bool
+		collectors map[string]Collector
+		want       bool
+	}{
+		{"disabled", false, map[string]Collector{"test": &snapshotCountingCollector{}}, false},
+		{"no snapshot collector", true, map[string]Collector{"test": &countingCollector{}}, false},
+		{"snapshot collector", true, map[string]Collector{"plain": &countingCollector{}, "test": &snapshotCountingCollector{}}, true},
+		{"scheduled snapshot collector", true, map[string]Collector{"test": newScheduledCollector("test", &snapshotCountingCollector{}, schedule)}, true},
+		// scheduledCollector implements snapshotCollector whether the
+		// collector it runs does or not.
+		{"scheduled collector", true, map[string]Collector{"test": newScheduledCollector("test", &countingCollector{}, schedule)}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			*consistentSnapshot = tc.enabled
+			n := NodeCollector{Collectors: tc.collectors}
+			if got := n.snapshot() != nil; got != tc.want {
+				t.Errorf("want snapshot %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nosockmem
+// +build !nosockmem
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/go-kit/log"
+	"github.com/mdlayher/netlink"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const sockmemSubsystem = "sockmem"
+
+// sockmemProtocols are the protocols dumped with inet_diag, named like in
+// /proc/net/protocols. The file is used to skip disabled families.
+var sockmemProtocols = []struct {
+	name     string
+	family   uint8
+	protocol uint8
+	procFile string
+}{
+	{"TCP", syscall.AF_INET, syscall.IPPROTO_TCP, "net/tcp"},
+	{"TCPv6", syscall.AF_INET6, syscall.IPPROTO_TCP, "net/tcp6"},
+	{"UDP", syscall.AF_INET, syscall.IPPROTO_UDP, "net/udp"},
+	{"UDPv6", syscall.AF_INET6, syscall.IPPROTO_UDP, "net/udp6"},
+}
+
+// sockmemFields are the SK_MEMINFO_* values, in the order of the
+// INET_DIAG_SKMEMINFO attribute, exposed summed over the sockets. The drops
+// which follow aren't exposed, their sum decreases when sockets are closed.
+var sockmemFields = []struct {
+	name string
+	help string
+}{
+	{"receive_allocated_bytes", "Memory allocated to the receive queues of the sockets."},
+	{"receive_buffer_bytes", "Size of the receive buffers of the sockets."},
+	{"send_allocated_bytes", "Memory allocated to the send queues of the sockets, for packets handed to the network layer."},
+	{"send_buffer_bytes", "Size of the send buffers of the sockets."},
+	{"forward_allocated_bytes", "Memory reserved by the sockets for future allocations."},
+	{"send_queued_bytes", "Memory of the packets queued for sending by the sockets."},
+	{"option_bytes", "Memory used for socket options and other control data."},
+	{"backlog_bytes", "Memory of the packets in the backlog of the sockets, waiting for them to be unlocked."},
+}
+
+type sockmemCollector struct {
+	sockets  *prometheus.Desc
+	fields   []*prometheus.Desc
+	pressure *prometheus.Desc
+	logger   log.Logger
+}
+
+func init() {
+	registerCollector(sockmemSubsystem, defaultDisabled, NewSockmemCollector)
+}
+
+// NewSockmemCollector returns a new Collector exposing the memory used by
+// TCP and UDP sockets.
+func NewSockmemCollector(logger log.Logger) (Collector, error) {
+	c := &sockmemCollector{
+		sockets: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sockmemSubsystem, "sockets"),
+			"Number of sockets of the protocol.",
+			[]string{"protocol"}, nil,
+		),
+		pressure: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sockmemSubsystem, "protocol_memory_pressure"),
+			"Whether the protocol is under memory pressure, in which case sockets buffers are shrunk.",
+			[]string{"protocol"}, nil,
+		),
+		logger: logger,
+	}
+	for _, f := range sockmemFields {
+		c.fields = append(c.fields, prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, sockmemSubsystem, f.name),
+			f.help,
+			[]string{"protocol"}, nil,
+		))
+	}
+	return c, nil
+}
+
+func (c *sockmemCollector) Update(ch chan<- prometheus.Metric) error {
+	for _, p := range sockmemProtocols {
+		if _, err := os.Stat(procFilePath(p.procFile)); err != nil {
+			continue
+		}
+		msgs, err := inetDiagDump(p.family, p.protocol, tcpfAll, 1<<(inetDiagSkmeminfo-1))
+		if err != nil {
+			return fmt.Errorf("couldn't get %s sockets: %w", p.name, err)
+		}
+		sockets, sums := parseSockmem(msgs)
+		ch <- prometheus.MustNewConstMetric(c.sockets, prometheus.GaugeValue, sockets, p.name)
+		for i, desc := range c.fields {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, sums[i], p.name)
+		}
+	}
+
+	f, err := os.Open(procFilePath("net/protocols"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	pressure, err := parseProtocolsPressure(f)
+	if err != nil {
+		return fmt.Errorf("couldn't parse %s: %w", f.Name(), err)
+	}
+	for protocol, underPressure := range pressure {
+		ch <- prometheus.MustNewConstMetric(c.pressure, prometheus.GaugeValue, boolToFloat(underPressure), protocol)
+	}
+	return nil
+}
+
+// parseSockmem counts the sockets of an inet_diag dump and sums their
+// SK_MEMINFO_* values.
+func parseSockmem(msgs []netlink.Message) (float64, []float64) {
+	sums := make([]float64, len(sockmemFields))
+	sockets := 0.0
+	for _, m := range msgs {
+		if len(m.Data) < int(unsafe.Sizeof(InetDiagMsg{})) {
+			continue
+		}
+		sockets++
+		skmem := inetDiagSkmem(m.Data)
+		for i := range sums {
+			if i < len(skmem) {
+				sums[i] += float64(skmem[i])
+			}
+		}
+	}
+	return sockets, sums
+}
+
+// parseProtocolsPressure returns whether the protocols of /proc/net/protocols
+// which track their memory are under memory pressure.
+func parseProtocolsPressure(r io.Reader) (map[string]bool, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, scanner.Err()
+	}
+	press := -1
+	for i, column := range strings.Fields(scanner.Text()) {
+		if column == "press" {
+			press = i
+		}
+	}
+	if press < 0 {
+		return nil, fmt.Errorf("no press column in header %q", scanner.Text())
+	}
+
+	pressure := map[string]bool{}
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) <= press {
+			continue
+		}
+		switch fields[press] {
+		case "yes":
+			pressure[fields[0]] = true
+		case "no":
+			pressure[fields[0]] = false
+		}
+	}
+	return pressure, scanner.Err()
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nosockmem
+// +build !nosockmem
+
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/josharian/native"
+	"github.com/mdlayher/netlink"
+)
+
+func TestParseSockmem(t *testing.T) {
+	encode := func(skmem ...uint32) netlink.Message {
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, native.Endian, InetDiagMsg{Family: syscall.AF_INET}); err != nil {
+			t.Fatal(err)
+		}
+		ae := netlink.NewAttributeEncoder()
+		ae.Do(inetDiagSkmeminfo, func() ([]byte, error) {
+			var b bytes.Buffer
+			err := binary.Write(&b, native.Endian, skmem)
+			return b.Bytes(), err
+		})
+		attrs, err := ae.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return netlink.Message{Data: append(buf.Bytes(), attrs...)}
+	}
+
+	sockets, sums := parseSockmem([]netlink.Message{
+		encode(100, 131072, 0, 16384, 3996, 0, 0, 0, 2),
+		encode(2048, 131072, 768, 87040, 1280, 4352, 320, 512, 0),
+	})
+
+	if want, got := 2.0, sockets; want != got {
+		t.Errorf("want %f sockets, got %f", want, got)
+	}
+	for i, want := range []float64{2148, 262144, 768, 103424, 5276, 4352, 320, 512} {
+		if got := sums[i]; want != got {
+			t.Errorf("want %s %f, got %f", sockmemFields[i].name, want, got)
+		}
+	}
+}
+
+func TestParseProtocolsPressure(t *testing.T) {
+	f, err := os.Open("fixtures/proc/net/protocols")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pressure, err := parseProtocolsPressure(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{"MPTCPv6": false, "TCPv6": false, "MPTCP": false, "TCP": true}
+	if len(pressure) != len(want) {
+		t.Fatalf("want %v, got %v", want, pressure)
+	}
+	for protocol, underPressure := range want {
+		if got, ok := pressure[protocol]; !ok || got != underPressure {
+			t.Errorf("want %s under pressure %t, got %t", protocol, underPressure, got)
+		}
+	}
+}
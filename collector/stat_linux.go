@@ -96,7 +96,21 @@ func (c *statCollector) Update(ch chan<- prometheus.Metric) error {
 	if err != nil {
 		return err
 	}
+	c.updateStat(ch, stats)
+	return nil
+}
+
+// updateFromSnapshot implements snapshotCollector.
+func (c *statCollector) updateFromSnapshot(ch chan<- prometheus.Metric, s *procSnapshot) error {
+	stats, err := s.Stat()
+	if err != nil {
+		return err
+	}
+	c.updateStat(ch, stats)
+	return nil
+}
 
+func (c *statCollector) updateStat(ch chan<- prometheus.Metric, stats procfs.Stat) {
 	ch <- prometheus.MustNewConstMetric(c.intr, prometheus.CounterValue, float64(stats.IRQTotal))
 	ch <- prometheus.MustNewConstMetric(c.ctxt, prometheus.CounterValue, float64(stats.ContextSwitches))
 	ch <- prometheus.MustNewConstMetric(c.forks, prometheus.CounterValue, float64(stats.ProcessCreated))
@@ -127,6 +141,4 @@ func (c *statCollector) Update(ch chan<- prometheus.Metric) error {
 			ch <- prometheus.MustNewConstMetric(c.softIRQ, prometheus.CounterValue, float64(vec.value), vec.name)
 		}
 	}
-
-	return nil
 }
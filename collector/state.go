@@ -0,0 +1,110 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+var stateFile = kingpin.Flag("collector.state-file", "File keeping the counters which collectors derive themselves across restarts of the exporter, instead of starting them from zero.").Default("").String()
+
+// counterState holds the derived counters of each collector by key, as in the
+// state file.
+var counterState struct {
+	mtx      sync.Mutex
+	loaded   bool
+	counters map[string]map[string]float64
+}
+
+// loadCounterState reads the state file on the first call, counterState.mtx
+// must be held.
+func loadCounterState() error {
+	if counterState.loaded {
+		return nil
+	}
+	counterState.loaded = true
+	counterState.counters = map[string]map[string]float64{}
+	content, err := os.ReadFile(*stateFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(content, &counterState.counters); err != nil {
+		return fmt.Errorf("%w: invalid state file: %s", ErrParse, err)
+	}
+	return nil
+}
+
+// restoreCounters returns the counters of collector saved in the state file,
+// nil if there is no state file.
+func restoreCounters(collector string) (map[string]float64, error) {
+	if *stateFile == "" {
+		return nil, nil
+	}
+	counterState.mtx.Lock()
+	defer counterState.mtx.Unlock()
+	if err := loadCounterState(); err != nil {
+		return nil, err
+	}
+	counters := make(map[string]float64, len(counterState.counters[collector]))
+	for key, value := range counterState.counters[collector] {
+		counters[key] = value
+	}
+	return counters, nil
+}
+
+// persistCounters replaces the counters of collector in the state file, if
+// there is one.
+func persistCounters(collector string, counters map[string]float64) error {
+	if *stateFile == "" {
+		return nil
+	}
+	counterState.mtx.Lock()
+	defer counterState.mtx.Unlock()
+	// A state file which can't be read is overwritten.
+	_ = loadCounterState()
+	saved := make(map[string]float64, len(counters))
+	for key, value := range counters {
+		saved[key] = value
+	}
+	counterState.counters[collector] = saved
+
+	content, err := json.Marshal(counterState.counters)
+	if err != nil {
+		return err
+	}
+	// Replace the file atomically, a crash mustn't leave it truncated.
+	tmp, err := os.CreateTemp(filepath.Dir(*stateFile), filepath.Base(*stateFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), *stateFile)
+}
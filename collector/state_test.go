@@ -0,0 +1,73 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCounterState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	*stateFile = path
+	restart := func() {
+		counterState.loaded = false
+		counterState.counters = nil
+	}
+	defer func() {
+		*stateFile = ""
+		restart()
+	}()
+
+	restart()
+	if counters, err := restoreCounters("test"); err != nil || len(counters) != 0 {
+		t.Fatalf("without state file: want no counters, got %v (err %v)", counters, err)
+	}
+	if err := persistCounters("test", map[string]float64{"a": 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := persistCounters("other", map[string]float64{"b": 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	restart()
+	counters, err := restoreCounters("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counters) != 1 || counters["a"] != 1.5 {
+		t.Errorf("unexpected restored counters %v", counters)
+	}
+	if counters, _ := restoreCounters("other"); counters["b"] != 2 {
+		t.Errorf("unexpected restored counters of other collector %v", counters)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	if _, err := restoreCounters("test"); !errors.Is(err, ErrParse) {
+		t.Errorf("corrupt state file: want ErrParse, got %v", err)
+	}
+	// It's overwritten by the next save.
+	if err := persistCounters("test", map[string]float64{"a": 3}); err != nil {
+		t.Fatal(err)
+	}
+	restart()
+	if counters, err := restoreCounters("test"); err != nil || counters["a"] != 3 {
+		t.Errorf("after overwriting corrupt state file: got %v (err %v)", counters, err)
+	}
+}
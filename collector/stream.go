@@ -0,0 +1,107 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CollectByCollector runs the collectors like Collect, but hands the metrics
+// of each collector to fn as soon as the collector has finished, so the
+// caller can encode and send them while slower collectors are still running.
+// fn isn't called concurrently, collectors which finished wait for it to
+// return for the previous one, so a slow client holds back the collectors
+// rather than letting their metrics pile up. The metrics about the collectors
+// themselves, to which all of them contribute, are handed over last.
+func (n NodeCollector) CollectByCollector(fn func([]prometheus.Metric)) {
+	var shared []prometheus.Metric
+	sharedCh := make(chan prometheus.Metric)
+	sharedDone := make(chan struct{})
+	go func() {
+		for m := range sharedCh {
+			shared = append(shared, m)
+		}
+		close(sharedDone)
+	}()
+
+	collectAutoDisabled(sharedCh)
+	if skipped := n.pressureSkipped(sharedCh); len(skipped) > 0 {
+		n = n.without(skipped)
+	}
+	if skipped := n.leaderSkipped(sharedCh); len(skipped) > 0 {
+		n = n.without(skipped)
+	}
+	snapshot := n.snapshot()
+	if *resourceAccounting {
+		// The collectors run one after the other to account their
+		// usage, there is nothing to gain from streaming.
+		n.collectAccounted(sharedCh, snapshot)
+	} else {
+		n.collectEach(sharedCh, snapshot, fn)
+	}
+
+	close(sharedCh)
+	<-sharedDone
+	fn(shared)
+}
+
+// collectEach runs the collectors concurrently and calls fn with the metrics
+// of each collector in the order they finish. The scrape metrics are sent to
+// shared.
+func (n NodeCollector) collectEach(shared chan<- prometheus.Metric, snapshot *procSnapshot, fn func([]prometheus.Metric)) {
+	results := make(chan []prometheus.Metric)
+	wg := sync.WaitGroup{}
+	wg.Add(len(n.Collectors))
+	for name, c := range n.Collectors {
+		go func(name string, c Collector) {
+			defer wg.Done()
+			var metrics []prometheus.Metric
+			ch := make(chan prometheus.Metric)
+			done := make(chan struct{})
+			go func() {
+				for m := range ch {
+					if isScrapeMetric(m) {
+						shared <- m
+						continue
+					}
+					metrics = append(metrics, m)
+				}
+				close(done)
+			}()
+			execute(name, c, ch, n.logger, snapshot)
+			close(ch)
+			<-done
+			results <- metrics
+		}(name, c)
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	for metrics := range results {
+		fn(metrics)
+	}
+}
+
+// isScrapeMetric reports whether m is one of the metrics exposed about a
+// collector rather than by it, by execute or a scheduled collector.
+func isScrapeMetric(m prometheus.Metric) bool {
+	switch m.Desc() {
+	case scrapeDurationDesc, scrapeSuccessDesc, scrapeFailureReasonDesc, scrapeLastRunDesc:
+		return true
+	}
+	return false
+}
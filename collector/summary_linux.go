@@ -0,0 +1,274 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nosummary && !nodiskstats && !nofilesystem
+// +build !nosummary,!nodiskstats,!nofilesystem
+
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+	"github.com/prometheus/procfs/blockdevice"
+)
+
+const summarySubsystem = "summary"
+
+// summaryMetrics are the help texts of the metrics of the summary collector,
+// by name. All are gauges without labels.
+var summaryMetrics = map[string]string{
+	"cpu_count":                       "Number of CPUs.",
+	"cpu_busy_ratio":                  "Ratio of CPU time not idle or waiting for I/O since the last scrape.",
+	"cpu_iowait_ratio":                "Ratio of CPU time waiting for I/O since the last scrape.",
+	"load1_per_cpu":                   "1m load average divided by the number of CPUs.",
+	"processes_blocked":               "Number of processes blocked waiting for I/O.",
+	"uptime_seconds":                  "Seconds since boot.",
+	"memory_available_ratio":          "Ratio of memory available for starting new applications.",
+	"swap_used_ratio":                 "Ratio of swap space used, 0 without swap.",
+	"filesystem_max_used_ratio":       "Highest ratio of space used of the filesystems, of the space not reserved for root.",
+	"filesystem_max_files_used_ratio": "Highest ratio of inodes used of the filesystems.",
+	"filesystem_readonly":             "Number of filesystems mounted read-only.",
+	"filesystem_device_errors":        "Number of filesystems whose usage couldn't be read.",
+	"disk_max_busy_ratio":             "Highest ratio of time a disk was busy since the last scrape.",
+	"network_error_ratio":             "Ratio of packets with errors since the last scrape, on all interfaces except lo.",
+	"network_drop_ratio":              "Ratio of packets dropped since the last scrape, on all interfaces except lo.",
+	"pressure_cpu_waiting_ratio":      "Ratio of time some tasks waited for CPU over the last 10 seconds.",
+	"pressure_memory_waiting_ratio":   "Ratio of time some tasks waited for memory over the last 10 seconds.",
+	"pressure_io_waiting_ratio":       "Ratio of time some tasks waited for I/O over the last 10 seconds.",
+}
+
+// summarySnapshot holds the counters the ratios since the last scrape are
+// computed from.
+type summarySnapshot struct {
+	time                            time.Time
+	cpuTotal, cpuIdle, cpuIOWait    float64
+	netPackets, netErrors, netDrops float64
+	diskBusySeconds                 map[string]float64
+}
+
+type summaryCollector struct {
+	fs           procfs.FS
+	blockFS      blockdevice.FS
+	diskFilter   deviceFilter
+	filesystems  *filesystemCollector
+	descs        map[string]*prometheus.Desc
+	logger       log.Logger
+	mtx          sync.Mutex
+	lastSnapshot *summarySnapshot
+}
+
+func init() {
+	registerCollector(summarySubsystem, defaultDisabled, NewSummaryCollector)
+}
+
+// NewSummaryCollector returns a new Collector exposing a small fixed set of
+// health gauges of the node, for scraping large fleets cheaply.
+func NewSummaryCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	blockFS, err := blockdevice.NewFS(*procPath, *sysPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sysfs: %w", err)
+	}
+	diskFilter, err := newDiskstatsDeviceFilter(logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse device filter flags: %w", err)
+	}
+	// The filesystems are selected like by the filesystem collector.
+	filesystems, err := NewFilesystemCollector(logger)
+	if err != nil {
+		return nil, err
+	}
+	descs := make(map[string]*prometheus.Desc, len(summaryMetrics))
+	for name, help := range summaryMetrics {
+		descs[name] = prometheus.NewDesc(prometheus.BuildFQName(namespace, summarySubsystem, name), help, nil, nil)
+	}
+	return &summaryCollector{
+		fs:          fs,
+		blockFS:     blockFS,
+		diskFilter:  diskFilter,
+		filesystems: filesystems.(*filesystemCollector),
+		descs:       descs,
+		logger:      logger,
+	}, nil
+}
+
+func (c *summaryCollector) Update(ch chan<- prometheus.Metric) error {
+	emit := func(name string, value float64) {
+		ch <- prometheus.MustNewConstMetric(c.descs[name], prometheus.GaugeValue, value)
+	}
+
+	stat, err := c.fs.Stat()
+	if err != nil {
+		return fmt.Errorf("couldn't get stat: %w", err)
+	}
+	now := time.Now()
+	snapshot := summarySnapshot{
+		time:            now,
+		cpuIdle:         stat.CPUTotal.Idle,
+		cpuIOWait:       stat.CPUTotal.Iowait,
+		diskBusySeconds: map[string]float64{},
+	}
+	cpu := stat.CPUTotal
+	// Guest time is included in user time.
+	snapshot.cpuTotal = cpu.User + cpu.Nice + cpu.System + cpu.Idle + cpu.Iowait + cpu.IRQ + cpu.SoftIRQ + cpu.Steal
+	cpus := float64(len(stat.CPU))
+	emit("cpu_count", cpus)
+	emit("processes_blocked", float64(stat.ProcessesBlocked))
+	emit("uptime_seconds", now.Sub(time.Unix(int64(stat.BootTime), 0)).Seconds())
+
+	if load, err := c.fs.LoadAvg(); err == nil && cpus > 0 {
+		emit("load1_per_cpu", load.Load1/cpus)
+	} else if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't get load average", "err", err)
+	}
+
+	meminfo, err := c.fs.Meminfo()
+	if err != nil {
+		return fmt.Errorf("couldn't get meminfo: %w", err)
+	}
+	if available, ok := summaryMemAvailable(meminfo); ok && meminfo.MemTotal != nil {
+		emit("memory_available_ratio", summaryRatio(available, float64(*meminfo.MemTotal)))
+	}
+	if meminfo.SwapTotal != nil && meminfo.SwapFree != nil {
+		emit("swap_used_ratio", summaryRatio(float64(*meminfo.SwapTotal-*meminfo.SwapFree), float64(*meminfo.SwapTotal)))
+	}
+
+	c.updateFilesystems(emit)
+
+	if netDev, err := c.fs.NetDev(); err == nil {
+		for name, dev := range netDev {
+			if name == "lo" {
+				continue
+			}
+			snapshot.netPackets += float64(dev.RxPackets + dev.TxPackets)
+			snapshot.netErrors += float64(dev.RxErrors + dev.TxErrors)
+			snapshot.netDrops += float64(dev.RxDropped + dev.TxDropped)
+		}
+	} else {
+		level.Debug(c.logger).Log("msg", "couldn't get network devices", "err", err)
+	}
+
+	if disks, err := c.blockFS.ProcDiskstats(); err == nil {
+		for _, disk := range disks {
+			if c.diskFilter.ignored(disk.DeviceName) {
+				continue
+			}
+			snapshot.diskBusySeconds[disk.DeviceName] = float64(disk.IOsTotalTicks) * secondsPerTick
+		}
+	} else {
+		level.Debug(c.logger).Log("msg", "couldn't get disk stats", "err", err)
+	}
+
+	for _, resource := range []string{"cpu", "memory", "io"} {
+		psi, err := c.fs.PSIStatsForResource(resource)
+		if err != nil || psi.Some == nil {
+			continue
+		}
+		emit("pressure_"+resource+"_waiting_ratio", psi.Some.Avg10/100)
+	}
+
+	c.mtx.Lock()
+	last := c.lastSnapshot
+	if last == nil {
+		// The ratios of the first scrape are since boot.
+		last = &summarySnapshot{time: time.Unix(int64(stat.BootTime), 0)}
+	}
+	c.lastSnapshot = &snapshot
+	c.mtx.Unlock()
+
+	if cpuTime := snapshot.cpuTotal - last.cpuTotal; cpuTime > 0 {
+		idle, iowait := snapshot.cpuIdle-last.cpuIdle, snapshot.cpuIOWait-last.cpuIOWait
+		emit("cpu_busy_ratio", 1-(idle+iowait)/cpuTime)
+		emit("cpu_iowait_ratio", iowait/cpuTime)
+	}
+	packets := snapshot.netPackets - last.netPackets
+	emit("network_error_ratio", summaryRatio(snapshot.netErrors-last.netErrors, packets))
+	emit("network_drop_ratio", summaryRatio(snapshot.netDrops-last.netDrops, packets))
+	if elapsed := snapshot.time.Sub(last.time).Seconds(); elapsed > 0 {
+		busiest := 0.0
+		for name, busy := range snapshot.diskBusySeconds {
+			lastBusy, ok := last.diskBusySeconds[name]
+			if !ok && last.diskBusySeconds != nil {
+				// The disk appeared since the last scrape.
+				continue
+			}
+			if r := (busy - lastBusy) / elapsed; r > busiest {
+				busiest = r
+			}
+		}
+		if busiest > 1 {
+			busiest = 1
+		}
+		emit("disk_max_busy_ratio", busiest)
+	}
+	return nil
+}
+
+// updateFilesystems emits the usage of the fullest filesystems.
+func (c *summaryCollector) updateFilesystems(emit func(string, float64)) {
+	stats, err := c.filesystems.GetStats()
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't get filesystem stats", "err", err)
+		return
+	}
+	var maxUsed, maxFilesUsed, readonly, deviceErrors float64
+	for _, s := range stats {
+		if s.deviceError > 0 {
+			deviceErrors++
+			continue
+		}
+		readonly += s.ro
+		// Like df, the space reserved for root counts as neither used nor
+		// available.
+		if used := summaryRatio(s.size-s.free, s.size-s.free+s.avail); used > maxUsed {
+			maxUsed = used
+		}
+		if used := summaryRatio(s.files-s.filesFree, s.files); used > maxFilesUsed {
+			maxFilesUsed = used
+		}
+	}
+	emit("filesystem_max_used_ratio", maxUsed)
+	emit("filesystem_max_files_used_ratio", maxFilesUsed)
+	emit("filesystem_readonly", readonly)
+	emit("filesystem_device_errors", deviceErrors)
+}
+
+// summaryMemAvailable returns MemAvailable, or its approximation on kernels
+// older than 3.14 lacking it.
+func summaryMemAvailable(meminfo procfs.Meminfo) (float64, bool) {
+	if meminfo.MemAvailable != nil {
+		return float64(*meminfo.MemAvailable), true
+	}
+	if meminfo.MemFree == nil || meminfo.Buffers == nil || meminfo.Cached == nil {
+		return 0, false
+	}
+	return float64(*meminfo.MemFree + *meminfo.Buffers + *meminfo.Cached), true
+}
+
+// summaryRatio returns a/b, 0 if b isn't positive or a is negative after a
+// counter reset.
+func summaryRatio(a, b float64) float64 {
+	if b <= 0 || a < 0 {
+		return 0
+	}
+	return a / b
+}
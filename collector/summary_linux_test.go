@@ -0,0 +1,82 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nosummary && !nodiskstats && !nofilesystem
+// +build !nosummary,!nodiskstats,!nofilesystem
+
+package collector
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSummary(t *testing.T) {
+	*procPath = "fixtures/proc"
+	*sysPath = "fixtures/sys"
+	collector, err := NewSummaryCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{collector})
+	gather := func() map[string]float64 {
+		mfs, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]float64{}
+		for _, mf := range mfs {
+			got[mf.GetName()] = mf.GetMetric()[0].GetGauge().GetValue()
+		}
+		return got
+	}
+
+	// The ratios of the first scrape are since boot.
+	got := gather()
+	for name, want := range map[string]float64{
+		"node_summary_cpu_count":                  8,
+		"node_summary_load1_per_cpu":              0.21 / 8,
+		"node_summary_memory_available_ratio":     (225472.0 + 22040 + 930888) / 3742148,
+		"node_summary_swap_used_ratio":            (4194300.0 - 3155360) / 4194300,
+		"node_summary_cpu_busy_ratio":             1 - (8979004.0+3552)/(301854+612+111922+8979004+3552+2+3944),
+		"node_summary_cpu_iowait_ratio":           3552.0 / (301854 + 612 + 111922 + 8979004 + 3552 + 2 + 3944),
+		"node_summary_pressure_cpu_waiting_ratio": 0,
+	} {
+		value, ok := got[name]
+		if !ok {
+			t.Errorf("%s missing", name)
+			continue
+		}
+		if math.Abs(value-want) > 1e-9 {
+			t.Errorf("%s: want %v, got %v", name, want, value)
+		}
+	}
+
+	// Without any change since, the CPU ratios are absent and the others 0.
+	got = gather()
+	if _, ok := got["node_summary_cpu_busy_ratio"]; ok {
+		t.Error("node_summary_cpu_busy_ratio present without CPU time elapsed")
+	}
+	for _, name := range []string{"node_summary_disk_max_busy_ratio", "node_summary_network_error_ratio"} {
+		if value, ok := got[name]; !ok || value != 0 {
+			t.Errorf("%s: want 0, got %v (present %t)", name, value, ok)
+		}
+	}
+	if len(got) > len(summaryMetrics) {
+		t.Errorf("want at most %d metrics, got %d", len(summaryMetrics), len(got))
+	}
+}
@@ -119,6 +119,18 @@ func (c *supervisordCollector) isRunning(state int) bool {
 	return false
 }
 
+// Validate implements Validator.
+func (c *supervisordCollector) Validate() error {
+	u, err := url.Parse(*supervisordURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "unix" {
+		return checkSocket(u.Path)
+	}
+	return nil
+}
+
 func (c *supervisordCollector) Update(ch chan<- prometheus.Metric) error {
 	var info struct {
 		Name          string `xmlrpc:"name"`
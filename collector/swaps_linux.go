@@ -0,0 +1,84 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noswaps
+// +build !noswaps
+
+package collector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/procfs"
+)
+
+const swapsSubsystem = "swaps"
+
+type swapsCollector struct {
+	fs       procfs.FS
+	size     *prometheus.Desc
+	used     *prometheus.Desc
+	priority *prometheus.Desc
+	logger   log.Logger
+}
+
+func init() {
+	registerCollector(swapsSubsystem, defaultEnabled, NewSwapsCollector)
+}
+
+// NewSwapsCollector returns a new Collector exposing the usage of each swap
+// device and file.
+func NewSwapsCollector(logger log.Logger) (Collector, error) {
+	fs, err := procfs.NewFS(*procPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open procfs: %w", err)
+	}
+	labels := []string{"device", "type"}
+	return &swapsCollector{
+		fs: fs,
+		size: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapsSubsystem, "size_bytes"),
+			"Size of the swap device or file.",
+			labels, nil,
+		),
+		used: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapsSubsystem, "used_bytes"),
+			"Space used of the swap device or file.",
+			labels, nil,
+		),
+		priority: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, swapsSubsystem, "priority"),
+			"Priority of the swap device or file, the ones of higher priority are used first.",
+			labels, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *swapsCollector) Update(ch chan<- prometheus.Metric) error {
+	swaps, err := c.fs.Swaps()
+	if err != nil {
+		return fmt.Errorf("couldn't get swaps: %w", err)
+	}
+	for _, swap := range swaps {
+		// Like in /proc/mounts, spaces of the path are escaped in octal.
+		device := strings.NewReplacer("\\040", " ", "\\011", "\t").Replace(swap.Filename)
+		ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(swap.Size)*1024, device, swap.Type)
+		ch <- prometheus.MustNewConstMetric(c.used, prometheus.GaugeValue, float64(swap.Used)*1024, device, swap.Type)
+		ch <- prometheus.MustNewConstMetric(c.priority, prometheus.GaugeValue, float64(swap.Priority), device, swap.Type)
+	}
+	return nil
+}
@@ -56,7 +56,8 @@ var (
 	oldUnitExclude         = kingpin.Flag("collector.systemd.unit-blacklist", "DEPRECATED: Use collector.systemd.unit-exclude").Hidden().String()
 	systemdPrivate         = kingpin.Flag("collector.systemd.private", "Establish a private, direct connection to systemd without dbus (Strongly discouraged since it requires root. For testing purposes only).").Hidden().Bool()
 	enableTaskMetrics      = kingpin.Flag("collector.systemd.enable-task-metrics", "Enables service unit tasks metrics unit_tasks_current and unit_tasks_max").Bool()
-	enableRestartsMetrics  = kingpin.Flag("collector.systemd.enable-restarts-metrics", "Enables service unit metric service_restart_total").Bool()
+	enableRestartsMetrics  = kingpin.Flag("collector.systemd.enable-restarts-metrics", "Enables service unit metrics service_restart_total and service_main_start_time_seconds").Bool()
+	enableWatchdogMetrics  = kingpin.Flag("collector.systemd.enable-watchdog-metrics", "Enables service unit metrics service_watchdog_timeout_seconds and service_watchdog_last_ping_seconds").Bool()
 	enableStartTimeMetrics = kingpin.Flag("collector.systemd.enable-start-time-metrics", "Enables service unit metric unit_start_time_seconds").Bool()
 
 	systemdVersionRE = regexp.MustCompile(`[0-9]{3,}(\.[0-9]+)?`)
@@ -70,6 +71,9 @@ type systemdCollector struct {
 	systemRunningDesc             *prometheus.Desc
 	summaryDesc                   *prometheus.Desc
 	nRestartsDesc                 *prometheus.Desc
+	mainStartTimeDesc             *prometheus.Desc
+	watchdogTimeoutDesc           *prometheus.Desc
+	watchdogLastPingDesc          *prometheus.Desc
 	timerLastTriggerDesc          *prometheus.Desc
 	socketAcceptedConnectionsDesc *prometheus.Desc
 	socketCurrentConnectionsDesc  *prometheus.Desc
@@ -117,6 +121,15 @@ func NewSystemdCollector(logger log.Logger) (Collector, error) {
 	nRestartsDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "service_restart_total"),
 		"Service unit count of Restart triggers", []string{"name"}, nil)
+	mainStartTimeDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_main_start_time_seconds"),
+		"Start time of the main process of the service unit since unix epoch in seconds.", []string{"name"}, nil)
+	watchdogTimeoutDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_watchdog_timeout_seconds"),
+		"Watchdog timeout of the service unit, the service is considered failed if it doesn't ping the watchdog within it.", []string{"name"}, nil)
+	watchdogLastPingDesc := prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, subsystem, "service_watchdog_last_ping_seconds"),
+		"Time of the last watchdog ping of the service unit since unix epoch in seconds.", []string{"name"}, nil)
 	timerLastTriggerDesc := prometheus.NewDesc(
 		prometheus.BuildFQName(namespace, subsystem, "timer_last_trigger_seconds"),
 		"Seconds since epoch of last trigger.", []string{"name"}, nil)
@@ -162,6 +175,9 @@ func NewSystemdCollector(logger log.Logger) (Collector, error) {
 		systemRunningDesc:             systemRunningDesc,
 		summaryDesc:                   summaryDesc,
 		nRestartsDesc:                 nRestartsDesc,
+		mainStartTimeDesc:             mainStartTimeDesc,
+		watchdogTimeoutDesc:           watchdogTimeoutDesc,
+		watchdogLastPingDesc:          watchdogLastPingDesc,
 		timerLastTriggerDesc:          timerLastTriggerDesc,
 		socketAcceptedConnectionsDesc: socketAcceptedConnectionsDesc,
 		socketCurrentConnectionsDesc:  socketCurrentConnectionsDesc,
@@ -173,6 +189,14 @@ func NewSystemdCollector(logger log.Logger) (Collector, error) {
 	}, nil
 }
 
+// Validate implements Validator.
+func (c *systemdCollector) Validate() error {
+	if *systemdPrivate {
+		return checkSocket("/run/systemd/private")
+	}
+	return checkSystemBus()
+}
+
 // Update gathers metrics from systemd.  Dbus collection is done in parallel
 // to reduce wait time for responses.
 func (c *systemdCollector) Update(ch chan<- prometheus.Metric) error {
@@ -304,10 +328,52 @@ func (c *systemdCollector) collectUnitStatusMetrics(conn *dbus.Conn, ch chan<- p
 					c.nRestartsDesc, prometheus.CounterValue,
 					float64(restartsCount.Value.Value().(uint32)), unit.Name)
 			}
+			// The timestamp is 0 if the main process never started.
+			mainStartTime, err := conn.GetUnitTypePropertyContext(context.TODO(), unit.Name, "Service", "ExecMainStartTimestamp")
+			if err != nil {
+				level.Debug(c.logger).Log("msg", "couldn't get unit ExecMainStartTimestamp", "unit", unit.Name, "err", err)
+			} else if usec := mainStartTime.Value.Value().(uint64); usec > 0 {
+				ch <- prometheus.MustNewConstMetric(
+					c.mainStartTimeDesc, prometheus.GaugeValue,
+					float64(usec)/1e6, unit.Name)
+			}
+		}
+		if *enableWatchdogMetrics && strings.HasSuffix(unit.Name, ".service") {
+			c.collectWatchdog(conn, ch, unit)
 		}
 	}
 }
 
+// collectWatchdog exposes the watchdog of services which have WatchdogSec
+// set.
+func (c *systemdCollector) collectWatchdog(conn *dbus.Conn, ch chan<- prometheus.Metric, unit unit) {
+	timeout, err := conn.GetUnitTypePropertyContext(context.TODO(), unit.Name, "Service", "WatchdogUSec")
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't get unit WatchdogUSec", "unit", unit.Name, "err", err)
+		return
+	}
+	timeoutUsec := timeout.Value.Value().(uint64)
+	if timeoutUsec == 0 {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.watchdogTimeoutDesc, prometheus.GaugeValue,
+		float64(timeoutUsec)/1e6, unit.Name)
+
+	// WatchdogTimestamp is the time of the last ping, or the start of
+	// the main process before the first one.
+	lastPing, err := conn.GetUnitTypePropertyContext(context.TODO(), unit.Name, "Service", "WatchdogTimestamp")
+	if err != nil {
+		level.Debug(c.logger).Log("msg", "couldn't get unit WatchdogTimestamp", "unit", unit.Name, "err", err)
+		return
+	}
+	if usec := lastPing.Value.Value().(uint64); usec > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.watchdogLastPingDesc, prometheus.GaugeValue,
+			float64(usec)/1e6, unit.Name)
+	}
+}
+
 func (c *systemdCollector) collectSockets(conn *dbus.Conn, ch chan<- prometheus.Metric, units []unit) {
 	for _, unit := range units {
 		if !strings.HasSuffix(unit.Name, ".socket") {
@@ -0,0 +1,91 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !notaint
+// +build !notaint
+
+package collector
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const taintSubsystem = "taint"
+
+// taintFlags are the names of the bits of /proc/sys/kernel/tainted, see
+// https://docs.kernel.org/admin-guide/tainted-kernels.html.
+var taintFlags = []string{
+	"proprietary_module",
+	"forced_module_load",
+	"cpu_out_of_spec",
+	"forced_module_unload",
+	"machine_check",
+	"bad_page",
+	"user_request",
+	"died",
+	"acpi_table_overridden",
+	"warning",
+	"staging_driver",
+	"firmware_workaround",
+	"out_of_tree_module",
+	"unsigned_module",
+	"soft_lockup",
+	"live_patch",
+	"auxiliary",
+	"randstruct",
+	"test",
+	"fwctl",
+}
+
+type taintCollector struct {
+	mask   *prometheus.Desc
+	flag   *prometheus.Desc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector(taintSubsystem, defaultEnabled, NewTaintCollector)
+}
+
+// NewTaintCollector returns a new Collector exposing the taint flags of the
+// kernel.
+func NewTaintCollector(logger log.Logger) (Collector, error) {
+	return &taintCollector{
+		mask: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, taintSubsystem, "mask"),
+			"Taint flags of the kernel as the bitmask in /proc/sys/kernel/tainted.",
+			nil, nil,
+		),
+		flag: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, taintSubsystem, "flag"),
+			"Whether the kernel is tainted with the flag. Once set, flags stay set until reboot.",
+			[]string{"flag"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *taintCollector) Update(ch chan<- prometheus.Metric) error {
+	mask, err := readUintFromFile(procFilePath("sys/kernel/tainted"))
+	if err != nil {
+		return fmt.Errorf("couldn't get taint flags: %w", err)
+	}
+	ch <- prometheus.MustNewConstMetric(c.mask, prometheus.GaugeValue, float64(mask))
+	for bit, flag := range taintFlags {
+		ch <- prometheus.MustNewConstMetric(c.flag, prometheus.GaugeValue, float64(mask>>bit&1), flag)
+	}
+	return nil
+}
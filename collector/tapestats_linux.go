@@ -50,6 +50,7 @@ type tapestatsCollector struct {
 
 func init() {
 	registerCollector("tapestats", defaultEnabled, NewTapestatsCollector)
+	registerHardwareProbe("tapestats", "no SCSI tape drives", sysfsProbe("class/scsi_tape/*"))
 }
 
 // NewTapestatsCollector returns a new Collector exposing tape device stats.
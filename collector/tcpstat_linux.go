@@ -17,16 +17,21 @@
 package collector
 
 import (
+	"encoding/binary"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"syscall"
-	"unsafe"
 
+	"github.com/alecthomas/kingpin/v2"
 	"github.com/go-kit/log"
 	"github.com/mdlayher/netlink"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var tcpstatPorts = kingpin.Flag("collector.tcpstat.ports", "Comma separated list of local ports to expose the connection states of, e.g. the ports of listening services.").Default("").String()
+
 type tcpConnectionState int
 
 const (
@@ -59,8 +64,10 @@ const (
 )
 
 type tcpStatCollector struct {
-	desc   typedDesc
-	logger log.Logger
+	desc     typedDesc
+	portDesc typedDesc
+	ports    map[uint16]bool
+	logger   log.Logger
 }
 
 func init() {
@@ -69,134 +76,109 @@ func init() {
 
 // NewTCPStatCollector returns a new Collector exposing network stats.
 func NewTCPStatCollector(logger log.Logger) (Collector, error) {
+	ports := map[uint16]bool{}
+	for _, p := range strings.Split(*tcpstatPorts, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q in --collector.tcpstat.ports: %w", p, err)
+		}
+		ports[uint16(port)] = true
+	}
 	return &tcpStatCollector{
 		desc: typedDesc{prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, "tcp", "connection_states"),
 			"Number of connection states.",
 			[]string{"state"}, nil,
 		), prometheus.GaugeValue},
+		portDesc: typedDesc{prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "tcp", "port_connection_states"),
+			"Number of connection states by local port.",
+			[]string{"port", "state"}, nil,
+		), prometheus.GaugeValue},
+		ports:  ports,
 		logger: logger,
 	}, nil
 }
 
-// InetDiagSockID (inet_diag_sockid) contains the socket identity.
-// https://github.com/torvalds/linux/blob/v4.0/include/uapi/linux/inet_diag.h#L13
-type InetDiagSockID struct {
-	SourcePort [2]byte
-	DestPort   [2]byte
-	SourceIP   [4][4]byte
-	DestIP     [4][4]byte
-	Interface  uint32
-	Cookie     [2]uint32
-}
-
-// InetDiagReqV2 (inet_diag_req_v2) is used to request diagnostic data.
-// https://github.com/torvalds/linux/blob/v4.0/include/uapi/linux/inet_diag.h#L37
-type InetDiagReqV2 struct {
-	Family   uint8
-	Protocol uint8
-	Ext      uint8
-	Pad      uint8
-	States   uint32
-	ID       InetDiagSockID
-}
-
-const sizeOfDiagRequest = 0x38
-
-func (req *InetDiagReqV2) Serialize() []byte {
-	return (*(*[sizeOfDiagRequest]byte)(unsafe.Pointer(req)))[:]
-}
-
-func (req *InetDiagReqV2) Len() int {
-	return sizeOfDiagRequest
-}
-
-type InetDiagMsg struct {
-	Family  uint8
-	State   uint8
-	Timer   uint8
-	Retrans uint8
-	ID      InetDiagSockID
-	Expires uint32
-	RQueue  uint32
-	WQueue  uint32
-	UID     uint32
-	Inode   uint32
-}
-
-func parseInetDiagMsg(b []byte) *InetDiagMsg {
-	return (*InetDiagMsg)(unsafe.Pointer(&b[0]))
-}
-
 func (c *tcpStatCollector) Update(ch chan<- prometheus.Metric) error {
-	tcpStats, err := getTCPStats(syscall.AF_INET)
+	msgs, err := dumpTCPSockets(syscall.AF_INET)
 	if err != nil {
 		return fmt.Errorf("couldn't get tcpstats: %w", err)
 	}
 
 	// if enabled ipv6 system
 	if _, hasIPv6 := os.Stat(procFilePath("net/tcp6")); hasIPv6 == nil {
-		tcp6Stats, err := getTCPStats(syscall.AF_INET6)
+		msgs6, err := dumpTCPSockets(syscall.AF_INET6)
 		if err != nil {
 			return fmt.Errorf("couldn't get tcp6stats: %w", err)
 		}
-
-		for st, value := range tcp6Stats {
-			tcpStats[st] += value
-		}
+		msgs = append(msgs, msgs6...)
 	}
 
+	tcpStats, err := parseTCPStats(msgs)
+	if err != nil {
+		return err
+	}
 	for st, value := range tcpStats {
 		ch <- c.desc.mustNewConstMetric(value, st.String())
 	}
 
+	if len(c.ports) == 0 {
+		return nil
+	}
+	for port, stats := range parseTCPPortStats(msgs, c.ports) {
+		for st, value := range stats {
+			ch <- c.portDesc.mustNewConstMetric(value, strconv.Itoa(int(port)), st.String())
+		}
+	}
 	return nil
 }
 
-func getTCPStats(family uint8) (map[tcpConnectionState]float64, error) {
-	const TCPFAll = 0xFFF
-	const InetDiagInfo = 2
-	const SockDiagByFamily = 20
+// dumpTCPSockets lists the TCP sockets of a family with the inet_diag netlink
+// interface, which is much cheaper than reading /proc/net/tcp on hosts with
+// many sockets.
+func dumpTCPSockets(family uint8) ([]netlink.Message, error) {
+	return inetDiagDump(family, syscall.IPPROTO_TCP, tcpfAll, 0)
+}
 
-	conn, err := netlink.Dial(syscall.NETLINK_INET_DIAG, nil)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't connect netlink: %w", err)
-	}
-	defer conn.Close()
-
-	msg := netlink.Message{
-		Header: netlink.Header{
-			Type:  SockDiagByFamily,
-			Flags: syscall.NLM_F_REQUEST | syscall.NLM_F_DUMP,
-		},
-		Data: (&InetDiagReqV2{
-			Family:   family,
-			Protocol: syscall.IPPROTO_TCP,
-			States:   TCPFAll,
-			Ext:      0 | 1<<(InetDiagInfo-1),
-		}).Serialize(),
-	}
+func parseTCPStats(msgs []netlink.Message) (map[tcpConnectionState]float64, error) {
+	tcpStats := map[tcpConnectionState]float64{}
 
-	messages, err := conn.Execute(msg)
-	if err != nil {
-		return nil, err
+	for _, m := range msgs {
+		addTCPStats(tcpStats, parseInetDiagMsg(m.Data))
 	}
 
-	return parseTCPStats(messages)
+	return tcpStats, nil
 }
 
-func parseTCPStats(msgs []netlink.Message) (map[tcpConnectionState]float64, error) {
-	tcpStats := map[tcpConnectionState]float64{}
+// parseTCPPortStats counts the connection states of the sockets bound to the
+// given local ports.
+func parseTCPPortStats(msgs []netlink.Message, ports map[uint16]bool) map[uint16]map[tcpConnectionState]float64 {
+	portStats := map[uint16]map[tcpConnectionState]float64{}
 
 	for _, m := range msgs {
 		msg := parseInetDiagMsg(m.Data)
-
-		tcpStats[tcpTxQueuedBytes] += float64(msg.WQueue)
-		tcpStats[tcpRxQueuedBytes] += float64(msg.RQueue)
-		tcpStats[tcpConnectionState(msg.State)]++
+		port := binary.BigEndian.Uint16(msg.ID.SourcePort[:])
+		if !ports[port] {
+			continue
+		}
+		if portStats[port] == nil {
+			portStats[port] = map[tcpConnectionState]float64{}
+		}
+		addTCPStats(portStats[port], msg)
 	}
 
-	return tcpStats, nil
+	return portStats
+}
+
+func addTCPStats(tcpStats map[tcpConnectionState]float64, msg *InetDiagMsg) {
+	tcpStats[tcpTxQueuedBytes] += float64(msg.WQueue)
+	tcpStats[tcpRxQueuedBytes] += float64(msg.RQueue)
+	tcpStats[tcpConnectionState(msg.State)]++
 }
 
 func (st tcpConnectionState) String() string {
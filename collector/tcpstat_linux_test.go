@@ -85,3 +85,51 @@ func Test_parseTCPStats(t *testing.T) {
 	}
 
 }
+
+func Test_parseTCPPortStats(t *testing.T) {
+	encode := func(m InetDiagMsg) []byte {
+		var buf bytes.Buffer
+		err := binary.Write(&buf, native.Endian, m)
+		if err != nil {
+			panic(err)
+		}
+		return buf.Bytes()
+	}
+	socket := func(state tcpConnectionState, port uint16) netlink.Message {
+		id := InetDiagSockID{}
+		binary.BigEndian.PutUint16(id.SourcePort[:], port)
+		return netlink.Message{Data: encode(InetDiagMsg{
+			Family: syscall.AF_INET,
+			State:  uint8(state),
+			ID:     id,
+			RQueue: 3,
+		})}
+	}
+
+	msg := []netlink.Message{
+		socket(tcpListen, 443),
+		socket(tcpEstablished, 443),
+		socket(tcpEstablished, 443),
+		socket(tcpTimeWait, 443),
+		socket(tcpListen, 22),
+		socket(tcpEstablished, 8080),
+	}
+
+	portStats := parseTCPPortStats(msg, map[uint16]bool{443: true, 8080: true, 9100: true})
+
+	if want, got := 2, len(portStats); want != got {
+		t.Fatalf("want %d ports, got %d", want, got)
+	}
+	if want, got := 2, int(portStats[443][tcpEstablished]); want != got {
+		t.Errorf("want number of established state on port 443 %d, got %d", want, got)
+	}
+	if want, got := 1, int(portStats[443][tcpListen]); want != got {
+		t.Errorf("want number of listen state on port 443 %d, got %d", want, got)
+	}
+	if want, got := 12, int(portStats[443][tcpRxQueuedBytes]); want != got {
+		t.Errorf("want number of bytes in rx queue on port 443 %d, got %d", want, got)
+	}
+	if want, got := 1, int(portStats[8080][tcpEstablished]); want != got {
+		t.Errorf("want number of established state on port 8080 %d, got %d", want, got)
+	}
+}
@@ -186,6 +186,23 @@ func (c *textFileCollector) exportMTimes(mtimes map[string]time.Time, ch chan<-
 	}
 }
 
+// Validate implements Validator.
+func (c *textFileCollector) Validate() error {
+	if c.path == "" {
+		return nil
+	}
+	paths, err := filepath.Glob(c.path)
+	if err != nil || len(paths) == 0 {
+		paths = []string{c.path}
+	}
+	for _, path := range paths {
+		if err := checkReadableDir(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Update implements the Collector interface.
 func (c *textFileCollector) Update(ch chan<- prometheus.Metric) error {
 	// Iterate over files and accumulate their metrics, but also track any
@@ -40,6 +40,7 @@ type thermalZoneCollector struct {
 
 func init() {
 	registerCollector("thermal_zone", defaultEnabled, NewThermalZoneCollector)
+	registerHardwareProbe("thermal_zone", "no thermal zones", sysfsProbe("class/thermal/thermal_zone*"))
 }
 
 // NewThermalZoneCollector returns a new Collector exposing kernel/system statistics.
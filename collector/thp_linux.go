@@ -0,0 +1,152 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nothp
+// +build !nothp
+
+package collector
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const thpSubsystem = "thp"
+
+type thpCollector struct {
+	info        *prometheus.Desc
+	pageSize    *prometheus.Desc
+	fullScans   *prometheus.Desc
+	collapsed   *prometheus.Desc
+	pagesToScan *prometheus.Desc
+	scanSleep   *prometheus.Desc
+	events      *prometheus.Desc
+	logger      log.Logger
+}
+
+func init() {
+	registerCollector(thpSubsystem, defaultEnabled, NewTHPCollector)
+	registerHardwareProbe(thpSubsystem, "kernel without transparent huge pages", sysfsProbe("kernel/mm/transparent_hugepage"))
+}
+
+// NewTHPCollector returns a new Collector exposing the settings and activity
+// of transparent huge pages.
+func NewTHPCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, thpSubsystem, name), help, labels, nil)
+	}
+	return &thpCollector{
+		info:        desc("info", "Settings of transparent huge pages for anonymous memory, its defragmentation and shmem.", "enabled", "defrag", "shmem_enabled"),
+		pageSize:    desc("page_size_bytes", "Size of the transparent huge pages."),
+		fullScans:   desc("khugepaged_full_scans_total", "Number of times khugepaged scanned all the memory it may collapse."),
+		collapsed:   desc("khugepaged_pages_collapsed_total", "Number of huge pages khugepaged collapsed from small pages."),
+		pagesToScan: desc("khugepaged_pages_to_scan", "Number of pages khugepaged scans in each pass."),
+		scanSleep:   desc("khugepaged_scan_sleep_seconds", "Time khugepaged sleeps between two passes."),
+		events:      desc("events_total", "Transparent huge page events from the thp_ fields of /proc/vmstat, like allocation fallbacks and splits.", "event"),
+		logger:      logger,
+	}, nil
+}
+
+func (c *thpCollector) Update(ch chan<- prometheus.Metric) error {
+	dir := sysFilePath("kernel/mm/transparent_hugepage")
+	var settings []string
+	for _, name := range []string{"enabled", "defrag", "shmem_enabled"} {
+		setting, err := readTHPSetting(filepath.Join(dir, name))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		settings = append(settings, setting)
+	}
+	if settings[0] == "" {
+		return ErrNoData
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, settings...)
+
+	for _, v := range []struct {
+		desc      *prometheus.Desc
+		file      string
+		valueType prometheus.ValueType
+		scale     float64
+	}{
+		{c.pageSize, "hpage_pmd_size", prometheus.GaugeValue, 1},
+		{c.fullScans, "khugepaged/full_scans", prometheus.CounterValue, 1},
+		{c.collapsed, "khugepaged/pages_collapsed", prometheus.CounterValue, 1},
+		{c.pagesToScan, "khugepaged/pages_to_scan", prometheus.GaugeValue, 1},
+		{c.scanSleep, "khugepaged/scan_sleep_millisecs", prometheus.GaugeValue, 0.001},
+	} {
+		value, err := readUintFromFile(filepath.Join(dir, v.file))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(v.desc, v.valueType, float64(value)*v.scale)
+	}
+
+	events, err := readTHPEvents(procFilePath("vmstat"))
+	if err != nil {
+		return fmt.Errorf("couldn't get vmstat: %w", err)
+	}
+	for event, value := range events {
+		ch <- prometheus.MustNewConstMetric(c.events, prometheus.CounterValue, value, event)
+	}
+	return nil
+}
+
+// readTHPSetting returns the selected value of a setting, the one in brackets
+// like madvise in "always [madvise] never".
+func readTHPSetting(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	for _, value := range strings.Fields(string(b)) {
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			return strings.Trim(value, "[]"), nil
+		}
+	}
+	return "", fmt.Errorf("no value selected in %s", path)
+}
+
+// readTHPEvents returns the thp_ fields of vmstat without the prefix.
+func readTHPEvents(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	events := map[string]float64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || !strings.HasPrefix(name, "thp_") {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+		events[strings.TrimPrefix(name, "thp_")] = v
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,90 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sort"
+
+	"github.com/go-kit/log"
+)
+
+// Validator is implemented by collectors which can check, without
+// collecting, that the files and sockets they are configured to use are
+// accessible.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationResult is the outcome of a preflight check, Err is nil if it
+// passed.
+type ValidationResult struct {
+	Check string
+	Err   error
+}
+
+// Validate checks that the procfs, sysfs and rootfs mountpoints are readable,
+// that the enabled collectors can be created and runs the checks of those
+// implementing Validator.
+func Validate(logger log.Logger) []ValidationResult {
+	results := []ValidationResult{
+		{"path.procfs", checkReadableDir(*procPath)},
+		{"path.sysfs", checkReadableDir(*sysPath)},
+		{"path.rootfs", checkReadableDir(*rootfsPath)},
+	}
+
+	nc, err := NewNodeCollector(logger)
+	if err != nil {
+		return append(results, ValidationResult{"collectors", err})
+	}
+	names := make([]string, 0, len(nc.Collectors))
+	for name := range nc.Collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results = append(results, ValidationResult{"collectors", nil})
+	for _, name := range names {
+		if v, ok := unwrapCollector(nc.Collectors[name]).(Validator); ok {
+			results = append(results, ValidationResult{"collector." + name, v.Validate()})
+		}
+	}
+	return results
+}
+
+// checkReadableDir returns an error if path isn't a directory which can be
+// listed.
+func checkReadableDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// checkSocket returns an error if the unix socket at path can't be connected
+// to.
+func checkSocket(path string) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
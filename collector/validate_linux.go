@@ -0,0 +1,52 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// systemBusSocket returns the path of the socket of the system D-Bus, as
+// used by the dbus library.
+func systemBusSocket() (string, error) {
+	address := os.Getenv("DBUS_SYSTEM_BUS_ADDRESS")
+	if address == "" {
+		return "/var/run/dbus/system_bus_socket", nil
+	}
+	// The address may list several transports separated by semicolons, only
+	// unix paths are checked.
+	for _, transport := range strings.Split(address, ";") {
+		if !strings.HasPrefix(transport, "unix:") {
+			continue
+		}
+		for _, kv := range strings.Split(strings.TrimPrefix(transport, "unix:"), ",") {
+			if strings.HasPrefix(kv, "path=") {
+				return strings.TrimPrefix(kv, "path="), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no unix socket path in DBUS_SYSTEM_BUS_ADDRESS %q", address)
+}
+
+// checkSystemBus returns an error if the system D-Bus socket can't be
+// connected to.
+func checkSystemBus() error {
+	path, err := systemBusSocket()
+	if err != nil {
+		return err
+	}
+	return checkSocket(path)
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckReadableDir(t *testing.T) {
+	if err := checkReadableDir("fixtures/proc"); err != nil {
+		t.Errorf("unexpected error for existing directory: %v", err)
+	}
+	if err := checkReadableDir(t.TempDir()); err != nil {
+		t.Errorf("unexpected error for empty directory: %v", err)
+	}
+	if err := checkReadableDir("fixtures/nonexistent"); err == nil {
+		t.Error("want error for missing directory")
+	}
+	if err := checkReadableDir("fixtures/proc/stat"); err == nil {
+		t.Error("want error for file")
+	}
+}
+
+func TestCheckSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.sock")
+	if err := checkSocket(path); err == nil {
+		t.Error("want error for missing socket")
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := checkSocket(path); err != nil {
+		t.Errorf("unexpected error for listening socket: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noversioncheck
+// +build !noversioncheck
+
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/version"
+)
+
+const versionCheckSubsystem = "versioncheck"
+
+var (
+	versionCheckURL      = kingpin.Flag("collector.versioncheck.url", "URL answering the version of node_exporter expected on this node, as plain text or as the version field of a JSON object.").Default("").String()
+	versionCheckInterval = kingpin.Flag("collector.versioncheck.interval", "Minimum interval between two requests of the expected version.").Default("5m").Duration()
+	versionCheckTimeout  = kingpin.Flag("collector.versioncheck.timeout", "Timeout of the requests of the expected version.").Default("5s").Duration()
+)
+
+type versionCheckCollector struct {
+	info    *prometheus.Desc
+	drift   *prometheus.Desc
+	checked *prometheus.Desc
+	url     string
+	client  *http.Client
+	logger  log.Logger
+
+	// The expected version is only requested again after the interval.
+	mtx         sync.Mutex
+	expected    string
+	lastCheck   time.Time
+	lastSuccess time.Time
+}
+
+var errVersionCheckURL = errors.New("--collector.versioncheck.url is required")
+
+func init() {
+	registerCollector(versionCheckSubsystem, defaultDisabled, NewVersionCheckCollector)
+}
+
+// NewVersionCheckCollector returns a new Collector comparing the version of
+// node_exporter with the one expected by a rollout endpoint.
+func NewVersionCheckCollector(logger log.Logger) (Collector, error) {
+	return &versionCheckCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, versionCheckSubsystem, "info"),
+			"Version of node_exporter running and the one expected by the rollout endpoint.",
+			[]string{"running", "expected"}, nil,
+		),
+		drift: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, versionCheckSubsystem, "drift"),
+			"Whether the running version of node_exporter differs from the expected one.",
+			nil, nil,
+		),
+		checked: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, versionCheckSubsystem, "last_success_timestamp_seconds"),
+			"Time the expected version was last requested successfully.",
+			nil, nil,
+		),
+		url:    *versionCheckURL,
+		client: &http.Client{Timeout: *versionCheckTimeout},
+		logger: logger,
+	}, nil
+}
+
+// Validate implements Validator.
+func (c *versionCheckCollector) Validate() error {
+	if c.url == "" {
+		return errVersionCheckURL
+	}
+	return nil
+}
+
+func (c *versionCheckCollector) Update(ch chan<- prometheus.Metric) error {
+	if c.url == "" {
+		// Failing to create the collector would fail every scrape.
+		return fmt.Errorf("%w: %s", ErrNoData, errVersionCheckURL)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if now := time.Now(); now.Sub(c.lastCheck) >= *versionCheckInterval {
+		c.lastCheck = now
+		expected, err := c.fetch()
+		if err != nil {
+			return fmt.Errorf("couldn't get expected version from %s: %w", c.url, err)
+		}
+		c.expected, c.lastSuccess = expected, now
+	}
+	if c.lastSuccess.IsZero() {
+		// The last request failed and none succeeded before.
+		return ErrNoData
+	}
+
+	running := version.Version
+	drift := 0.0
+	if normalizeVersion(running) != normalizeVersion(c.expected) {
+		drift = 1
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, running, c.expected)
+	ch <- prometheus.MustNewConstMetric(c.drift, prometheus.GaugeValue, drift)
+	ch <- prometheus.MustNewConstMetric(c.checked, prometheus.GaugeValue, float64(c.lastSuccess.Unix()))
+	return nil
+}
+
+// fetch requests the expected version.
+func (c *versionCheckCollector) fetch() (string, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", err
+	}
+	return parseExpectedVersion(body)
+}
+
+// parseExpectedVersion returns the version of a plain text body, or of the
+// version field of a JSON object.
+func parseExpectedVersion(body []byte) (string, error) {
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "{") {
+		var v struct {
+			Version string `json:"version"`
+		}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", err
+		}
+		text = strings.TrimSpace(v.Version)
+	}
+	if text == "" || strings.ContainsAny(text, " \n") {
+		return "", fmt.Errorf("invalid version %q", text)
+	}
+	return text, nil
+}
+
+// normalizeVersion strips the v prefix of tags, so that v1.6.1 matches the
+// version 1.6.1 node_exporter is built with.
+func normalizeVersion(v string) string {
+	return strings.TrimPrefix(v, "v")
+}
@@ -0,0 +1,113 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !noversioncheck
+// +build !noversioncheck
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/version"
+)
+
+func TestVersionCheck(t *testing.T) {
+	expected := "v1.7.0"
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"version": %q}`, expected)
+	}))
+	defer server.Close()
+
+	defer func(v string) { version.Version = v }(version.Version)
+	version.Version = "1.7.0"
+	*versionCheckURL = server.URL
+	*versionCheckInterval = time.Hour
+	*versionCheckTimeout = time.Second
+	c, err := NewVersionCheckCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectorAdapter{c})
+
+	want := `# HELP node_versioncheck_drift Whether the running version of node_exporter differs from the expected one.
+# TYPE node_versioncheck_drift gauge
+node_versioncheck_drift 0
+# HELP node_versioncheck_info Version of node_exporter running and the one expected by the rollout endpoint.
+# TYPE node_versioncheck_info gauge
+node_versioncheck_info{expected="v1.7.0",running="1.7.0"} 1
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_versioncheck_drift", "node_versioncheck_info"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The expected version is only requested again after the interval.
+	expected = "1.8.0"
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_versioncheck_drift", "node_versioncheck_info"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("want 1 request, got %d", requests)
+	}
+	c.(*versionCheckCollector).lastCheck = time.Time{}
+	want = strings.NewReplacer("drift 0", "drift 1", "v1.7.0", "1.8.0").Replace(want)
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "node_versioncheck_drift", "node_versioncheck_info"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionCheckWithoutURL(t *testing.T) {
+	*versionCheckURL = ""
+	c, err := NewVersionCheckCollector(log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.(Validator).Validate(); err == nil {
+		t.Error("validation without URL: want error")
+	}
+	if err := c.Update(make(chan prometheus.Metric, 10)); !errors.Is(err, ErrNoData) {
+		t.Errorf("scrape without URL: want ErrNoData, got %v", err)
+	}
+}
+
+func TestParseExpectedVersion(t *testing.T) {
+	for body, want := range map[string]string{
+		"1.7.0\n":                 "1.7.0",
+		`{"version": "v1.7.0"}`:   "v1.7.0",
+		"":                        "",
+		"<html>not found</html> ": "",
+	} {
+		got, err := parseExpectedVersion([]byte(body))
+		if want == "" {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", body, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("%q: want %q, got %q (%v)", body, want, got, err)
+		}
+	}
+}
@@ -34,7 +34,7 @@ const (
 )
 
 var (
-	vmStatFields = kingpin.Flag("collector.vmstat.fields", "Regexp of fields to return for vmstat collector.").Default("^(oom_kill|pgpg|pswp|pg.*fault).*").String()
+	vmStatFields = kingpin.Flag("collector.vmstat.fields", "Regexp of fields to return for vmstat collector, .* for all of them.").Default("^(oom_kill|pgpg|pswp|pg.*fault).*").String()
 )
 
 type vmStatCollector struct {
@@ -48,7 +48,10 @@ func init() {
 
 // NewvmStatCollector returns a new Collector exposing vmstat stats.
 func NewvmStatCollector(logger log.Logger) (Collector, error) {
-	pattern := regexp.MustCompile(*vmStatFields)
+	pattern, err := regexp.Compile(*vmStatFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --collector.vmstat.fields: %w", err)
+	}
 	return &vmStatCollector{
 		fieldPattern: pattern,
 		logger:       logger,
@@ -65,12 +68,12 @@ func (c *vmStatCollector) Update(ch chan<- prometheus.Metric) error {
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 || !c.fieldPattern.MatchString(parts[0]) {
+			continue
+		}
 		value, err := strconv.ParseFloat(parts[1], 64)
 		if err != nil {
-			return err
-		}
-		if !c.fieldPattern.MatchString(parts[0]) {
-			continue
+			return fmt.Errorf("invalid value of vmstat field %s: %w", parts[0], err)
 		}
 
 		ch <- prometheus.MustNewConstMetric(
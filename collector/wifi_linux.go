@@ -40,6 +40,8 @@ type wifiCollector struct {
 	stationTransmitBitsPerSecond *prometheus.Desc
 	stationReceiveBytesTotal     *prometheus.Desc
 	stationTransmitBytesTotal    *prometheus.Desc
+	stationReceivePacketsTotal   *prometheus.Desc
+	stationTransmitPacketsTotal  *prometheus.Desc
 	stationSignalDBM             *prometheus.Desc
 	stationTransmitRetriesTotal  *prometheus.Desc
 	stationTransmitFailedTotal   *prometheus.Desc
@@ -133,6 +135,20 @@ func NewWifiCollector(logger log.Logger) (Collector, error) {
 			nil,
 		),
 
+		stationReceivePacketsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_receive_packets_total"),
+			"The total number of packets received by a WiFi station.",
+			labels,
+			nil,
+		),
+
+		stationTransmitPacketsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "station_transmit_packets_total"),
+			"The total number of packets transmitted by a WiFi station.",
+			labels,
+			nil,
+		),
+
 		stationSignalDBM: prometheus.NewDesc(
 			prometheus.BuildFQName(namespace, subsystem, "station_signal_dbm"),
 			"The current WiFi signal strength, in decibel-milliwatts (dBm).",
@@ -172,12 +188,7 @@ func (c *wifiCollector) Update(ch chan<- prometheus.Metric) error {
 			level.Debug(c.logger).Log("msg", "wifi collector metrics are not available for this system")
 			return ErrNoData
 		}
-		if errors.Is(err, os.ErrPermission) {
-			level.Debug(c.logger).Log("msg", "wifi collector got permission denied when accessing metrics")
-			return ErrNoData
-		}
-
-		return fmt.Errorf("failed to access wifi data: %w", err)
+		return fmt.Errorf("failed to access wifi data: %w", expectedPermissionError(err))
 	}
 	defer stat.Close()
 
@@ -295,6 +306,22 @@ func (c *wifiCollector) updateStationStats(ch chan<- prometheus.Metric, device s
 		info.HardwareAddr.String(),
 	)
 
+	ch <- prometheus.MustNewConstMetric(
+		c.stationReceivePacketsTotal,
+		prometheus.CounterValue,
+		float64(info.ReceivedPackets),
+		device,
+		info.HardwareAddr.String(),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.stationTransmitPacketsTotal,
+		prometheus.CounterValue,
+		float64(info.TransmittedPackets),
+		device,
+		info.HardwareAddr.String(),
+	)
+
 	ch <- prometheus.MustNewConstMetric(
 		c.stationSignalDBM,
 		prometheus.GaugeValue,
@@ -0,0 +1,72 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collector
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// wslUnsupportedCollectors expose hardware which the virtual machine or
+// translation layer of the Windows Subsystem for Linux fakes or hides.
+var wslUnsupportedCollectors = []string{"edac", "hwmon", "thermal_zone"}
+
+// wslVersion returns 1 or 2 if running in the Windows Subsystem for Linux,
+// detected from the kernel release, and 0 otherwise. WSL1 translates Linux
+// system calls and reports a release ending in "-Microsoft", the kernels of
+// WSL2 have "microsoft-standard" in their release.
+func wslVersion() (int, error) {
+	release, err := os.ReadFile(procFilePath("sys/kernel/osrelease"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	switch r := string(release); {
+	case strings.Contains(r, "Microsoft"):
+		return 1, nil
+	case strings.Contains(strings.ToLower(r), "microsoft"):
+		return 2, nil
+	}
+	return 0, nil
+}
+
+// DisableWSLCollectors disables the collectors which return meaningless
+// values in the Windows Subsystem for Linux when running there, unless they
+// have been explicitly enabled on the command line. They are exposed as
+// auto-disabled.
+func DisableWSLCollectors(logger log.Logger) {
+	version, err := wslVersion()
+	if err != nil {
+		level.Debug(logger).Log("msg", "Couldn't detect WSL", "err", err)
+		return
+	}
+	if version == 0 {
+		return
+	}
+	for _, name := range wslUnsupportedCollectors {
+		enabled, ok := collectorState[name]
+		if !ok || !*enabled || forcedCollectors[name] {
+			continue
+		}
+		level.Info(logger).Log("msg", "Disabling collector", "collector", name, "reason", "unsupported on WSL")
+		*enabled = false
+		autoDisabledCollectors[name] = "unsupported on WSL"
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nowsl
+// +build !nowsl
+
+package collector
+
+import (
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type wslCollector struct {
+	info   *prometheus.Desc
+	logger log.Logger
+}
+
+func init() {
+	registerCollector("wsl", defaultDisabled, NewWSLCollector)
+}
+
+// NewWSLCollector returns a new Collector exposing whether the node runs in
+// the Windows Subsystem for Linux.
+func NewWSLCollector(logger log.Logger) (Collector, error) {
+	return &wslCollector{
+		info: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "wsl", "info"),
+			"Version of the Windows Subsystem for Linux the node runs in.",
+			[]string{"version"}, nil,
+		),
+		logger: logger,
+	}, nil
+}
+
+func (c *wslCollector) Update(ch chan<- prometheus.Metric) error {
+	version, err := wslVersion()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		// Not being in WSL isn't a failure, node_wsl_info is just absent.
+		return nil
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, strconv.Itoa(version))
+	return nil
+}
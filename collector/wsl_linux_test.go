@@ -0,0 +1,87 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nowsl
+// +build !nowsl
+
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+)
+
+func TestWSLVersion(t *testing.T) {
+	savedProcPath := *procPath
+	defer func() { *procPath = savedProcPath }()
+	*procPath = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(*procPath, "sys/kernel"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for release, want := range map[string]int{
+		"4.4.0-19041-Microsoft\n":             1,
+		"5.15.90.1-microsoft-standard-WSL2\n": 2,
+		"6.1.0-13-amd64\n":                    0,
+	} {
+		if err := os.WriteFile(filepath.Join(*procPath, "sys/kernel/osrelease"), []byte(release), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := wslVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%q: want version %d, got %d", release, want, got)
+		}
+	}
+}
+
+func TestDisableWSLCollectors(t *testing.T) {
+	savedProcPath, savedState := *procPath, collectorState
+	defer func() {
+		*procPath, collectorState = savedProcPath, savedState
+		autoDisabledCollectors = map[string]string{}
+		delete(forcedCollectors, "hwmon")
+	}()
+	*procPath = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(*procPath, "sys/kernel"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(*procPath, "sys/kernel/osrelease"), []byte("5.15.90.1-microsoft-standard-WSL2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := func() *bool { b := true; return &b }
+	collectorState = map[string]*bool{
+		"cpu":          enabled(),
+		"edac":         enabled(),
+		"hwmon":        enabled(),
+		"thermal_zone": enabled(),
+	}
+	forcedCollectors["hwmon"] = true
+
+	DisableWSLCollectors(log.NewNopLogger())
+
+	for name, want := range map[string]bool{"cpu": true, "edac": false, "hwmon": true, "thermal_zone": false} {
+		if *collectorState[name] != want {
+			t.Errorf("%s: want enabled %t, got %t", name, want, *collectorState[name])
+		}
+	}
+	if len(autoDisabledCollectors) != 2 || autoDisabledCollectors["edac"] != "unsupported on WSL" {
+		t.Errorf("unexpected auto-disabled collectors %v", autoDisabledCollectors)
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nozram
+// +build !nozram
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const zramSubsystem = "zram"
+
+// zramBDPageSize is the unit of the counters of bd_stat, independent of the
+// page size.
+const zramBDPageSize = 4096
+
+type zramCollector struct {
+	info             *prometheus.Desc
+	diskSize         *prometheus.Desc
+	originalData     *prometheus.Desc
+	compressedData   *prometheus.Desc
+	compressionRatio *prometheus.Desc
+	memoryUsed       *prometheus.Desc
+	memoryLimit      *prometheus.Desc
+	memoryUsedMax    *prometheus.Desc
+	samePages        *prometheus.Desc
+	hugePages        *prometheus.Desc
+	pagesCompacted   *prometheus.Desc
+	failedReads      *prometheus.Desc
+	failedWrites     *prometheus.Desc
+	backingDevice    *prometheus.Desc
+	backingReads     *prometheus.Desc
+	backingWrites    *prometheus.Desc
+	logger           log.Logger
+}
+
+func init() {
+	registerCollector(zramSubsystem, defaultEnabled, NewZramCollector)
+	registerHardwareProbe(zramSubsystem, "no zram devices", sysfsProbe("block/zram[0-9]*"))
+}
+
+// NewZramCollector returns a new Collector exposing the usage of the
+// compressed RAM block devices.
+func NewZramCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, zramSubsystem, name), help, append([]string{"device"}, labels...), nil)
+	}
+	return &zramCollector{
+		info:             desc("info", "Compression algorithm of the zram device.", "algorithm"),
+		diskSize:         desc("disk_size_bytes", "Size of the zram device."),
+		originalData:     desc("original_data_bytes", "Size of the data stored in the zram device, before compression."),
+		compressedData:   desc("compressed_data_bytes", "Size of the data stored in the zram device, after compression."),
+		compressionRatio: desc("compression_ratio", "Size of the data stored in the zram device divided by its compressed size."),
+		memoryUsed:       desc("memory_used_bytes", "Memory used by the zram device, including the allocator overhead."),
+		memoryLimit:      desc("memory_limit_bytes", "Maximum memory the zram device may use, 0 without limit."),
+		memoryUsedMax:    desc("memory_used_max_bytes", "Highest memory used by the zram device since it was reset."),
+		samePages:        desc("same_pages", "Number of pages filled with the same value, stored without memory."),
+		hugePages:        desc("huge_pages", "Number of pages stored uncompressed because they don't compress."),
+		pagesCompacted:   desc("pages_compacted_total", "Number of pages freed by the compaction of the zram device."),
+		failedReads:      desc("failed_reads_total", "Number of failed reads of the zram device."),
+		failedWrites:     desc("failed_writes_total", "Number of failed writes to the zram device."),
+		backingDevice:    desc("backing_device_bytes", "Size of the data written back to the backing device of the zram device."),
+		backingReads:     desc("backing_device_read_bytes_total", "Bytes read from the backing device of the zram device."),
+		backingWrites:    desc("backing_device_written_bytes_total", "Bytes written back to the backing device of the zram device."),
+		logger:           logger,
+	}, nil
+}
+
+func (c *zramCollector) Update(ch chan<- prometheus.Metric) error {
+	devices, err := filepath.Glob(sysFilePath("block/zram[0-9]*"))
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		return ErrNoData
+	}
+	for _, dir := range devices {
+		if err := c.updateDevice(ch, dir); err != nil {
+			return fmt.Errorf("couldn't get stats of %s: %w", filepath.Base(dir), err)
+		}
+	}
+	return nil
+}
+
+func (c *zramCollector) updateDevice(ch chan<- prometheus.Metric, dir string) error {
+	device := filepath.Base(dir)
+	diskSize, err := readUintFromFile(filepath.Join(dir, "disksize"))
+	if err != nil {
+		return err
+	}
+	if diskSize == 0 {
+		// The device isn't initialized, it has no stats.
+		return nil
+	}
+	algorithm, err := os.ReadFile(filepath.Join(dir, "comp_algorithm"))
+	if err != nil {
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.info, prometheus.GaugeValue, 1, device, zramSelected(string(algorithm)))
+	ch <- prometheus.MustNewConstMetric(c.diskSize, prometheus.GaugeValue, float64(diskSize), device)
+
+	// mm_stat has grown over time, older kernels lack the last columns.
+	mmStat, err := readZramStat(filepath.Join(dir, "mm_stat"))
+	if err != nil {
+		return err
+	}
+	for i, v := range []struct {
+		desc      *prometheus.Desc
+		valueType prometheus.ValueType
+	}{
+		{c.originalData, prometheus.GaugeValue},
+		{c.compressedData, prometheus.GaugeValue},
+		{c.memoryUsed, prometheus.GaugeValue},
+		{c.memoryLimit, prometheus.GaugeValue},
+		{c.memoryUsedMax, prometheus.GaugeValue},
+		{c.samePages, prometheus.GaugeValue},
+		{c.pagesCompacted, prometheus.CounterValue},
+		{c.hugePages, prometheus.GaugeValue},
+	} {
+		if i < len(mmStat) {
+			ch <- prometheus.MustNewConstMetric(v.desc, v.valueType, float64(mmStat[i]), device)
+		}
+	}
+	if len(mmStat) > 1 && mmStat[1] > 0 {
+		ch <- prometheus.MustNewConstMetric(c.compressionRatio, prometheus.GaugeValue, float64(mmStat[0])/float64(mmStat[1]), device)
+	}
+
+	ioStat, err := readZramStat(filepath.Join(dir, "io_stat"))
+	if err != nil {
+		return err
+	}
+	if len(ioStat) > 1 {
+		ch <- prometheus.MustNewConstMetric(c.failedReads, prometheus.CounterValue, float64(ioStat[0]), device)
+		ch <- prometheus.MustNewConstMetric(c.failedWrites, prometheus.CounterValue, float64(ioStat[1]), device)
+	}
+
+	// bd_stat is absent without CONFIG_ZRAM_WRITEBACK.
+	bdStat, err := readZramStat(filepath.Join(dir, "bd_stat"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(bdStat) > 2 {
+		ch <- prometheus.MustNewConstMetric(c.backingDevice, prometheus.GaugeValue, float64(bdStat[0]*zramBDPageSize), device)
+		ch <- prometheus.MustNewConstMetric(c.backingReads, prometheus.CounterValue, float64(bdStat[1]*zramBDPageSize), device)
+		ch <- prometheus.MustNewConstMetric(c.backingWrites, prometheus.CounterValue, float64(bdStat[2]*zramBDPageSize), device)
+	}
+	return nil
+}
+
+// readZramStat returns the columns of a stat file of a zram device.
+func readZramStat(path string) ([]uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(b))
+	values := make([]uint64, 0, len(fields))
+	for _, field := range fields {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value in %s: %w", path, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// zramSelected returns the algorithm in brackets of comp_algorithm, like zstd
+// in "lzo lzo-rle [zstd]".
+func zramSelected(algorithms string) string {
+	for _, algorithm := range strings.Fields(algorithms) {
+		if strings.HasPrefix(algorithm, "[") && strings.HasSuffix(algorithm, "]") {
+			return strings.Trim(algorithm, "[]")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !nozswap
+// +build !nozswap
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const zswapSubsystem = "zswap"
+
+type zswapCollector struct {
+	enabled          *prometheus.Desc
+	pool             *prometheus.Desc
+	storedPages      *prometheus.Desc
+	sameFilledPages  *prometheus.Desc
+	compressionRatio *prometheus.Desc
+	writtenBack      *prometheus.Desc
+	poolLimitHit     *prometheus.Desc
+	rejects          *prometheus.Desc
+	pageSize         float64
+	logger           log.Logger
+}
+
+func init() {
+	registerCollector(zswapSubsystem, defaultEnabled, NewZswapCollector)
+	registerHardwareProbe(zswapSubsystem, "kernel without zswap", sysfsProbe("module/zswap"))
+}
+
+// NewZswapCollector returns a new Collector exposing the compressed cache of
+// swapped out pages.
+func NewZswapCollector(logger log.Logger) (Collector, error) {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, zswapSubsystem, name), help, labels, nil)
+	}
+	return &zswapCollector{
+		enabled:          desc("enabled", "Whether zswap caches the pages being swapped out."),
+		pool:             desc("pool_bytes", "Memory used by the compressed pool of zswap."),
+		storedPages:      desc("stored_pages", "Number of pages stored in zswap."),
+		sameFilledPages:  desc("same_filled_pages", "Number of pages stored in zswap filled with the same value, without memory."),
+		compressionRatio: desc("compression_ratio", "Size of the pages stored in zswap divided by the memory used by its pool."),
+		writtenBack:      desc("written_back_pages_total", "Number of pages written back from zswap to the swap device."),
+		poolLimitHit:     desc("pool_limit_hit_total", "Number of times the pool of zswap was full and pages were written back or rejected."),
+		rejects:          desc("rejected_pages_total", "Number of pages zswap refused to store, by reason.", "reason"),
+		pageSize:         float64(os.Getpagesize()),
+		logger:           logger,
+	}, nil
+}
+
+func (c *zswapCollector) Update(ch chan<- prometheus.Metric) error {
+	enabled, err := os.ReadFile(sysFilePath("module/zswap/parameters/enabled"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoData
+		}
+		return err
+	}
+	ch <- prometheus.MustNewConstMetric(c.enabled, prometheus.GaugeValue, boolToFloat(strings.TrimSpace(string(enabled)) == "Y"))
+
+	// The statistics are in debugfs, which is usually only readable by root.
+	dir := sysFilePath("kernel/debug/zswap")
+	stats := map[string]uint64{}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			level.Debug(c.logger).Log("msg", "Couldn't read zswap statistics", "err", err)
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		value, err := readUintFromFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("couldn't read zswap %s: %w", entry.Name(), err)
+		}
+		stats[entry.Name()] = value
+	}
+
+	for desc, name := range map[*prometheus.Desc]string{
+		c.pool:            "pool_total_size",
+		c.storedPages:     "stored_pages",
+		c.sameFilledPages: "same_filled_pages",
+	} {
+		if value, ok := stats[name]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value))
+		}
+	}
+	for desc, name := range map[*prometheus.Desc]string{
+		c.writtenBack:  "written_back_pages",
+		c.poolLimitHit: "pool_limit_hit",
+	} {
+		if value, ok := stats[name]; ok {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value))
+		}
+	}
+	for name, value := range stats {
+		if strings.HasPrefix(name, "reject_") {
+			ch <- prometheus.MustNewConstMetric(c.rejects, prometheus.CounterValue, float64(value), strings.TrimPrefix(name, "reject_"))
+		}
+	}
+	// Same filled pages use no memory of the pool.
+	if pool := stats["pool_total_size"]; pool > 0 && stats["stored_pages"] >= stats["same_filled_pages"] {
+		stored := float64(stats["stored_pages"]-stats["same_filled_pages"]) * c.pageSize
+		ch <- prometheus.MustNewConstMetric(c.compressionRatio, prometheus.GaugeValue, stored/float64(pool))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package collectors embeds collectors of the node_exporter in other Go
+// programs, which can then expose or forward the metrics of the node without
+// running a separate exporter.
+//
+// The collectors are configured with the same flags as the node_exporter,
+// which are global to the process. They are parsed on the first call to
+// NewRegistry with an application of their own, so programs embedding the
+// collectors can parse their flags with kingpin.CommandLine, as long as they
+// do so before the first call to NewRegistry: parsing kingpin.CommandLine
+// sets the flags of the collectors it doesn't get to their defaults.
+package collectors
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// Options selects and configures the collectors of a registry.
+type Options struct {
+	// Collectors are the names of the collectors to run, as in their
+	// --collector.<name> flags. At least one is required.
+	Collectors []string
+	// Flags are the values of node_exporter flags by name without the
+	// leading dashes, e.g. "path.procfs" or
+	// "collector.filesystem.mount-points-exclude". Flags which aren't set
+	// have their default value. All registries of a process must use the
+	// same flags.
+	Flags map[string]string
+	// Logger receives the log messages of the collectors. If nil, they
+	// are discarded.
+	Logger log.Logger
+}
+
+var (
+	// collectorFlags are the flags registered with kingpin.CommandLine by
+	// the collector package, which is initialized before this one and so
+	// before the flags of the programs embedding the collectors.
+	collectorFlags = kingpin.CommandLine.Model().Flags
+
+	flagsMtx sync.Mutex
+	// parsedFlags are the flags of the first registry, nil until then.
+	parsedFlags map[string]string
+)
+
+// NewRegistry returns a Gatherer running the selected collectors on every
+// call to Gather, along with the node_scrape_collector_* metrics about them.
+// It is safe for concurrent use.
+func NewRegistry(opts Options) (prometheus.Gatherer, error) {
+	if len(opts.Collectors) == 0 {
+		return nil, errors.New("no collectors selected")
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	if err := parseFlags(opts.Flags); err != nil {
+		return nil, err
+	}
+	if err := collector.EnableCollectors(opts.Collectors...); err != nil {
+		return nil, err
+	}
+	nc, err := collector.NewNodeCollector(logger, opts.Collectors...)
+	if err != nil {
+		return nil, err
+	}
+	r := prometheus.NewRegistry()
+	if err := r.Register(nc); err != nil {
+		return nil, fmt.Errorf("couldn't register node collector: %w", err)
+	}
+	return r, nil
+}
+
+// parseFlags parses flags into the package level variables of the collectors
+// on the first call. Later calls only check that the flags are the same, as
+// the collectors created before keep using them.
+func parseFlags(flags map[string]string) error {
+	flagsMtx.Lock()
+	defer flagsMtx.Unlock()
+	if parsedFlags != nil {
+		if !sameFlags(parsedFlags, flags) {
+			return errors.New("flags differ from the ones of the first registry")
+		}
+		return nil
+	}
+
+	args := make([]string, 0, len(flags))
+	for name, value := range flags {
+		args = append(args, fmt.Sprintf("--%s=%s", name, value))
+	}
+	// Parse in a stable order, so that errors don't depend on the map.
+	sort.Strings(args)
+	if _, err := newFlagApp().Parse(args); err != nil {
+		return fmt.Errorf("couldn't parse flags: %w", err)
+	}
+	parsedFlags = make(map[string]string, len(flags))
+	for name, value := range flags {
+		parsedFlags[name] = value
+	}
+	return nil
+}
+
+// newFlagApp returns an application with the flags of the collectors, which
+// set the same variables when parsed.
+func newFlagApp() *kingpin.Application {
+	app := kingpin.New("node_exporter", "")
+	for _, f := range collectorFlags {
+		if f.Value == nil || app.GetFlag(f.Name) != nil {
+			continue
+		}
+		flag := app.Flag(f.Name, f.Help).Default(f.Default...)
+		if f.Envar != "" {
+			flag.Envar(f.Envar)
+		}
+		flag.SetValue(f.Value)
+	}
+	return app
+}
+
+func sameFlags(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, value := range a {
+		if v, ok := b[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
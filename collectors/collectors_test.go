@@ -0,0 +1,72 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collectors
+
+import (
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+func TestNewRegistry(t *testing.T) {
+	flags := map[string]string{
+		"path.procfs": "../collector/fixtures/proc",
+		"path.sysfs":  "../collector/fixtures/sys",
+	}
+	// The program's own flags parsed before aren't reset.
+	own := kingpin.Flag("test.own", "Flag of the embedding program.").Default("default").String()
+	if _, err := kingpin.CommandLine.Parse([]string{"--test.own=value"}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewRegistry(Options{Collectors: []string{"loadavg"}, Flags: flags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *own != "value" {
+		t.Errorf("flag of the embedding program = %q, want %q", *own, "value")
+	}
+	mfs, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]float64{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			got[mf.GetName()] = m.GetGauge().GetValue()
+		}
+	}
+	for name, want := range map[string]float64{
+		"node_load1":                    0.21,
+		"node_scrape_collector_success": 1,
+	} {
+		if got[name] != want {
+			t.Errorf("%s = %v, want %v", name, got[name], want)
+		}
+	}
+	if _, ok := got["node_cpu_seconds_total"]; ok {
+		t.Error("collector which isn't selected ran")
+	}
+
+	// A second registry can select other collectors, but not change the
+	// flags.
+	if _, err := NewRegistry(Options{Collectors: []string{"uname"}, Flags: flags}); err != nil {
+		t.Errorf("second registry with the same flags: %v", err)
+	}
+	if _, err := NewRegistry(Options{Collectors: []string{"uname"}}); err == nil {
+		t.Error("second registry with other flags didn't fail")
+	}
+	if _, err := NewRegistry(Options{Collectors: []string{"nonexistent"}, Flags: flags}); err == nil {
+		t.Error("registry with unknown collector didn't fail")
+	}
+}
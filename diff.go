@@ -0,0 +1,166 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// scrapeDiffer wraps the gatherer of the unfiltered exposition and keeps the
+// metric families of its last two scrapes, to show what changed between them.
+// Serving the differences doesn't gather, so it neither bypasses the limit of
+// concurrent scrapes nor advances the state of collectors between scrapes.
+type scrapeDiffer struct {
+	gatherer prometheus.Gatherer
+
+	mtx      sync.Mutex
+	prev     []*dto.MetricFamily
+	prevTime time.Time
+	last     []*dto.MetricFamily
+	lastTime time.Time
+}
+
+func newScrapeDiffer() *scrapeDiffer {
+	return &scrapeDiffer{}
+}
+
+// wrap makes the differ gather the metrics of g and returns it as the
+// gatherer to use for scrapes.
+func (d *scrapeDiffer) wrap(g prometheus.Gatherer) prometheus.Gatherer {
+	d.gatherer = g
+	return d
+}
+
+// Gather implements prometheus.Gatherer.
+func (d *scrapeDiffer) Gather() ([]*dto.MetricFamily, error) {
+	mfs, err := d.gatherer.Gather()
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.prev, d.prevTime = d.last, d.lastTime
+	d.last, d.lastTime = mfs, time.Now()
+	return mfs, err
+}
+
+// ServeHTTP serves the differences between the last scrape and the one
+// before. Values are only compared with the values query parameter,
+// otherwise the series and their metadata are.
+func (d *scrapeDiffer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	values, _ := strconv.ParseBool(r.URL.Query().Get("values"))
+	d.mtx.Lock()
+	prev, prevTime, last, lastTime := d.prev, d.prevTime, d.last, d.lastTime
+	d.mtx.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if prevTime.IsZero() {
+		fmt.Fprintln(w, "# Less than two scrapes of the telemetry path yet, nothing to compare.")
+		return
+	}
+	fmt.Fprintf(w, "# Changes between the scrapes at %s and %s.\n", prevTime.UTC().Format(time.RFC3339), lastTime.UTC().Format(time.RFC3339))
+	writeExpositionDiff(w, prev, last, values)
+}
+
+// writeExpositionDiff writes the lines of the exposition of b which aren't
+// in the one of a prefixed by "+" and the reverse by "-", grouped by metric
+// family in sorted order.
+func writeExpositionDiff(w io.Writer, a, b []*dto.MetricFamily, values bool) {
+	linesA, linesB := expositionLines(a, values), expositionLines(b, values)
+	names := make([]string, 0, len(linesB))
+	for name := range linesA {
+		names = append(names, name)
+	}
+	for name := range linesB {
+		if _, ok := linesA[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		inA, inB := map[string]bool{}, map[string]bool{}
+		for _, l := range linesA[name] {
+			inA[l] = true
+		}
+		for _, l := range linesB[name] {
+			inB[l] = true
+		}
+		for _, l := range linesA[name] {
+			if !inB[l] {
+				fmt.Fprintf(w, "- %s\n", l)
+			}
+		}
+		for _, l := range linesB[name] {
+			if !inA[l] {
+				fmt.Fprintf(w, "+ %s\n", l)
+			}
+		}
+	}
+}
+
+// expositionLines returns the HELP and TYPE lines and the series of each
+// metric family in the order of the text format. Histograms and summaries are
+// a single series with their sample count as value.
+func expositionLines(mfs []*dto.MetricFamily, values bool) map[string][]string {
+	lines := make(map[string][]string, len(mfs))
+	for _, mf := range mfs {
+		name := mf.GetName()
+		l := []string{
+			fmt.Sprintf("# HELP %s %s", name, mf.GetHelp()),
+			fmt.Sprintf("# TYPE %s %s", name, strings.ToLower(mf.GetType().String())),
+		}
+		for _, m := range mf.GetMetric() {
+			series := formatSeries(name, m.GetLabel())
+			if values {
+				series += " " + strconv.FormatFloat(sampleValue(m), 'g', -1, 64)
+			}
+			l = append(l, series)
+		}
+		lines[name] = l
+	}
+	return lines
+}
+
+func formatSeries(name string, labels []*dto.LabelPair) string {
+	if len(labels) == 0 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sampleValue(m *dto.Metric) float64 {
+	switch {
+	case m.Counter != nil:
+		return m.GetCounter().GetValue()
+	case m.Gauge != nil:
+		return m.GetGauge().GetValue()
+	case m.Histogram != nil:
+		return float64(m.GetHistogram().GetSampleCount())
+	case m.Summary != nil:
+		return float64(m.GetSummary().GetSampleCount())
+	}
+	return m.GetUntyped().GetValue()
+}
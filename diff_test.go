@@ -0,0 +1,99 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestScrapeDiffer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	up := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_up", Help: "Up."}, []string{"device"})
+	reg.MustRegister(up)
+	up.WithLabelValues("a").Set(1)
+	up.WithLabelValues("b").Set(1)
+
+	counting := &countingGatherer{Gatherer: reg}
+	differ := newScrapeDiffer()
+	g := differ.wrap(counting)
+	get := func(query string) string {
+		rec := httptest.NewRecorder()
+		differ.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics/diff"+query, nil))
+		body, _ := io.ReadAll(rec.Body)
+		// Drop the line with the times of the scrapes.
+		_, diff, _ := strings.Cut(string(body), "\n")
+		return diff
+	}
+	scrape := func() {
+		if _, err := g.Gather(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := get(""); got != "" {
+		t.Errorf("no scrape: got %q, want nothing", got)
+	}
+	scrape()
+	if got := get(""); got != "" {
+		t.Errorf("one scrape: got %q, want nothing", got)
+	}
+
+	reg.MustRegister(prometheus.NewCounter(prometheus.CounterOpts{Name: "test_new_total", Help: "New."}))
+	up.DeleteLabelValues("a")
+	up.WithLabelValues("b").Set(0)
+	scrape()
+	series := `+ # HELP test_new_total New.
++ # TYPE test_new_total counter
++ test_new_total
+- test_up{device="a"}
+`
+	if got := get(""); got != series {
+		t.Errorf("diff of series: got\n%s\nwant\n%s", got, series)
+	}
+	values := `+ # HELP test_new_total New.
++ # TYPE test_new_total counter
++ test_new_total 0
+- test_up{device="a"} 1
+- test_up{device="b"} 1
++ test_up{device="b"} 0
+`
+	if got := get("?values=true"); got != values {
+		t.Errorf("diff of values: got\n%s\nwant\n%s", got, values)
+	}
+
+	// Serving the diff doesn't gather, the diff only changes with the next
+	// scrape of the telemetry path.
+	if got := get(""); got != series {
+		t.Errorf("repeated diff: got\n%s\nwant\n%s", got, series)
+	}
+	if counting.n != 2 {
+		t.Errorf("want 2 gathers, got %d", counting.n)
+	}
+}
+
+type countingGatherer struct {
+	prometheus.Gatherer
+	n int
+}
+
+func (g *countingGatherer) Gather() ([]*dto.MetricFamily, error) {
+	g.n++
+	return g.Gatherer.Gather()
+}
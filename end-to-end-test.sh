@@ -3,9 +3,13 @@
 set -euf -o pipefail
 
 enabled_collectors=$(cat << COLLECTORS
+  accel
   arp
+  autofs
   bcache
   bonding
+  boottime
+  bridge
   btrfs
   buddyinfo
   cgroups
@@ -13,12 +17,14 @@ enabled_collectors=$(cat << COLLECTORS
   cpu
   cpufreq
   diskstats
+  drm
   dmi
   drbd
   edac
   entropy
   fibrechannel
   filefd
+  firmware
   hwmon
   infiniband
   interrupts
@@ -34,6 +40,8 @@ enabled_collectors=$(cat << COLLECTORS
   netstat
   nfs
   nfsd
+  oom
+  pagetypeinfo
   pressure
   processes
   qdisc
@@ -44,15 +52,21 @@ enabled_collectors=$(cat << COLLECTORS
   sockstat
   softirqs
   stat
+  swaps
   sysctl
+  taint
   textfile
   thermal_zone
+  thp
   udp_queues
   vmstat
   wifi
+  wsl
   xfs
   zfs
   zoneinfo
+  zram
+  zswap
 COLLECTORS
 )
 disabled_collectors=$(cat << COLLECTORS
@@ -66,7 +80,7 @@ cd "$(dirname $0)"
 port="$((10000 + (RANDOM % 10000)))"
 tmpdir=$(mktemp -d /tmp/node_exporter_e2e_test.XXXXXX)
 
-skip_re="^(go_|node_exporter_build_info|node_scrape_collector_duration_seconds|process_|node_textfile_mtime_seconds|node_time_(zone|seconds)|node_network_(receive|transmit)_(bytes|packets)_total)"
+skip_re="^(go_|node_exporter_(build|build_environment|flag)_info|node_scrape_collector_duration_seconds|process_|node_suspend_time_seconds_total|node_textfile_mtime_seconds|node_time_(zone|seconds)|node_network_(receive|transmit)_(bytes|packets)_total)"
 
 arch="$(uname -m)"
 
@@ -131,13 +145,18 @@ fi
   --collector.qdisc.fixtures="collector/fixtures/qdisc/" \
   --collector.qdisk.device-include="(wlan0|eth0)" \
   --collector.arp.device-exclude="nope" \
-  --collector.netclass.ignored-devices="(dmz|int)" \
+  --collector.netclass.ignored-devices="(br0|dmz|int)" \
   --collector.netclass.ignore-invalid-speed \
   --collector.netdev.device-include="lo" \
-  --collector.bcache.priorityStats \
+  --collector.bcache.priorityStats --collector.infiniband.hw-counters \
+  --collector.cgroups.slice-depth=2 \
+  --collector.conntrack.per-cpu \
+  --collector.pressure.averages \
+  --collector.cgroups.unit-include="(init.scope|system.slice|system.slice/.+|user.slice)" \
   "${cpu_info_collector}" \
   --collector.cpu.info.bugs-include="${cpu_info_bugs}" \
   --collector.cpu.info.flags-include="${cpu_info_flags}" \
+  --collector.filefd.top-processes=3 \
   --collector.stat.softirq \
   --collector.sysctl.include="kernel.threads-max" \
   --collector.sysctl.include="fs.file-nr" \
@@ -0,0 +1,63 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// endpointsConfig is the content of the file given with --web.endpoints-file.
+type endpointsConfig struct {
+	Endpoints []endpoint `yaml:"endpoints"`
+}
+
+// endpoint is an additional path serving a subset of the enabled collectors,
+// e.g. to scrape slow collectors at a longer interval.
+type endpoint struct {
+	Path       string   `yaml:"path"`
+	Collectors []string `yaml:"collectors"`
+}
+
+func loadEndpoints(file string) ([]endpoint, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseEndpoints(content)
+}
+
+func parseEndpoints(content []byte) ([]endpoint, error) {
+	var c endpointsConfig
+	if err := yaml.UnmarshalStrict(content, &c); err != nil {
+		return nil, err
+	}
+	paths := map[string]bool{}
+	for _, e := range c.Endpoints {
+		if !strings.HasPrefix(e.Path, "/") {
+			return nil, fmt.Errorf("endpoint path %q must start with /", e.Path)
+		}
+		if paths[e.Path] {
+			return nil, fmt.Errorf("duplicate endpoint path %q", e.Path)
+		}
+		paths[e.Path] = true
+		if len(e.Collectors) == 0 {
+			return nil, fmt.Errorf("endpoint %s has no collectors", e.Path)
+		}
+	}
+	return c.Endpoints, nil
+}
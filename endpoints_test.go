@@ -0,0 +1,51 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := parseEndpoints([]byte(`
+endpoints:
+  - path: /metrics/fast
+    collectors: [cpu, meminfo]
+  - path: /metrics/slow
+    collectors:
+      - textfile
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []endpoint{
+		{Path: "/metrics/fast", Collectors: []string{"cpu", "meminfo"}},
+		{Path: "/metrics/slow", Collectors: []string{"textfile"}},
+	}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("want %v, got %v", want, endpoints)
+	}
+
+	for _, invalid := range []string{
+		"endpoints: [{path: metrics/fast, collectors: [cpu]}]",
+		"endpoints: [{path: /a, collectors: [cpu]}, {path: /a, collectors: [meminfo]}]",
+		"endpoints: [{path: /a}]",
+		"endpoints: [{path: /a, collectors: [cpu], interval: 1m}]",
+	} {
+		if _, err := parseEndpoints([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sensitiveFlags lists flags whose values may contain credentials. Their
+// values are only exposed as a hash, as are URLs with userinfo passed to any
+// other flag.
+var sensitiveFlags = map[string]bool{
+	"collector.supervisord.url":  true,
+	"collector.versioncheck.url": true,
+}
+
+// flagValueTypes maps the types of kingpin's flag values to the clause
+// creating them, so that the default of a flag can be parsed with a new value
+// of the same type.
+var flagValueTypes = func() map[reflect.Type]func(*kingpin.FlagClause) {
+	types := map[reflect.Type]func(*kingpin.FlagClause){}
+	for _, newValue := range []func(*kingpin.FlagClause){
+		func(f *kingpin.FlagClause) { f.Bool() },
+		func(f *kingpin.FlagClause) { f.Duration() },
+		func(f *kingpin.FlagClause) { f.Float64() },
+		func(f *kingpin.FlagClause) { f.Int() },
+		func(f *kingpin.FlagClause) { f.Int64() },
+		func(f *kingpin.FlagClause) { f.String() },
+		func(f *kingpin.FlagClause) { f.Strings() },
+		func(f *kingpin.FlagClause) { f.Uint() },
+		func(f *kingpin.FlagClause) { f.Uint64() },
+	} {
+		f := kingpin.New("", "").Flag("value", "")
+		newValue(f)
+		types[reflect.TypeOf(f.Model().Value)] = newValue
+	}
+	return types
+}()
+
+// newFlagInfoCollector returns a collector exposing every flag of app whose
+// value differs from its default, whether it was set on the command line or
+// through an environment variable. The defaults are parsed like the values,
+// so equal durations like "5m" and "5m0s" aren't reported.
+func newFlagInfoCollector(app *kingpin.Application) prometheus.Collector {
+	flagInfo := prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "node_exporter",
+			Name:      "flag_info",
+			Help:      "A metric with a constant '1' value labeled by the name and value of each flag not set to its default. Values of sensitive flags are hashed.",
+		},
+		[]string{"flag", "value"},
+	)
+	for _, f := range app.Model().Flags {
+		if f.Value == nil {
+			continue
+		}
+		value := f.Value.String()
+		if value == flagDefault(f) {
+			continue
+		}
+		if sensitiveFlags[f.Name] || hasUserinfo(value) {
+			value = hashFlagValue(value)
+		}
+		flagInfo.WithLabelValues(f.Name, value).Set(1)
+	}
+	return flagInfo
+}
+
+// flagDefault returns the default of f in the string form of its value. The
+// defaults of values of unknown types are returned as they were declared.
+func flagDefault(f *kingpin.FlagModel) string {
+	newValue, ok := flagValueTypes[reflect.TypeOf(f.Value)]
+	if !ok {
+		return strings.Join(f.Default, ",")
+	}
+	clause := kingpin.New("", "").Flag("value", "")
+	newValue(clause)
+	value := clause.Model().Value
+	for _, d := range f.Default {
+		if err := value.Set(d); err != nil {
+			return strings.Join(f.Default, ",")
+		}
+	}
+	return value.String()
+}
+
+// hasUserinfo returns whether value is a URL with a username or password.
+func hasUserinfo(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.User != nil
+}
+
+func hashFlagValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
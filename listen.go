@@ -0,0 +1,148 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/go-kit/log"
+	"github.com/pires/go-proxyproto"
+	"github.com/prometheus/exporter-toolkit/web"
+)
+
+// proxyProtocolHeaderTimeout bounds how long a connection may take to send
+// its PROXY protocol header.
+const proxyProtocolHeaderTimeout = 10 * time.Second
+
+// listenConfig holds the flags deciding which listeners the exporter serves
+// on.
+type listenConfig struct {
+	addresses            []string
+	systemdSocket        bool
+	network              string
+	iface                string
+	proxyProtocol        bool
+	proxyProtocolTrusted []string
+}
+
+// open returns the sockets passed by systemd with --web.systemd-socket,
+// otherwise a listener on each address, wrapped to accept PROXY protocol
+// headers if enabled.
+func (c listenConfig) open() ([]net.Listener, error) {
+	var listeners []net.Listener
+	if c.systemdSocket {
+		if c.network != "tcp" || c.iface != "" {
+			return nil, errors.New("--web.listen-network and --web.listen-interface can't be used with --web.systemd-socket")
+		}
+		var err error
+		if listeners, err = activation.Listeners(); err != nil {
+			return nil, err
+		}
+		if len(listeners) == 0 {
+			return nil, errors.New("no socket activation file descriptors found")
+		}
+	} else {
+		if len(c.addresses) == 0 {
+			return nil, web.ErrNoListeners
+		}
+		var err error
+		if listeners, err = listen(c.addresses, c.network, c.iface); err != nil {
+			return nil, err
+		}
+	}
+	if !c.proxyProtocol {
+		return listeners, nil
+	}
+	wrapped, err := proxyProtocolListeners(listeners, c.proxyProtocolTrusted)
+	if err != nil {
+		for _, l := range listeners {
+			l.Close()
+		}
+		return nil, err
+	}
+	return wrapped, nil
+}
+
+// listenAndNotify opens the listeners and only then tells systemd that the
+// exporter is ready, so that a unit with Type=notify fails to start rather
+// than being reported as started when the address can't be listened on.
+func listenAndNotify(ctx context.Context, c listenConfig, alive func(timeout time.Duration) error, logger log.Logger) ([]net.Listener, error) {
+	listeners, err := c.open()
+	if err != nil {
+		return nil, err
+	}
+	notifySystemd(ctx, alive, logger)
+	return listeners, nil
+}
+
+// listen opens a listener on each address. network is tcp, tcp4 or tcp6 and
+// restricts the IP version. If iface isn't empty, the sockets are bound to
+// that network interface and only accept connections received on it.
+func listen(addresses []string, network, iface string) ([]net.Listener, error) {
+	lc := net.ListenConfig{}
+	if iface != "" {
+		lc.Control = func(_, _ string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = bindToDevice(fd, iface)
+			}); err != nil {
+				return err
+			}
+			return sockErr
+		}
+	}
+
+	listeners := make([]net.Listener, 0, len(addresses))
+	for _, address := range addresses {
+		l, err := lc.Listen(context.Background(), network, address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("couldn't listen on %s: %w", address, err)
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+// proxyProtocolListeners wraps the listeners to accept PROXY protocol v1 and
+// v2 headers, so the address of the connection is the one of the client
+// behind the load balancer. The header is optional. It is only accepted from
+// connections coming from the trusted IP addresses or CIDR ranges, and
+// ignored from others, which could otherwise spoof their address.
+func proxyProtocolListeners(listeners []net.Listener, trusted []string) ([]net.Listener, error) {
+	if len(trusted) == 0 {
+		return nil, errors.New("--web.proxy-protocol requires at least one --web.proxy-protocol.trusted source")
+	}
+	policy, err := proxyproto.LaxWhiteListPolicy(trusted)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted PROXY protocol source: %w", err)
+	}
+	wrapped := make([]net.Listener, 0, len(listeners))
+	for _, l := range listeners {
+		wrapped = append(wrapped, &proxyproto.Listener{
+			Listener:          l,
+			Policy:            policy,
+			ReadHeaderTimeout: proxyProtocolHeaderTimeout,
+		})
+	}
+	return wrapped, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestProxyProtocolListeners(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		header  string
+		want    string
+	}{
+		{
+			name:    "no header",
+			trusted: []string{"127.0.0.1"},
+			want:    "127.0.0.1",
+		},
+		{
+			name:    "trusted source",
+			trusted: []string{"127.0.0.0/8"},
+			header:  "PROXY TCP4 192.0.2.1 192.0.2.2 56324 9100\r\n",
+			want:    "192.0.2.1",
+		},
+		{
+			// The header is ignored, the client can't spoof its address.
+			name:    "untrusted source",
+			trusted: []string{"198.51.100.0/24"},
+			header:  "PROXY TCP4 192.0.2.1 192.0.2.2 56324 9100\r\n",
+			want:    "127.0.0.1",
+		},
+		{
+			name:    "trusted source v2",
+			trusted: []string{"127.0.0.0/8"},
+			header:  "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c\xc0\x00\x02\x01\xc0\x00\x02\x02\xdb\xfc\x23\x8c",
+			want:    "192.0.2.1",
+		},
+		{
+			name:    "untrusted source v2",
+			trusted: []string{"198.51.100.0/24"},
+			header:  "\r\n\r\n\x00\r\nQUIT\n\x21\x11\x00\x0c\xc0\x00\x02\x01\xc0\x00\x02\x02\xdb\xfc\x23\x8c",
+			want:    "127.0.0.1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			listeners, err := listen([]string{"127.0.0.1:0"}, "tcp4", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			listeners, err = proxyProtocolListeners(listeners, test.trusted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := listeners[0]
+			defer l.Close()
+
+			go func() {
+				conn, err := net.Dial("tcp", l.Addr().String())
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				fmt.Fprint(conn, test.header+"GET / HTTP/1.0\r\n\r\n")
+			}()
+			conn, err := l.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			// The header is read on the first read of the connection.
+			buf := make([]byte, 3)
+			if _, err := conn.Read(buf); err != nil {
+				t.Fatal(err)
+			}
+			if string(buf) != "GET" {
+				t.Errorf("want payload to start with GET, got %q", buf)
+			}
+			host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host != test.want {
+				t.Errorf("want remote address %s, got %s", test.want, host)
+			}
+		})
+	}
+
+	if _, err := proxyProtocolListeners(nil, []string{"not an address"}); err == nil {
+		t.Error("want error for invalid trusted source")
+	}
+	if _, err := proxyProtocolListeners(nil, nil); err == nil {
+		t.Error("want error without trusted sources")
+	}
+}
@@ -14,14 +14,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	stdlog "log"
 	"net/http"
-	_ "net/http/pprof"
 	"os"
 	"os/user"
+	"path"
+	"regexp"
 	"runtime"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/prometheus/common/promlog"
 	"github.com/prometheus/common/promlog/flag"
@@ -32,10 +37,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	promcollectors "github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/prometheus/exporter-toolkit/web/kingpinflag"
 	"github.com/prometheus/node_exporter/collector"
+	"golang.org/x/exp/slices"
 )
 
 // handler wraps an unfiltered http.Handler but uses a filtered handler,
@@ -48,14 +55,29 @@ type handler struct {
 	exporterMetricsRegistry *prometheus.Registry
 	includeExporterMetrics  bool
 	maxRequests             int
-	logger                  log.Logger
+	// streaming makes the handler send the metrics of each collector as
+	// soon as it finished, see streamingHandler.
+	streaming bool
+	// differ records the unfiltered scrapes for the diff view, if
+	// enabled.
+	differ *scrapeDiffer
+	// rates tracks counters for the precomputed_rates query parameter.
+	rates *rateTracker
+	// collectors restricts the handler to a subset of the enabled
+	// collectors, all are used if empty.
+	collectors []string
+	logger     log.Logger
 }
 
-func newHandler(includeExporterMetrics bool, maxRequests int, logger log.Logger) *handler {
+func newHandler(includeExporterMetrics bool, maxRequests int, streaming bool, differ *scrapeDiffer, rates *rateTracker, logger log.Logger, collectors ...string) *handler {
 	h := &handler{
 		exporterMetricsRegistry: prometheus.NewRegistry(),
 		includeExporterMetrics:  includeExporterMetrics,
 		maxRequests:             maxRequests,
+		streaming:               streaming,
+		differ:                  differ,
+		rates:                   rates,
+		collectors:              collectors,
 		logger:                  logger,
 	}
 	if h.includeExporterMetrics {
@@ -64,7 +86,7 @@ func newHandler(includeExporterMetrics bool, maxRequests int, logger log.Logger)
 			promcollectors.NewGoCollector(),
 		)
 	}
-	if innerHandler, err := h.innerHandler(); err != nil {
+	if innerHandler, err := h.innerHandler(0); err != nil {
 		panic(fmt.Sprintf("Couldn't create metrics handler: %s", err))
 	} else {
 		h.unfilteredHandler = innerHandler
@@ -75,15 +97,26 @@ func newHandler(includeExporterMetrics bool, maxRequests int, logger log.Logger)
 // ServeHTTP implements http.Handler.
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	filters := r.URL.Query()["collect[]"]
-	level.Debug(h.logger).Log("msg", "collect query:", "filters", filters)
+	level.Debug(h.logger).Log("msg", "collect query:", "filters", filters, "remote_addr", r.RemoteAddr)
 
-	if len(filters) == 0 {
+	var rateWindow time.Duration
+	if param := r.URL.Query().Get("precomputed_rates"); param != "" {
+		window, err := model.ParseDuration(param)
+		if err != nil || window <= 0 || time.Duration(window) > h.rates.maxWindow {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("Invalid precomputed_rates window %q, must be a duration between 0 and %s", param, h.rates.maxWindow)))
+			return
+		}
+		rateWindow = time.Duration(window)
+	}
+
+	if len(filters) == 0 && rateWindow == 0 {
 		// No filters, use the prepared unfiltered handler.
 		h.unfilteredHandler.ServeHTTP(w, r)
 		return
 	}
 	// To serve filtered metrics, we create a filtering handler on the fly.
-	filteredHandler, err := h.innerHandler(filters...)
+	filteredHandler, err := h.innerHandler(rateWindow, filters...)
 	if err != nil {
 		level.Warn(h.logger).Log("msg", "Couldn't create filtered metrics handler:", "err", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -95,18 +128,31 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // innerHandler is used to create both the one unfiltered http.Handler to be
 // wrapped by the outer handler and also the filtered handlers created on the
-// fly. The former is accomplished by calling innerHandler without any arguments
-// (in which case it will log all the collectors enabled via command-line
-// flags).
-func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
+// fly. The former is accomplished by calling innerHandler without a rate
+// window and filters (in which case it will log all the collectors enabled
+// via command-line flags).
+func (h *handler) innerHandler(rateWindow time.Duration, filters ...string) (http.Handler, error) {
+	// Only log the creation of an unfiltered handler, which should happen
+	// only once upon startup.
+	unfiltered := len(filters) == 0 && rateWindow == 0
+	logCollectors := unfiltered && len(h.collectors) == 0
+
+	if len(h.collectors) > 0 {
+		if len(filters) == 0 {
+			filters = h.collectors
+		}
+		for _, f := range filters {
+			if !slices.Contains(h.collectors, f) {
+				return nil, fmt.Errorf("collector %s isn't served by this endpoint", f)
+			}
+		}
+	}
 	nc, err := collector.NewNodeCollector(h.logger, filters...)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create collector: %s", err)
 	}
 
-	// Only log the creation of an unfiltered handler, which should happen
-	// only once upon startup.
-	if len(filters) == 0 {
+	if logCollectors {
 		level.Info(h.logger).Log("msg", "Enabled collectors")
 		collectors := []string{}
 		for n := range nc.Collectors {
@@ -119,19 +165,26 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 	}
 
 	r := prometheus.NewRegistry()
-	r.MustRegister(version.NewCollector("node_exporter"))
-	if err := r.Register(nc); err != nil {
-		return nil, fmt.Errorf("couldn't register node collector: %s", err)
-	}
-	handler := promhttp.HandlerFor(
-		prometheus.Gatherers{h.exporterMetricsRegistry, r},
-		promhttp.HandlerOpts{
-			ErrorLog:            stdlog.New(log.NewStdlibAdapter(level.Error(h.logger)), "", 0),
-			ErrorHandling:       promhttp.ContinueOnError,
-			MaxRequestsInFlight: h.maxRequests,
-			Registry:            h.exporterMetricsRegistry,
-		},
+	r.MustRegister(
+		version.NewCollector("node_exporter"),
+		newBuildEnvironmentCollector(),
+		newFlagInfoCollector(kingpin.CommandLine),
 	)
+	var handler http.Handler
+	if h.streaming && rateWindow == 0 {
+		// Precomputed rates need all counters at once, these requests
+		// aren't streamed.
+		handler = newStreamingHandler(prometheus.Gatherers{h.exporterMetricsRegistry, r}, nc, h.rates, h.maxRequests, h.logger)
+	} else {
+		if err := r.Register(nc); err != nil {
+			return nil, fmt.Errorf("couldn't register node collector: %s", err)
+		}
+		var g prometheus.Gatherer = prometheus.Gatherers{h.exporterMetricsRegistry, r}
+		if h.differ != nil && unfiltered {
+			g = h.differ.wrap(g)
+		}
+		handler = h.gatheringHandler(g, rateWindow)
+	}
 	if h.includeExporterMetrics {
 		// Note that we have to use h.exporterMetricsRegistry here to
 		// use the same promhttp metrics for all expositions.
@@ -142,6 +195,24 @@ func (h *handler) innerHandler(filters ...string) (http.Handler, error) {
 	return handler, nil
 }
 
+// gatheringHandler returns a handler gathering all metrics of g before
+// encoding them.
+func (h *handler) gatheringHandler(g prometheus.Gatherer, rateWindow time.Duration) http.Handler {
+	return promhttp.HandlerFor(
+		rateGatherer{
+			Gatherer: g,
+			tracker:  h.rates,
+			window:   rateWindow,
+		},
+		promhttp.HandlerOpts{
+			ErrorLog:            stdlog.New(log.NewStdlibAdapter(level.Error(h.logger)), "", 0),
+			ErrorHandling:       promhttp.ContinueOnError,
+			MaxRequestsInFlight: h.maxRequests,
+			Registry:            h.exporterMetricsRegistry,
+		},
+	)
+}
+
 func main() {
 	var (
 		metricsPath = kingpin.Flag(
@@ -160,9 +231,61 @@ func main() {
 			"collector.disable-defaults",
 			"Set all collectors to disabled by default.",
 		).Default("false").Bool()
+		autoDisableCollectors = kingpin.Flag(
+			"collector.auto-disable",
+			"Disable collectors at startup if the hardware or kernel feature they expose isn't present, unless they are enabled explicitly.",
+		).Default("false").Bool()
+		precomputedRatesInclude = kingpin.Flag(
+			"web.precomputed-rates.include",
+			"Regexp of counters whose rates can be requested with the precomputed_rates query parameter.",
+		).Default("^node_(cpu_seconds|disk_(read|written)_bytes|disk_io_time_seconds|network_(receive|transmit)_(bytes|packets|errs|drop))_total$").String()
+		endpointsFile = kingpin.Flag(
+			"web.endpoints-file",
+			"Path to a YAML file defining additional paths serving a subset of the enabled collectors.",
+		).Default("").String()
+		precomputedRatesMaxWindow = kingpin.Flag(
+			"web.precomputed-rates.max-window",
+			"Maximum window which can be requested with the precomputed_rates query parameter.",
+		).Default("15m").Duration()
 		maxProcs = kingpin.Flag(
 			"runtime.gomaxprocs", "The target number of CPUs Go will run on (GOMAXPROCS)",
 		).Envar("GOMAXPROCS").Default("1").Int()
+		listenNetwork = kingpin.Flag(
+			"web.listen-network",
+			"Network to listen on: tcp for IPv4 and IPv6, tcp4 or tcp6 for only one of them.",
+		).Default("tcp").Enum("tcp", "tcp4", "tcp6")
+		listenInterface = kingpin.Flag(
+			"web.listen-interface",
+			"Only accept connections received on this network interface (Linux only).",
+		).Default("").String()
+		proxyProtocol = kingpin.Flag(
+			"web.proxy-protocol",
+			"Accept PROXY protocol v1 and v2 headers on incoming connections, to use the address of the client behind a load balancer.",
+		).Default("false").Bool()
+		proxyProtocolTrusted = kingpin.Flag(
+			"web.proxy-protocol.trusted",
+			"IP address or CIDR range allowed to send PROXY protocol headers, headers from others are ignored. Can be repeated, required with --web.proxy-protocol.",
+		).Strings()
+		warmUpCollectors = kingpin.Flag(
+			"web.warm-up",
+			"Run all collectors once at startup, before listening, so the first scrape doesn't pay for cold caches.",
+		).Default("false").Bool()
+		streaming = kingpin.Flag(
+			"web.streaming",
+			"Send the metrics of each collector as soon as it finished instead of after all collectors, which lowers the memory used by scrapes of large expositions.",
+		).Default("false").Bool()
+		stableExposition = kingpin.Flag(
+			"web.stable-exposition",
+			"Always sort the metric families and series of the telemetry path, even with --web.streaming, and serve the changes between its last two scrapes under <web.telemetry-path>/diff.",
+		).Default("false").Bool()
+		listCollectors = kingpin.Flag(
+			"collector.list",
+			"Print the collectors compiled in and whether they are enabled, then exit.",
+		).Default("false").Bool()
+		validateConfig = kingpin.Flag(
+			"validate",
+			"Check the flags, configuration files and the paths and sockets used by the enabled collectors, print a report and exit. Exits non-zero on problems.",
+		).Default("false").Bool()
 		toolkitFlags = kingpinflag.AddFlags(kingpin.CommandLine, ":9100")
 	)
 
@@ -177,28 +300,76 @@ func main() {
 	if *disableDefaultCollectors {
 		collector.DisableDefaultCollectors()
 	}
+	collector.DisableWSLCollectors(logger)
+	if *autoDisableCollectors {
+		collector.AutoDisableCollectors(logger)
+	}
+	if *listCollectors {
+		printCollectors(os.Stdout)
+		os.Exit(0)
+	}
+	if *validateConfig {
+		cfg := preflightConfig{
+			webConfigFile:        *toolkitFlags.WebConfigFile,
+			endpointsFile:        *endpointsFile,
+			ratesInclude:         *precomputedRatesInclude,
+			listenInterface:      *listenInterface,
+			proxyProtocol:        *proxyProtocol,
+			proxyProtocolTrusted: *proxyProtocolTrusted,
+		}
+		if !preflight(os.Stdout, cfg, logger) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 	level.Info(logger).Log("msg", "Starting node_exporter", "version", version.Info())
-	level.Info(logger).Log("msg", "Build context", "build_context", version.BuildContext())
+	level.Info(logger).Log("msg", "Build context", "build_context", version.BuildContext(), "cgo", cgoEnabled, "libc", libc)
 	if user, err := user.Current(); err == nil && user.Uid == "0" {
 		level.Warn(logger).Log("msg", "Node Exporter is running as root user. This exporter is designed to run as unprivileged user, root is not required.")
 	}
 	runtime.GOMAXPROCS(*maxProcs)
 	level.Debug(logger).Log("msg", "Go MAXPROCS", "procs", runtime.GOMAXPROCS(0))
 
-	http.Handle(*metricsPath, newHandler(!*disableExporterMetrics, *maxRequests, logger))
-	if *metricsPath != "/" {
-		landingConfig := web.LandingConfig{
-			Name:        "Node Exporter",
-			Description: "Prometheus Node Exporter",
-			Version:     version.Info(),
-			Links: []web.LandingLinks{
-				{
-					Address: *metricsPath,
-					Text:    "Metrics",
-				},
-			},
+	ratesInclude, err := regexp.Compile(*precomputedRatesInclude)
+	if err != nil {
+		level.Error(logger).Log("msg", "Invalid --web.precomputed-rates.include", "err", err)
+		os.Exit(1)
+	}
+	rates := newRateTracker(ratesInclude, *precomputedRatesMaxWindow)
+
+	var differ *scrapeDiffer
+	if *stableExposition {
+		differ = newScrapeDiffer()
+	}
+	scrapes := newScrapeTracker()
+	metricsHandler := newHandler(!*disableExporterMetrics, *maxRequests, *streaming && differ == nil, differ, rates, logger)
+	http.Handle(*metricsPath, scrapes.wrap(metricsHandler))
+	if differ != nil {
+		http.Handle(path.Join(*metricsPath, "diff"), differ)
+	}
+	if *endpointsFile != "" {
+		endpoints, err := loadEndpoints(*endpointsFile)
+		if err != nil {
+			level.Error(logger).Log("msg", "Couldn't load endpoints", "file", *endpointsFile, "err", err)
+			os.Exit(1)
 		}
-		landingPage, err := web.NewLandingPage(landingConfig)
+		for _, e := range endpoints {
+			if e.Path == *metricsPath || e.Path == "/" {
+				level.Error(logger).Log("msg", "Endpoint path conflicts with the telemetry path or landing page", "path", e.Path)
+				os.Exit(1)
+			}
+			if _, err := collector.NewNodeCollector(logger, e.Collectors...); err != nil {
+				level.Error(logger).Log("msg", "Invalid collectors for endpoint", "path", e.Path, "err", err)
+				os.Exit(1)
+			}
+			level.Info(logger).Log("msg", "Serving collectors on endpoint", "path", e.Path, "collectors", strings.Join(e.Collectors, ","))
+			// The metrics about the exporter itself are only exposed on the
+			// telemetry path.
+			http.Handle(e.Path, scrapes.wrap(newHandler(false, *maxRequests, *streaming, nil, rates, logger, e.Collectors...)))
+		}
+	}
+	if *metricsPath != "/" {
+		landingPage, err := newLandingPage(*metricsPath)
 		if err != nil {
 			level.Error(logger).Log("err", err)
 			os.Exit(1)
@@ -206,9 +377,54 @@ func main() {
 		http.Handle("/", landingPage)
 	}
 
+	if *warmUpCollectors {
+		level.Info(logger).Log("msg", "Running warm-up collection")
+		duration, err := warmUp(rates, logger)
+		if err != nil {
+			level.Warn(logger).Log("msg", "Warm-up collection returned errors", "err", err)
+		}
+		level.Info(logger).Log("msg", "Warm-up collection done", "duration_seconds", duration.Seconds())
+		if !*disableExporterMetrics {
+			metricsHandler.exporterMetricsRegistry.MustRegister(newWarmUpCollector(duration))
+		}
+	}
+
+	listeners, err := listenAndNotify(context.Background(), listenConfig{
+		addresses:            *toolkitFlags.WebListenAddresses,
+		systemdSocket:        *toolkitFlags.WebSystemdSocket,
+		network:              *listenNetwork,
+		iface:                *listenInterface,
+		proxyProtocol:        *proxyProtocol,
+		proxyProtocolTrusted: *proxyProtocolTrusted,
+	}, scrapes.alive, logger)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+		os.Exit(1)
+	}
+	if *toolkitFlags.WebSystemdSocket {
+		level.Info(logger).Log("msg", "Listening on systemd activated listeners instead of port listeners.")
+	}
 	server := &http.Server{}
-	if err := web.ListenAndServe(server, toolkitFlags, logger); err != nil {
+	if err := web.ServeMultiple(listeners, server, toolkitFlags, logger); err != nil {
 		level.Error(logger).Log("err", err)
 		os.Exit(1)
 	}
 }
+
+// printCollectors prints the collectors compiled in, sorted by name, with
+// whether they are enabled.
+func printCollectors(w io.Writer) {
+	collectors := collector.Collectors()
+	names := make([]string, 0, len(collectors))
+	for name := range collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		state := "disabled"
+		if collectors[name] {
+			state = "enabled"
+		}
+		fmt.Fprintf(w, "%-20s %s\n", name, state)
+	}
+}
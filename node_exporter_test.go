@@ -17,12 +17,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-kit/log"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/procfs"
 )
 
@@ -108,6 +114,80 @@ func TestHandlingOfDuplicatedMetrics(t *testing.T) {
 	}
 }
 
+func TestProtobufExposition(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	rates := newRateTracker(regexp.MustCompile("^$"), time.Minute)
+	server := httptest.NewServer(newHandler(true, 0, false, nil, rates, log.NewNopLogger()))
+	defer server.Close()
+
+	// The Accept header sent by Prometheus with native histograms enabled.
+	const accept = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1"
+	for _, query := range []string{"", "?collect[]=time"} {
+		req, err := http.NewRequest(http.MethodGet, server.URL+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		if format := expfmt.ResponseFormat(resp.Header); format != expfmt.FmtProtoDelim {
+			t.Fatalf("%q: want format %s, got %s", query, expfmt.FmtProtoDelim, format)
+		}
+		names := map[string]bool{}
+		dec := expfmt.NewDecoder(resp.Body, expfmt.FmtProtoDelim)
+		for {
+			var mf dto.MetricFamily
+			if err := dec.Decode(&mf); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%q: couldn't decode response: %s", query, err)
+			}
+			names[mf.GetName()] = true
+		}
+		for _, name := range []string{"node_time_seconds", "go_goroutines", "node_exporter_build_info"} {
+			if !names[name] {
+				t.Errorf("%q: %s missing from the exposition", query, name)
+			}
+		}
+	}
+}
+
+func TestStreamingExposition(t *testing.T) {
+	if _, err := kingpin.CommandLine.Parse([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	rates := newRateTracker(regexp.MustCompile("^node_time_seconds$"), time.Minute)
+	server := httptest.NewServer(newHandler(true, 0, true, nil, rates, log.NewNopLogger()))
+	defer server.Close()
+
+	for _, query := range []string{"", "?collect[]=time"} {
+		resp, err := http.Get(server.URL + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		// The text parser fails if a family is split, which the
+		// streaming must not do.
+		var parser expfmt.TextParser
+		mfs, err := parser.TextToMetricFamilies(resp.Body)
+		if err != nil {
+			t.Fatalf("%q: couldn't parse response: %s", query, err)
+		}
+		for _, name := range []string{"node_time_seconds", "node_scrape_collector_success", "go_goroutines", "node_exporter_build_info"} {
+			if _, ok := mfs[name]; !ok {
+				t.Errorf("%q: %s missing from the exposition", query, name)
+			}
+		}
+	}
+}
+
 func queryExporter(address string) error {
 	resp, err := http.Get(fmt.Sprintf("http://%s/metrics", address))
 	if err != nil {
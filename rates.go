@@ -0,0 +1,189 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// rateTracker records the samples of a set of counters seen in each scrape
+// so that per-second rates can be computed for consumers which can't run
+// PromQL themselves.
+type rateTracker struct {
+	include   *regexp.Regexp
+	maxWindow time.Duration
+
+	mtx    sync.Mutex
+	series map[string]*rateSeries
+}
+
+type rateSeries struct {
+	name    string
+	labels  []*dto.LabelPair
+	samples []rateSample
+}
+
+type rateSample struct {
+	t time.Time
+	v float64
+}
+
+func newRateTracker(include *regexp.Regexp, maxWindow time.Duration) *rateTracker {
+	return &rateTracker{
+		include:   include,
+		maxWindow: maxWindow,
+		series:    map[string]*rateSeries{},
+	}
+}
+
+// observe records the value of all included counters in mfs.
+func (t *rateTracker) observe(now time.Time, mfs []*dto.MetricFamily) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, mf := range mfs {
+		if mf.GetType() != dto.MetricType_COUNTER || !t.include.MatchString(mf.GetName()) {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			key := seriesKey(mf.GetName(), m.GetLabel())
+			s, ok := t.series[key]
+			if !ok {
+				s = &rateSeries{name: mf.GetName(), labels: m.GetLabel()}
+				t.series[key] = s
+			}
+			v := m.GetCounter().GetValue()
+			// Only keep the samples since the last counter reset.
+			if n := len(s.samples); n > 0 && v < s.samples[n-1].v {
+				s.samples = s.samples[:0]
+			}
+			s.samples = append(s.samples, rateSample{t: now, v: v})
+		}
+	}
+
+	cutoff := now.Add(-t.maxWindow)
+	for key, s := range t.series {
+		i := 0
+		for i < len(s.samples) && s.samples[i].t.Before(cutoff) {
+			i++
+		}
+		s.samples = s.samples[i:]
+		if len(s.samples) == 0 {
+			delete(t.series, key)
+		}
+	}
+}
+
+// rates returns gauges with the per-second rate of each tracked counter in
+// names over the given window, for all series with at least two samples in
+// it.
+func (t *rateTracker) rates(now time.Time, window time.Duration, names map[string]bool) []*dto.MetricFamily {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	windowLabel := model.Duration(window).String()
+	cutoff := now.Add(-window)
+	families := map[string]*dto.MetricFamily{}
+	for _, s := range t.series {
+		if !names[s.name] {
+			continue
+		}
+		var first *rateSample
+		for i := range s.samples {
+			if !s.samples[i].t.Before(cutoff) {
+				first = &s.samples[i]
+				break
+			}
+		}
+		last := s.samples[len(s.samples)-1]
+		if first == nil || !last.t.After(first.t) {
+			continue
+		}
+
+		name := strings.TrimSuffix(s.name, "_total") + "_per_second"
+		mf, ok := families[name]
+		if !ok {
+			help := "Per-second rate of " + s.name + " over the requested window, computed by node_exporter."
+			mf = &dto.MetricFamily{Name: &name, Help: &help, Type: dto.MetricType_GAUGE.Enum()}
+			families[name] = mf
+		}
+		value := (last.v - first.v) / last.t.Sub(first.t).Seconds()
+		labels := append([]*dto.LabelPair{{Name: stringPtr("window"), Value: &windowLabel}}, s.labels...)
+		sort.Sort(labelPairSorter(labels))
+		mf.Metric = append(mf.Metric, &dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: &value}})
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		sort.Slice(mf.Metric, func(i, j int) bool {
+			return seriesKey("", mf.Metric[i].GetLabel()) < seriesKey("", mf.Metric[j].GetLabel())
+		})
+		result = append(result, mf)
+	}
+	return result
+}
+
+// rateGatherer records the counters of every gathering in a rateTracker and,
+// if a window is set, adds their rates over that window.
+type rateGatherer struct {
+	prometheus.Gatherer
+	tracker *rateTracker
+	window  time.Duration
+}
+
+// Gather implements prometheus.Gatherer.
+func (g rateGatherer) Gather() ([]*dto.MetricFamily, error) {
+	now := time.Now()
+	mfs, err := g.Gatherer.Gather()
+	g.tracker.observe(now, mfs)
+	if g.window > 0 {
+		names := make(map[string]bool, len(mfs))
+		for _, mf := range mfs {
+			names[mf.GetName()] = true
+		}
+		mfs = append(mfs, g.tracker.rates(now, g.window, names)...)
+		sort.Slice(mfs, func(i, j int) bool { return mfs[i].GetName() < mfs[j].GetName() })
+	}
+	return mfs, err
+}
+
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, l := range labels {
+		b.WriteByte(0)
+		b.WriteString(l.GetName())
+		b.WriteByte(0)
+		b.WriteString(l.GetValue())
+	}
+	return b.String()
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+type labelPairSorter []*dto.LabelPair
+
+func (s labelPairSorter) Len() int           { return len(s) }
+func (s labelPairSorter) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s labelPairSorter) Less(i, j int) bool { return s[i].GetName() < s[j].GetName() }
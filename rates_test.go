@@ -0,0 +1,78 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func counterFamily(name string, value float64) []*dto.MetricFamily {
+	return []*dto.MetricFamily{{
+		Name: &name,
+		Type: dto.MetricType_COUNTER.Enum(),
+		Metric: []*dto.Metric{{
+			Label:   []*dto.LabelPair{{Name: stringPtr("device"), Value: stringPtr("eth0")}},
+			Counter: &dto.Counter{Value: &value},
+		}},
+	}}
+}
+
+func TestRateTracker(t *testing.T) {
+	tracker := newRateTracker(regexp.MustCompile("^node_network_receive_bytes_total$"), 10*time.Minute)
+	start := time.Unix(1000, 0)
+
+	tracker.observe(start, counterFamily("node_network_receive_bytes_total", 100))
+	tracker.observe(start, counterFamily("node_ignored_total", 100))
+	names := map[string]bool{"node_network_receive_bytes_total": true, "node_ignored_total": true}
+	if got := tracker.rates(start, 5*time.Minute, names); len(got) != 0 {
+		t.Fatalf("expected no rates from a single sample, got %v", got)
+	}
+
+	tracker.observe(start.Add(time.Minute), counterFamily("node_network_receive_bytes_total", 700))
+	tracker.observe(start.Add(2*time.Minute), counterFamily("node_network_receive_bytes_total", 1300))
+
+	if got := tracker.rates(start.Add(2*time.Minute), 5*time.Minute, nil); len(got) != 0 {
+		t.Errorf("expected no rates for counters which weren't gathered, got %v", got)
+	}
+	got := tracker.rates(start.Add(2*time.Minute), 5*time.Minute, names)
+	if len(got) != 1 {
+		t.Fatalf("expected one family, got %d", len(got))
+	}
+	if want := "node_network_receive_bytes_per_second"; got[0].GetName() != want {
+		t.Errorf("want name %s, got %s", want, got[0].GetName())
+	}
+	m := got[0].GetMetric()[0]
+	if want := 10.0; m.GetGauge().GetValue() != want {
+		t.Errorf("want rate %v, got %v", want, m.GetGauge().GetValue())
+	}
+	if want := "5m"; m.GetLabel()[1].GetValue() != want {
+		t.Errorf("want window label %s, got %s", want, m.GetLabel()[1].GetValue())
+	}
+
+	// A counter reset discards the samples before it.
+	tracker.observe(start.Add(3*time.Minute), counterFamily("node_network_receive_bytes_total", 10))
+	if got := tracker.rates(start.Add(3*time.Minute), 5*time.Minute, names); len(got) != 0 {
+		t.Errorf("expected no rates after counter reset, got %v", got)
+	}
+
+	// Series not seen for longer than the maximum window are dropped.
+	tracker.observe(start.Add(20*time.Minute), nil)
+	if len(tracker.series) != 0 {
+		t.Errorf("expected stale series to be dropped, got %d", len(tracker.series))
+	}
+}
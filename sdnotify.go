@@ -0,0 +1,124 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// notifySystemd tells systemd that the exporter is ready, for units with
+// Type=notify. If the unit sets WatchdogSec, it then calls alive every half
+// of the watchdog interval and sends a keep-alive each time it returns no
+// error, so that systemd restarts the exporter if scrapes deadlock. It does
+// nothing when not started by systemd.
+func notifySystemd(ctx context.Context, alive func(timeout time.Duration) error, logger log.Logger) {
+	sent, err := daemon.SdNotify(false, daemon.SdNotifyReady)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Couldn't notify systemd", "err", err)
+		return
+	}
+	if !sent {
+		return
+	}
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Invalid systemd watchdog settings", "err", err)
+		return
+	}
+	if interval == 0 {
+		return
+	}
+	level.Info(logger).Log("msg", "Sending systemd watchdog keep-alives while scrapes complete", "interval", interval/2)
+	go watchdogLoop(ctx, func() error { return alive(interval) }, interval/2, func() {
+		if _, err := daemon.SdNotify(false, daemon.SdNotifyWatchdog); err != nil {
+			level.Warn(logger).Log("msg", "Couldn't send systemd watchdog keep-alive", "err", err)
+		}
+	}, logger)
+}
+
+// watchdogLoop calls keepAlive every interval as long as alive returns no
+// error, until ctx is done.
+func watchdogLoop(ctx context.Context, alive func() error, interval time.Duration, keepAlive func(), logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := alive(); err != nil {
+			level.Warn(logger).Log("msg", "Not sending systemd watchdog keep-alive", "err", err)
+		} else {
+			keepAlive()
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scrapeTracker keeps the start times of the scrapes in progress, to tell
+// a deadlocked exporter from an idle one without collecting metrics.
+type scrapeTracker struct {
+	mtx     sync.Mutex
+	next    uint64
+	running map[uint64]time.Time
+	now     func() time.Time
+}
+
+func newScrapeTracker() *scrapeTracker {
+	return &scrapeTracker{running: map[uint64]time.Time{}, now: time.Now}
+}
+
+// wrap returns a handler tracking the requests it passes to h.
+func (t *scrapeTracker) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer t.start()()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// start records a scrape, the returned function records its end.
+func (t *scrapeTracker) start() func() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	id := t.next
+	t.next++
+	t.running[id] = t.now()
+	return func() {
+		t.mtx.Lock()
+		defer t.mtx.Unlock()
+		delete(t.running, id)
+	}
+}
+
+// alive returns an error if a scrape has been in progress for longer than
+// timeout.
+func (t *scrapeTracker) alive(timeout time.Duration) error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	now := t.now()
+	for _, begin := range t.running {
+		if d := now.Sub(begin); d > timeout {
+			return fmt.Errorf("scrape running for %s", d)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+func TestNotifySystemd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", "100000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var checks atomic.Int32
+	notifySystemd(ctx, func(timeout time.Duration) error {
+		if timeout != 100*time.Millisecond {
+			t.Errorf("want timeout of the watchdog interval, got %s", timeout)
+		}
+		if checks.Add(1) > 2 {
+			// Deadlocked scrape, the keep-alives stop.
+			return errors.New("scrape running")
+		}
+		return nil
+	}, log.NewNopLogger())
+
+	buf := make([]byte, 64)
+	var messages []string
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			break
+		}
+		messages = append(messages, string(buf[:n]))
+	}
+	want := []string{"READY=1", "WATCHDOG=1", "WATCHDOG=1"}
+	if len(messages) != len(want) {
+		t.Fatalf("want messages %q, got %q", want, messages)
+	}
+	for i := range want {
+		if messages[i] != want[i] {
+			t.Errorf("message %d: want %q, got %q", i, want[i], messages[i])
+		}
+	}
+}
+
+func TestWatchdogLoopStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchdogLoop(ctx, func() error { return nil }, time.Hour, func() {}, log.NewNopLogger())
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watchdog loop didn't stop")
+	}
+}
+
+func TestScrapeTracker(t *testing.T) {
+	now := time.Date(2023, time.June, 14, 12, 0, 0, 0, time.UTC)
+	tracker := newScrapeTracker()
+	tracker.now = func() time.Time { return now }
+
+	if err := tracker.alive(time.Minute); err != nil {
+		t.Errorf("idle: want no error, got %v", err)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := tracker.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	finished := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics", nil))
+		close(finished)
+	}()
+	<-started
+
+	if err := tracker.alive(time.Minute); err != nil {
+		t.Errorf("scrape just started: want no error, got %v", err)
+	}
+	tracker.mtx.Lock()
+	now = now.Add(2 * time.Minute)
+	tracker.mtx.Unlock()
+	if err := tracker.alive(time.Minute); err == nil {
+		t.Error("scrape running for longer than the timeout: want error")
+	}
+	close(release)
+	<-finished
+	if err := tracker.alive(time.Minute); err != nil {
+		t.Errorf("scrape finished: want no error, got %v", err)
+	}
+}
+
+func TestListenAndNotify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	t.Setenv("NOTIFY_SOCKET", path)
+	alive := func(time.Duration) error { return nil }
+	notified := func() bool {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		return err == nil && string(buf[:n]) == "READY=1"
+	}
+
+	// The address is already in use, systemd must not be told that the
+	// exporter is ready.
+	busy, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	cfg := listenConfig{addresses: []string{busy.Addr().String()}, network: "tcp4"}
+	if _, err := listenAndNotify(context.Background(), cfg, alive, log.NewNopLogger()); err == nil {
+		t.Error("address in use: want error")
+	}
+	if notified() {
+		t.Error("address in use: want no readiness notification")
+	}
+
+	cfg.addresses = []string{"127.0.0.1:0"}
+	listeners, err := listenAndNotify(context.Background(), cfg, alive, log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listeners[0].Close()
+	if !notified() {
+		t.Error("listening: want readiness notification")
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// streamingHandler serves metrics like promhttp.HandlerFor, but encodes and
+// sends the metrics of each collector as soon as it has finished instead of
+// gathering the metrics of all collectors first. This bounds the memory used
+// by a scrape by the output of the largest collector rather than the whole
+// exposition, and slow clients receive data early.
+type streamingHandler struct {
+	// gatherer has the metrics which aren't produced by the node
+	// collector, they are sent first.
+	gatherer prometheus.Gatherer
+	nc       *collector.NodeCollector
+	// rates observes the counters of every scrape, like rateGatherer.
+	rates    *rateTracker
+	inFlight chan struct{}
+	logger   log.Logger
+}
+
+func newStreamingHandler(gatherer prometheus.Gatherer, nc *collector.NodeCollector, rates *rateTracker, maxRequests int, logger log.Logger) *streamingHandler {
+	h := &streamingHandler{
+		gatherer: gatherer,
+		nc:       nc,
+		rates:    rates,
+		logger:   logger,
+	}
+	if maxRequests > 0 {
+		h.inFlight = make(chan struct{}, maxRequests)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *streamingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.inFlight != nil {
+		select {
+		case h.inFlight <- struct{}{}:
+			defer func() { <-h.inFlight }()
+		default:
+			http.Error(w, fmt.Sprintf("Limit of concurrent requests reached (%d), try again later.", cap(h.inFlight)), http.StatusServiceUnavailable)
+			return
+		}
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+	out := io.Writer(w)
+	var gz *gzip.Writer
+	if gzipAccepted(r.Header) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(w)
+		defer gz.Close()
+		out = gz
+	}
+	s := &metricStream{
+		enc:     expfmt.NewEncoder(out, format),
+		written: map[string]bool{},
+		logger:  h.logger,
+	}
+	flush := func() {
+		if gz != nil {
+			gz.Flush()
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+
+	now := time.Now()
+	mfs, err := h.gatherer.Gather()
+	if err != nil {
+		level.Error(h.logger).Log("msg", "Error gathering metrics", "err", err)
+	}
+	s.encode(mfs)
+	flush()
+	h.nc.CollectByCollector(func(metrics []prometheus.Metric) {
+		mfs := gatherMetrics(metrics, h.logger)
+		h.rates.observe(now, mfs)
+		s.encode(mfs)
+		flush()
+	})
+}
+
+// metricStream writes metric families to a response, making sure that each
+// family is written only once.
+type metricStream struct {
+	enc     expfmt.Encoder
+	written map[string]bool
+	// err is the first error writing to the client, the collectors still
+	// run to completion but nothing is written anymore.
+	err    error
+	logger log.Logger
+}
+
+func (s *metricStream) encode(mfs []*dto.MetricFamily) {
+	for _, mf := range mfs {
+		if s.err != nil {
+			return
+		}
+		if s.written[mf.GetName()] {
+			// The text format doesn't allow a family to be split.
+			// Collectors are expected to expose distinct families.
+			level.Error(s.logger).Log("msg", "Dropping metric family exposed by more than one collector", "name", mf.GetName())
+			continue
+		}
+		s.written[mf.GetName()] = true
+		if err := s.enc.Encode(mf); err != nil {
+			level.Debug(s.logger).Log("msg", "Error encoding and sending metric family", "err", err)
+			s.err = err
+		}
+	}
+}
+
+// gatherMetrics turns the metrics of a collector into sorted and validated
+// metric families.
+func gatherMetrics(metrics []prometheus.Metric, logger log.Logger) []*dto.MetricFamily {
+	r := prometheus.NewRegistry()
+	r.MustRegister(metricSlice(metrics))
+	mfs, err := r.Gather()
+	if err != nil {
+		level.Error(logger).Log("msg", "Error gathering metrics", "err", err)
+	}
+	return mfs
+}
+
+// metricSlice is an unchecked collector replaying metrics collected before.
+type metricSlice []prometheus.Metric
+
+func (m metricSlice) Describe(chan<- *prometheus.Desc) {}
+
+func (m metricSlice) Collect(ch chan<- prometheus.Metric) {
+	for _, metric := range m {
+		ch <- metric
+	}
+}
+
+// gzipAccepted returns whether the client accepts gzip-encoded content.
+func gzipAccepted(header http.Header) bool {
+	for _, part := range strings.Split(header.Get("Accept-Encoding"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "gzip" || strings.HasPrefix(part, "gzip;") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// preflightConfig is the configuration checked by --validate in addition to
+// the one of the collectors.
+type preflightConfig struct {
+	webConfigFile        string
+	endpointsFile        string
+	ratesInclude         string
+	listenInterface      string
+	proxyProtocol        bool
+	proxyProtocolTrusted []string
+}
+
+// preflight runs the checks of --validate and writes a report to w. It
+// returns whether all checks passed.
+func preflight(w io.Writer, cfg preflightConfig, logger log.Logger) bool {
+	var results []collector.ValidationResult
+	check := func(name string, err error) {
+		results = append(results, collector.ValidationResult{Check: name, Err: err})
+	}
+
+	if cfg.webConfigFile != "" {
+		check("web.config.file", web.Validate(cfg.webConfigFile))
+	}
+	if cfg.endpointsFile != "" {
+		endpoints, err := loadEndpoints(cfg.endpointsFile)
+		check("web.endpoints-file", err)
+		for _, e := range endpoints {
+			_, err := collector.NewNodeCollector(logger, e.Collectors...)
+			check("web.endpoints-file "+e.Path, err)
+		}
+	}
+	_, err := regexp.Compile(cfg.ratesInclude)
+	check("web.precomputed-rates.include", err)
+	if cfg.listenInterface != "" {
+		_, err := net.InterfaceByName(cfg.listenInterface)
+		check("web.listen-interface", err)
+	}
+	if cfg.proxyProtocol || len(cfg.proxyProtocolTrusted) > 0 {
+		_, err := proxyProtocolListeners(nil, cfg.proxyProtocolTrusted)
+		check("web.proxy-protocol.trusted", err)
+	}
+	results = append(results, collector.Validate(logger)...)
+
+	ok := true
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "FAIL %s: %s\n", r.Check, r.Err)
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "OK   %s\n", r.Check)
+	}
+	return ok
+}
@@ -0,0 +1,55 @@
+// Copyright 2023 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/node_exporter/collector"
+)
+
+// warmUp runs all enabled collectors once, so that the first scrape doesn't
+// pay for filling caches and the rate tracker has a baseline for the
+// counters. It returns how long the collection took.
+func warmUp(rates *rateTracker, logger log.Logger) (time.Duration, error) {
+	begin := time.Now()
+	nc, err := collector.NewNodeCollector(logger)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't create collector: %w", err)
+	}
+	r := prometheus.NewRegistry()
+	if err := r.Register(nc); err != nil {
+		return 0, fmt.Errorf("couldn't register node collector: %w", err)
+	}
+	// Failing collectors are logged by the node collector, errors returned
+	// are about inconsistent metrics which a scrape would return as well.
+	_, err = rateGatherer{Gatherer: r, tracker: rates}.Gather()
+	return time.Since(begin), err
+}
+
+// newWarmUpCollector returns a collector exposing the duration of the warm-up
+// collection.
+func newWarmUpCollector(duration time.Duration) prometheus.Collector {
+	return prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "node_exporter",
+			Name:      "warmup_duration_seconds",
+			Help:      "Duration of the collection run at startup with --web.warm-up.",
+		},
+		func() float64 { return duration.Seconds() },
+	)
+}